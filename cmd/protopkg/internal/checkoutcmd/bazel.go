@@ -0,0 +1,97 @@
+package checkoutcmd
+
+import (
+	"log"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+)
+
+// wellKnownTypesPrefix is the path prefix of the files of the well-known
+// types, which resolve to the @com_google_protobuf repository unless they are
+// in the tree.
+const wellKnownTypesPrefix = "google/protobuf/"
+
+// makeBuildFiles returns the content of a BUILD.bazel file for each directory
+// of the tree (by slash-separated path, empty for the root), with a
+// proto_library of the files of the directory.
+func makeBuildFiles(t tree) map[string][]byte {
+	dirs := make(map[string][]string)
+	for _, filename := range t.paths() {
+		dir := packageDir(filename)
+		dirs[dir] = append(dirs[dir], filename)
+	}
+
+	files := make(map[string][]byte)
+	for dir, filenames := range dirs {
+		srcs := make([]string, len(filenames))
+		depSet := make(map[string]bool)
+		for i, filename := range filenames {
+			srcs[i] = path.Base(filename)
+			for _, imp := range t[filename].GetFile().GetDependency() {
+				if dep, ok := importLabel(t, imp); ok {
+					if dep.Pkg != dir || dep.Repo != "" {
+						depSet[dep.String()] = true
+					}
+				} else {
+					log.Printf("%s: unresolved import not added to the deps of %s: %s", filename, protoLibraryName(dir), imp)
+				}
+			}
+		}
+		deps := make([]string, 0, len(depSet))
+		for dep := range depSet {
+			deps = append(deps, dep)
+		}
+		sort.Strings(deps)
+
+		f := rule.EmptyFile(path.Join(dir, "BUILD.bazel"), dir)
+		load := rule.NewLoad("@rules_proto//proto:defs.bzl")
+		load.Add("proto_library")
+		load.Insert(f, 0)
+
+		r := rule.NewRule("proto_library", protoLibraryName(dir))
+		r.SetAttr("srcs", srcs)
+		r.SetAttr("visibility", []string{"//visibility:public"})
+		if len(deps) > 0 {
+			r.SetAttr("deps", deps)
+		}
+		r.Insert(f)
+		files[dir] = f.Format()
+	}
+	return files
+}
+
+// importLabel returns the label of the proto_library of an imported file:
+// the library of its directory if it is in the tree, else the library of a
+// well-known type.
+func importLabel(t tree, imp string) (label.Label, bool) {
+	if _, ok := t[imp]; ok {
+		dir := packageDir(imp)
+		return label.New("", dir, protoLibraryName(dir)), true
+	}
+	if strings.HasPrefix(imp, wellKnownTypesPrefix) && !strings.Contains(strings.TrimPrefix(imp, wellKnownTypesPrefix), "/") {
+		return label.New("com_google_protobuf", "", strings.TrimSuffix(path.Base(imp), ".proto")+"_proto"), true
+	}
+	return label.NoLabel, false
+}
+
+// packageDir returns the bazel package of a file: its directory, or the empty string for
+// the root.
+func packageDir(filename string) string {
+	if dir := path.Dir(filename); dir != "." {
+		return dir
+	}
+	return ""
+}
+
+// protoLibraryName returns the name of the proto_library of a directory,
+// named after the directory as gazelle does.
+func protoLibraryName(dir string) string {
+	if dir == "" {
+		return "root_proto"
+	}
+	return path.Base(dir) + "_proto"
+}
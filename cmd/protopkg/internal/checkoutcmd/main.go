@@ -0,0 +1,300 @@
+package checkoutcmd
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/protopkg/apis/cmd/protopkg/internal/cli"
+	"github.com/protopkg/apis/pkg/dial"
+	"github.com/protopkg/apis/pkg/protohash"
+	"github.com/protopkg/apis/pkg/store"
+	regpb "github.com/protopkg/apis/protopkg/registry/v1alpha1"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	"google.golang.org/protobuf/proto"
+)
+
+type flagName string
+
+const (
+	protoPackageSetFileFlagName   flagName = "pkgset_file"
+	packagesServerAddressFlagName flagName = "packages_server_address"
+	timeoutFlagName               flagName = "timeout"
+	packageFlagName               flagName = "package"
+	depsFlagName                  flagName = "deps"
+	outputDirFlagName             flagName = "output_dir"
+	overwriteFlagName             flagName = "overwrite"
+	bazelFlagName                 flagName = "bazel"
+)
+
+// Command is the 'checkout' subcommand.
+var Command = &cli.Command{
+	Name:    "checkout",
+	Summary: "Write the source files of a package to a directory at their import paths",
+	Flags:   flags,
+	Run:     run,
+}
+
+var flags = flag.NewFlagSet("checkout", flag.ContinueOnError)
+
+var (
+	protoPackageSetFile   = flags.String(string(protoPackageSetFileFlagName), "", "path to a proto package set file that contains the package (and its dependencies)")
+	packagesServerAddress = flags.String(string(packagesServerAddressFlagName), "", "address of a packages server to get the package (and its dependencies) from")
+	timeout               = flags.Duration(string(timeoutFlagName), time.Minute, "deadline of the packages server requests (0 for none)")
+	packageName           = flags.String(string(packageFlagName), "", "name, ref or id (NAME@HASH) of the package; the most recently committed package is used for a name")
+	deps                  = flags.Bool(string(depsFlagName), false, "also write the files of the transitive dependencies of the package")
+	outputDir             = flags.String(string(outputDirFlagName), "", "path of the directory to write the files to")
+	overwrite             = flags.Bool(string(overwriteFlagName), false, "replace existing files that have different content")
+	bazel                 = flags.Bool(string(bazelFlagName), false, "also write a BUILD.bazel file with a proto_library per directory; the labels assume the output directory is the root of a bazel repository")
+	dialOptions           dial.Options
+)
+
+func init() {
+	dialOptions.RegisterFlags(flags)
+}
+
+func run() error {
+	if *packageName == "" {
+		return errorFlagRequired(packageFlagName)
+	}
+	if *outputDir == "" {
+		return errorFlagRequired(outputDirFlagName)
+	}
+	if (*protoPackageSetFile == "") == (*packagesServerAddress == "") {
+		return cli.UsageErrorf("exactly one of -%s or -%s is required", protoPackageSetFileFlagName, packagesServerAddressFlagName)
+	}
+
+	var lookup lookupFunc
+	if *protoPackageSetFile != "" {
+		pkgset, err := readProtoPackageFileSet(protoPackageSetFileFlagName, *protoPackageSetFile)
+		if err != nil {
+			return err
+		}
+		lookup = pkgsetLookup(pkgset)
+	} else {
+		conn, err := dial.Dial(*packagesServerAddress, &dialOptions)
+		if err != nil {
+			return fmt.Errorf("checkout failed: %w", err)
+		}
+		defer conn.Close()
+		lookup = registryLookup(regpb.NewRegistryClient(conn))
+	}
+
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	pkgs, err := collectPackages(ctx, lookup, *packageName, *deps)
+	if err != nil {
+		return err
+	}
+	for _, pkg := range pkgs {
+		if err := verifyPackage(pkg); err != nil {
+			return fmt.Errorf("%s: %w", store.Ref(pkg), err)
+		}
+	}
+	tree, err := makeTree(pkgs)
+	if err != nil {
+		return err
+	}
+
+	for _, filename := range tree.paths() {
+		if err := writeFile(filepath.Join(*outputDir, filepath.FromSlash(filename)), []byte(tree[filename].SourceCode)); err != nil {
+			return err
+		}
+	}
+	if *bazel {
+		buildFiles := makeBuildFiles(tree)
+		dirs := make([]string, 0, len(buildFiles))
+		for dir := range buildFiles {
+			dirs = append(dirs, dir)
+		}
+		sort.Strings(dirs)
+		for _, dir := range dirs {
+			if err := writeFile(filepath.Join(*outputDir, filepath.FromSlash(dir), "BUILD.bazel"), buildFiles[dir]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// lookupFunc returns the package with the given name, ref or id.
+type lookupFunc func(ctx context.Context, key string) (*pppb.ProtoPackage, error)
+
+// pkgsetLookup looks up the packages of a proto package set.
+func pkgsetLookup(pkgset *pppb.ProtoPackageSet) lookupFunc {
+	return func(ctx context.Context, key string) (*pppb.ProtoPackage, error) {
+		var found []*pppb.ProtoPackage
+		for _, pkg := range pkgset.Packages {
+			if pkg.Name == key || store.Ref(pkg) == key || store.ID(pkg.Name, pkg.Hash) == key {
+				found = append(found, pkg)
+			}
+		}
+		if len(found) == 0 {
+			return nil, fmt.Errorf("package not found in the proto package set: %s", key)
+		}
+		// a name may match several versions: the most recently committed is
+		// used.
+		sort.SliceStable(found, func(i, j int) bool {
+			return found[i].GetArchive().GetCommitTime().AsTime().After(found[j].GetArchive().GetCommitTime().AsTime())
+		})
+		return found[0], nil
+	}
+}
+
+// registryLookup looks up the packages of a packages server.
+func registryLookup(registry regpb.RegistryClient) lookupFunc {
+	return func(ctx context.Context, key string) (*pppb.ProtoPackage, error) {
+		switch {
+		case strings.Contains(key, ":") && !strings.Contains(key, "@"):
+			return registry.LookupProtoPackage(ctx, &regpb.LookupProtoPackageRequest{Ref: key})
+		case strings.Contains(key, "@"):
+			return registry.LookupProtoPackage(ctx, &regpb.LookupProtoPackageRequest{Id: key})
+		default:
+			// the packages are listed most recently committed first.
+			resp, err := registry.ListProtoPackages(ctx, &regpb.ListProtoPackagesRequest{Name: key, PageSize: 1})
+			if err != nil {
+				return nil, err
+			}
+			if len(resp.Packages) == 0 {
+				return nil, fmt.Errorf("package not found: %s", key)
+			}
+			return resp.Packages[0], nil
+		}
+	}
+}
+
+// collectPackages returns the package, and its transitive dependencies if
+// deps is set.
+func collectPackages(ctx context.Context, lookup lookupFunc, key string, deps bool) ([]*pppb.ProtoPackage, error) {
+	pkg, err := lookup(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", key, err)
+	}
+	pkgs := []*pppb.ProtoPackage{pkg}
+	if !deps {
+		return pkgs, nil
+	}
+	seen := map[string]bool{store.Ref(pkg): true}
+	for i := 0; i < len(pkgs); i++ {
+		for _, dep := range pkgs[i].Dependencies {
+			if seen[dep] {
+				continue
+			}
+			seen[dep] = true
+			depPkg, err := lookup(ctx, dep)
+			if err != nil {
+				return nil, fmt.Errorf("%s: dependency %s: %w", store.Ref(pkgs[i]), dep, err)
+			}
+			pkgs = append(pkgs, depPkg)
+		}
+	}
+	return pkgs, nil
+}
+
+// verifyPackage checks the files of the package before they are written:
+// their names must stay in the output directory, and their descriptors match
+// the file and package hashes (see protohash.Verify).  The source code is not
+// part of the hashes.
+func verifyPackage(pkg *pppb.ProtoPackage) error {
+	for _, file := range pkg.Files {
+		name := file.GetFile().GetName()
+		if !isSafePath(name) {
+			return fmt.Errorf("invalid file name: %q", name)
+		}
+		if file.SourceCode == "" {
+			return fmt.Errorf("%s: no source code", name)
+		}
+	}
+	return protohash.Verify(pkg)
+}
+
+// isSafePath reports whether the import path stays in the output directory.
+func isSafePath(name string) bool {
+	return name != "" && path.Clean(name) == name && !path.IsAbs(name) &&
+		name != ".." && !strings.HasPrefix(name, "../") && !strings.Contains(name, "\\")
+}
+
+// tree maps the import paths of the files to write to the files.
+type tree map[string]*pppb.ProtoFile
+
+// makeTree returns the files of the packages.  It is an error if two
+// packages have different files with the same path.
+func makeTree(pkgs []*pppb.ProtoPackage) (tree, error) {
+	t := make(tree)
+	owners := make(map[string]*pppb.ProtoPackage)
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			name := file.GetFile().GetName()
+			if other, ok := t[name]; ok {
+				if other.Hash != file.Hash || other.SourceCode != file.SourceCode {
+					return nil, fmt.Errorf("conflicting versions of %s: %s and %s", name, store.Ref(owners[name]), store.Ref(pkg))
+				}
+				continue
+			}
+			t[name] = file
+			owners[name] = pkg
+		}
+	}
+	return t, nil
+}
+
+// paths returns the sorted paths of the tree.
+func (t tree) paths() []string {
+	paths := make([]string, 0, len(t))
+	for p := range t {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// writeFile writes the file, unless it exists with the same content.  An
+// existing file with different content is only replaced if -overwrite is
+// set.
+func writeFile(filename string, data []byte) error {
+	if existing, err := os.ReadFile(filename); err == nil {
+		if bytes.Equal(existing, data) {
+			return nil
+		}
+		if !*overwrite {
+			return fmt.Errorf("%s: file exists with different content (use -%s to replace it)", filename, overwriteFlagName)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), os.ModePerm); err != nil {
+		return fmt.Errorf("creating directory: %w", err)
+	}
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("writing file: %w", err)
+	}
+	log.Println("wrote:", filename)
+	return nil
+}
+
+func readProtoPackageFileSet(flag flagName, filename string) (*pppb.ProtoPackageSet, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", flag, err)
+	}
+	var msg pppb.ProtoPackageSet
+	if err := proto.Unmarshal(data, &msg); err != nil {
+		return nil, fmt.Errorf("unmarshaling %s: %w", flag, err)
+	}
+	return &msg, nil
+}
+
+func errorFlagRequired(name flagName) error {
+	return cli.UsageErrorf("flag required but not provided: -%s", name)
+}
@@ -0,0 +1,260 @@
+// Package cli implements the command line interface shared by the protopkg
+// subcommands: global flags, dispatch, help, logging and exit codes.
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Exit codes of the protopkg binary.
+const (
+	// ExitOK is returned when the command succeeds.
+	ExitOK = 0
+	// ExitFailure is returned when the command fails.
+	ExitFailure = 1
+	// ExitUsage is returned when the command line is invalid.
+	ExitUsage = 2
+)
+
+const (
+	textLogFormat = "text"
+	jsonLogFormat = "json"
+)
+
+// Command is a protopkg subcommand.
+type Command struct {
+	// Name is the name of the subcommand, as given on the command line.
+	Name string
+	// Summary is a one line description of the subcommand.
+	Summary string
+	// Flags are the flags of the subcommand.  The global flags are added to
+	// the set by Main.
+	Flags *flag.FlagSet
+	// Run runs the subcommand after the flags have been parsed.
+	Run func() error
+}
+
+// usageError is an error caused by an invalid command line.
+type usageError struct {
+	err error
+}
+
+func (e *usageError) Error() string { return e.err.Error() }
+
+func (e *usageError) Unwrap() error { return e.err }
+
+// UsageErrorf returns an error that reports an invalid command line; the
+// command exits with ExitUsage rather than ExitFailure.
+func UsageErrorf(format string, args ...interface{}) error {
+	return &usageError{fmt.Errorf(format, args...)}
+}
+
+// globalFlags are accepted both before and after the subcommand name.
+type globalFlags struct {
+	logFormat string
+	quiet     bool
+}
+
+// register adds the global flags to the set.  The current values are the
+// defaults, such that flags given before the subcommand name are kept.
+func (g *globalFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&g.logFormat, "log_format", g.logFormat, "format of the log messages written to stderr ('text' or 'json')")
+	fs.BoolVar(&g.quiet, "quiet", g.quiet, "only log errors")
+}
+
+// Main runs the subcommand named by the first non-flag argument and returns
+// the exit code.  Logs and errors are written to stderr; stdout is reserved
+// for the output of the subcommand.
+func Main(name string, args []string, commands ...*Command) int {
+	globals := globalFlags{logFormat: textLogFormat}
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	globals.register(fs)
+	fs.Usage = func() { printUsage(fs.Output(), name, fs, commands) }
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitOK
+		}
+		return ExitUsage
+	}
+	args = fs.Args()
+	if len(args) == 0 {
+		fs.Usage()
+		return ExitUsage
+	}
+
+	if args[0] == "help" {
+		if len(args) == 1 {
+			printUsage(os.Stdout, name, fs, commands)
+			return ExitOK
+		}
+		cmd := findCommand(commands, args[1])
+		if cmd == nil {
+			fmt.Fprintf(os.Stderr, "%s: unknown command %q (run '%s help' for a list of commands)\n", name, args[1], name)
+			return ExitUsage
+		}
+		globals.register(cmd.Flags)
+		cmd.Flags.SetOutput(os.Stdout)
+		printCommandUsage(name, cmd)
+		return ExitOK
+	}
+
+	cmd := findCommand(commands, args[0])
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "%s: unknown command %q (run '%s help' for a list of commands)\n", name, args[0], name)
+		return ExitUsage
+	}
+	globals.register(cmd.Flags)
+	cmd.Flags.Init(name+" "+cmd.Name, flag.ContinueOnError)
+	cmd.Flags.SetOutput(os.Stderr)
+	cmd.Flags.Usage = func() { printCommandUsage(name, cmd) }
+
+	if err := cmd.Flags.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitOK
+		}
+		return ExitUsage
+	}
+	if cmd.Flags.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "%s %s: unexpected arguments: %s\n", name, cmd.Name, strings.Join(cmd.Flags.Args(), " "))
+		return ExitUsage
+	}
+
+	logger, err := newLogger(globals, name, cmd.Name, os.Stderr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s %s: %v\n", name, cmd.Name, err)
+		return ExitUsage
+	}
+	logger.install()
+
+	if err := cmd.Run(); err != nil {
+		logger.error(err)
+		var usage *usageError
+		if errors.As(err, &usage) {
+			fmt.Fprintf(os.Stderr, "run '%s %s -help' for usage\n", name, cmd.Name)
+			return ExitUsage
+		}
+		return ExitFailure
+	}
+	return ExitOK
+}
+
+func findCommand(commands []*Command, name string) *Command {
+	for _, cmd := range commands {
+		if cmd.Name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+func printUsage(w io.Writer, name string, fs *flag.FlagSet, commands []*Command) {
+	fmt.Fprintf(w, "usage: %s [global flags] <command> [flags]\n\ncommands:\n", name)
+	sorted := make([]*Command, len(commands))
+	copy(sorted, commands)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	for _, cmd := range sorted {
+		fmt.Fprintf(w, "  %-18s %s\n", cmd.Name, cmd.Summary)
+	}
+	fmt.Fprintf(w, "\nglobal flags:\n")
+	out := fs.Output()
+	fs.SetOutput(w)
+	fs.PrintDefaults()
+	fs.SetOutput(out)
+	fmt.Fprintf(w, "\nrun '%s <command> -help' for the flags of a command\n", name)
+}
+
+func printCommandUsage(name string, cmd *Command) {
+	w := cmd.Flags.Output()
+	fmt.Fprintf(w, "usage: %s %s [flags]\n\n%s\n\nflags:\n", name, cmd.Name, cmd.Summary)
+	cmd.Flags.PrintDefaults()
+}
+
+// logger writes the log messages of a command to stderr, either as plain
+// text or as one json object per line.
+type logger struct {
+	format  string
+	quiet   bool
+	name    string
+	command string
+	out     io.Writer
+	mu      sync.Mutex
+}
+
+func newLogger(globals globalFlags, name, command string, out io.Writer) (*logger, error) {
+	switch globals.logFormat {
+	case textLogFormat, jsonLogFormat:
+	default:
+		return nil, UsageErrorf("invalid -log_format: %q (must be one of %q, %q)", globals.logFormat, textLogFormat, jsonLogFormat)
+	}
+	return &logger{
+		format:  globals.logFormat,
+		quiet:   globals.quiet,
+		name:    name,
+		command: command,
+		out:     out,
+	}, nil
+}
+
+// install makes the logger the output of the standard log package.
+func (l *logger) install() {
+	if l.quiet {
+		log.SetOutput(io.Discard)
+		return
+	}
+	if l.format == jsonLogFormat {
+		log.SetFlags(0)
+		log.SetOutput(writerFunc(func(p []byte) (int, error) {
+			l.write("info", string(p))
+			return len(p), nil
+		}))
+		return
+	}
+	log.SetOutput(l.out)
+}
+
+func (l *logger) error(err error) {
+	if l.format == jsonLogFormat {
+		l.write("error", err.Error())
+		return
+	}
+	fmt.Fprintf(l.out, "%s %s: %v\n", l.name, l.command, err)
+}
+
+// logRecord is a log message in the json format.
+type logRecord struct {
+	Time    string `json:"time"`
+	Level   string `json:"level"`
+	Command string `json:"command"`
+	Msg     string `json:"msg"`
+}
+
+func (l *logger) write(level, msg string) {
+	data, err := json.Marshal(&logRecord{
+		Time:    time.Now().UTC().Format(time.RFC3339Nano),
+		Level:   level,
+		Command: l.command,
+		Msg:     strings.TrimSuffix(msg, "\n"),
+	})
+	if err != nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.out.Write(append(data, '\n'))
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
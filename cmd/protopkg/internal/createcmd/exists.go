@@ -0,0 +1,122 @@
+package createcmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/protopkg/apis/pkg/store"
+	regpb "github.com/protopkg/apis/protopkg/registry/v1alpha1"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// checkProtoPackages asks the server which of the packages it already has.
+// The returned list has the status of each package, in order.  If the server
+// does not implement the Registry service, nil is returned (and all packages
+// are assumed to be missing).
+func checkProtoPackages(ctx context.Context, registry regpb.RegistryClient, pkgs []*pppb.ProtoPackage) ([]*regpb.ProtoPackageStatus, error) {
+	if len(pkgs) == 0 {
+		return nil, nil
+	}
+	req := &regpb.CheckProtoPackagesRequest{}
+	for _, pkg := range pkgs {
+		req.Packages = append(req.Packages, &regpb.ProtoPackageRef{
+			Ref:  store.Ref(pkg),
+			Name: pkg.Name,
+			Hash: pkg.Hash,
+		})
+	}
+	resp, err := registry.CheckProtoPackages(ctx, req)
+	if status.Code(err) == codes.Unimplemented {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("checking existing packages: %w", err)
+	}
+	if len(resp.Packages) != len(pkgs) {
+		return nil, fmt.Errorf("checking existing packages: server returned %d statuses for %d packages", len(resp.Packages), len(pkgs))
+	}
+	return resp.Packages, nil
+}
+
+// filterExistingProtoPackages returns the packages that the server does not
+// have, and the ones that it has.  It is an error if the server has a
+// different version of a package under the same ref.
+func filterExistingProtoPackages(ctx context.Context, registry regpb.RegistryClient, pkgs []*pppb.ProtoPackage) (missing, existing []*pppb.ProtoPackage, err error) {
+	if len(pkgs) == 0 {
+		return pkgs, nil, nil
+	}
+	statuses, err := checkProtoPackages(ctx, registry, pkgs)
+	if err != nil {
+		return nil, nil, err
+	}
+	if statuses == nil {
+		log.Println("server does not support existence checks: uploading all packages")
+		return pkgs, nil, nil
+	}
+	for i, pkg := range pkgs {
+		st := statuses[i]
+		switch st.State {
+		case regpb.ProtoPackageStatus_EXISTS:
+			log.Printf("exists: %s (%s)", pkg.Name, st.Id)
+			existing = append(existing, pkg)
+		case regpb.ProtoPackageStatus_CONFLICT:
+			return nil, nil, fmt.Errorf("%s: the server has %s with hash %s (want %s)", pkg.Name, st.GetPkg().GetRef(), st.Hash, pkg.Hash)
+		default:
+			missing = append(missing, pkg)
+		}
+	}
+	return missing, existing, nil
+}
+
+// resolveProtoPackageIdentities returns the mapping from package key (the
+// name, or the ref of a default package; see store.Key) to the server-side
+// identity of the package.  Packages that the server does not
+// (yet) have are mapped to the empty string.
+func resolveProtoPackageIdentities(ctx context.Context, registry regpb.RegistryClient, pkgs []*pppb.ProtoPackage) (map[string]string, error) {
+	statuses, err := checkProtoPackages(ctx, registry, pkgs)
+	if err != nil || statuses == nil {
+		return nil, err
+	}
+	ids := make(map[string]string)
+	for i, pkg := range pkgs {
+		if statuses[i].State == regpb.ProtoPackageStatus_EXISTS {
+			ids[store.Key(pkg)] = statuses[i].Id
+		} else {
+			ids[store.Key(pkg)] = ""
+		}
+	}
+	return ids, nil
+}
+
+// logProtoPackageIdentities prints the identity mapping.
+func logProtoPackageIdentities(ids map[string]string) {
+	names := make([]string, 0, len(ids))
+	for name := range ids {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		id := ids[name]
+		if id == "" {
+			id = "(pending)"
+		}
+		log.Printf("identity: %s -> %s", name, id)
+	}
+}
+
+func writeIdentitiesFile(ids map[string]string, filename string) error {
+	data, err := json.MarshalIndent(ids, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling identities: %w", err)
+	}
+	if err := os.WriteFile(filename, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("writing identities: %w", err)
+	}
+	return nil
+}
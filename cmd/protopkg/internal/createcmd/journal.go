@@ -0,0 +1,135 @@
+package createcmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+)
+
+// journalEntry records that a package was accepted by a server.  The journal
+// file is a sequence of json lines.
+type journalEntry struct {
+	// Address is the packages server address.
+	Address string `json:"address"`
+	// Name is the package name.
+	Name string `json:"name"`
+	// Hash is the package hash.
+	Hash string `json:"hash"`
+	// Operation is the name of the operation that accepted the package, or
+	// empty if the server reported that it already had the package.
+	Operation string `json:"operation"`
+	// Time is when the package was accepted.
+	Time time.Time `json:"time"`
+}
+
+// uploadJournal remembers which packages have been accepted by which server,
+// such that re-running an upload skips them.
+type uploadJournal struct {
+	filename string
+	entries  map[string]*journalEntry
+}
+
+// defaultJournalFile returns the default location of the journal file in the
+// user cache directory, or the empty string if there is none.
+func defaultJournalFile() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "protopkg", "upload_journal.jsonl")
+}
+
+// readUploadJournal reads the journal file.  An empty filename disables the
+// journal.
+func readUploadJournal(filename string) (*uploadJournal, error) {
+	j := &uploadJournal{
+		filename: filename,
+		entries:  make(map[string]*journalEntry),
+	}
+	if filename == "" {
+		return j, nil
+	}
+
+	f, err := os.Open(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening upload journal: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		var entry journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// a partially written line (e.g. interrupted process) is skipped
+			continue
+		}
+		j.entries[journalKey(entry.Address, entry.Name, entry.Hash)] = &entry
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading upload journal: %w", err)
+	}
+	return j, nil
+}
+
+// lookup returns the journal entry of the package, or nil if the server has
+// not accepted it.
+func (j *uploadJournal) lookup(address string, pkg *pppb.ProtoPackage) *journalEntry {
+	return j.entries[journalKey(address, pkg.Name, pkg.Hash)]
+}
+
+// record appends entries for the packages accepted in the given operation
+// (empty for the packages that the server already has).
+func (j *uploadJournal) record(address, operation string, pkgs []*pppb.ProtoPackage) error {
+	if len(pkgs) == 0 {
+		return nil
+	}
+	now := time.Now().UTC()
+	entries := make([]*journalEntry, len(pkgs))
+	for i, pkg := range pkgs {
+		entries[i] = &journalEntry{
+			Address:   address,
+			Name:      pkg.Name,
+			Hash:      pkg.Hash,
+			Operation: operation,
+			Time:      now,
+		}
+		j.entries[journalKey(address, pkg.Name, pkg.Hash)] = entries[i]
+	}
+	if j.filename == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(j.filename), 0755); err != nil {
+		return fmt.Errorf("creating upload journal directory: %w", err)
+	}
+	f, err := os.OpenFile(j.filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("opening upload journal: %w", err)
+	}
+	defer f.Close()
+
+	for _, entry := range entries {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("marshaling journal entry: %w", err)
+		}
+		if _, err := f.Write(append(data, '\n')); err != nil {
+			return fmt.Errorf("writing upload journal: %w", err)
+		}
+	}
+	return f.Sync()
+}
+
+func journalKey(address, name, hash string) string {
+	return address + " " + name + " " + hash
+}
@@ -0,0 +1,249 @@
+package createcmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	longrunningpb "cloud.google.com/go/longrunning/autogen/longrunningpb"
+	"github.com/protopkg/apis/cmd/protopkg/internal/cli"
+	"github.com/protopkg/apis/pkg/chunk"
+	"github.com/protopkg/apis/pkg/deporder"
+	"github.com/protopkg/apis/pkg/dial"
+	regpb "github.com/protopkg/apis/protopkg/registry/v1alpha1"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+type flagName string
+
+const (
+	protoPackageSetFileFlagName   flagName = "output_file"
+	packagesServerAddressFlagName flagName = "packages_server_address"
+	protoOutputFileFlagName       flagName = "proto_out"
+	jsonOutputFileFlagName        flagName = "json_out"
+	journalFileFlagName           flagName = "journal_file"
+	maxAttemptsFlagName           flagName = "max_attempts"
+	initialBackoffFlagName        flagName = "initial_backoff"
+	maxBackoffFlagName            flagName = "max_backoff"
+	timeoutFlagName               flagName = "timeout"
+	dryRunFlagName                flagName = "dry_run"
+	planFormatFlagName            flagName = "plan_format"
+	waitFlagName                  flagName = "wait"
+	waitTimeoutFlagName           flagName = "wait_timeout"
+	pollIntervalFlagName          flagName = "poll_interval"
+	compressionFlagName           flagName = "compression"
+	maxMessageSizeFlagName        flagName = "max_message_size"
+	checkExistingFlagName         flagName = "check_existing"
+	identitiesOutputFileFlagName  flagName = "identities_out"
+	signaturesFileFlagName        flagName = "signatures_file"
+)
+
+// Command is the 'create' subcommand.
+var Command = &cli.Command{
+	Name:    "create",
+	Summary: "Upload a proto package set to a packages server",
+	Flags:   flags,
+	Run:     run,
+}
+
+var flags = flag.NewFlagSet("create", flag.ContinueOnError)
+
+var (
+	protoPackageSetFile   = flags.String(string(protoPackageSetFileFlagName), "", "path to the proto package set file")
+	packagesServerAddress = flags.String(string(packagesServerAddressFlagName), "", "address of the packages server")
+	protoOutputFile       = flags.String(string(protoOutputFileFlagName), "", "path of file to write the generated proto file")
+	jsonOutputFile        = flags.String(string(jsonOutputFileFlagName), "", "path of file to write the generated json file")
+	journalFile           = flags.String(string(journalFileFlagName), defaultJournalFile(), "path of the journal of accepted packages; packages found in the journal are not uploaded again (empty to disable)")
+	maxAttempts           = flags.Int(string(maxAttemptsFlagName), 5, "maximum number of upload attempts on retryable errors")
+	initialBackoff        = flags.Duration(string(initialBackoffFlagName), time.Second, "delay before the first retry, doubled on each subsequent retry")
+	maxBackoff            = flags.Duration(string(maxBackoffFlagName), 30*time.Second, "maximum delay between retries")
+	timeout               = flags.Duration(string(timeoutFlagName), 5*time.Minute, "deadline of a single upload attempt (0 for none)")
+	dryRun                = flags.Bool(string(dryRunFlagName), false, "validate the package set and print the upload plan (asking the server which packages it has, unless -check_existing=false), without sending anything")
+	planFormat            = flags.String(string(planFormatFlagName), textPlanFormat, "format of the -dry_run plan ('text' or 'json')")
+	wait                  = flags.Bool(string(waitFlagName), false, "wait for the operation returned by the server to complete, and fail if the operation fails")
+	waitTimeout           = flags.Duration(string(waitTimeoutFlagName), 10*time.Minute, "maximum time to -wait for the operation (0 for none)")
+	pollInterval          = flags.Duration(string(pollIntervalFlagName), 2*time.Second, "interval between operation status requests when using -wait")
+	compression           = flags.String(string(compressionFlagName), noCompression, "compression of the upload stream ('gzip' or 'none'); the server must support it")
+	maxMessageSize        = flags.Int(string(maxMessageSizeFlagName), chunk.DefaultMaxMessageSize, "maximum size in bytes of an (uncompressed) upload message; larger packages are sent in file-level chunks")
+	checkExisting         = flags.Bool(string(checkExistingFlagName), true, "ask the server which packages it already has, and upload only the missing ones")
+	identitiesOutputFile  = flags.String(string(identitiesOutputFileFlagName), "", "path of json file to write the mapping of package name to server-side identity, once the operation succeeded (see -wait)")
+	signaturesFile        = flags.String(string(signaturesFileFlagName), "", "path of a signatures file written by 'protopkg sign', uploaded along with the packages")
+	dialOptions           dial.Options
+)
+
+func init() {
+	dialOptions.RegisterFlags(flags)
+}
+
+func run() error {
+	pkg, err := readProtoPackageFileSet(protoPackageSetFileFlagName, *protoPackageSetFile)
+	if err != nil {
+		return err
+	}
+
+	if err := validateProtoPackageSet(pkg); err != nil {
+		return err
+	}
+
+	if pkg.Packages, err = deporder.Packages(pkg.Packages); err != nil {
+		return err
+	}
+
+	journal, err := readUploadJournal(*journalFile)
+	if err != nil {
+		return err
+	}
+
+	transfer, err := makeTransferOptions(*compression, *maxMessageSize)
+	if err != nil {
+		return err
+	}
+
+	client, conn, err := createPackagesClient(*packagesServerAddress, &dialOptions)
+	if err != nil {
+		return fmt.Errorf("create failed: %v", err)
+	}
+	defer conn.Close()
+
+	var registry regpb.RegistryClient
+	if *checkExisting {
+		registry = regpb.NewRegistryClient(conn)
+	}
+	ctx := context.Background()
+
+	if *dryRun {
+		plan, err := makeUploadPlan(ctx, pkg, *packagesServerAddress, journal, registry, transfer)
+		if err != nil {
+			return err
+		}
+		if err := writeUploadPlan(os.Stdout, plan, *planFormat); err != nil {
+			return err
+		}
+		if n := plan.conflicts(); n > 0 {
+			return fmt.Errorf("%d package(s) conflict with the versions on %s", n, *packagesServerAddress)
+		}
+		return nil
+	}
+
+	policy := &retryPolicy{
+		maxAttempts:    *maxAttempts,
+		initialBackoff: *initialBackoff,
+		maxBackoff:     *maxBackoff,
+		attemptTimeout: *timeout,
+	}
+	response, sent, err := uploadProtoPackages(ctx, pkg, client, registry, *packagesServerAddress, journal, policy, transfer)
+	if err != nil {
+		return fmt.Errorf("send failed: %v", err)
+	}
+
+	if *signaturesFile != "" {
+		if err := uploadSignatures(ctx, regpb.NewRegistryClient(conn), *signaturesFile, pkg.Packages); err != nil {
+			return err
+		}
+	}
+
+	if *wait {
+		response, err = waitForOperation(ctx, longrunningpb.NewOperationsClient(conn), response, *pollInterval, *waitTimeout)
+		if err != nil {
+			return fmt.Errorf("wait failed: %v", err)
+		}
+	}
+
+	if *protoOutputFile != "" {
+		if err := writeProtoOutputFile(response, *protoOutputFile); err != nil {
+			return fmt.Errorf("write proto output file failed: %v", err)
+		}
+	}
+	if *jsonOutputFile != "" {
+		if err := writeJsonOutputFile(response, *jsonOutputFile); err != nil {
+			return fmt.Errorf("write json output file failed: %v", err)
+		}
+	}
+
+	if err := operationError(response); err != nil {
+		return err
+	}
+	if !response.Done {
+		log.Printf("operation %s is still running: packages are journaled and identities resolved with -%s", response.Name, waitFlagName)
+		return nil
+	}
+	if *wait {
+		logOperationResponse(response)
+	}
+
+	// the operation succeeded: the server has the packages.
+	if err := recordAcceptedProtoPackages(journal, *packagesServerAddress, response, sent); err != nil {
+		return err
+	}
+	if registry != nil {
+		ids, err := resolveProtoPackageIdentities(ctx, registry, pkg.Packages)
+		if err != nil {
+			return err
+		}
+		logProtoPackageIdentities(ids)
+		if *identitiesOutputFile != "" && ids != nil {
+			if err := writeIdentitiesFile(ids, *identitiesOutputFile); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func createPackagesClient(address string, opts *dial.Options) (pppb.PackagesClient, *grpc.ClientConn, error) {
+	conn, err := dial.Dial(address, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pppb.NewPackagesClient(conn), conn, nil
+}
+
+func readProtoPackageFileSet(flag flagName, filename string) (*pppb.ProtoPackageSet, error) {
+	if filename == "" {
+		return nil, fmt.Errorf("flag required but not provided: %s", flag)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", flag, err)
+	}
+	var msg pppb.ProtoPackageSet
+	if err := proto.Unmarshal(data, &msg); err != nil {
+		return nil, fmt.Errorf("unmarshaling %s: %w", flag, err)
+	}
+	return &msg, nil
+}
+
+func writeProtoOutputFile(msg proto.Message, filename string) error {
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("marshaling generated data: %v", err)
+	}
+	if err := os.WriteFile(filename, data, os.ModePerm); err != nil {
+		return fmt.Errorf("writing proto file: %w", err)
+	}
+	log.Println("wrote:", filename)
+	return nil
+}
+
+func writeJsonOutputFile(msg proto.Message, filename string) error {
+	marshaler := protojson.MarshalOptions{
+		Multiline: true,
+		Indent:    "  ",
+	}
+	jsonstr, err := marshaler.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("marshaling json: %w", err)
+	}
+	if err := os.WriteFile(filename, []byte(jsonstr), os.ModePerm); err != nil {
+		return fmt.Errorf("writing json file: %w", err)
+	}
+	log.Println("wrote:", filename)
+	return nil
+}
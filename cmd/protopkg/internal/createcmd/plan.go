@@ -0,0 +1,172 @@
+package createcmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"text/tabwriter"
+
+	"github.com/protopkg/apis/pkg/chunk"
+	regpb "github.com/protopkg/apis/protopkg/registry/v1alpha1"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	textPlanFormat = "text"
+	jsonPlanFormat = "json"
+)
+
+// uploadPlan describes what an upload would send, without sending it.
+type uploadPlan struct {
+	// Address is the packages server address.
+	Address string `json:"address"`
+	// Packages lists the packages of the set, in upload order.
+	Packages []*plannedPackage `json:"packages"`
+}
+
+const (
+	sendAction     = "send"
+	skipAction     = "skip"
+	conflictAction = "conflict"
+)
+
+type plannedPackage struct {
+	Name  string `json:"name"`
+	Hash  string `json:"hash"`
+	Files int    `json:"files"`
+	Size  int    `json:"size"`
+	// Chunks is the number of request messages the package is sent in.
+	Chunks       int      `json:"chunks"`
+	Dependencies []string `json:"dependencies"`
+	// Action is what the upload would do with the package: 'send', 'skip'
+	// or 'conflict' (the upload fails).
+	Action string `json:"action"`
+	// Server is the state of the package reported by the server ('EXISTS',
+	// 'MISSING' or 'CONFLICT'), or empty if the server was not asked or
+	// does not support existence checks.
+	Server string `json:"server,omitempty"`
+	// Journaled is true if the journal records that the server accepted the
+	// package.
+	Journaled bool `json:"journaled"`
+	// Operation is the operation that accepted the package, if journaled.
+	Operation string `json:"operation,omitempty"`
+}
+
+// makeUploadPlan creates the plan for uploading the package set to the given
+// server address.  Like the upload, the plan skips the journaled packages and
+// the ones that the server reports to exist (if registry is not nil).  It
+// fails if a package cannot be split to fit the maximum message size.
+func makeUploadPlan(ctx context.Context, pkgset *pppb.ProtoPackageSet, address string, journal *uploadJournal, registry regpb.RegistryClient, transfer *transferOptions) (*uploadPlan, error) {
+	var statuses []*regpb.ProtoPackageStatus
+	if registry != nil {
+		var err error
+		if statuses, err = checkProtoPackages(ctx, registry, pkgset.Packages); err != nil {
+			return nil, err
+		}
+		if statuses == nil {
+			log.Println("server does not support existence checks: all packages not in the journal would be sent")
+		}
+	}
+
+	plan := &uploadPlan{Address: address}
+	for i, pkg := range pkgset.Packages {
+		chunks, err := chunk.Split(pkg, transfer.maxMessageSize)
+		if err != nil {
+			return nil, err
+		}
+		planned := &plannedPackage{
+			Name:         pkg.Name,
+			Hash:         pkg.Hash,
+			Files:        len(pkg.Files),
+			Size:         proto.Size(&pppb.CreateProtoPackageRequest{Pkg: pkg}),
+			Chunks:       len(chunks),
+			Dependencies: pkg.Dependencies,
+			Action:       sendAction,
+		}
+		if planned.Dependencies == nil {
+			planned.Dependencies = []string{}
+		}
+		if statuses != nil {
+			planned.Server = statuses[i].State.String()
+			switch statuses[i].State {
+			case regpb.ProtoPackageStatus_EXISTS:
+				planned.Action = skipAction
+			case regpb.ProtoPackageStatus_CONFLICT:
+				planned.Action = conflictAction
+			}
+		}
+		if entry := journal.lookup(address, pkg); entry != nil {
+			planned.Journaled = true
+			planned.Operation = entry.Operation
+			planned.Action = skipAction
+		}
+		plan.Packages = append(plan.Packages, planned)
+	}
+	return plan, nil
+}
+
+// conflicts returns the number of packages that the server has a different
+// version of, which fail the upload.
+func (p *uploadPlan) conflicts() int {
+	n := 0
+	for _, pkg := range p.Packages {
+		if pkg.Action == conflictAction {
+			n++
+		}
+	}
+	return n
+}
+
+// writeUploadPlan prints the plan in the given format.
+func writeUploadPlan(w io.Writer, plan *uploadPlan, format string) error {
+	switch format {
+	case textPlanFormat:
+		return writeTextUploadPlan(w, plan)
+	case jsonPlanFormat:
+		data, err := json.MarshalIndent(plan, "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshaling plan: %w", err)
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	default:
+		return fmt.Errorf("invalid plan format %q (must be one of %q, %q)", format, textPlanFormat, jsonPlanFormat)
+	}
+}
+
+// writeTextUploadPlan prints the plan as a table, with a row per package.
+// The server and journal columns show why a package is skipped.
+func writeTextUploadPlan(w io.Writer, plan *uploadPlan) error {
+	var send, skip, size int
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ACTION\tPACKAGE\tFILES\tBYTES\tCHUNKS\tSERVER\tJOURNAL")
+	for _, pkg := range plan.Packages {
+		switch pkg.Action {
+		case sendAction:
+			send++
+			size += pkg.Size
+		case skipAction:
+			skip++
+		}
+		server := pkg.Server
+		if server == "" {
+			server = "-"
+		}
+		journal := "-"
+		if pkg.Journaled {
+			journal = "exists"
+			if pkg.Operation != "" {
+				journal = "accepted in " + pkg.Operation
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%s\t%s\n", pkg.Action, pkg.Name, pkg.Files, pkg.Size, pkg.Chunks, server, journal)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "plan: %d package(s) to send (%d bytes), %d already on %s, %d conflict(s)\n", send, size, skip, plan.Address, plan.conflicts())
+	return err
+}
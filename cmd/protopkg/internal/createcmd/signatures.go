@@ -0,0 +1,49 @@
+package createcmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/protopkg/apis/pkg/signature"
+	"github.com/protopkg/apis/pkg/store"
+	regpb "github.com/protopkg/apis/protopkg/registry/v1alpha1"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+)
+
+// uploadSignatures sends the signature envelopes of the packages of the set
+// (as written by 'protopkg sign') to the server.  Envelopes of packages that
+// are not in the set are ignored.
+func uploadSignatures(ctx context.Context, registry regpb.RegistryClient, filename string, pkgs []*pppb.ProtoPackage) error {
+	list, err := signature.ReadEnvelopes(filename)
+	if err != nil {
+		return err
+	}
+	hashes := make(map[string]string)
+	for _, pkg := range pkgs {
+		hashes[store.Ref(pkg)] = pkg.Hash
+	}
+
+	req := &regpb.PutProtoPackageSignaturesRequest{}
+	for _, env := range list.Envelopes {
+		stmt, err := signature.ParseStatement(env)
+		if err != nil {
+			return fmt.Errorf("%s: %w", filename, err)
+		}
+		if hash, ok := hashes[stmt.Ref]; !ok || hash != stmt.Hash {
+			log.Printf("skipped signature: %s (%s) is not in the package set", stmt.Name, stmt.Hash)
+			continue
+		}
+		req.Envelopes = append(req.Envelopes, env)
+	}
+	if len(req.Envelopes) == 0 {
+		return fmt.Errorf("%s: no signatures for the packages of the set", filename)
+	}
+
+	resp, err := registry.PutProtoPackageSignatures(ctx, req)
+	if err != nil {
+		return fmt.Errorf("uploading signatures: %w", err)
+	}
+	log.Printf("uploaded: %d signature envelope(s)", resp.Stored)
+	return nil
+}
@@ -0,0 +1,246 @@
+package createcmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	longrunningpb "cloud.google.com/go/longrunning/autogen/longrunningpb"
+	"github.com/protopkg/apis/pkg/chunk"
+	regpb "github.com/protopkg/apis/protopkg/registry/v1alpha1"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// retryPolicy configures how failed uploads are retried.
+type retryPolicy struct {
+	// maxAttempts is the total number of attempts (including the first).
+	maxAttempts int
+	// initialBackoff is the delay before the first retry.  It doubles on each
+	// subsequent retry, up to maxBackoff.
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	// attemptTimeout is the deadline of a single attempt.
+	attemptTimeout time.Duration
+}
+
+// transferOptions configures how packages are sent on the stream.
+type transferOptions struct {
+	// compression is the name of the grpc compressor ('gzip'), or empty for
+	// none.
+	compression string
+	// maxMessageSize is the maximum size of a single request message.
+	// Packages that are larger are sent in file-level chunks.
+	maxMessageSize int
+}
+
+// noCompression is the -compression value that disables compression.
+const noCompression = "none"
+
+// makeTransferOptions validates the compression and message size flags.
+func makeTransferOptions(compression string, maxMessageSize int) (*transferOptions, error) {
+	if maxMessageSize <= 0 {
+		return nil, fmt.Errorf("invalid -%s: %d (must be positive)", maxMessageSizeFlagName, maxMessageSize)
+	}
+	switch compression {
+	case noCompression, "":
+		return &transferOptions{maxMessageSize: maxMessageSize}, nil
+	case gzip.Name:
+		return &transferOptions{compression: gzip.Name, maxMessageSize: maxMessageSize}, nil
+	default:
+		return nil, fmt.Errorf("invalid -%s: %q (must be one of %q, %q)", compressionFlagName, compression, gzip.Name, noCompression)
+	}
+}
+
+// callOptions returns the grpc call options of the upload stream.
+func (o *transferOptions) callOptions() []grpc.CallOption {
+	opts := []grpc.CallOption{grpc.MaxCallSendMsgSize(o.maxMessageSize)}
+	if o.compression != "" {
+		opts = append(opts, grpc.UseCompressor(o.compression))
+	}
+	return opts
+}
+
+// backoff returns the delay before the given retry (1-based).
+func (p *retryPolicy) backoff(retry int) time.Duration {
+	d := p.initialBackoff
+	for i := 1; i < retry && d < p.maxBackoff; i++ {
+		d *= 2
+	}
+	if d > p.maxBackoff {
+		d = p.maxBackoff
+	}
+	return d
+}
+
+// messageSizeError is part of the message of the ResourceExhausted errors
+// that grpc reports for a message exceeding the maximum size, on either side
+// (e.g. 'grpc: received message larger than max (5242880 vs. 4194304)').
+const messageSizeError = "message larger than max"
+
+// isRetryable reports whether the error is transient, such that the upload may
+// succeed if it is repeated.  ResourceExhausted is transient if the server is
+// out of quota, but not if a message exceeds the maximum size: the same
+// message would be rejected again (lower -max_message_size instead).
+func isRetryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.Aborted, codes.DeadlineExceeded:
+		return true
+	case codes.ResourceExhausted:
+		return !strings.Contains(status.Convert(err).Message(), messageSizeError)
+	}
+	return false
+}
+
+// uploadProtoPackages sends the packages that the journal does not know to
+// have been accepted by the server, and that the server reports as missing
+// (if registry is not nil).  Packages are sent in the order of the set, which
+// must be dependency-ordered.  The packages that the server confirms to
+// exist are journaled; the sent packages are returned, to be journaled once
+// the operation succeeds (see recordAcceptedProtoPackages).  If none remain,
+// the upload is a no-op and the operation that accepted the last package is
+// returned.
+func uploadProtoPackages(ctx context.Context, pkgset *pppb.ProtoPackageSet, client pppb.PackagesClient, registry regpb.RegistryClient, address string, journal *uploadJournal, policy *retryPolicy, transfer *transferOptions) (*longrunningpb.Operation, []*pppb.ProtoPackage, error) {
+	var pending []*pppb.ProtoPackage
+	var accepted *journalEntry
+	for _, pkg := range pkgset.Packages {
+		if entry := journal.lookup(address, pkg); entry != nil {
+			if entry.Operation != "" {
+				log.Printf("skipped: %s (accepted in %s)", pkg.Name, entry.Operation)
+				accepted = entry
+			} else {
+				log.Printf("skipped: %s (known to exist)", pkg.Name)
+			}
+			continue
+		}
+		pending = append(pending, pkg)
+	}
+	if registry != nil {
+		var existing []*pppb.ProtoPackage
+		var err error
+		if pending, existing, err = filterExistingProtoPackages(ctx, registry, pending); err != nil {
+			return nil, nil, err
+		}
+		if err := journal.record(address, "", existing); err != nil {
+			return nil, nil, err
+		}
+	}
+	if len(pending) == 0 {
+		log.Println("nothing to upload: all packages have been accepted")
+		if accepted == nil {
+			return &longrunningpb.Operation{Done: true}, nil, nil
+		}
+		// journaled operations have succeeded
+		return &longrunningpb.Operation{Name: accepted.Operation, Done: true}, nil, nil
+	}
+
+	operation, err := sendProtoPackageWithRetry(ctx, pending, client, policy, transfer)
+	if err != nil {
+		return nil, nil, err
+	}
+	return operation, pending, nil
+}
+
+// recordAcceptedProtoPackages journals the sent packages if the operation has
+// completed successfully.  Packages of an operation that is still running or
+// failed are not journaled, such that the next run sends them again.
+func recordAcceptedProtoPackages(journal *uploadJournal, address string, operation *longrunningpb.Operation, pkgs []*pppb.ProtoPackage) error {
+	if len(pkgs) == 0 {
+		return nil
+	}
+	if !operation.Done || operation.GetError() != nil {
+		log.Printf("not journaled: operation %s has not succeeded (yet)", operation.Name)
+		return nil
+	}
+	return journal.record(address, operation.Name, pkgs)
+}
+
+// sendProtoPackageWithRetry sends the packages, repeating the complete stream
+// with exponential backoff if it fails with a retryable error.
+func sendProtoPackageWithRetry(ctx context.Context, pkgs []*pppb.ProtoPackage, client pppb.PackagesClient, policy *retryPolicy, transfer *transferOptions) (*longrunningpb.Operation, error) {
+	chunks, chunked, err := splitProtoPackages(pkgs, transfer.maxMessageSize)
+	if err != nil {
+		return nil, err
+	}
+	if chunked {
+		ctx = metadata.AppendToOutgoingContext(ctx, chunk.MetadataKey, chunk.MetadataValue)
+	}
+
+	for attempt := 1; ; attempt++ {
+		operation, err := sendProtoPackageAttempt(ctx, chunks, client, policy.attemptTimeout, transfer)
+		if err == nil {
+			return operation, nil
+		}
+		if !isRetryable(err) || attempt >= policy.maxAttempts {
+			return nil, fmt.Errorf("attempt %d/%d: %w", attempt, policy.maxAttempts, err)
+		}
+		delay := policy.backoff(attempt)
+		log.Printf("attempt %d/%d failed (retrying in %v): %v", attempt, policy.maxAttempts, delay, err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// splitProtoPackages splits the packages that exceed the maximum message size
+// into chunks.  It reports whether any package was split.
+func splitProtoPackages(pkgs []*pppb.ProtoPackage, maxSize int) ([][]*pppb.ProtoPackage, bool, error) {
+	chunked := false
+	result := make([][]*pppb.ProtoPackage, len(pkgs))
+	for i, pkg := range pkgs {
+		chunks, err := chunk.Split(pkg, maxSize)
+		if err != nil {
+			return nil, false, err
+		}
+		if len(chunks) > 1 {
+			log.Printf("chunked: %s (%d bytes, %d chunks)", pkg.Name, proto.Size(pkg), len(chunks))
+			chunked = true
+		}
+		result[i] = chunks
+	}
+	return result, chunked, nil
+}
+
+func sendProtoPackageAttempt(ctx context.Context, pkgs [][]*pppb.ProtoPackage, client pppb.PackagesClient, timeout time.Duration, transfer *transferOptions) (*longrunningpb.Operation, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	stream, err := client.CreateProtoPackage(ctx, transfer.callOptions()...)
+	if err != nil {
+		return nil, fmt.Errorf("creating client stream call: %w", err)
+	}
+send:
+	for _, chunks := range pkgs {
+		for _, pkg := range chunks {
+			req := &pppb.CreateProtoPackageRequest{Pkg: pkg}
+			if err := stream.Send(req); err != nil {
+				if errors.Is(err, io.EOF) {
+					// the server ended the stream, the actual error is
+					// reported by CloseAndRecv
+					break send
+				}
+				return nil, fmt.Errorf("sending package %s: %w", pkg.Name, err)
+			}
+		}
+		log.Println("uploaded:", chunks[0].Name)
+	}
+	operation, err := stream.CloseAndRecv()
+	if err != nil {
+		return nil, fmt.Errorf("close-recv stream call: %w", err)
+	}
+	return operation, nil
+}
@@ -0,0 +1,55 @@
+package createcmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestIsRetryable(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "unavailable", err: status.Error(codes.Unavailable, "connection refused"), want: true},
+		{name: "aborted", err: status.Error(codes.Aborted, "conflict"), want: true},
+		{name: "deadline", err: status.Error(codes.DeadlineExceeded, "context deadline exceeded"), want: true},
+		{name: "quota", err: status.Error(codes.ResourceExhausted, "too many uploads"), want: true},
+		{name: "received message size", err: status.Error(codes.ResourceExhausted, "grpc: received message larger than max (5242880 vs. 4194304)")},
+		{name: "sent message size", err: status.Error(codes.ResourceExhausted, "grpc: trying to send message larger than max (5242880 vs. 4194304)")},
+		{name: "wrapped message size", err: fmt.Errorf("close-recv stream call: %w", status.Error(codes.ResourceExhausted, "grpc: received message larger than max (5242880 vs. 4194304)"))},
+		{name: "wrapped unavailable", err: fmt.Errorf("sending package acme.v1: %w", status.Error(codes.Unavailable, "transport is closing")), want: true},
+		{name: "invalid argument", err: status.Error(codes.InvalidArgument, "invalid proto package")},
+		{name: "failed precondition", err: status.Error(codes.FailedPrecondition, "missing dependency")},
+		{name: "unauthenticated", err: status.Error(codes.Unauthenticated, "no token")},
+		{name: "not a status", err: errors.New("marshaling failed")},
+		{name: "canceled", err: context.Canceled},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isRetryable(tc.err); got != tc.want {
+				t.Errorf("isRetryable(%v): got %v, want %v", tc.err, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	policy := &retryPolicy{initialBackoff: time.Second, maxBackoff: 5 * time.Second}
+	for retry, want := range map[int]time.Duration{
+		1: time.Second,
+		2: 2 * time.Second,
+		3: 4 * time.Second,
+		4: 5 * time.Second,
+		9: 5 * time.Second,
+	} {
+		if got := policy.backoff(retry); got != want {
+			t.Errorf("backoff(%d): got %v, want %v", retry, got, want)
+		}
+	}
+}
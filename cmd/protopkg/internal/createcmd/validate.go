@@ -0,0 +1,36 @@
+package createcmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/protopkg/apis/pkg/validate"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+)
+
+// validationError collects the problems found in a package set.
+type validationError struct {
+	problems []string
+}
+
+func (e *validationError) Error() string {
+	return fmt.Sprintf("invalid proto package set (%d problem(s)):\n  %s", len(e.problems), strings.Join(e.problems, "\n  "))
+}
+
+// validateProtoPackageSet checks the package set before it is sent to the
+// server, with the same checks the server applies (see package validate).  It
+// returns a *validationError listing all problems found.
+func validateProtoPackageSet(pkgset *pppb.ProtoPackageSet) error {
+	var problems []string
+	if len(pkgset.Packages) == 0 {
+		problems = append(problems, "package set is empty")
+	}
+	for _, problem := range validate.Packages(pkgset.Packages) {
+		problems = append(problems, problem.String())
+	}
+
+	if len(problems) > 0 {
+		return &validationError{problems: problems}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package createcmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	longrunningpb "cloud.google.com/go/longrunning/autogen/longrunningpb"
+	_ "google.golang.org/genproto/googleapis/rpc/errdetails" // register error detail types for printing
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// waitForOperation polls the operation until it is done, or the timeout
+// expires.  Progress (changes in the operation metadata) is logged.  The final
+// state of the operation is returned; failure of the operation itself is
+// reported by operationError.
+func waitForOperation(ctx context.Context, client longrunningpb.OperationsClient, op *longrunningpb.Operation, interval, timeout time.Duration) (*longrunningpb.Operation, error) {
+	if op.Done {
+		return op, nil
+	}
+	if op.Name == "" {
+		return nil, errors.New("cannot wait: the server returned an operation without a name")
+	}
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	log.Printf("waiting for operation %s", op.Name)
+	start := time.Now()
+	var lastMetadata *anypb.Any
+	for {
+		if !proto.Equal(op.Metadata, lastMetadata) {
+			log.Printf("operation %s: running (%v): %s", op.Name, time.Since(start).Round(time.Second), formatAny(op.Metadata))
+			lastMetadata = op.Metadata
+		}
+
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return nil, fmt.Errorf("operation %s did not complete within %v", op.Name, timeout)
+		}
+
+		next, err := client.GetOperation(ctx, &longrunningpb.GetOperationRequest{Name: op.Name})
+		if err != nil {
+			if isRetryable(err) && ctx.Err() == nil {
+				log.Printf("operation %s: polling failed (will retry): %v", op.Name, err)
+				continue
+			}
+			return nil, fmt.Errorf("getting operation %s: %w", op.Name, err)
+		}
+		op = next
+		if op.Done {
+			log.Printf("operation %s: done (%v)", op.Name, time.Since(start).Round(time.Second))
+			return op, nil
+		}
+	}
+}
+
+// operationError returns an error describing the failure of a completed
+// operation (including the status details), or nil if it succeeded.
+func operationError(op *longrunningpb.Operation) error {
+	rpcStatus := op.GetError()
+	if rpcStatus == nil {
+		return nil
+	}
+	st := status.FromProto(rpcStatus)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "operation %s failed: %s: %s", op.Name, st.Code(), st.Message())
+	for _, detail := range rpcStatus.Details {
+		fmt.Fprintf(&b, "\n  %s", formatAny(detail))
+	}
+	return errors.New(b.String())
+}
+
+// logOperationResponse logs the response of a successfully completed
+// operation.
+func logOperationResponse(op *longrunningpb.Operation) {
+	if response := op.GetResponse(); response != nil {
+		log.Printf("operation %s succeeded: %s", op.Name, formatAny(response))
+	} else {
+		log.Printf("operation %s succeeded", op.Name)
+	}
+}
+
+// formatAny renders the message as single-line json if its type is known,
+// or just the type url otherwise.
+func formatAny(msg *anypb.Any) string {
+	if msg == nil {
+		return "{}"
+	}
+	data, err := protojson.Marshal(msg)
+	if err != nil {
+		return fmt.Sprintf("%s (%d bytes)", msg.TypeUrl, len(msg.Value))
+	}
+	return string(data)
+}
@@ -0,0 +1,332 @@
+package exportcmd
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/protopkg/apis/cmd/protopkg/internal/cli"
+	"github.com/protopkg/apis/pkg/protoorder"
+	"github.com/protopkg/apis/pkg/store"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+type flagName string
+
+const (
+	protoPackageSetFileFlagName     flagName = "pkgset_file"
+	packagesFlagName                flagName = "packages"
+	filesFlagName                   flagName = "files"
+	symbolsFlagName                 flagName = "symbols"
+	includeSourceInfoFlagName       flagName = "include_source_info"
+	declarationOrderFlagName        flagName = "declaration_order"
+	descriptorSetOutputFileFlagName flagName = "descriptor_set_out"
+)
+
+// Command is the 'export' subcommand.
+var Command = &cli.Command{
+	Name:    "export",
+	Summary: "Export files of a proto package set as a self-contained FileDescriptorSet",
+	Flags:   flags,
+	Run:     run,
+}
+
+var flags = flag.NewFlagSet("export", flag.ContinueOnError)
+
+var (
+	protoPackageSetFile     = flags.String(string(protoPackageSetFileFlagName), "", "path to the proto package set file")
+	packages                = flags.String(string(packagesFlagName), "", "comma-separated list of the names or refs of the packages to export")
+	files                   = flags.String(string(filesFlagName), "", "comma-separated list of the paths of the files to export")
+	symbols                 = flags.String(string(symbolsFlagName), "", "comma-separated list of the full names of the symbols whose files are exported")
+	includeSourceInfo       = flags.Bool(string(includeSourceInfoFlagName), false, "keep the SourceCodeInfo of the files (its paths follow the declaration order, see -declaration_order)")
+	declarationOrder        = flags.Bool(string(declarationOrderFlagName), false, "restore the declaration order of the canonically sorted files, from their source code info and source code")
+	descriptorSetOutputFile = flags.String(string(descriptorSetOutputFileFlagName), "", "path of file to write the FileDescriptorSet")
+)
+
+func run() error {
+	if *protoPackageSetFile == "" {
+		return errorFlagRequired(protoPackageSetFileFlagName)
+	}
+	if *descriptorSetOutputFile == "" {
+		return errorFlagRequired(descriptorSetOutputFileFlagName)
+	}
+
+	pkgset, err := readProtoPackageFileSet(protoPackageSetFileFlagName, *protoPackageSetFile)
+	if err != nil {
+		return err
+	}
+	set := newFileSet(pkgset.Packages)
+
+	targets, err := set.selectFiles(splitList(*packages), splitList(*files), splitList(*symbols))
+	if err != nil {
+		return err
+	}
+	ordered, err := set.closure(targets)
+	if err != nil {
+		return err
+	}
+
+	fds := &descriptorpb.FileDescriptorSet{}
+	for _, f := range ordered {
+		file := proto.Clone(f.file.File).(*descriptorpb.FileDescriptorProto)
+		if *declarationOrder {
+			restored := proto.Clone(file).(*descriptorpb.FileDescriptorProto)
+			if err := protoorder.Restore(restored, f.file.SourceCode); err != nil {
+				log.Printf("warning: %s: keeping the canonical order: %v", file.GetName(), err)
+			} else {
+				file = restored
+			}
+		}
+		if !*includeSourceInfo {
+			file.SourceCodeInfo = nil
+		}
+		fds.File = append(fds.File, file)
+	}
+
+	if err := writeProtoOutputFile(fds, *descriptorSetOutputFile); err != nil {
+		return err
+	}
+	return nil
+}
+
+// file is a file of a package of the set.
+type file struct {
+	pkg  *pppb.ProtoPackage
+	file *pppb.ProtoFile
+}
+
+func (f *file) path() string {
+	return f.file.GetFile().GetName()
+}
+
+// fileSet indexes the files of the packages of a proto package set.
+type fileSet struct {
+	pkgs []*pppb.ProtoPackage
+	// byRef maps the ref of a package to its files by path.
+	byRef map[string]map[string]*file
+	// byPath maps a path to the files with that path, in the order of the
+	// packages.
+	byPath map[string][]*file
+	// bySymbol maps the full name of a symbol to the files that define it.
+	bySymbol map[string][]*file
+}
+
+func newFileSet(pkgs []*pppb.ProtoPackage) *fileSet {
+	s := &fileSet{
+		pkgs:     pkgs,
+		byRef:    make(map[string]map[string]*file),
+		byPath:   make(map[string][]*file),
+		bySymbol: make(map[string][]*file),
+	}
+	for _, pkg := range pkgs {
+		files := make(map[string]*file)
+		s.byRef[store.Ref(pkg)] = files
+		for _, pf := range pkg.Files {
+			f := &file{pkg: pkg, file: pf}
+			files[f.path()] = f
+			s.byPath[f.path()] = append(s.byPath[f.path()], f)
+			for _, symbol := range fileSymbols(pf.GetFile()) {
+				s.bySymbol[symbol] = append(s.bySymbol[symbol], f)
+			}
+		}
+	}
+	return s
+}
+
+// selectFiles returns the files of the packages, the files with the paths
+// and the files that define the symbols.  All files of the set are selected
+// if none are given.
+func (s *fileSet) selectFiles(packages, paths, symbols []string) ([]*file, error) {
+	var selected []*file
+	selectPackage := func(pkg *pppb.ProtoPackage) {
+		for _, pf := range pkg.Files {
+			selected = append(selected, s.byRef[store.Ref(pkg)][pf.GetFile().GetName()])
+		}
+	}
+	if len(packages) == 0 && len(paths) == 0 && len(symbols) == 0 {
+		for _, pkg := range s.pkgs {
+			selectPackage(pkg)
+		}
+		return selected, nil
+	}
+	for _, nameOrRef := range packages {
+		found := false
+		for _, pkg := range s.pkgs {
+			if pkg.Name == nameOrRef || store.Ref(pkg) == nameOrRef {
+				selectPackage(pkg)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("-%s: package not found: %s", packagesFlagName, nameOrRef)
+		}
+	}
+	for _, path := range paths {
+		candidates := s.byPath[path]
+		if len(candidates) == 0 {
+			return nil, fmt.Errorf("-%s: file not found: %s", filesFlagName, path)
+		}
+		selected = append(selected, candidates[0])
+	}
+	for _, symbol := range symbols {
+		candidates := s.bySymbol[strings.TrimPrefix(symbol, ".")]
+		if len(candidates) == 0 {
+			return nil, fmt.Errorf("-%s: symbol not found: %s", symbolsFlagName, symbol)
+		}
+		selected = append(selected, candidates[0])
+	}
+	return selected, nil
+}
+
+// resolve returns the file that an import of the given file refers to: the
+// file of the same package, else of a dependency of the package, else of any
+// package of the set.
+func (s *fileSet) resolve(from *file, path string) (*file, bool) {
+	if f, ok := s.byRef[store.Ref(from.pkg)][path]; ok {
+		return f, true
+	}
+	for _, dep := range from.pkg.Dependencies {
+		if f, ok := s.byRef[dep][path]; ok {
+			return f, true
+		}
+	}
+	if candidates := s.byPath[path]; len(candidates) > 0 {
+		return candidates[0], true
+	}
+	return nil, false
+}
+
+// closure returns the targets and their transitive imports, deduplicated and
+// in dependency order: each file comes after the files it imports.  It is an
+// error if an import is not in the set, or if two different files with the
+// same path are needed.
+func (s *fileSet) closure(targets []*file) ([]*file, error) {
+	var ordered []*file
+	// visited maps the paths of the visited files to the file, and done is
+	// set once the imports of the file are ordered.
+	visited := make(map[string]*file)
+	done := make(map[string]bool)
+	var visit func(f *file, importedBy string) error
+	visit = func(f *file, importedBy string) error {
+		path := f.path()
+		if other, ok := visited[path]; ok {
+			if other.file.Hash != f.file.Hash {
+				return fmt.Errorf("conflicting versions of %s: %s and %s", path, store.Ref(other.pkg), store.Ref(f.pkg))
+			}
+			if !done[path] {
+				return fmt.Errorf("import cycle: %s imports %s", importedBy, path)
+			}
+			return nil
+		}
+		visited[path] = f
+		for _, dep := range f.file.GetFile().GetDependency() {
+			imported, ok := s.resolve(f, dep)
+			if !ok {
+				return fmt.Errorf("%s: import not found in the proto package set: %s", path, dep)
+			}
+			if err := visit(imported, path); err != nil {
+				return err
+			}
+		}
+		done[path] = true
+		ordered = append(ordered, f)
+		return nil
+	}
+	for _, f := range targets {
+		if err := visit(f, ""); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// fileSymbols returns the full names of the symbols that the file defines.
+// As in the registry, enum values are scoped by the parent of their enum.
+func fileSymbols(fd *descriptorpb.FileDescriptorProto) []string {
+	var names []string
+	add := func(scope, name string) string {
+		fullName := name
+		if scope != "" {
+			fullName = scope + "." + name
+		}
+		names = append(names, fullName)
+		return fullName
+	}
+	addEnums := func(scope string, enums []*descriptorpb.EnumDescriptorProto) {
+		for _, enum := range enums {
+			add(scope, enum.GetName())
+			for _, value := range enum.Value {
+				add(scope, value.GetName())
+			}
+		}
+	}
+	addFields := func(scope string, fields []*descriptorpb.FieldDescriptorProto) {
+		for _, field := range fields {
+			add(scope, field.GetName())
+		}
+	}
+	var addMessages func(scope string, messages []*descriptorpb.DescriptorProto)
+	addMessages = func(scope string, messages []*descriptorpb.DescriptorProto) {
+		for _, message := range messages {
+			name := add(scope, message.GetName())
+			addFields(name, message.Field)
+			addFields(name, message.Extension)
+			addMessages(name, message.NestedType)
+			addEnums(name, message.EnumType)
+		}
+	}
+	scope := fd.GetPackage()
+	addMessages(scope, fd.MessageType)
+	addEnums(scope, fd.EnumType)
+	addFields(scope, fd.Extension)
+	for _, service := range fd.Service {
+		name := add(scope, service.GetName())
+		for _, method := range service.Method {
+			add(name, method.GetName())
+		}
+	}
+	return names
+}
+
+func readProtoPackageFileSet(flag flagName, filename string) (*pppb.ProtoPackageSet, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", flag, err)
+	}
+	var msg pppb.ProtoPackageSet
+	if err := proto.Unmarshal(data, &msg); err != nil {
+		return nil, fmt.Errorf("unmarshaling %s: %w", flag, err)
+	}
+	return &msg, nil
+}
+
+func writeProtoOutputFile(msg proto.Message, filename string) error {
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("marshaling proto: %w", err)
+	}
+	if err := os.WriteFile(filename, data, os.ModePerm); err != nil {
+		return fmt.Errorf("writing proto file: %w", err)
+	}
+	log.Println("wrote:", filename)
+	return nil
+}
+
+// splitList splits a comma-separated list, ignoring empty elements.
+func splitList(value string) []string {
+	var list []string
+	for _, elem := range strings.Split(value, ",") {
+		if elem = strings.TrimSpace(elem); elem != "" {
+			list = append(list, elem)
+		}
+	}
+	return list
+}
+
+func errorFlagRequired(name flagName) error {
+	return cli.UsageErrorf("flag required but not provided: -%s", name)
+}
@@ -1,4 +1,4 @@
-package main
+package filecmd
 
 import (
 	"context"
@@ -15,8 +15,9 @@ import (
 	"github.com/google/go-github/github"
 	"github.com/gregjones/httpcache"
 	"github.com/gregjones/httpcache/diskcache"
+	"github.com/protopkg/apis/cmd/protopkg/internal/cli"
+	"github.com/protopkg/apis/pkg/protohash"
 	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
-	"github.com/stackb/protoreflecthash"
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/descriptorpb"
@@ -38,21 +39,41 @@ const (
 	protoFileDirectDependenciesFileFlagName flagName = "proto_file_direct_dependency_files"
 	protoOutputFileFlagName                 flagName = "proto_out"
 	jsonOutputFileFlagName                  flagName = "json_out"
+	hashModeFlagName                        flagName = "hash_mode"
 )
 
+const (
+	// objectHashMode hashes the canonicalized FileDescriptorProto.
+	objectHashMode = "object"
+	// wireHashMode hashes the wire-compatible projection of the
+	// FileDescriptorProto (see protohash.WireFile).
+	wireHashMode = "wire"
+)
+
+// Command is the 'file' subcommand.
+var Command = &cli.Command{
+	Name:    "file",
+	Summary: "Create a proto file set from a compiled FileDescriptorSet",
+	Flags:   flags,
+	Run:     run,
+}
+
+var flags = flag.NewFlagSet("file", flag.ContinueOnError)
+
 var (
-	protoCompilerName                    = flag.String(string(protoCompilerNameFlagName), "", "proto compiler name")
-	protoCompilerVersionFile             = flag.String(string(protoCompilerVersionFileFlagName), "", "path to the proto_compiler version file")
-	protoSourceFiles                     = flag.String(string(protoSourceFilesFlagName), "", "comma-separated path list path to the proto source files")
-	protoDescriptorSetFile               = flag.String(string(protoDescriptorSetFileFlagName), "", "path to the compiled FileDescriptoSet")
-	protoPackageSetDirectDependencyFiles = flag.String(string(protoFileDirectDependenciesFileFlagName), "", "comma-separated path list to a proto packages that represents the direct package dependencies of this one")
-	protoRepositoryHost                  = flag.String(string(protoRepositoryHostFlagName), "", "value of the proto_repository.host")
-	protoRepositoryOwner                 = flag.String(string(protoRepositoryOwnerFlagName), "", "value of the proto_repository.owner")
-	protoRepositoryRepo                  = flag.String(string(protoRepositoryRepoFlagName), "", "value of the proto_repository.repo")
-	protoRepositoryCommit                = flag.String(string(protoRepositoryCommitFlagName), "", "value of the proto_repository.commit")
-	protoRepositoryRoot                  = flag.String(string(protoRepositoryRootFlagName), "", "value of the proto_repository.root")
-	protoOutputFile                      = flag.String(string(protoOutputFileFlagName), "", "path of file to write the generated proto file")
-	jsonOutputFile                       = flag.String(string(jsonOutputFileFlagName), "", "path of file to write the generated json file")
+	protoCompilerName                    = flags.String(string(protoCompilerNameFlagName), "", "proto compiler name")
+	protoCompilerVersionFile             = flags.String(string(protoCompilerVersionFileFlagName), "", "path to the proto_compiler version file")
+	protoSourceFiles                     = flags.String(string(protoSourceFilesFlagName), "", "comma-separated path list path to the proto source files")
+	protoDescriptorSetFile               = flags.String(string(protoDescriptorSetFileFlagName), "", "path to the compiled FileDescriptoSet")
+	protoPackageSetDirectDependencyFiles = flags.String(string(protoFileDirectDependenciesFileFlagName), "", "comma-separated path list to a proto packages that represents the direct package dependencies of this one")
+	protoRepositoryHost                  = flags.String(string(protoRepositoryHostFlagName), "", "value of the proto_repository.host")
+	protoRepositoryOwner                 = flags.String(string(protoRepositoryOwnerFlagName), "", "value of the proto_repository.owner")
+	protoRepositoryRepo                  = flags.String(string(protoRepositoryRepoFlagName), "", "value of the proto_repository.repo")
+	protoRepositoryCommit                = flags.String(string(protoRepositoryCommitFlagName), "", "value of the proto_repository.commit")
+	protoRepositoryRoot                  = flags.String(string(protoRepositoryRootFlagName), "", "value of the proto_repository.root")
+	protoOutputFile                      = flags.String(string(protoOutputFileFlagName), "", "path of file to write the generated proto file")
+	jsonOutputFile                       = flags.String(string(jsonOutputFileFlagName), "", "path of file to write the generated json file")
+	hashMode                             = flags.String(string(hashModeFlagName), objectHashMode, "how proto file hashes are calculated ('object' or 'wire')")
 )
 
 var (
@@ -60,15 +81,7 @@ var (
 	packageDeps = make(map[string]*pppb.ProtoPackage)
 )
 
-func main() {
-	if err := run(); err != nil {
-		fmt.Println(err)
-	}
-}
-
 func run() error {
-	flag.Parse()
-
 	deps, err := readProtoPackageSetDirectDependencies(protoFileDirectDependenciesFileFlagName, *protoPackageSetDirectDependencyFiles)
 	if err != nil {
 		return err
@@ -321,6 +334,20 @@ func makeProtoPackage(data []byte,
 	return pkg, nil
 }
 
+// makeProtoPackageHash calculates the hash of the files of the package.  In
+// object mode this is the hash of the files as they are, source code
+// included, as it always was, such that the hashes of existing packages do
+// not change.  In wire mode the hash is the edition-independent wire hash of
+// protohash.Package.
+func makeProtoPackageHash(files []*pppb.ProtoFile) (string, error) {
+	if *hashMode == wireHashMode {
+		return protohash.Package(files)
+	}
+	return protohash.Message(&pppb.ProtoPackage{
+		Files: files,
+	})
+}
+
 func makeProtoPackageName(pkg *pppb.ProtoPackage) string {
 	name := path.Join(pkg.Archive.Repository.FullName, pkg.Archive.Root)
 	if len(pkg.Files) == 1 {
@@ -333,12 +360,19 @@ func makeProtoPackageName(pkg *pppb.ProtoPackage) string {
 
 func makeProtoFile(file *descriptorpb.FileDescriptorProto) (*pppb.ProtoFile, error) {
 	sortFile(file)
+	// only editions files carry features; proto2 and proto3 files are hashed
+	// as they always were.
+	if file.GetSyntax() == "editions" {
+		if err := protohash.NormalizeFeatures(file); err != nil {
+			return nil, fmt.Errorf("normalizing features: %w", err)
+		}
+	}
 
 	data, err := proto.Marshal(file)
 	if err != nil {
 		return nil, fmt.Errorf("marshaling asset FileDescriptorProto: %w", err)
 	}
-	hash, err := protoreflectHash(file)
+	hash, err := makeProtoFileHash(file)
 	if err != nil {
 		return nil, fmt.Errorf("calculating fileset hash: %w", err)
 	}
@@ -356,22 +390,27 @@ func makeProtoFile(file *descriptorpb.FileDescriptorProto) (*pppb.ProtoFile, err
 	}, nil
 }
 
+// makeProtoFileHash calculates the hash of the file according to the
+// -hash_mode flag.  Wire hashes carry a 'wire.' prefix so they are never
+// confused with object hashes.
+func makeProtoFileHash(file *descriptorpb.FileDescriptorProto) (string, error) {
+	switch *hashMode {
+	case objectHashMode:
+		return protohash.File(file, false)
+	case wireHashMode:
+		return protohash.File(file, true)
+	default:
+		return "", fmt.Errorf("invalid -%s: %q (must be one of %q, %q)", hashModeFlagName, *hashMode, objectHashMode, wireHashMode)
+	}
+}
+
 func sha256Bytes(data []byte) string {
 	digest := sha256.Sum256(data)
 	return hex.EncodeToString(digest[:])
 }
 
 func errorFlagRequired(name flagName) error {
-	return fmt.Errorf("flag required but not provided: -%s", name)
-}
-
-func protoreflectHash(msg proto.Message) (string, error) {
-	hasher := protoreflecthash.NewHasher()
-	data, err := hasher.HashProto(msg.ProtoReflect())
-	if err != nil {
-		return "", fmt.Errorf("hashing proto: %w", err)
-	}
-	return fmt.Sprintf("protoreflecthash.v0:%x", data), nil
+	return cli.UsageErrorf("flag required but not provided: -%s", name)
 }
 
 func makeProtoFileDependencies(deps []string) ([]string, error) {
@@ -506,8 +545,8 @@ func sortMessageType(m *descriptorpb.DescriptorProto) {
 }
 
 func sortFieldType(f *descriptorpb.FieldDescriptorProto) {
-	// TODO: f.Label?
-	// TODO: f.Type?
+	// The label and type are left as-is; the legacy required label and group
+	// type are mapped onto their feature equivalent in protohash.WireFile.
 	if f.Options != nil {
 		sortFieldOptions(f.Options)
 	}
@@ -662,12 +701,6 @@ func fileHashKey(file *pppb.ProtoFile) string {
 	return fmt.Sprintf("%s@%s", *file.File.Name, file.Hash)
 }
 
-func makeProtoPackageHash(files []*pppb.ProtoFile) (string, error) {
-	return protoreflectHash(&pppb.ProtoPackage{
-		Files: files,
-	})
-}
-
 func makePackagePrefix(prefix string) string {
 	if prefix == "" {
 		prefix = "~"
@@ -0,0 +1,148 @@
+package filecmd
+
+import (
+	"testing"
+
+	"github.com/protopkg/apis/pkg/protohash"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// testFile returns a file with a single message M, which has an int32 field
+// a, a repeated int32 field b and a string field s.
+func testFile(syntax string, edition descriptorpb.Edition, fileFeatures *descriptorpb.FeatureSet, fieldOptions ...*descriptorpb.FieldOptions) *descriptorpb.FileDescriptorProto {
+	label := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+	repeated := descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	f := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("test.proto"),
+		Package: proto.String("test"),
+		Syntax:  proto.String(syntax),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("M"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("a"), Number: proto.Int32(1), Label: label, Type: descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum(), JsonName: proto.String("a")},
+				{Name: proto.String("b"), Number: proto.Int32(2), Label: repeated, Type: descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum(), JsonName: proto.String("b")},
+				{Name: proto.String("s"), Number: proto.Int32(3), Label: label, Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(), JsonName: proto.String("s")},
+			},
+		}},
+	}
+	if syntax == "editions" {
+		f.Edition = edition.Enum()
+	}
+	if fileFeatures != nil {
+		f.Options = &descriptorpb.FileOptions{Features: fileFeatures}
+	}
+	for i, opts := range fieldOptions {
+		f.MessageType[0].Field[i].Options = opts
+	}
+	return f
+}
+
+func TestMakeProtoFile(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		file *descriptorpb.FileDescriptorProto
+		// want is the file descriptor after normalization.
+		want *descriptorpb.FileDescriptorProto
+	}{
+		{
+			name: "proto2",
+			file: func() *descriptorpb.FileDescriptorProto {
+				f := testFile("proto2", 0, nil)
+				f.Options = &descriptorpb.FileOptions{}
+				return f
+			}(),
+			want: func() *descriptorpb.FileDescriptorProto {
+				f := testFile("proto2", 0, nil)
+				f.Options = &descriptorpb.FileOptions{}
+				return f
+			}(),
+		},
+		{
+			name: "proto3",
+			file: testFile("proto3", 0, nil),
+			want: testFile("proto3", 0, nil),
+		},
+		{
+			name: "2023",
+			file: testFile("editions", descriptorpb.Edition_EDITION_2023,
+				&descriptorpb.FeatureSet{FieldPresence: descriptorpb.FeatureSet_EXPLICIT.Enum()},
+				&descriptorpb.FieldOptions{Features: &descriptorpb.FeatureSet{Utf8Validation: descriptorpb.FeatureSet_VERIFY.Enum()}},
+			),
+			want: testFile("editions", descriptorpb.Edition_EDITION_2023, nil),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			file, err := makeProtoFile(tc.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !proto.Equal(file.File, tc.want) {
+				t.Errorf("got %v, want %v", file.File, tc.want)
+			}
+			hash, err := protohash.Message(tc.want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if file.Hash != hash {
+				t.Errorf("hash: got %s, want %s", file.Hash, hash)
+			}
+		})
+	}
+}
+
+func TestWirePackageHash(t *testing.T) {
+	defer func(mode string) { *hashMode = mode }(*hashMode)
+	*hashMode = wireHashMode
+
+	var hashes []string
+	for _, f := range []*descriptorpb.FileDescriptorProto{
+		testFile("proto3", 0, nil),
+		testFile("editions", descriptorpb.Edition_EDITION_2023,
+			&descriptorpb.FeatureSet{FieldPresence: descriptorpb.FeatureSet_IMPLICIT.Enum()},
+		),
+	} {
+		file, err := makeProtoFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hash, err := protohash.Package([]*pppb.ProtoFile{file})
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashes = append(hashes, hash)
+	}
+	if hashes[0] != hashes[1] {
+		t.Errorf("proto3 package hashes to %s, editions package to %s", hashes[0], hashes[1])
+	}
+}
+
+func TestObjectPackageHash(t *testing.T) {
+	archive := &pppb.ProtoArchive{
+		Repository: &pppb.ProtoRepository{FullName: "github.com/example/protos"},
+		CommitSha1: "0123456789abcdef0123456789abcdef01234567",
+	}
+	makePackage := func(source string) *pppb.ProtoPackage {
+		t.Helper()
+		ds := &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{testFile("proto3", 0, nil)}}
+		pkg, err := makeProtoPackage(nil, ds, archive, nil, map[string][]byte{"test.proto": []byte(source)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pkg
+	}
+
+	pkg := makePackage("syntax = \"proto3\";")
+	// the object hash covers the files as they are, as it always did
+	want, err := protohash.Message(&pppb.ProtoPackage{Files: pkg.Files})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg.Hash != want {
+		t.Errorf("got %s, want %s", pkg.Hash, want)
+	}
+	if other := makePackage("syntax = \"proto3\"; // comment"); other.Hash == pkg.Hash {
+		t.Error("the source code does not contribute to the object package hash")
+	}
+}
@@ -0,0 +1,65 @@
+package fsckcmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/protopkg/apis/cmd/protopkg/internal/cli"
+	"github.com/protopkg/apis/pkg/store"
+)
+
+type flagName string
+
+const (
+	storeDirFlagName flagName = "store_dir"
+	repairFlagName   flagName = "repair"
+)
+
+// Command is the 'fsck' subcommand.
+var Command = &cli.Command{
+	Name:    "fsck",
+	Summary: "Check the consistency of a filesystem package store",
+	Flags:   flags,
+	Run:     run,
+}
+
+var flags = flag.NewFlagSet("fsck", flag.ContinueOnError)
+
+var (
+	storeDir = flags.String(string(storeDirFlagName), "", "directory of the store")
+	repair   = flags.Bool(string(repairFlagName), false, "repair the problems found: invalid files are moved to the 'lost' directory of the store, unused objects and temporary files are removed")
+)
+
+func run() error {
+	if *storeDir == "" {
+		return errorFlagRequired(storeDirFlagName)
+	}
+	st, err := store.OpenFS(*storeDir)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	problems, err := st.Check(context.Background(), *repair)
+	if err != nil {
+		return err
+	}
+	unrepaired := 0
+	for _, problem := range problems {
+		log.Println("problem:", problem)
+		if !problem.Repaired {
+			unrepaired++
+		}
+	}
+	if unrepaired > 0 {
+		return fmt.Errorf("%s: %d problem(s) found (run with -%s to repair)", *storeDir, unrepaired, repairFlagName)
+	}
+	log.Printf("%s: ok (%d problem(s) repaired)", *storeDir, len(problems))
+	return nil
+}
+
+func errorFlagRequired(name flagName) error {
+	return cli.UsageErrorf("flag required but not provided: -%s", name)
+}
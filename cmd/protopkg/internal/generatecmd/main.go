@@ -0,0 +1,220 @@
+package generatecmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/protopkg/apis/cmd/protopkg/internal/cli"
+	"github.com/protopkg/apis/pkg/dial"
+	"github.com/protopkg/apis/pkg/store"
+	regpb "github.com/protopkg/apis/protopkg/registry/v1alpha1"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	"google.golang.org/protobuf/proto"
+)
+
+type flagName string
+
+const (
+	protoPackageSetFileFlagName   flagName = "pkgset_file"
+	packagesServerAddressFlagName flagName = "packages_server_address"
+	timeoutFlagName               flagName = "timeout"
+	packageFlagName               flagName = "package"
+	pluginFlagName                flagName = "plugin"
+	parameterFlagName             flagName = "parameter"
+	outputDirFlagName             flagName = "output_dir"
+)
+
+// Command is the 'generate' subcommand.
+var Command = &cli.Command{
+	Name:    "generate",
+	Summary: "Run a protoc plugin against the files of a package",
+	Flags:   flags,
+	Run:     run,
+}
+
+var flags = flag.NewFlagSet("generate", flag.ContinueOnError)
+
+var (
+	protoPackageSetFile   = flags.String(string(protoPackageSetFileFlagName), "", "path to a proto package set file that contains the package and its dependencies")
+	packagesServerAddress = flags.String(string(packagesServerAddressFlagName), "", "address of a packages server to get the package and its dependencies from")
+	timeout               = flags.Duration(string(timeoutFlagName), 5*time.Minute, "deadline of the packages server requests and of the plugin (0 for none)")
+	packageName           = flags.String(string(packageFlagName), "", "name, ref or id (NAME@HASH) of the package; the most recently committed package is used for a name")
+	plugin                = flags.String(string(pluginFlagName), "", "path of the plugin executable, or NAME for the protoc-gen-NAME executable in the PATH (as protoc --NAME_out)")
+	parameter             = flags.String(string(parameterFlagName), "", "parameter passed to the plugin (as protoc --NAME_opt, e.g. 'paths=source_relative')")
+	outputDir             = flags.String(string(outputDirFlagName), "", "path of the directory to write the generated files to")
+	dialOptions           dial.Options
+)
+
+func init() {
+	dialOptions.RegisterFlags(flags)
+}
+
+func run() error {
+	if *packageName == "" {
+		return errorFlagRequired(packageFlagName)
+	}
+	if *plugin == "" {
+		return errorFlagRequired(pluginFlagName)
+	}
+	if *outputDir == "" {
+		return errorFlagRequired(outputDirFlagName)
+	}
+	if (*protoPackageSetFile == "") == (*packagesServerAddress == "") {
+		return cli.UsageErrorf("exactly one of -%s or -%s is required", protoPackageSetFileFlagName, packagesServerAddressFlagName)
+	}
+
+	pluginPath, err := findPlugin(*plugin)
+	if err != nil {
+		return err
+	}
+
+	var lookup lookupFunc
+	if *protoPackageSetFile != "" {
+		pkgset, err := readProtoPackageFileSet(protoPackageSetFileFlagName, *protoPackageSetFile)
+		if err != nil {
+			return err
+		}
+		lookup = pkgsetLookup(pkgset)
+	} else {
+		conn, err := dial.Dial(*packagesServerAddress, &dialOptions)
+		if err != nil {
+			return fmt.Errorf("generate failed: %w", err)
+		}
+		defer conn.Close()
+		lookup = registryLookup(regpb.NewRegistryClient(conn))
+	}
+
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	pkgs, err := collectPackages(ctx, lookup, *packageName)
+	if err != nil {
+		return err
+	}
+	req, err := makeCodeGeneratorRequest(pkgs, *parameter)
+	if err != nil {
+		return err
+	}
+	resp, err := runPlugin(ctx, pluginPath, req)
+	if err != nil {
+		return err
+	}
+	if err := checkFeatures(*plugin, req, resp); err != nil {
+		return err
+	}
+	return writeCodeGeneratorResponse(*outputDir, resp)
+}
+
+// findPlugin returns the path of the plugin executable: the value itself if
+// it is a path, else the protoc-gen-NAME executable in the PATH.
+func findPlugin(value string) (string, error) {
+	if strings.ContainsRune(value, os.PathSeparator) || strings.ContainsRune(value, '/') {
+		return value, nil
+	}
+	name := value
+	if !strings.HasPrefix(name, "protoc-gen-") {
+		name = "protoc-gen-" + name
+	}
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return "", fmt.Errorf("-%s: %w", pluginFlagName, err)
+	}
+	return path, nil
+}
+
+// lookupFunc returns the package with the given name, ref or id.
+type lookupFunc func(ctx context.Context, key string) (*pppb.ProtoPackage, error)
+
+// pkgsetLookup looks up the packages of a proto package set.
+func pkgsetLookup(pkgset *pppb.ProtoPackageSet) lookupFunc {
+	return func(ctx context.Context, key string) (*pppb.ProtoPackage, error) {
+		var found []*pppb.ProtoPackage
+		for _, pkg := range pkgset.Packages {
+			if pkg.Name == key || store.Ref(pkg) == key || store.ID(pkg.Name, pkg.Hash) == key {
+				found = append(found, pkg)
+			}
+		}
+		if len(found) == 0 {
+			return nil, fmt.Errorf("package not found in the proto package set: %s", key)
+		}
+		// a name may match several versions: the most recently committed is
+		// used.
+		sort.SliceStable(found, func(i, j int) bool {
+			return found[i].GetArchive().GetCommitTime().AsTime().After(found[j].GetArchive().GetCommitTime().AsTime())
+		})
+		return found[0], nil
+	}
+}
+
+// registryLookup looks up the packages of a packages server.
+func registryLookup(registry regpb.RegistryClient) lookupFunc {
+	return func(ctx context.Context, key string) (*pppb.ProtoPackage, error) {
+		switch {
+		case strings.Contains(key, ":") && !strings.Contains(key, "@"):
+			return registry.LookupProtoPackage(ctx, &regpb.LookupProtoPackageRequest{Ref: key})
+		case strings.Contains(key, "@"):
+			return registry.LookupProtoPackage(ctx, &regpb.LookupProtoPackageRequest{Id: key})
+		default:
+			// the packages are listed most recently committed first.
+			resp, err := registry.ListProtoPackages(ctx, &regpb.ListProtoPackagesRequest{Name: key, PageSize: 1})
+			if err != nil {
+				return nil, err
+			}
+			if len(resp.Packages) == 0 {
+				return nil, fmt.Errorf("package not found: %s", key)
+			}
+			return resp.Packages[0], nil
+		}
+	}
+}
+
+// collectPackages returns the package, followed by its transitive
+// dependencies.
+func collectPackages(ctx context.Context, lookup lookupFunc, key string) ([]*pppb.ProtoPackage, error) {
+	pkg, err := lookup(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", key, err)
+	}
+	pkgs := []*pppb.ProtoPackage{pkg}
+	seen := map[string]bool{store.Ref(pkg): true}
+	for i := 0; i < len(pkgs); i++ {
+		for _, dep := range pkgs[i].Dependencies {
+			if seen[dep] {
+				continue
+			}
+			seen[dep] = true
+			depPkg, err := lookup(ctx, dep)
+			if err != nil {
+				return nil, fmt.Errorf("%s: dependency %s: %w", store.Ref(pkgs[i]), dep, err)
+			}
+			pkgs = append(pkgs, depPkg)
+		}
+	}
+	return pkgs, nil
+}
+
+func readProtoPackageFileSet(flag flagName, filename string) (*pppb.ProtoPackageSet, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", flag, err)
+	}
+	var msg pppb.ProtoPackageSet
+	if err := proto.Unmarshal(data, &msg); err != nil {
+		return nil, fmt.Errorf("unmarshaling %s: %w", flag, err)
+	}
+	return &msg, nil
+}
+
+func errorFlagRequired(name flagName) error {
+	return cli.UsageErrorf("flag required but not provided: -%s", name)
+}
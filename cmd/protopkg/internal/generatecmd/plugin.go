@@ -0,0 +1,290 @@
+package generatecmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/protopkg/apis/pkg/protoorder"
+	"github.com/protopkg/apis/pkg/store"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// makeCodeGeneratorRequest returns the request to generate the files of the
+// first package, as protoc makes it: proto_file holds the files to generate
+// and their transitive imports in dependency order, and only the files to
+// generate keep their source code info.  The files are restored to their
+// declaration order, such that the source code info (and the order of the
+// generated code) matches the source.
+func makeCodeGeneratorRequest(pkgs []*pppb.ProtoPackage, parameter string) (*pluginpb.CodeGeneratorRequest, error) {
+	// files maps the paths of the files of the packages to the file, the
+	// files of a package taking precedence over the files of its
+	// dependencies.
+	files := make(map[string]*pppb.ProtoFile)
+	owners := make(map[string]*pppb.ProtoPackage)
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			name := file.GetFile().GetName()
+			if other, ok := files[name]; ok {
+				if other.Hash != file.Hash {
+					return nil, fmt.Errorf("conflicting versions of %s: %s and %s", name, store.Ref(owners[name]), store.Ref(pkg))
+				}
+				continue
+			}
+			files[name] = file
+			owners[name] = pkg
+		}
+	}
+
+	req := &pluginpb.CodeGeneratorRequest{
+		CompilerVersion: parseCompilerVersion(pkgs[0].GetCompiler().GetVersion()),
+	}
+	if parameter != "" {
+		req.Parameter = proto.String(parameter)
+	}
+	generate := make(map[string]bool)
+	for _, file := range pkgs[0].Files {
+		req.FileToGenerate = append(req.FileToGenerate, file.GetFile().GetName())
+		generate[file.GetFile().GetName()] = true
+	}
+
+	// visit appends the file to proto_file after its imports.
+	visited := make(map[string]bool)
+	var visit func(name, importedBy string) error
+	visit = func(name, importedBy string) error {
+		if visited[name] {
+			return nil
+		}
+		visited[name] = true
+		file, ok := files[name]
+		if !ok {
+			return fmt.Errorf("%s: import not found in the package or its dependencies: %s", importedBy, name)
+		}
+		for _, dep := range file.GetFile().GetDependency() {
+			if err := visit(dep, name); err != nil {
+				return err
+			}
+		}
+		fd := proto.Clone(file.File).(*descriptorpb.FileDescriptorProto)
+		if err := protoorder.Restore(fd, file.SourceCode); err != nil {
+			if generate[name] {
+				log.Printf("warning: %s: keeping the canonical order, without source code info: %v", name, err)
+			}
+			fd = proto.Clone(file.File).(*descriptorpb.FileDescriptorProto)
+			fd.SourceCodeInfo = nil
+		}
+		if generate[name] {
+			req.SourceFileDescriptors = append(req.SourceFileDescriptors, fd)
+			fd = proto.Clone(fd).(*descriptorpb.FileDescriptorProto)
+		} else {
+			fd.SourceCodeInfo = nil
+		}
+		req.ProtoFile = append(req.ProtoFile, fd)
+		return nil
+	}
+	for _, name := range req.FileToGenerate {
+		if err := visit(name, store.Ref(pkgs[0])); err != nil {
+			return nil, err
+		}
+	}
+	return req, nil
+}
+
+// parseCompilerVersion returns the version of the compiler of the package
+// (e.g. 'libprotoc 23.3'), or nil if it does not parse.
+func parseCompilerVersion(value string) *pluginpb.Version {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return nil
+	}
+	version, suffix, _ := strings.Cut(fields[len(fields)-1], "-")
+	parts := strings.Split(version, ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return nil
+	}
+	numbers := make([]int32, 3)
+	for i, part := range parts {
+		n, err := strconv.ParseInt(part, 10, 32)
+		if err != nil {
+			return nil
+		}
+		numbers[i] = int32(n)
+	}
+	v := &pluginpb.Version{
+		Major: proto.Int32(numbers[0]),
+		Minor: proto.Int32(numbers[1]),
+		Patch: proto.Int32(numbers[2]),
+	}
+	if suffix != "" {
+		v.Suffix = proto.String(suffix)
+	}
+	return v
+}
+
+// runPlugin runs the plugin with the request on its stdin, and returns the
+// response from its stdout.  The stderr of the plugin is passed through.  An
+// error reported in the response is returned with the message of the plugin.
+func runPlugin(ctx context.Context, pluginPath string, req *pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorResponse, error) {
+	data, err := proto.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling CodeGeneratorRequest: %w", err)
+	}
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, pluginPath)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("plugin %s failed: %w", pluginPath, err)
+	}
+	var resp pluginpb.CodeGeneratorResponse
+	if err := proto.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return nil, fmt.Errorf("plugin %s: unmarshaling CodeGeneratorResponse: %w", pluginPath, err)
+	}
+	if resp.Error != nil {
+		return nil, fmt.Errorf("plugin %s: %s", pluginPath, resp.GetError())
+	}
+	return &resp, nil
+}
+
+// checkFeatures checks that the plugin supports the features used by the
+// files to generate, as protoc does.
+func checkFeatures(plugin string, req *pluginpb.CodeGeneratorRequest, resp *pluginpb.CodeGeneratorResponse) error {
+	supports := func(feature pluginpb.CodeGeneratorResponse_Feature) bool {
+		return resp.GetSupportedFeatures()&uint64(feature) != 0
+	}
+	for _, fd := range req.SourceFileDescriptors {
+		if fd.GetSyntax() == "editions" {
+			if !supports(pluginpb.CodeGeneratorResponse_FEATURE_SUPPORTS_EDITIONS) {
+				return fmt.Errorf("%s is an editions file, but plugin %s hasn't been updated to support editions", fd.GetName(), plugin)
+			}
+			edition := int32(fd.GetEdition())
+			if resp.MinimumEdition != nil && edition < resp.GetMinimumEdition() || resp.MaximumEdition != nil && edition > resp.GetMaximumEdition() {
+				return fmt.Errorf("%s: edition %s is not supported by plugin %s", fd.GetName(), fd.GetEdition(), plugin)
+			}
+		}
+		if hasProto3Optional(fd) && !supports(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL) {
+			return fmt.Errorf("%s is a proto3 file that contains optional fields, but plugin %s hasn't been updated to support optional fields in proto3", fd.GetName(), plugin)
+		}
+	}
+	return nil
+}
+
+func hasProto3Optional(fd *descriptorpb.FileDescriptorProto) bool {
+	var visit func(messages []*descriptorpb.DescriptorProto) bool
+	visit = func(messages []*descriptorpb.DescriptorProto) bool {
+		for _, m := range messages {
+			for _, field := range m.Field {
+				if field.GetProto3Optional() {
+					return true
+				}
+			}
+			if visit(m.NestedType) {
+				return true
+			}
+		}
+		return false
+	}
+	return visit(fd.MessageType)
+}
+
+// writeCodeGeneratorResponse writes the files of the response to the
+// directory.  As with protoc, a file without a name continues the previous
+// file, and a file with an insertion point is inserted into a file of the
+// response, before the line of its '@@protoc_insertion_point(NAME)'.
+func writeCodeGeneratorResponse(dir string, resp *pluginpb.CodeGeneratorResponse) error {
+	var names []string
+	contents := make(map[string][]byte)
+	var last string
+	for _, file := range resp.File {
+		name := file.GetName()
+		switch {
+		case name == "":
+			if last == "" {
+				return fmt.Errorf("first generated file has no name")
+			}
+			contents[last] = append(contents[last], file.GetContent()...)
+		case file.InsertionPoint != nil:
+			content, ok := contents[name]
+			if !ok {
+				return fmt.Errorf("%s: insertion point %q: file not generated", name, file.GetInsertionPoint())
+			}
+			inserted, err := insert(content, file.GetInsertionPoint(), file.GetContent())
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			contents[name] = inserted
+			last = name
+		default:
+			if !isSafePath(name) {
+				return fmt.Errorf("invalid generated file name: %q", name)
+			}
+			if _, ok := contents[name]; ok {
+				return fmt.Errorf("%s: generated more than once", name)
+			}
+			names = append(names, name)
+			contents[name] = []byte(file.GetContent())
+			last = name
+		}
+	}
+
+	for _, name := range names {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), os.ModePerm); err != nil {
+			return fmt.Errorf("creating directory: %w", err)
+		}
+		if err := os.WriteFile(filename, contents[name], 0644); err != nil {
+			return fmt.Errorf("writing generated file: %w", err)
+		}
+		log.Println("wrote:", filename)
+	}
+	return nil
+}
+
+// insert inserts the text before the line of the insertion point, indented
+// as the insertion point.
+func insert(content []byte, point, text string) ([]byte, error) {
+	marker := []byte("@@protoc_insertion_point(" + point + ")")
+	pos := bytes.Index(content, marker)
+	if pos < 0 {
+		return nil, fmt.Errorf("insertion point not found: %s", point)
+	}
+	lineStart := bytes.LastIndexByte(content[:pos], '\n') + 1
+	indent := content[lineStart:pos]
+	// the indent is the whitespace before the marker (not a comment prefix).
+	indent = indent[:len(indent)-len(bytes.TrimLeft(indent, " \t"))]
+
+	var b bytes.Buffer
+	b.Write(content[:lineStart])
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if line == "" {
+			continue
+		}
+		if line != "\n" {
+			b.Write(indent)
+		}
+		b.WriteString(line)
+	}
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		b.WriteByte('\n')
+	}
+	b.Write(content[lineStart:])
+	return b.Bytes(), nil
+}
+
+// isSafePath reports whether the generated file stays in the output
+// directory.
+func isSafePath(name string) bool {
+	return path.Clean(name) == name && !path.IsAbs(name) &&
+		name != ".." && !strings.HasPrefix(name, "../") && !strings.Contains(name, "\\")
+}
@@ -0,0 +1,287 @@
+package graphcmd
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/protopkg/apis/pkg/store"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+)
+
+// wellKnownTypesPackage is the package of the well-known types.
+const wellKnownTypesPackage = "google.protobuf"
+
+// wellKnownTypesPrefix is the path prefix of the files of the well-known
+// types.
+const wellKnownTypesPrefix = "google/protobuf/"
+
+// node is a package or a file of the graph.
+type node struct {
+	// id is unique in the graph: the ref of a package, or the ref of its
+	// package and the path of a file.
+	id    string
+	label string
+	// pkg is the name of the package of the node, and ref its ref.
+	pkg        string
+	ref        string
+	repository string
+	// external is set for the nodes that are not in the input, but are
+	// depended on by a node that is.
+	external  bool
+	wellKnown bool
+}
+
+// edge is a dependency of the 'from' node on the 'to' node.
+type edge struct {
+	from, to string
+}
+
+// graph is a directed dependency graph.  The nodes and edges are ordered,
+// such that the output is stable for the same input.
+type graph struct {
+	nodes []*node
+	edges []edge
+	index map[string]*node
+}
+
+func newGraph() *graph {
+	return &graph{index: make(map[string]*node)}
+}
+
+// add adds the node, unless a node with the same id was added before.
+func (g *graph) add(n *node) {
+	if _, ok := g.index[n.id]; ok {
+		return
+	}
+	g.index[n.id] = n
+	g.nodes = append(g.nodes, n)
+}
+
+func (g *graph) connect(from, to string) {
+	if from != to {
+		g.edges = append(g.edges, edge{from, to})
+	}
+}
+
+// sort orders the nodes by id, and removes duplicate edges.
+func (g *graph) sort() {
+	sort.Slice(g.nodes, func(i, j int) bool { return g.nodes[i].id < g.nodes[j].id })
+	sort.Slice(g.edges, func(i, j int) bool {
+		if g.edges[i].from != g.edges[j].from {
+			return g.edges[i].from < g.edges[j].from
+		}
+		return g.edges[i].to < g.edges[j].to
+	})
+	var edges []edge
+	for i, e := range g.edges {
+		if i == 0 || e != g.edges[i-1] {
+			edges = append(edges, e)
+		}
+	}
+	g.edges = edges
+}
+
+// filter returns the graph of the nodes for which keep returns true.
+func (g *graph) filter(keep func(n *node) bool) *graph {
+	out := newGraph()
+	for _, n := range g.nodes {
+		if keep(n) {
+			out.add(n)
+		}
+	}
+	for _, e := range g.edges {
+		if out.index[e.from] != nil && out.index[e.to] != nil {
+			out.edges = append(out.edges, e)
+		}
+	}
+	return out
+}
+
+// roots returns the ids of the nodes of the package with the given name or
+// ref.
+func (g *graph) roots(nameOrRef string) []string {
+	var ids []string
+	for _, n := range g.nodes {
+		if !n.external && (n.pkg == nameOrRef || n.ref == nameOrRef) {
+			ids = append(ids, n.id)
+		}
+	}
+	return ids
+}
+
+// reachable returns the graph of the nodes reachable from the roots in at
+// most depth edges, or in any number of edges if depth is 0.
+func (g *graph) reachable(roots []string, depth int) *graph {
+	deps := make(map[string][]string)
+	for _, e := range g.edges {
+		deps[e.from] = append(deps[e.from], e.to)
+	}
+	distance := make(map[string]int)
+	queue := roots
+	for _, id := range roots {
+		distance[id] = 0
+	}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if depth > 0 && distance[id] == depth {
+			continue
+		}
+		for _, dep := range deps[id] {
+			if _, ok := distance[dep]; !ok {
+				distance[dep] = distance[id] + 1
+				queue = append(queue, dep)
+			}
+		}
+	}
+	return g.filter(func(n *node) bool {
+		_, ok := distance[n.id]
+		return ok
+	})
+}
+
+// makePackageGraph returns the graph of the packages, with an edge per
+// package dependency.  The packages that are depended on but are not in the
+// input are external nodes.
+func makePackageGraph(pkgs []*pppb.ProtoPackage) *graph {
+	g := newGraph()
+	versions := countVersions(pkgs)
+	for _, pkg := range pkgs {
+		ref := store.Ref(pkg)
+		label := pkg.Name
+		if versions[pkg.Name] > 1 {
+			label += "@" + pkg.GetArchive().GetShortSha1()
+		}
+		g.add(&node{
+			id:         ref,
+			label:      label,
+			pkg:        pkg.Name,
+			ref:        ref,
+			repository: pkg.GetArchive().GetRepository().GetFullName(),
+			wellKnown:  pkg.Name == wellKnownTypesPackage,
+		})
+	}
+	for _, pkg := range pkgs {
+		ref := store.Ref(pkg)
+		for _, dep := range pkg.Dependencies {
+			if _, ok := g.index[dep]; !ok {
+				repository, name := parseRef(dep)
+				g.add(&node{
+					id:         dep,
+					label:      name,
+					pkg:        name,
+					ref:        dep,
+					repository: repository,
+					external:   true,
+					wellKnown:  name == wellKnownTypesPackage,
+				})
+			}
+			g.connect(ref, dep)
+		}
+	}
+	g.sort()
+	return g
+}
+
+// makeFileGraph returns the graph of the files of the packages, with an edge
+// per import.  An import resolves to the file of the same package, else to
+// the file of a dependency of the package, else to the file of any package
+// in the input.  The imports that do not resolve are external nodes.
+func makeFileGraph(pkgs []*pppb.ProtoPackage) *graph {
+	g := newGraph()
+	versions := countVersions(pkgs)
+
+	// providers maps a package ref to the node ids of its files by path, and
+	// anyProvider a path to the first node id of the file in the input.
+	providers := make(map[string]map[string]string)
+	anyProvider := make(map[string]string)
+	for _, pkg := range pkgs {
+		ref := store.Ref(pkg)
+		files := make(map[string]string)
+		providers[ref] = files
+		for _, file := range pkg.Files {
+			path := file.GetFile().GetName()
+			label := path
+			if versions[pkg.Name] > 1 {
+				label += "@" + pkg.GetArchive().GetShortSha1()
+			}
+			n := &node{
+				id:         fileID(ref, path),
+				label:      label,
+				pkg:        pkg.Name,
+				ref:        ref,
+				repository: pkg.GetArchive().GetRepository().GetFullName(),
+				wellKnown:  strings.HasPrefix(path, wellKnownTypesPrefix),
+			}
+			g.add(n)
+			files[path] = n.id
+			if _, ok := anyProvider[path]; !ok {
+				anyProvider[path] = n.id
+			}
+		}
+	}
+
+	resolve := func(pkg *pppb.ProtoPackage, path string) string {
+		if id, ok := providers[store.Ref(pkg)][path]; ok {
+			return id
+		}
+		for _, dep := range pkg.Dependencies {
+			if id, ok := providers[dep][path]; ok {
+				return id
+			}
+		}
+		if id, ok := anyProvider[path]; ok {
+			return id
+		}
+		id := fileID("", path)
+		g.add(&node{
+			id:        id,
+			label:     path,
+			external:  true,
+			wellKnown: strings.HasPrefix(path, wellKnownTypesPrefix),
+		})
+		return id
+	}
+
+	for _, pkg := range pkgs {
+		ref := store.Ref(pkg)
+		for _, file := range pkg.Files {
+			from := fileID(ref, file.GetFile().GetName())
+			for _, dep := range file.GetFile().GetDependency() {
+				g.connect(from, resolve(pkg, dep))
+			}
+		}
+	}
+	g.sort()
+	return g
+}
+
+// fileID returns the node id of a file of the package with the given ref.
+func fileID(ref, path string) string {
+	return ref + "|" + path
+}
+
+// countVersions returns the number of versions of each package name.
+func countVersions(pkgs []*pppb.ProtoPackage) map[string]int {
+	versions := make(map[string]int)
+	for _, pkg := range pkgs {
+		versions[pkg.Name]++
+	}
+	return versions
+}
+
+// parseRef returns the repository and package name of a package ref (e.g.
+// 'github.com/googleapis/googleapis/0123456/~:google.api').
+func parseRef(ref string) (repository, name string) {
+	location, name, ok := strings.Cut(ref, ":")
+	if !ok {
+		return "", ref
+	}
+	// strip the root and the commit.
+	for i := 0; i < 2; i++ {
+		if slash := strings.LastIndexByte(location, '/'); slash >= 0 {
+			location = location[:slash]
+		}
+	}
+	return location, name
+}
@@ -0,0 +1,179 @@
+package graphcmd
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/protopkg/apis/cmd/protopkg/internal/cli"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+type flagName string
+
+const (
+	protoPackageSetFileFlagName   flagName = "pkgset_file"
+	jsonFileFlagName              flagName = "json_file"
+	levelFlagName                 flagName = "level"
+	formatFlagName                flagName = "format"
+	rootFlagName                  flagName = "root"
+	depthFlagName                 flagName = "depth"
+	excludeWellKnownTypesFlagName flagName = "exclude_well_known_types"
+	clusterByRepositoryFlagName   flagName = "cluster_by_repository"
+	graphOutputFileFlagName       flagName = "graph_out"
+)
+
+const (
+	// packageLevel makes a node per package, and an edge per package
+	// dependency.
+	packageLevel = "package"
+	// fileLevel makes a node per file, and an edge per import.
+	fileLevel = "file"
+)
+
+const (
+	dotFormat     = "dot"
+	mermaidFormat = "mermaid"
+	graphmlFormat = "graphml"
+)
+
+// Command is the 'graph' subcommand.
+var Command = &cli.Command{
+	Name:    "graph",
+	Summary: "Export the dependency graph of packages as DOT, Mermaid or GraphML",
+	Flags:   flags,
+	Run:     run,
+}
+
+var flags = flag.NewFlagSet("graph", flag.ContinueOnError)
+
+var (
+	protoPackageSetFile   = flags.String(string(protoPackageSetFileFlagName), "", "path to the proto package set file")
+	jsonFile              = flags.String(string(jsonFileFlagName), "", "path to a json query result written by 'protopkg get', 'list' or 'find', or to a json proto package set ('-' for stdin)")
+	level                 = flags.String(string(levelFlagName), packageLevel, "level of the graph ('package' or 'file')")
+	format                = flags.String(string(formatFlagName), dotFormat, "format of the graph ('dot', 'mermaid' or 'graphml')")
+	root                  = flags.String(string(rootFlagName), "", "only include the package with this name or ref, and its dependencies")
+	depth                 = flags.Int(string(depthFlagName), 0, "maximum number of dependency edges from the -root package (0 for no limit)")
+	excludeWellKnownTypes = flags.Bool(string(excludeWellKnownTypesFlagName), false, "exclude the google.protobuf package and the google/protobuf/ files")
+	clusterByRepository   = flags.Bool(string(clusterByRepositoryFlagName), false, "group the nodes by repository")
+	graphOutputFile       = flags.String(string(graphOutputFileFlagName), "", "path of file to write the graph (default is stdout)")
+)
+
+func run() error {
+	if *level != packageLevel && *level != fileLevel {
+		return cli.UsageErrorf("invalid -%s: %q (must be one of %q, %q)", levelFlagName, *level, packageLevel, fileLevel)
+	}
+	write, ok := writers[*format]
+	if !ok {
+		return cli.UsageErrorf("invalid -%s: %q (must be one of %q, %q, %q)", formatFlagName, *format, dotFormat, mermaidFormat, graphmlFormat)
+	}
+	if *depth < 0 {
+		return cli.UsageErrorf("invalid -%s: %d", depthFlagName, *depth)
+	}
+	if *depth > 0 && *root == "" {
+		return cli.UsageErrorf("-%s requires -%s", depthFlagName, rootFlagName)
+	}
+
+	pkgs, err := readProtoPackages()
+	if err != nil {
+		return err
+	}
+
+	var g *graph
+	if *level == packageLevel {
+		g = makePackageGraph(pkgs)
+	} else {
+		g = makeFileGraph(pkgs)
+	}
+	if *excludeWellKnownTypes {
+		g = g.filter(func(n *node) bool { return !n.wellKnown })
+	}
+	if *root != "" {
+		roots := g.roots(*root)
+		if len(roots) == 0 {
+			return fmt.Errorf("-%s: package not found: %s", rootFlagName, *root)
+		}
+		g = g.reachable(roots, *depth)
+	}
+
+	var buf bytes.Buffer
+	if err := write(&buf, g, *clusterByRepository); err != nil {
+		return err
+	}
+	if *graphOutputFile == "" {
+		_, err := os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	if err := os.WriteFile(*graphOutputFile, buf.Bytes(), os.ModePerm); err != nil {
+		return fmt.Errorf("writing graph file: %w", err)
+	}
+	log.Println("wrote:", *graphOutputFile)
+	return nil
+}
+
+// readProtoPackages reads the packages of the -pkgset_file or -json_file.
+func readProtoPackages() ([]*pppb.ProtoPackage, error) {
+	switch {
+	case *protoPackageSetFile != "" && *jsonFile != "":
+		return nil, cli.UsageErrorf("-%s and -%s cannot be combined", protoPackageSetFileFlagName, jsonFileFlagName)
+	case *protoPackageSetFile != "":
+		pkgset, err := readProtoPackageFileSet(protoPackageSetFileFlagName, *protoPackageSetFile)
+		if err != nil {
+			return nil, err
+		}
+		return pkgset.Packages, nil
+	case *jsonFile != "":
+		return readJsonFile(jsonFileFlagName, *jsonFile)
+	default:
+		return nil, cli.UsageErrorf("one of -%s or -%s is required", protoPackageSetFileFlagName, jsonFileFlagName)
+	}
+}
+
+func readProtoPackageFileSet(flag flagName, filename string) (*pppb.ProtoPackageSet, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", flag, err)
+	}
+	var msg pppb.ProtoPackageSet
+	if err := proto.Unmarshal(data, &msg); err != nil {
+		return nil, fmt.Errorf("unmarshaling %s: %w", flag, err)
+	}
+	return &msg, nil
+}
+
+// readJsonFile reads the packages of a json file: a list of packages (a
+// ListProtoPackagesResponse or a ProtoPackageSet, which share the 'packages'
+// field), or a single ProtoPackage.
+func readJsonFile(flag flagName, filename string) ([]*pppb.ProtoPackage, error) {
+	var data []byte
+	var err error
+	if filename == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(filename)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", flag, err)
+	}
+	unmarshaler := protojson.UnmarshalOptions{DiscardUnknown: true}
+	var pkgset pppb.ProtoPackageSet
+	if err := unmarshaler.Unmarshal(data, &pkgset); err != nil {
+		return nil, fmt.Errorf("unmarshaling %s: %w", flag, err)
+	}
+	if len(pkgset.Packages) > 0 {
+		return pkgset.Packages, nil
+	}
+	var pkg pppb.ProtoPackage
+	if err := unmarshaler.Unmarshal(data, &pkg); err != nil {
+		return nil, fmt.Errorf("unmarshaling %s: %w", flag, err)
+	}
+	if pkg.Name == "" {
+		return nil, nil
+	}
+	return []*pppb.ProtoPackage{&pkg}, nil
+}
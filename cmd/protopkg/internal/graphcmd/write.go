@@ -0,0 +1,235 @@
+package graphcmd
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// writers writes the graph in a format.  The nodes are grouped by repository
+// if clustered is set.
+var writers = map[string]func(w io.Writer, g *graph, clustered bool) error{
+	dotFormat:     writeDot,
+	mermaidFormat: writeMermaid,
+	graphmlFormat: writeGraphml,
+}
+
+// cluster is a group of nodes of the same repository.
+type cluster struct {
+	repository string
+	nodes      []*node
+}
+
+// makeClusters groups the nodes by repository, in the order of the nodes.
+// The nodes without a repository are returned separately.
+func makeClusters(nodes []*node) (clusters []*cluster, unclustered []*node) {
+	index := make(map[string]*cluster)
+	for _, n := range nodes {
+		if n.repository == "" {
+			unclustered = append(unclustered, n)
+			continue
+		}
+		c, ok := index[n.repository]
+		if !ok {
+			c = &cluster{repository: n.repository}
+			index[n.repository] = c
+			clusters = append(clusters, c)
+		}
+		c.nodes = append(c.nodes, n)
+	}
+	return
+}
+
+// writeDot writes the graph in the graphviz DOT language.
+func writeDot(w io.Writer, g *graph, clustered bool) error {
+	var b strings.Builder
+	b.WriteString("digraph dependencies {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box];\n")
+	writeNode := func(indent string, n *node) {
+		attrs := "label=" + dotQuote(n.label)
+		if n.external {
+			attrs += ", style=dashed"
+		}
+		fmt.Fprintf(&b, "%s%s [%s];\n", indent, dotQuote(n.id), attrs)
+	}
+	nodes := g.nodes
+	if clustered {
+		var clusters []*cluster
+		clusters, nodes = makeClusters(g.nodes)
+		for i, c := range clusters {
+			fmt.Fprintf(&b, "  subgraph cluster_%d {\n", i)
+			fmt.Fprintf(&b, "    label=%s;\n", dotQuote(c.repository))
+			for _, n := range c.nodes {
+				writeNode("    ", n)
+			}
+			b.WriteString("  }\n")
+		}
+	}
+	for _, n := range nodes {
+		writeNode("  ", n)
+	}
+	for _, e := range g.edges {
+		fmt.Fprintf(&b, "  %s -> %s;\n", dotQuote(e.from), dotQuote(e.to))
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// dotQuote returns s as a DOT quoted string.
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+// writeMermaid writes the graph as a mermaid flowchart.  The node ids are
+// not valid mermaid ids, so the nodes are numbered in order.
+func writeMermaid(w io.Writer, g *graph, clustered bool) error {
+	ids := make(map[string]string)
+	for i, n := range g.nodes {
+		ids[n.id] = fmt.Sprintf("n%d", i)
+	}
+	var b strings.Builder
+	b.WriteString("graph LR\n")
+	writeNode := func(indent string, n *node) {
+		fmt.Fprintf(&b, "%s%s[%s]\n", indent, ids[n.id], mermaidQuote(n.label))
+	}
+	nodes := g.nodes
+	if clustered {
+		var clusters []*cluster
+		clusters, nodes = makeClusters(g.nodes)
+		for i, c := range clusters {
+			fmt.Fprintf(&b, "  subgraph c%d[%s]\n", i, mermaidQuote(c.repository))
+			for _, n := range c.nodes {
+				writeNode("    ", n)
+			}
+			b.WriteString("  end\n")
+		}
+	}
+	for _, n := range nodes {
+		writeNode("  ", n)
+	}
+	for _, e := range g.edges {
+		fmt.Fprintf(&b, "  %s --> %s\n", ids[e.from], ids[e.to])
+	}
+	var external []string
+	for _, n := range g.nodes {
+		if n.external {
+			external = append(external, ids[n.id])
+		}
+	}
+	if len(external) > 0 {
+		b.WriteString("  classDef external stroke-dasharray: 5 5\n")
+		fmt.Fprintf(&b, "  class %s external\n", strings.Join(external, ","))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// mermaidQuote returns s as a mermaid quoted string.  Quotes cannot be
+// escaped, but are written as an entity code.
+func mermaidQuote(s string) string {
+	return `"` + strings.NewReplacer(`"`, "#quot;", "\n", " ").Replace(s) + `"`
+}
+
+// graphml is the root element of a GraphML document.
+type graphml struct {
+	XMLName xml.Name     `xml:"graphml"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Keys    []graphmlKey `xml:"key"`
+	Graph   graphmlGraph `xml:"graph"`
+}
+
+type graphmlKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+type graphmlGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphmlNode `xml:"node"`
+	Edges       []graphmlEdge `xml:"edge"`
+}
+
+type graphmlNode struct {
+	ID    string        `xml:"id,attr"`
+	Data  []graphmlData `xml:"data"`
+	Graph *graphmlGraph `xml:"graph,omitempty"`
+}
+
+type graphmlEdge struct {
+	Source string `xml:"source,attr"`
+	Target string `xml:"target,attr"`
+}
+
+type graphmlData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// writeGraphml writes the graph as GraphML.  The clusters are nodes with a
+// nested graph of the nodes of the repository.
+func writeGraphml(w io.Writer, g *graph, clustered bool) error {
+	doc := &graphml{
+		Xmlns: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphmlKey{
+			{ID: "label", For: "node", AttrName: "label", AttrType: "string"},
+			{ID: "repository", For: "node", AttrName: "repository", AttrType: "string"},
+			{ID: "kind", For: "node", AttrName: "kind", AttrType: "string"},
+		},
+		Graph: graphmlGraph{ID: "dependencies", EdgeDefault: "directed"},
+	}
+	makeNode := func(n *node) graphmlNode {
+		kind := *level
+		if n.external {
+			kind = "external"
+		}
+		data := []graphmlData{{Key: "label", Value: n.label}}
+		if n.repository != "" {
+			data = append(data, graphmlData{Key: "repository", Value: n.repository})
+		}
+		data = append(data, graphmlData{Key: "kind", Value: kind})
+		return graphmlNode{ID: n.id, Data: data}
+	}
+	nodes := g.nodes
+	if clustered {
+		var clusters []*cluster
+		clusters, nodes = makeClusters(g.nodes)
+		for i, c := range clusters {
+			id := fmt.Sprintf("cluster_%d", i)
+			sub := &graphmlGraph{ID: id + ":", EdgeDefault: "directed"}
+			for _, n := range c.nodes {
+				sub.Nodes = append(sub.Nodes, makeNode(n))
+			}
+			doc.Graph.Nodes = append(doc.Graph.Nodes, graphmlNode{
+				ID: id,
+				Data: []graphmlData{
+					{Key: "label", Value: c.repository},
+					{Key: "repository", Value: c.repository},
+					{Key: "kind", Value: "repository"},
+				},
+				Graph: sub,
+			})
+		}
+	}
+	for _, n := range nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, makeNode(n))
+	}
+	for _, e := range g.edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphmlEdge{Source: e.from, Target: e.to})
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("encoding graphml: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
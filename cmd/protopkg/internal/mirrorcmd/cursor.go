@@ -0,0 +1,162 @@
+package mirrorcmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+)
+
+// cursor records, per repository, the commit time of the most recent package
+// that the destination has.  Packages committed before it are not considered
+// by later runs.  Packages committed at the same time are considered again,
+// as a commit may have been mirrored partially.  The cursor only holds for
+// the servers and filters it was written for: a cursor of a run restricted to
+// some package names says nothing about the other packages of a repository.
+type cursor struct {
+	filename string
+	// Source is the address of the source server.
+	Source string `json:"source"`
+	// Destination is the address of the destination server.
+	Destination string `json:"destination"`
+	// Filter selects the packages that were mirrored.
+	Filter cursorFilter `json:"filter"`
+	// Repositories maps the full name of a repository to the commit time.
+	Repositories map[string]time.Time `json:"repositories"`
+}
+
+// cursorFilter is the selection of the mirrored packages.
+type cursorFilter struct {
+	// Repositories are the full names of the mirrored repositories, sorted;
+	// empty for all repositories.
+	Repositories []string `json:"repositories,omitempty"`
+	// Names are the names of the mirrored packages, sorted; empty for all
+	// packages.
+	Names []string `json:"names,omitempty"`
+}
+
+// makeCursorFilter returns the filter of the repositories and package names
+// given on the command line.
+func makeCursorFilter(repositories, names []string) cursorFilter {
+	return cursorFilter{
+		Repositories: sortedCopy(repositories),
+		Names:        sortedCopy(names),
+	}
+}
+
+func (f cursorFilter) equal(other cursorFilter) bool {
+	return strings.Join(f.Repositories, ",") == strings.Join(other.Repositories, ",") &&
+		strings.Join(f.Names, ",") == strings.Join(other.Names, ",")
+}
+
+func (f cursorFilter) String() string {
+	repositories := "all repositories"
+	if len(f.Repositories) > 0 {
+		repositories = "repositories " + strings.Join(f.Repositories, ",")
+	}
+	names := "all packages"
+	if len(f.Names) > 0 {
+		names = "packages " + strings.Join(f.Names, ",")
+	}
+	return names + " of " + repositories
+}
+
+// readCursor reads the cursor file.  A missing file is an empty cursor, and
+// an empty filename disables the cursor.  It is an error if the cursor was
+// written for other servers or another filter.
+func readCursor(filename, source, destination string, filter cursorFilter) (*cursor, error) {
+	c := &cursor{
+		filename:     filename,
+		Source:       source,
+		Destination:  destination,
+		Filter:       filter,
+		Repositories: make(map[string]time.Time),
+	}
+	if filename == "" {
+		return c, nil
+	}
+
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading cursor: %w", err)
+	}
+	var stored cursor
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("unmarshaling cursor %s: %w", filename, err)
+	}
+	if stored.Source != source || stored.Destination != destination {
+		return nil, fmt.Errorf("cursor %s is for mirroring %s to %s, not %s to %s", filename, stored.Source, stored.Destination, source, destination)
+	}
+	if !stored.Filter.equal(filter) {
+		return nil, fmt.Errorf("cursor %s is for mirroring %s, not %s (use another cursor file per filter)", filename, stored.Filter, filter)
+	}
+	for repo, t := range stored.Repositories {
+		c.Repositories[repo] = t
+	}
+	return c, nil
+}
+
+// before reports whether the package was committed before the cursor of its
+// repository.
+func (c *cursor) before(pkg *pppb.ProtoPackage) bool {
+	t, ok := c.Repositories[pkg.GetArchive().GetRepository().GetFullName()]
+	return ok && pkg.GetArchive().GetCommitTime().AsTime().Before(t)
+}
+
+// advance moves the cursor of the repositories of the packages to their most
+// recent commit.  It reports whether the cursor changed.
+func (c *cursor) advance(pkgs []*pppb.ProtoPackage) bool {
+	changed := false
+	for _, pkg := range pkgs {
+		repo := pkg.GetArchive().GetRepository().GetFullName()
+		t := pkg.GetArchive().GetCommitTime().AsTime()
+		if current, ok := c.Repositories[repo]; !ok || t.After(current) {
+			c.Repositories[repo] = t
+			changed = true
+		}
+	}
+	return changed
+}
+
+// write replaces the cursor file.  The file is written next to it and
+// renamed, such that an interrupted write leaves the previous cursor.
+func (c *cursor) write() error {
+	if c.filename == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling cursor: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.filename), 0755); err != nil {
+		return fmt.Errorf("creating cursor directory: %w", err)
+	}
+	tmp := c.filename + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("writing cursor: %w", err)
+	}
+	if err := os.Rename(tmp, c.filename); err != nil {
+		return fmt.Errorf("writing cursor: %w", err)
+	}
+	log.Println("wrote:", c.filename)
+	return nil
+}
+
+func sortedCopy(list []string) []string {
+	if len(list) == 0 {
+		return nil
+	}
+	sorted := append([]string(nil), list...)
+	sort.Strings(sorted)
+	return sorted
+}
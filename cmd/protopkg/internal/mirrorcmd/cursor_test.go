@@ -0,0 +1,143 @@
+package mirrorcmd
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	testSource      = "source:1080"
+	testDestination = "destination:1080"
+)
+
+func TestReadCursor(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "mirror", "cursor.json")
+	filter := makeCursorFilter([]string{"github.com/b/protos", "github.com/a/protos"}, nil)
+	commitTime := time.Unix(1700000000, 0).UTC()
+
+	// a missing file is an empty cursor
+	c, err := readCursor(filename, testSource, testDestination, filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Repositories) != 0 {
+		t.Fatalf("got repositories %v, want none", c.Repositories)
+	}
+	c.Repositories["github.com/a/protos"] = commitTime
+	if err := c.write(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the order of the repositories does not matter
+	same := makeCursorFilter([]string{"github.com/a/protos", "github.com/b/protos"}, nil)
+	c, err = readCursor(filename, testSource, testDestination, same)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Repositories["github.com/a/protos"]; !got.Equal(commitTime) {
+		t.Errorf("got commit time %v, want %v", got, commitTime)
+	}
+
+	for _, tc := range []struct {
+		name        string
+		source      string
+		destination string
+		filter      cursorFilter
+		wantErr     string
+	}{
+		{
+			name:        "other source",
+			source:      "other:1080",
+			destination: testDestination,
+			filter:      filter,
+			wantErr:     "is for mirroring source:1080 to destination:1080, not other:1080 to destination:1080",
+		},
+		{
+			name:        "other destination",
+			source:      testSource,
+			destination: "other:1080",
+			filter:      filter,
+			wantErr:     "is for mirroring source:1080 to destination:1080, not source:1080 to other:1080",
+		},
+		{
+			name:        "other repositories",
+			source:      testSource,
+			destination: testDestination,
+			filter:      makeCursorFilter([]string{"github.com/a/protos"}, nil),
+			wantErr:     "is for mirroring all packages of repositories github.com/a/protos,github.com/b/protos, not all packages of repositories github.com/a/protos",
+		},
+		{
+			name:        "other names",
+			source:      testSource,
+			destination: testDestination,
+			filter:      makeCursorFilter([]string{"github.com/a/protos", "github.com/b/protos"}, []string{"google.api"}),
+			wantErr:     "not packages google.api of repositories",
+		},
+		{
+			name:        "all",
+			source:      testSource,
+			destination: testDestination,
+			filter:      makeCursorFilter(nil, nil),
+			wantErr:     "not all packages of all repositories",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := readCursor(filename, tc.source, tc.destination, tc.filter)
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("got error %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestCursorAdvance(t *testing.T) {
+	c, err := readCursor("", testSource, testDestination, makeCursorFilter(nil, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := func(repository string, sec int64) *pppb.ProtoPackage {
+		return &pppb.ProtoPackage{
+			Name: "acme.v1",
+			Archive: &pppb.ProtoArchive{
+				Repository: &pppb.ProtoRepository{FullName: repository},
+				CommitTime: timestamppb.New(time.Unix(sec, 0)),
+			},
+		}
+	}
+
+	if c.before(pkg("github.com/a/protos", 100)) {
+		t.Error("an empty cursor is after a package")
+	}
+	if !c.advance([]*pppb.ProtoPackage{pkg("github.com/a/protos", 100), pkg("github.com/a/protos", 200), pkg("github.com/b/protos", 50)}) {
+		t.Error("advance: got unchanged, want changed")
+	}
+	if c.advance([]*pppb.ProtoPackage{pkg("github.com/a/protos", 150)}) {
+		t.Error("advance to an older commit: got changed, want unchanged")
+	}
+
+	for _, tc := range []struct {
+		pkg  *pppb.ProtoPackage
+		want bool
+	}{
+		{pkg: pkg("github.com/a/protos", 100), want: true},
+		// a commit at the cursor may have been mirrored partially
+		{pkg: pkg("github.com/a/protos", 200), want: false},
+		{pkg: pkg("github.com/b/protos", 49), want: true},
+		{pkg: pkg("github.com/c/protos", 0), want: false},
+	} {
+		repository := tc.pkg.Archive.Repository.FullName
+		if got := c.before(tc.pkg); got != tc.want {
+			t.Errorf("before(%s, %v): got %v, want %v", repository, tc.pkg.Archive.CommitTime.AsTime(), got, tc.want)
+		}
+	}
+
+	// the disabled cursor is not written
+	if err := c.write(); err != nil {
+		t.Fatal(err)
+	}
+}
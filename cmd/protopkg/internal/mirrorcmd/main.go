@@ -0,0 +1,170 @@
+package mirrorcmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	longrunningpb "cloud.google.com/go/longrunning/autogen/longrunningpb"
+	"github.com/protopkg/apis/cmd/protopkg/internal/cli"
+	"github.com/protopkg/apis/pkg/chunk"
+	"github.com/protopkg/apis/pkg/deporder"
+	"github.com/protopkg/apis/pkg/dial"
+	"github.com/protopkg/apis/pkg/store"
+	regpb "github.com/protopkg/apis/protopkg/registry/v1alpha1"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+)
+
+type flagName string
+
+const (
+	sourceAddressFlagName      flagName = "source_address"
+	destinationAddressFlagName flagName = "destination_address"
+	repositoriesFlagName       flagName = "repositories"
+	namesFlagName              flagName = "names"
+	cursorFileFlagName         flagName = "cursor_file"
+	dryRunFlagName             flagName = "dry_run"
+	timeoutFlagName            flagName = "timeout"
+	pollIntervalFlagName       flagName = "poll_interval"
+	maxMessageSizeFlagName     flagName = "max_message_size"
+	intervalFlagName           flagName = "interval"
+)
+
+// Command is the 'mirror' subcommand.
+var Command = &cli.Command{
+	Name:    "mirror",
+	Summary: "Copy the packages of a packages server that another one lacks",
+	Flags:   flags,
+	Run:     run,
+}
+
+var flags = flag.NewFlagSet("mirror", flag.ContinueOnError)
+
+var (
+	sourceAddress      = flags.String(string(sourceAddressFlagName), "", "address of the packages server to copy the packages from")
+	destinationAddress = flags.String(string(destinationAddressFlagName), "", "address of the packages server to copy the packages to")
+	repositories       = flags.String(string(repositoriesFlagName), "", "comma-separated list of the full names of the repositories to mirror (e.g. 'github.com/googleapis/googleapis'); all repositories if not set")
+	names              = flags.String(string(namesFlagName), "", "comma-separated list of the names of the packages to mirror; all packages of the repositories if not set")
+	cursorFile         = flags.String(string(cursorFileFlagName), "", "path of a json file that records the most recent commit mirrored per repository; packages committed before it are not considered again (remove the file to mirror everything); a cursor is only valid for the servers, -repositories and -names it was written for")
+	dryRun             = flags.Bool(string(dryRunFlagName), false, "print the packages that would be copied, without sending anything")
+	timeout            = flags.Duration(string(timeoutFlagName), 30*time.Minute, "deadline of a mirror run, including the wait for the destination to accept the packages (0 for none)")
+	pollInterval       = flags.Duration(string(pollIntervalFlagName), 2*time.Second, "interval between operation status requests of the destination")
+	maxMessageSize     = flags.Int(string(maxMessageSizeFlagName), chunk.DefaultMaxMessageSize, "maximum size in bytes of an upload message; larger packages are sent in file-level chunks")
+	interval           = flags.Duration(string(intervalFlagName), 0, "keep running, mirroring again after this interval (0 to mirror once)")
+	sourceDialOptions  dial.Options
+	destDialOptions    dial.Options
+)
+
+func init() {
+	sourceDialOptions.RegisterPrefixedFlags(flags, "source_")
+	destDialOptions.RegisterPrefixedFlags(flags, "destination_")
+}
+
+func run() error {
+	if *sourceAddress == "" {
+		return errorFlagRequired(sourceAddressFlagName)
+	}
+	if *destinationAddress == "" {
+		return errorFlagRequired(destinationAddressFlagName)
+	}
+	if *sourceAddress == *destinationAddress {
+		return cli.UsageErrorf("-%s and -%s must be different servers", sourceAddressFlagName, destinationAddressFlagName)
+	}
+
+	sourceConn, err := dial.Dial(*sourceAddress, &sourceDialOptions)
+	if err != nil {
+		return fmt.Errorf("mirror failed: source: %w", err)
+	}
+	defer sourceConn.Close()
+	destConn, err := dial.Dial(*destinationAddress, &destDialOptions)
+	if err != nil {
+		return fmt.Errorf("mirror failed: destination: %w", err)
+	}
+	defer destConn.Close()
+
+	m := &mirror{
+		source:       regpb.NewRegistryClient(sourceConn),
+		destination:  regpb.NewRegistryClient(destConn),
+		packages:     pppb.NewPackagesClient(destConn),
+		operations:   longrunningpb.NewOperationsClient(destConn),
+		filters:      makeFilters(splitList(*repositories), splitList(*names)),
+		cursorFilter: makeCursorFilter(splitList(*repositories), splitList(*names)),
+	}
+
+	if *interval <= 0 || *dryRun {
+		return runOnce(m)
+	}
+	for {
+		if err := runOnce(m); err != nil {
+			log.Printf("mirror failed (retrying in %v): %v", *interval, err)
+		}
+		time.Sleep(*interval)
+	}
+}
+
+// runOnce mirrors the packages that were committed since the cursor, and
+// advances the cursor once the destination has accepted them.
+func runOnce(m *mirror) error {
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	cursor, err := readCursor(*cursorFile, *sourceAddress, *destinationAddress, m.cursorFilter)
+	if err != nil {
+		return err
+	}
+	candidates, err := m.listCandidates(ctx, cursor)
+	if err != nil {
+		return err
+	}
+	missing, err := m.filterMissing(ctx, candidates)
+	if err != nil {
+		return err
+	}
+	pkgs, err := m.fetch(ctx, missing)
+	if err != nil {
+		return err
+	}
+	if pkgs, err = deporder.Packages(pkgs); err != nil {
+		return err
+	}
+
+	if *dryRun {
+		for _, pkg := range pkgs {
+			fmt.Println(store.Ref(pkg))
+		}
+		return nil
+	}
+
+	if len(pkgs) == 0 {
+		log.Printf("nothing to mirror: %d packages up to date", len(candidates))
+	} else if err := m.upload(ctx, pkgs, *maxMessageSize, *pollInterval); err != nil {
+		return err
+	}
+
+	if cursor.advance(candidates) {
+		return cursor.write()
+	}
+	return nil
+}
+
+// splitList splits a comma-separated list, ignoring empty elements.
+func splitList(value string) []string {
+	var list []string
+	for _, elem := range strings.Split(value, ",") {
+		if elem = strings.TrimSpace(elem); elem != "" {
+			list = append(list, elem)
+		}
+	}
+	return list
+}
+
+func errorFlagRequired(name flagName) error {
+	return cli.UsageErrorf("flag required but not provided: -%s", name)
+}
@@ -0,0 +1,204 @@
+package mirrorcmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	longrunningpb "cloud.google.com/go/longrunning/autogen/longrunningpb"
+	"github.com/protopkg/apis/pkg/protohash"
+	"github.com/protopkg/apis/pkg/store"
+	regpb "github.com/protopkg/apis/protopkg/registry/v1alpha1"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// listPageSize is the page size of the package listings, and the batch size
+// of the existence checks.
+const listPageSize = 500
+
+// headerMask selects the fields of the listed packages needed to decide
+// whether to copy them; the packages to copy are fetched in full.
+var headerMask = &fieldmaskpb.FieldMask{Paths: []string{"name", "hash", "archive"}}
+
+// mirror copies packages from the source server to the destination server.
+type mirror struct {
+	source      regpb.RegistryClient
+	destination regpb.RegistryClient
+	packages    pppb.PackagesClient
+	operations  longrunningpb.OperationsClient
+	// filters are the listings of the source that select the packages to
+	// mirror.
+	filters []*regpb.ListProtoPackagesRequest
+	// cursorFilter is the selection of the filters, as recorded in the
+	// cursor.
+	cursorFilter cursorFilter
+}
+
+// makeFilters returns a listing for each combination of repository and
+// package name.
+func makeFilters(repositories, names []string) []*regpb.ListProtoPackagesRequest {
+	if len(repositories) == 0 {
+		repositories = []string{""}
+	}
+	if len(names) == 0 {
+		names = []string{""}
+	}
+	var filters []*regpb.ListProtoPackagesRequest
+	for _, repo := range repositories {
+		for _, name := range names {
+			filters = append(filters, &regpb.ListProtoPackagesRequest{
+				Repository: repo,
+				Name:       name,
+				PageSize:   listPageSize,
+				ReadMask:   headerMask,
+			})
+		}
+	}
+	return filters
+}
+
+// listCandidates returns the headers of the packages of the source that
+// match the filters and were not committed before the cursor.
+func (m *mirror) listCandidates(ctx context.Context, c *cursor) ([]*pppb.ProtoPackage, error) {
+	var candidates []*pppb.ProtoPackage
+	seen := make(map[string]bool)
+	skipped := 0
+	for _, filter := range m.filters {
+		req := proto.Clone(filter).(*regpb.ListProtoPackagesRequest)
+	pages:
+		for {
+			resp, err := m.source.ListProtoPackages(ctx, req)
+			if err != nil {
+				return nil, fmt.Errorf("listing source packages: %w", err)
+			}
+			for _, pkg := range resp.Packages {
+				id := store.ID(pkg.Name, pkg.Hash)
+				if seen[id] {
+					continue
+				}
+				seen[id] = true
+				if c.before(pkg) {
+					skipped++
+					if req.Repository != "" {
+						// the packages are listed most recently committed
+						// first: the remaining ones are before the cursor
+						// too.
+						break pages
+					}
+					continue
+				}
+				candidates = append(candidates, pkg)
+			}
+			if resp.NextPageToken == "" {
+				break
+			}
+			req.PageToken = resp.NextPageToken
+		}
+	}
+	if skipped > 0 {
+		log.Printf("skipped %d packages committed before the cursor", skipped)
+	}
+	return candidates, nil
+}
+
+// check returns the status of the packages on the destination, in order.
+func (m *mirror) check(ctx context.Context, refs []*regpb.ProtoPackageRef) ([]*regpb.ProtoPackageStatus, error) {
+	var statuses []*regpb.ProtoPackageStatus
+	for start := 0; start < len(refs); start += listPageSize {
+		end := start + listPageSize
+		if end > len(refs) {
+			end = len(refs)
+		}
+		resp, err := m.destination.CheckProtoPackages(ctx, &regpb.CheckProtoPackagesRequest{Packages: refs[start:end]})
+		if err != nil {
+			return nil, fmt.Errorf("checking destination packages: %w", err)
+		}
+		if len(resp.Packages) != end-start {
+			return nil, fmt.Errorf("checking destination packages: server returned %d statuses for %d packages", len(resp.Packages), end-start)
+		}
+		statuses = append(statuses, resp.Packages...)
+	}
+	return statuses, nil
+}
+
+// filterMissing returns the packages that the destination does not have.  It
+// is an error if the destination has a different version of a package under
+// the same ref.
+func (m *mirror) filterMissing(ctx context.Context, pkgs []*pppb.ProtoPackage) ([]*pppb.ProtoPackage, error) {
+	refs := make([]*regpb.ProtoPackageRef, len(pkgs))
+	for i, pkg := range pkgs {
+		refs[i] = &regpb.ProtoPackageRef{
+			Ref:  store.Ref(pkg),
+			Name: pkg.Name,
+			Hash: pkg.Hash,
+		}
+	}
+	statuses, err := m.check(ctx, refs)
+	if err != nil {
+		return nil, err
+	}
+	var missing []*pppb.ProtoPackage
+	for i, pkg := range pkgs {
+		st := statuses[i]
+		switch st.State {
+		case regpb.ProtoPackageStatus_EXISTS:
+		case regpb.ProtoPackageStatus_CONFLICT:
+			return nil, fmt.Errorf("%s: the destination has hash %s (source has %s)", store.Ref(pkg), st.Hash, pkg.Hash)
+		default:
+			missing = append(missing, pkg)
+		}
+	}
+	return missing, nil
+}
+
+// fetch gets the packages from the source, along with the dependencies that
+// the destination does not have, and verifies their hashes.
+func (m *mirror) fetch(ctx context.Context, hdrs []*pppb.ProtoPackage) ([]*pppb.ProtoPackage, error) {
+	var pkgs []*pppb.ProtoPackage
+	seen := make(map[string]bool)
+	for _, hdr := range hdrs {
+		id := store.ID(hdr.Name, hdr.Hash)
+		pkg, err := m.source.LookupProtoPackage(ctx, &regpb.LookupProtoPackageRequest{Id: id})
+		if err != nil {
+			return nil, fmt.Errorf("fetching %s: %w", id, err)
+		}
+		if pkg.Hash != hdr.Hash {
+			return nil, fmt.Errorf("%s: the source returned hash %s", id, pkg.Hash)
+		}
+		seen[store.Ref(pkg)] = true
+		pkgs = append(pkgs, pkg)
+	}
+
+	for i := 0; i < len(pkgs); i++ {
+		ref := store.Ref(pkgs[i])
+		if err := protohash.Verify(pkgs[i]); err != nil {
+			return nil, fmt.Errorf("%s: %w", ref, err)
+		}
+		log.Println("fetched:", ref)
+		for _, dep := range pkgs[i].Dependencies {
+			if seen[dep] {
+				continue
+			}
+			seen[dep] = true
+			statuses, err := m.check(ctx, []*regpb.ProtoPackageRef{{Ref: dep}})
+			if err != nil {
+				return nil, err
+			}
+			switch statuses[0].State {
+			case regpb.ProtoPackageStatus_EXISTS:
+				continue
+			case regpb.ProtoPackageStatus_CONFLICT:
+				return nil, fmt.Errorf("%s: dependency %s: the destination has a different version (hash %s)", ref, dep, statuses[0].Hash)
+			}
+			depPkg, err := m.source.LookupProtoPackage(ctx, &regpb.LookupProtoPackageRequest{Ref: dep})
+			if err != nil {
+				return nil, fmt.Errorf("%s: fetching dependency %s: %w", ref, dep, err)
+			}
+			log.Printf("dependency: %s (of %s)", dep, ref)
+			pkgs = append(pkgs, depPkg)
+		}
+	}
+	return pkgs, nil
+}
@@ -0,0 +1,209 @@
+package mirrorcmd
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/protopkg/apis/pkg/protohash"
+	"github.com/protopkg/apis/pkg/server"
+	"github.com/protopkg/apis/pkg/store"
+	regpb "github.com/protopkg/apis/protopkg/registry/v1alpha1"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// newTestRegistry starts a server on an in-memory store with the given
+// packages, and returns a registry client of it.
+func newTestRegistry(t *testing.T, pkgs ...*pppb.ProtoPackage) regpb.RegistryClient {
+	t.Helper()
+	st := store.NewMemory()
+	for _, pkg := range pkgs {
+		if _, err := st.Put(context.Background(), pkg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lis := bufconn.Listen(1024 * 1024)
+	srv := server.New(st)
+	grpcServer := grpc.NewServer()
+	srv.Register(grpcServer)
+	go grpcServer.Serve(lis)
+	t.Cleanup(func() {
+		grpcServer.Stop()
+		srv.Wait()
+	})
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return regpb.NewRegistryClient(conn)
+}
+
+// newTestPackage returns a valid package of github.com/acme/protos with a
+// single file, whose content depends on the syntax.
+func newTestPackage(t *testing.T, name, syntax string, deps ...*pppb.ProtoPackage) *pppb.ProtoPackage {
+	t.Helper()
+	file := &pppb.ProtoFile{
+		File: &descriptorpb.FileDescriptorProto{
+			Name:    proto.String(strings.ReplaceAll(name, ".", "/") + "/file.proto"),
+			Package: proto.String(name),
+			Syntax:  proto.String(syntax),
+		},
+	}
+	fileHash, err := protohash.File(file.File, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Hash = fileHash
+	pkg := &pppb.ProtoPackage{
+		Name: name,
+		Archive: &pppb.ProtoArchive{
+			Repository: &pppb.ProtoRepository{FullName: "github.com/acme/protos"},
+			CommitSha1: "0123456789abcdef0123456789abcdef01234567",
+			ShortSha1:  "0123456",
+		},
+		Files: []*pppb.ProtoFile{file},
+	}
+	for _, dep := range deps {
+		pkg.Dependencies = append(pkg.Dependencies, store.Ref(dep))
+	}
+	if pkg.Hash, err = protohash.Package(pkg.Files); err != nil {
+		t.Fatal(err)
+	}
+	return pkg
+}
+
+// refs returns the refs of the packages.
+func refs(pkgs []*pppb.ProtoPackage) string {
+	var list []string
+	for _, pkg := range pkgs {
+		list = append(list, store.Ref(pkg))
+	}
+	return strings.Join(list, ",")
+}
+
+func TestFilterMissing(t *testing.T) {
+	common := newTestPackage(t, "acme.common", "proto3")
+	api := newTestPackage(t, "acme.api", "proto3", common)
+	other := newTestPackage(t, "acme.other", "proto3")
+	conflicting := newTestPackage(t, "acme.other", "proto2")
+
+	for _, tc := range []struct {
+		name        string
+		destination []*pppb.ProtoPackage
+		want        []*pppb.ProtoPackage
+		wantErr     string
+	}{
+		{
+			name: "empty destination",
+			want: []*pppb.ProtoPackage{common, api, other},
+		},
+		{
+			name:        "partial destination",
+			destination: []*pppb.ProtoPackage{common},
+			want:        []*pppb.ProtoPackage{api, other},
+		},
+		{
+			name:        "up to date",
+			destination: []*pppb.ProtoPackage{common, api, other},
+		},
+		{
+			name:        "conflict",
+			destination: []*pppb.ProtoPackage{conflicting},
+			wantErr:     "github.com/acme/protos/0123456/~:acme.other: the destination has hash " + conflicting.Hash + " (source has " + other.Hash + ")",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m := &mirror{destination: newTestRegistry(t, tc.destination...)}
+			got, err := m.filterMissing(context.Background(), []*pppb.ProtoPackage{common, api, other})
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("got error %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if refs(got) != refs(tc.want) {
+				t.Errorf("got %s, want %s", refs(got), refs(tc.want))
+			}
+		})
+	}
+}
+
+func TestFetch(t *testing.T) {
+	base := newTestPackage(t, "acme.base", "proto3")
+	common := newTestPackage(t, "acme.common", "proto3", base)
+	api := newTestPackage(t, "acme.api", "proto3", common)
+	tampered := proto.Clone(api).(*pppb.ProtoPackage)
+	tampered.Files[0].File.Syntax = proto.String("proto2")
+
+	for _, tc := range []struct {
+		name        string
+		source      []*pppb.ProtoPackage
+		destination []*pppb.ProtoPackage
+		want        []*pppb.ProtoPackage
+		wantErr     string
+	}{
+		{
+			// the missing dependencies are fetched along
+			name:   "dependencies",
+			source: []*pppb.ProtoPackage{base, common, api},
+			want:   []*pppb.ProtoPackage{api, common, base},
+		},
+		{
+			name:        "dependency on the destination",
+			source:      []*pppb.ProtoPackage{base, common, api},
+			destination: []*pppb.ProtoPackage{common},
+			want:        []*pppb.ProtoPackage{api},
+		},
+		{
+			name:    "dependency missing on the source",
+			source:  []*pppb.ProtoPackage{common, api},
+			wantErr: "fetching dependency github.com/acme/protos/0123456/~:acme.base",
+		},
+		{
+			name:    "tampered",
+			source:  []*pppb.ProtoPackage{base, common, tampered},
+			wantErr: "github.com/acme/protos/0123456/~:acme.api: acme/api/file.proto: hash mismatch",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m := &mirror{
+				source:      newTestRegistry(t, tc.source...),
+				destination: newTestRegistry(t, tc.destination...),
+			}
+			got, err := m.fetch(context.Background(), []*pppb.ProtoPackage{{Name: api.Name, Hash: api.Hash}})
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if refs(got) != refs(tc.want) {
+				t.Errorf("got %s, want %s", refs(got), refs(tc.want))
+			}
+			for i, pkg := range got {
+				if !proto.Equal(pkg, tc.want[i]) {
+					t.Errorf("got %v, want %v", pkg, tc.want[i])
+				}
+			}
+		})
+	}
+}
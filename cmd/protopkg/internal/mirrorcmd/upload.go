@@ -0,0 +1,141 @@
+package mirrorcmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	longrunningpb "cloud.google.com/go/longrunning/autogen/longrunningpb"
+	"github.com/protopkg/apis/pkg/chunk"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	_ "google.golang.org/genproto/googleapis/rpc/errdetails" // register error detail types for printing
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// upload sends the dependency-ordered packages to the destination, and waits
+// for the destination to accept them.
+func (m *mirror) upload(ctx context.Context, pkgs []*pppb.ProtoPackage, maxMessageSize int, pollInterval time.Duration) error {
+	for _, batch := range makeBatches(pkgs) {
+		if err := m.uploadBatch(ctx, batch, maxMessageSize, pollInterval); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// makeBatches splits the dependency-ordered packages into consecutive
+// batches of packages with distinct names, as a server does not accept two
+// versions of a package in one upload.  Each batch only depends on itself
+// and the batches before it.
+func makeBatches(pkgs []*pppb.ProtoPackage) [][]*pppb.ProtoPackage {
+	var batches [][]*pppb.ProtoPackage
+	var batch []*pppb.ProtoPackage
+	names := make(map[string]bool)
+	for _, pkg := range pkgs {
+		if names[pkg.Name] {
+			batches = append(batches, batch)
+			batch = nil
+			names = make(map[string]bool)
+		}
+		names[pkg.Name] = true
+		batch = append(batch, pkg)
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// uploadBatch sends the packages in a single stream, and waits for the
+// destination to accept them.
+func (m *mirror) uploadBatch(ctx context.Context, pkgs []*pppb.ProtoPackage, maxMessageSize int, pollInterval time.Duration) error {
+	chunked := false
+	chunks := make([][]*pppb.ProtoPackage, len(pkgs))
+	for i, pkg := range pkgs {
+		split, err := chunk.Split(pkg, maxMessageSize)
+		if err != nil {
+			return err
+		}
+		if len(split) > 1 {
+			log.Printf("chunked: %s (%d bytes, %d chunks)", pkg.Name, proto.Size(pkg), len(split))
+			chunked = true
+		}
+		chunks[i] = split
+	}
+	if chunked {
+		ctx = metadata.AppendToOutgoingContext(ctx, chunk.MetadataKey, chunk.MetadataValue)
+	}
+
+	stream, err := m.packages.CreateProtoPackage(ctx)
+	if err != nil {
+		return fmt.Errorf("creating client stream call: %w", err)
+	}
+send:
+	for _, split := range chunks {
+		for _, pkg := range split {
+			if err := stream.Send(&pppb.CreateProtoPackageRequest{Pkg: pkg}); err != nil {
+				if errors.Is(err, io.EOF) {
+					// the server ended the stream, the actual error is
+					// reported by CloseAndRecv
+					break send
+				}
+				return fmt.Errorf("sending package %s: %w", pkg.Name, err)
+			}
+		}
+		log.Println("uploaded:", split[0].Name)
+	}
+	op, err := stream.CloseAndRecv()
+	if err != nil {
+		return fmt.Errorf("close-recv stream call: %w", err)
+	}
+
+	if op, err = m.wait(ctx, op, pollInterval); err != nil {
+		return err
+	}
+	if rpcStatus := op.GetError(); rpcStatus != nil {
+		st := status.FromProto(rpcStatus)
+		var b strings.Builder
+		fmt.Fprintf(&b, "operation %s failed: %s: %s", op.Name, st.Code(), st.Message())
+		for _, detail := range rpcStatus.Details {
+			if data, err := protojson.Marshal(detail); err == nil {
+				fmt.Fprintf(&b, "\n  %s", data)
+			} else {
+				fmt.Fprintf(&b, "\n  %s", detail.TypeUrl)
+			}
+		}
+		return errors.New(b.String())
+	}
+	log.Printf("mirrored %d packages (operation %s)", len(pkgs), op.Name)
+	return nil
+}
+
+// wait polls the operation until it is done.
+func (m *mirror) wait(ctx context.Context, op *longrunningpb.Operation, interval time.Duration) (*longrunningpb.Operation, error) {
+	if op.Done {
+		return op, nil
+	}
+	if op.Name == "" {
+		return nil, errors.New("cannot wait: the destination returned an operation without a name")
+	}
+	log.Printf("waiting for operation %s", op.Name)
+	for !op.Done {
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for operation %s: %w", op.Name, ctx.Err())
+		}
+		next, err := m.operations.GetOperation(ctx, &longrunningpb.GetOperationRequest{Name: op.Name})
+		if err != nil {
+			return nil, fmt.Errorf("getting operation %s: %w", op.Name, err)
+		}
+		op = next
+	}
+	return op, nil
+}
@@ -0,0 +1,148 @@
+package ocicmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/protopkg/apis/pkg/oci"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// artifactType is the OCI artifact type of a proto package.
+	artifactType = "application/vnd.protopkg.package.v1"
+	// protoMediaType is the media type of the proto-encoded ProtoPackage
+	// layer.
+	protoMediaType = "application/vnd.protopkg.package.v1+proto"
+	// jsonMediaType is the media type of the json-encoded ProtoPackage layer.
+	jsonMediaType = "application/vnd.protopkg.package.v1+json"
+)
+
+// annotations of the package manifest.
+const (
+	packageNameAnnotation       = "com.protopkg.package.name"
+	packageHashAnnotation       = "com.protopkg.package.hash"
+	packageRepositoryAnnotation = "com.protopkg.package.repository"
+	packageCommitAnnotation     = "com.protopkg.package.commit"
+	packageRootAnnotation       = "com.protopkg.package.root"
+)
+
+// makeProtoPackageArtifact creates the OCI artifact of the package: a
+// manifest with the .pkg.pb, the .pkg.json and a tarball of the proto
+// sources as layers.  The artifact only depends on the package content, such
+// that publishing the same package twice yields the same digest.
+func makeProtoPackageArtifact(pkg *pppb.ProtoPackage) (*oci.Artifact, error) {
+	filename := strings.TrimPrefix(pkg.Name, "~")
+
+	protoData, err := proto.MarshalOptions{Deterministic: true}.Marshal(pkg)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling %s: %w", pkg.Name, err)
+	}
+	jsonData, err := marshalStableJson(pkg)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling %s: %w", pkg.Name, err)
+	}
+	sourcesData, err := makeSourcesTarball(pkg)
+	if err != nil {
+		return nil, fmt.Errorf("archiving sources of %s: %w", pkg.Name, err)
+	}
+
+	layers := []*oci.Blob{
+		oci.NewBlob(protoMediaType, protoData, map[string]string{oci.AnnotationTitle: filename + ".pkg.pb"}),
+		oci.NewBlob(jsonMediaType, jsonData, map[string]string{oci.AnnotationTitle: filename + ".pkg.json"}),
+		oci.NewBlob(oci.MediaTypeImageLayerGzip, sourcesData, map[string]string{oci.AnnotationTitle: filename + ".srcs.tar.gz"}),
+	}
+
+	archive := pkg.Archive
+	annotations := map[string]string{
+		packageNameAnnotation:       pkg.Name,
+		packageHashAnnotation:       pkg.Hash,
+		packageRepositoryAnnotation: archive.GetRepository().GetFullName(),
+		packageCommitAnnotation:     archive.GetCommitSha1(),
+		oci.AnnotationSource:        "https://" + archive.GetRepository().GetFullName(),
+		oci.AnnotationRevision:      archive.GetCommitSha1(),
+	}
+	if archive.GetRoot() != "" {
+		annotations[packageRootAnnotation] = archive.GetRoot()
+	}
+	if t := archive.GetCommitTime(); t != nil {
+		annotations[oci.AnnotationCreated] = t.AsTime().UTC().Format(time.RFC3339)
+	}
+
+	return oci.NewArtifact(artifactType, layers, annotations), nil
+}
+
+// makeSourcesTarball returns a gzipped tarball of the proto source files of
+// the package, by file name.  Entries are sorted and timestamps are zero,
+// such that the tarball is reproducible.
+func makeSourcesTarball(pkg *pppb.ProtoPackage) ([]byte, error) {
+	files := make([]*pppb.ProtoFile, len(pkg.Files))
+	copy(files, pkg.Files)
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].GetFile().GetName() < files[j].GetFile().GetName()
+	})
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, file := range files {
+		hdr := &tar.Header{
+			Name:     file.GetFile().GetName(),
+			Mode:     0644,
+			Size:     int64(len(file.SourceCode)),
+			Typeflag: tar.TypeReg,
+			ModTime:  time.Unix(0, 0),
+			Format:   tar.FormatPAX,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write([]byte(file.SourceCode)); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// marshalStableJson returns the indented json of the message.  protojson
+// output is deliberately unstable, so it is reformatted.
+func marshalStableJson(msg proto.Message) ([]byte, error) {
+	data, err := protojson.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "", "  "); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+var invalidRepositoryChars = regexp.MustCompile(`[^a-z0-9._/-]+`)
+
+// makeRepositoryName returns the OCI repository name of the package under the
+// given prefix (e.g. 'protopkg/google.api').
+func makeRepositoryName(prefix string, pkg *pppb.ProtoPackage) string {
+	name := strings.TrimPrefix(strings.ToLower(pkg.Name), "~")
+	name = strings.Trim(invalidRepositoryChars.ReplaceAllString(name, "-"), "-._")
+	if prefix == "" {
+		return name
+	}
+	return strings.TrimSuffix(prefix, "/") + "/" + name
+}
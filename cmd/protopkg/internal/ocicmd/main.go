@@ -0,0 +1,138 @@
+package ocicmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/protopkg/apis/cmd/protopkg/internal/cli"
+	"github.com/protopkg/apis/pkg/oci"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	"google.golang.org/protobuf/proto"
+)
+
+type flagName string
+
+const (
+	protoPackageSetFileFlagName flagName = "pkgset_file"
+	ociLayoutFlagName           flagName = "oci_layout"
+	registryFlagName            flagName = "registry"
+	repositoryPrefixFlagName    flagName = "repository_prefix"
+	tagFlagName                 flagName = "tag"
+	insecureFlagName            flagName = "registry_insecure"
+	usernameFlagName            flagName = "registry_username"
+	passwordEnvFlagName         flagName = "registry_password_env"
+)
+
+// Command is the 'oci' subcommand.
+var Command = &cli.Command{
+	Name:    "oci",
+	Summary: "Publish a proto package set as OCI artifacts",
+	Flags:   flags,
+	Run:     run,
+}
+
+var flags = flag.NewFlagSet("oci", flag.ContinueOnError)
+
+var (
+	protoPackageSetFile = flags.String(string(protoPackageSetFileFlagName), "", "path to the proto package set file")
+	ociLayout           = flags.String(string(ociLayoutFlagName), "", "path of an OCI image layout directory to write the artifacts to")
+	registry            = flags.String(string(registryFlagName), "", "host of the OCI registry to push the artifacts to (e.g. 'ghcr.io')")
+	repositoryPrefix    = flags.String(string(repositoryPrefixFlagName), "protopkg", "repository prefix; each package is pushed to '<prefix>/<package name>'")
+	tag                 = flags.String(string(tagFlagName), "", "tag of the artifacts (default is the short commit of the package archive)")
+	insecure            = flags.Bool(string(insecureFlagName), false, "use plain http to talk to the registry")
+	username            = flags.String(string(usernameFlagName), "", "registry username")
+	passwordEnv         = flags.String(string(passwordEnvFlagName), "", "name of the environment variable that holds the registry password or token")
+)
+
+func run() error {
+	pkgset, err := readProtoPackageFileSet(protoPackageSetFileFlagName, *protoPackageSetFile)
+	if err != nil {
+		return err
+	}
+
+	var targets []oci.Target
+	if *ociLayout != "" {
+		layout, err := oci.OpenLayout(*ociLayout)
+		if err != nil {
+			return err
+		}
+		targets = append(targets, layout)
+	}
+	if *registry != "" {
+		reg := &oci.Registry{Host: *registry, Insecure: *insecure, Username: *username}
+		if *passwordEnv != "" {
+			reg.Password = os.Getenv(*passwordEnv)
+			if reg.Password == "" {
+				return fmt.Errorf("environment variable %s (-%s) is empty or not set", *passwordEnv, passwordEnvFlagName)
+			}
+		}
+		targets = append(targets, reg)
+	}
+	if len(targets) == 0 {
+		return fmt.Errorf("one of -%s or -%s is required", ociLayoutFlagName, registryFlagName)
+	}
+
+	ctx := context.Background()
+	for _, pkg := range pkgset.Packages {
+		if err := publishProtoPackage(ctx, targets, pkg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func publishProtoPackage(ctx context.Context, targets []oci.Target, pkg *pppb.ProtoPackage) error {
+	artifact, err := makeProtoPackageArtifact(pkg)
+	if err != nil {
+		return err
+	}
+	repository := makeRepositoryName(*repositoryPrefix, pkg)
+	tag := *tag
+	if tag == "" {
+		tag = pkg.Archive.GetShortSha1()
+	}
+	if tag == "" {
+		return fmt.Errorf("%s: package archive has no commit, -%s is required", pkg.Name, tagFlagName)
+	}
+
+	for _, target := range targets {
+		desc, err := oci.Push(ctx, target, repository, tag, artifact)
+		if err != nil {
+			return fmt.Errorf("%s: %w", pkg.Name, err)
+		}
+		log.Printf("published: %s -> %s:%s@%s", pkg.Name, describeTarget(target, repository), tag, desc.Digest)
+	}
+	return nil
+}
+
+func describeTarget(target oci.Target, repository string) string {
+	switch t := target.(type) {
+	case *oci.Registry:
+		return t.Host + "/" + repository
+	default:
+		return strings.TrimSuffix(*ociLayout, "/") + "#" + repository
+	}
+}
+
+func readProtoPackageFileSet(flag flagName, filename string) (*pppb.ProtoPackageSet, error) {
+	if filename == "" {
+		return nil, errorFlagRequired(flag)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", flag, err)
+	}
+	var msg pppb.ProtoPackageSet
+	if err := proto.Unmarshal(data, &msg); err != nil {
+		return nil, fmt.Errorf("unmarshaling %s: %w", flag, err)
+	}
+	return &msg, nil
+}
+
+func errorFlagRequired(name flagName) error {
+	return cli.UsageErrorf("flag required but not provided: -%s", name)
+}
@@ -0,0 +1,97 @@
+package packagecmd
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+)
+
+// packageFilter decides which files and packages are retained in the
+// generated ProtoPackageSet.  Patterns use path.Match syntax.  A file pattern
+// that does not contain a '/' is matched against the basename of the file
+// (e.g. '*_test.proto').
+type packageFilter struct {
+	includePackages     []string
+	excludePackages     []string
+	includeFiles        []string
+	excludeFiles        []string
+	includeRepositories []string
+	excludeRepositories []string
+}
+
+func newPackageFilter(cfg *config) (*packageFilter, error) {
+	f := &packageFilter{
+		includePackages:     cfg.IncludePackages,
+		excludePackages:     cfg.ExcludePackages,
+		includeFiles:        cfg.IncludeFiles,
+		excludeFiles:        cfg.ExcludeFiles,
+		includeRepositories: cfg.IncludeRepositories,
+		excludeRepositories: cfg.ExcludeRepositories,
+	}
+	for _, patterns := range [][]string{
+		f.includePackages,
+		f.excludePackages,
+		f.includeFiles,
+		f.excludeFiles,
+		f.includeRepositories,
+		f.excludeRepositories,
+	} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid filter pattern %q: %w", pattern, err)
+			}
+		}
+	}
+	return f, nil
+}
+
+// keepFile reports whether the proto file having the given import path should
+// be retained.
+func (f *packageFilter) keepFile(filename string) bool {
+	return keep(f.includeFiles, f.excludeFiles, filename, matchFile)
+}
+
+// keepPackage reports whether the package should be retained, according to its
+// name and archive repository.
+func (f *packageFilter) keepPackage(pkg *pppb.ProtoPackage) bool {
+	if !keep(f.includePackages, f.excludePackages, pkg.Name, matchName) {
+		return false
+	}
+	var repository string
+	if pkg.Archive != nil && pkg.Archive.Repository != nil {
+		repository = pkg.Archive.Repository.FullName
+	}
+	return keep(f.includeRepositories, f.excludeRepositories, repository, matchName)
+}
+
+// keep returns true if the value matches one of the include patterns (or there
+// are none) and does not match any of the exclude patterns.
+func keep(include, exclude []string, value string, match func(pattern, value string) bool) bool {
+	if len(include) > 0 && !matchAny(include, value, match) {
+		return false
+	}
+	return !matchAny(exclude, value, match)
+}
+
+func matchAny(patterns []string, value string, match func(pattern, value string) bool) bool {
+	for _, pattern := range patterns {
+		if match(pattern, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchName(pattern, value string) bool {
+	ok, _ := path.Match(pattern, value)
+	return ok
+}
+
+func matchFile(pattern, filename string) bool {
+	if !strings.Contains(pattern, "/") {
+		filename = path.Base(filename)
+	}
+	return matchName(pattern, filename)
+}
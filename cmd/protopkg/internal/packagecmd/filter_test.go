@@ -0,0 +1,70 @@
+package packagecmd
+
+import (
+	"testing"
+
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+)
+
+func TestKeepFile(t *testing.T) {
+	filter := newTestFilter(t, &config{
+		IncludeFiles: []string{"google/*/*.proto", "*_service.proto"},
+		ExcludeFiles: []string{"*_test.proto", "google/internal/*"},
+	})
+	for filename, want := range map[string]bool{
+		"google/api/http.proto":          true,
+		"google/api/http_test.proto":     false,
+		"google/internal/secret.proto":   false,
+		"acme/v1/acme_service.proto":     true,
+		"acme/v1/acme.proto":             false,
+		"google/api/expr/v1/value.proto": false,
+	} {
+		if got := filter.keepFile(filename); got != want {
+			t.Errorf("keepFile(%q): got %v, want %v", filename, got, want)
+		}
+	}
+
+	// no patterns keep every file
+	if !newTestFilter(t, &config{}).keepFile("any/file.proto") {
+		t.Error("empty filter removes files")
+	}
+}
+
+func TestKeepPackage(t *testing.T) {
+	filter := newTestFilter(t, &config{
+		IncludePackages:     []string{"google.*"},
+		ExcludePackages:     []string{"google.longrunning"},
+		ExcludeRepositories: []string{"github.com/forks/*"},
+	})
+	for _, tc := range []struct {
+		name       string
+		repository string
+		want       bool
+	}{
+		{name: "google.api", repository: "github.com/googleapis/googleapis", want: true},
+		{name: "google.longrunning", repository: "github.com/googleapis/googleapis"},
+		{name: "acme.v1", repository: "github.com/googleapis/googleapis"},
+		{name: "google.api", repository: "github.com/forks/googleapis"},
+		// a package without an archive matches the empty repository
+		{name: "google.api", want: true},
+	} {
+		pkg := &pppb.ProtoPackage{Name: tc.name}
+		if tc.repository != "" {
+			pkg.Archive = &pppb.ProtoArchive{Repository: &pppb.ProtoRepository{FullName: tc.repository}}
+		}
+		if got := filter.keepPackage(pkg); got != tc.want {
+			t.Errorf("keepPackage(%s, %q): got %v, want %v", tc.name, tc.repository, got, tc.want)
+		}
+	}
+
+	include := newTestFilter(t, &config{IncludeRepositories: []string{"github.com/googleapis/*"}})
+	if include.keepPackage(&pppb.ProtoPackage{Name: "google.api"}) {
+		t.Error("a package without an archive matches an include repository pattern")
+	}
+}
+
+func TestNewPackageFilterInvalidPattern(t *testing.T) {
+	if _, err := newPackageFilter(&config{ExcludeFiles: []string{"["}}); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -0,0 +1,204 @@
+package packagecmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/protopkg/apis/pkg/store"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+)
+
+const (
+	// checkLockfileMode compares the package set against the lockfile and
+	// fails if they differ.
+	checkLockfileMode = "check"
+	// updateLockfileMode rewrites the lockfile from the package set.
+	updateLockfileMode = "update"
+)
+
+// lockfileData is the json representation of a lockfile.
+type lockfileData struct {
+	// Packages is the list of locked packages, sorted by name.
+	Packages []*lockedPackage `json:"packages"`
+}
+
+// lockedPackage pins a single dependency package of the package set.
+type lockedPackage struct {
+	// Name is the proto package name (e.g. 'google.api'), or the ref of a
+	// default package (see store.Key).
+	Name string `json:"name"`
+	// Ref is the package reference, in the same form as
+	// ProtoPackage.Dependencies (e.g.
+	// 'github.com/googleapis/googleapis/0123456/~:google.api').
+	Ref string `json:"ref"`
+	// Hash is the ProtoPackage hash.
+	Hash string `json:"hash"`
+	// Files is a mapping from proto file name to ProtoFile hash.
+	Files map[string]string `json:"files"`
+}
+
+// applyLockfile checks or updates the lockfile, according to mode.  The
+// errors of the check tell to run the update target (the '.lock' target of
+// the protopkg_package rule), or to run with -lockfile_mode=update if there is
+// none.
+func applyLockfile(pkgset *pppb.ProtoPackageSet, filename, mode, updateTarget string) error {
+	current := makeLockfileData(pkgset)
+
+	switch mode {
+	case checkLockfileMode:
+		update := fmt.Sprintf("run with -%s=%s", lockfileModeFlagName, updateLockfileMode)
+		if updateTarget != "" {
+			update = fmt.Sprintf("run 'bazel run %s'", updateTarget)
+		}
+		locked, err := readLockfile(filename)
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("no lockfile %s; %s to create it", filename, update)
+		}
+		if err != nil {
+			return err
+		}
+		if drift := diffLockfileData(locked, current); len(drift) > 0 {
+			return fmt.Errorf("%s is out of date (%d change(s)); %s to accept:\n  %s",
+				filename, len(drift), update, strings.Join(drift, "\n  "))
+		}
+		return nil
+	case updateLockfileMode:
+		if err := writeLockfile(current, filename); err != nil {
+			return err
+		}
+		log.Println("wrote:", filename)
+		return nil
+	default:
+		return fmt.Errorf("invalid -%s: %q (must be one of %q, %q)", lockfileModeFlagName, mode, checkLockfileMode, updateLockfileMode)
+	}
+}
+
+// makeLockfileData locks the dependencies of the package set: the packages
+// that another package of the set depends on.  The packages that nothing
+// depends on are the ones being built, and change with every edit of their
+// files, so they are not locked.
+func makeLockfileData(pkgset *pppb.ProtoPackageSet) *lockfileData {
+	dependencies := make(map[string]bool)
+	for _, pkg := range pkgset.Packages {
+		for _, dep := range pkg.Dependencies {
+			dependencies[dep] = true
+		}
+	}
+
+	var data lockfileData
+	for _, pkg := range pkgset.Packages {
+		if !dependencies[store.Ref(pkg)] {
+			continue
+		}
+		locked := &lockedPackage{
+			Name:  store.Key(pkg),
+			Ref:   store.Ref(pkg),
+			Hash:  pkg.Hash,
+			Files: make(map[string]string),
+		}
+		for _, file := range pkg.Files {
+			locked.Files[*file.File.Name] = file.Hash
+		}
+		data.Packages = append(data.Packages, locked)
+	}
+	sort.Slice(data.Packages, func(i, j int) bool {
+		return data.Packages[i].Name < data.Packages[j].Name
+	})
+	return &data
+}
+
+// readLockfile reads the given lockfile.  An empty file is treated as a
+// lockfile with no packages.
+func readLockfile(filename string) (*lockfileData, error) {
+	var data lockfileData
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading lockfile: %w", err)
+	}
+	if len(strings.TrimSpace(string(content))) == 0 {
+		return &data, nil
+	}
+	if err := json.Unmarshal(content, &data); err != nil {
+		return nil, fmt.Errorf("unmarshaling lockfile %s: %w", filename, err)
+	}
+	return &data, nil
+}
+
+func writeLockfile(data *lockfileData, filename string) error {
+	content, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling lockfile: %w", err)
+	}
+	if err := os.WriteFile(filename, append(content, '\n'), 0644); err != nil {
+		return fmt.Errorf("writing lockfile: %w", err)
+	}
+	return nil
+}
+
+// diffLockfileData returns a human readable list of the differences between
+// the locked and current state, or nil if they are the same.
+func diffLockfileData(locked, current *lockfileData) (drift []string) {
+	want := make(map[string]*lockedPackage)
+	for _, pkg := range locked.Packages {
+		want[pkg.Name] = pkg
+	}
+	got := make(map[string]*lockedPackage)
+	for _, pkg := range current.Packages {
+		got[pkg.Name] = pkg
+	}
+
+	for _, pkg := range current.Packages {
+		prev, ok := want[pkg.Name]
+		if !ok {
+			drift = append(drift, fmt.Sprintf("%s: added (%s)", pkg.Name, pkg.Ref))
+			continue
+		}
+		if prev.Ref != pkg.Ref {
+			drift = append(drift, fmt.Sprintf("%s: ref changed: %s -> %s", pkg.Name, prev.Ref, pkg.Ref))
+		}
+		if prev.Hash != pkg.Hash {
+			drift = append(drift, fmt.Sprintf("%s: hash changed: %s -> %s", pkg.Name, prev.Hash, pkg.Hash))
+		}
+		drift = append(drift, diffLockedFiles(pkg.Name, prev.Files, pkg.Files)...)
+	}
+	for _, pkg := range locked.Packages {
+		if _, ok := got[pkg.Name]; !ok {
+			drift = append(drift, fmt.Sprintf("%s: removed (%s)", pkg.Name, pkg.Ref))
+		}
+	}
+
+	return
+}
+
+func diffLockedFiles(pkgName string, want, got map[string]string) (drift []string) {
+	for _, name := range sortedKeys(got) {
+		prev, ok := want[name]
+		if !ok {
+			drift = append(drift, fmt.Sprintf("%s: file %s: added", pkgName, name))
+			continue
+		}
+		if prev != got[name] {
+			drift = append(drift, fmt.Sprintf("%s: file %s: hash changed: %s -> %s", pkgName, name, prev, got[name]))
+		}
+	}
+	for _, name := range sortedKeys(want) {
+		if _, ok := got[name]; !ok {
+			drift = append(drift, fmt.Sprintf("%s: file %s: removed", pkgName, name))
+		}
+	}
+	return
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
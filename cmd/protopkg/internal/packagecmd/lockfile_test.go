@@ -0,0 +1,143 @@
+package packagecmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/protopkg/apis/pkg/protohash"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+)
+
+// newTestLockedSet returns a package set of google.api, which depends on
+// google.protobuf.  The files of google.protobuf have the given syntax, such
+// that a change of it is a drift of the locked dependency.
+func newTestLockedSet(t *testing.T, syntax string, extraFiles ...string) *pppb.ProtoPackageSet {
+	t.Helper()
+	googleapis := testArchive("github.com/googleapis/googleapis")
+	protobuf := testArchive("github.com/protocolbuffers/protobuf")
+	descriptor := testFilePackage(t, protobuf, "google/protobuf/descriptor.proto", "google.protobuf")
+	descriptor.Files[0].File.Syntax = &syntax
+	hash, err := protohash.File(descriptor.Files[0].File, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	descriptor.Files[0].Hash = hash
+	transitive := []*pppb.ProtoPackage{descriptor}
+	for _, filename := range extraFiles {
+		transitive = append(transitive, testFilePackage(t, protobuf, filename, "google.protobuf"))
+	}
+	annotations := testFilePackage(t, googleapis, "google/api/annotations.proto", "google.api", "google/protobuf/descriptor.proto")
+	pkgset, _, err := makeProtoPackageSet([]*pppb.ProtoPackage{annotations}, transitive, newTestFilter(t, &config{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pkgset
+}
+
+func TestMakeLockfileData(t *testing.T) {
+	data := makeLockfileData(newTestLockedSet(t, "proto3"))
+	// google.api is being built, only its dependency is locked
+	if len(data.Packages) != 1 {
+		t.Fatalf("got %d locked packages, want 1", len(data.Packages))
+	}
+	locked := data.Packages[0]
+	if locked.Name != "google.protobuf" || locked.Ref != "github.com/protocolbuffers/protobuf/0123456/~:google.protobuf" {
+		t.Errorf("got %s (%s), want google.protobuf", locked.Name, locked.Ref)
+	}
+	if _, ok := locked.Files["google/protobuf/descriptor.proto"]; !ok || len(locked.Files) != 1 {
+		t.Errorf("got files %v, want google/protobuf/descriptor.proto", locked.Files)
+	}
+}
+
+func TestDiffLockfileData(t *testing.T) {
+	locked := makeLockfileData(newTestLockedSet(t, "proto3"))
+	for _, tc := range []struct {
+		name    string
+		current *lockfileData
+		want    []string
+	}{
+		{
+			name:    "same",
+			current: makeLockfileData(newTestLockedSet(t, "proto3")),
+		},
+		{
+			name:    "file changed",
+			current: makeLockfileData(newTestLockedSet(t, "proto2")),
+			want: []string{
+				"google.protobuf: hash changed: ",
+				"google.protobuf: file google/protobuf/descriptor.proto: hash changed: ",
+			},
+		},
+		{
+			name:    "file added",
+			current: makeLockfileData(newTestLockedSet(t, "proto3", "google/protobuf/any.proto")),
+			want: []string{
+				"google.protobuf: hash changed: ",
+				"google.protobuf: file google/protobuf/any.proto: added",
+			},
+		},
+		{
+			name:    "removed",
+			current: &lockfileData{},
+			want:    []string{"google.protobuf: removed (github.com/protocolbuffers/protobuf/0123456/~:google.protobuf)"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := diffLockfileData(locked, tc.current)
+			if len(got) != len(tc.want) {
+				t.Fatalf("got drift %q, want %q", got, tc.want)
+			}
+			for i := range got {
+				if !strings.HasPrefix(got[i], tc.want[i]) {
+					t.Errorf("drift #%d: got %q, want %q", i, got[i], tc.want[i])
+				}
+			}
+		})
+	}
+
+	added := diffLockfileData(&lockfileData{}, locked)
+	if want := "google.protobuf: added (github.com/protocolbuffers/protobuf/0123456/~:google.protobuf)"; len(added) != 1 || added[0] != want {
+		t.Errorf("got drift %q, want %q", added, want)
+	}
+}
+
+func TestApplyLockfile(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "protopkg.lock.json")
+	pkgset := newTestLockedSet(t, "proto3")
+
+	// a missing lockfile is an error in check mode
+	err := applyLockfile(pkgset, filename, checkLockfileMode, "//protos:pkg.lock")
+	if want := "no lockfile " + filename + "; run 'bazel run //protos:pkg.lock' to create it"; err == nil || err.Error() != want {
+		t.Errorf("missing: got error %v, want %q", err, want)
+	}
+
+	// an empty lockfile locks no packages
+	if err := os.WriteFile(filename, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	err = applyLockfile(pkgset, filename, checkLockfileMode, "//protos:pkg.lock")
+	if err == nil || !strings.HasPrefix(err.Error(), filename+" is out of date (1 change(s)); run 'bazel run //protos:pkg.lock' to accept:\n  google.protobuf: added") {
+		t.Errorf("empty: got error %v", err)
+	}
+
+	if err := applyLockfile(pkgset, filename, updateLockfileMode, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyLockfile(pkgset, filename, checkLockfileMode, "//protos:pkg.lock"); err != nil {
+		t.Errorf("updated: %v", err)
+	}
+
+	// without a target, the error names the flag
+	err = applyLockfile(newTestLockedSet(t, "proto2"), filename, checkLockfileMode, "")
+	if err == nil || !strings.Contains(err.Error(), "(2 change(s)); run with -lockfile_mode=update to accept") {
+		t.Errorf("drift: got error %v", err)
+	}
+
+	err = applyLockfile(pkgset, filename, "fix", "")
+	if want := `invalid -lockfile_mode: "fix" (must be one of "check", "update")`; err == nil || err.Error() != want {
+		t.Errorf("mode: got error %v, want %q", err, want)
+	}
+}
@@ -0,0 +1,420 @@
+package packagecmd
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/protopkg/apis/cmd/protopkg/internal/cli"
+	"github.com/protopkg/apis/pkg/protohash"
+	"github.com/protopkg/apis/pkg/store"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+type flagName string
+
+type config struct {
+	DirectDeps     []string `json:"direct_deps"`
+	TransitiveDeps []string `json:"transitive_deps"`
+	// IncludePackages is an optional list of proto package name patterns.  If
+	// non-empty, only packages matching one of the patterns are retained.
+	IncludePackages []string `json:"include_packages"`
+	// ExcludePackages is an optional list of proto package name patterns.
+	// Packages matching one of the patterns are removed.
+	ExcludePackages []string `json:"exclude_packages"`
+	// IncludeFiles is an optional list of proto file path patterns.  If
+	// non-empty, only files matching one of the patterns are retained.
+	IncludeFiles []string `json:"include_files"`
+	// ExcludeFiles is an optional list of proto file path patterns.  Files
+	// matching one of the patterns are removed.
+	ExcludeFiles []string `json:"exclude_files"`
+	// IncludeRepositories is an optional list of repository full name
+	// patterns (e.g. 'github.com/googleapis/*').  If non-empty, only packages
+	// from a matching repository are retained.
+	IncludeRepositories []string `json:"include_repositories"`
+	// ExcludeRepositories is an optional list of repository full name
+	// patterns.  Packages from a matching repository are removed.
+	ExcludeRepositories []string `json:"exclude_repositories"`
+}
+
+// packageReport lists what the filters removed from the generated package
+// set, and the files grouped under the default package.  It is written to its
+// own json output file (-report_out), such that the json output of the package
+// set stays a plain ProtoPackageSet.
+type packageReport struct {
+	// FilteredFiles are the files removed by the file filters.
+	FilteredFiles []string `json:"filteredFiles,omitempty"`
+	// FilteredPackages are the packages removed by the package and
+	// repository filters.
+	FilteredPackages []string `json:"filteredPackages,omitempty"`
+	// DefaultPackageFiles are the files that have no package statement, and
+	// are therefore grouped under store.DefaultPackageName, such that lint
+	// tooling can find them.
+	DefaultPackageFiles []string `json:"defaultPackageFiles,omitempty"`
+}
+
+type protoPackageFile struct {
+	// name is the proto package name of the file.
+	name string
+	pkg  *pppb.ProtoPackage
+	file *pppb.ProtoFile
+}
+
+const (
+	configFileJsonFlagName  flagName = "config_json_file"
+	pkgsetFileFlagName      flagName = "pkgset_file"
+	protoOutputFileFlagName flagName = "proto_out"
+	jsonOutputFileFlagName  flagName = "json_out"
+	reportOutputFlagName    flagName = "report_out"
+	lockfileFlagName        flagName = "lockfile"
+	lockfileModeFlagName    flagName = "lockfile_mode"
+	lockfileTargetFlagName  flagName = "lockfile_target"
+)
+
+// Command is the 'package' subcommand.
+var Command = &cli.Command{
+	Name:    "package",
+	Summary: "Group proto files into a proto package set",
+	Flags:   flags,
+	Run:     run,
+}
+
+var flags = flag.NewFlagSet("package", flag.ContinueOnError)
+
+var (
+	configJsonFile  = flags.String(string(configFileJsonFlagName), "", "path to json config file (containing string array of deps file names)")
+	pkgsetFile      = flags.String(string(pkgsetFileFlagName), "", "path to a previously generated proto package set file (alternative to -config_json_file)")
+	protoOutputFile = flags.String(string(protoOutputFileFlagName), "", "path of file to write the generated proto file")
+	jsonOutputFile  = flags.String(string(jsonOutputFileFlagName), "", "path of file to write the generated json file")
+	reportOutput    = flags.String(string(reportOutputFlagName), "", "path of file to write the json report of the filtered files and packages, and the default package files")
+	lockfile        = flags.String(string(lockfileFlagName), "", "path of the lockfile that pins the hashes of the dependency packages (those that another package of the set depends on)")
+	lockfileMode    = flags.String(string(lockfileModeFlagName), checkLockfileMode, "'check' fails if the package set has drifted from the lockfile; 'update' rewrites the lockfile")
+	lockfileTarget  = flags.String(string(lockfileTargetFlagName), "", "label of the bazel target that updates the lockfile, named by the 'check' errors")
+)
+
+func run() error {
+	var pkgset *pppb.ProtoPackageSet
+	var report *packageReport
+	var err error
+	if *pkgsetFile != "" {
+		pkgset, err = readProtoPackageSetFile(pkgsetFileFlagName, *pkgsetFile)
+	} else {
+		pkgset, report, err = makeProtoPackageSetFromConfig()
+	}
+	if err != nil {
+		return err
+	}
+
+	if report == nil {
+		report = &packageReport{}
+	}
+	for _, pkg := range pkgset.Packages {
+		if pkg.Name != store.DefaultPackageName {
+			continue
+		}
+		for _, file := range pkg.Files {
+			report.DefaultPackageFiles = append(report.DefaultPackageFiles, file.GetFile().GetName())
+		}
+	}
+
+	if *lockfile != "" {
+		if err := applyLockfile(pkgset, *lockfile, *lockfileMode, *lockfileTarget); err != nil {
+			return err
+		}
+	}
+
+	if *protoOutputFile != "" {
+		if err := writeProtoOutputFile(pkgset, *protoOutputFile); err != nil {
+			return err
+		}
+	}
+	if *jsonOutputFile != "" {
+		if err := writeJsonOutputFile(pkgset, *jsonOutputFile); err != nil {
+			return err
+		}
+	}
+
+	if *reportOutput != "" {
+		if err := writeReportOutputFile(report, *reportOutput); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func makeProtoPackageSetFromConfig() (*pppb.ProtoPackageSet, *packageReport, error) {
+	cfg, err := readConfigJsonFile(configFileJsonFlagName, *configJsonFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var directPkgs []*pppb.ProtoPackage
+	for _, filename := range cfg.DirectDeps {
+		fileDep, err := readProtoPackageFile(configFileJsonFlagName, filename)
+		if err != nil {
+			return nil, nil, err
+		}
+		directPkgs = append(directPkgs, fileDep)
+	}
+	var transitivePkgs []*pppb.ProtoPackage
+	for _, filename := range cfg.TransitiveDeps {
+		fileDep, err := readProtoPackageFile(configFileJsonFlagName, filename)
+		if err != nil {
+			return nil, nil, err
+		}
+		transitivePkgs = append(transitivePkgs, fileDep)
+	}
+
+	filter, err := newPackageFilter(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return makeProtoPackageSet(directPkgs, transitivePkgs, filter)
+}
+
+func readConfigJsonFile(flag flagName, filename string) (*config, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var cfg config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+func readProtoPackageFile(flag flagName, filename string) (*pppb.ProtoPackage, error) {
+	if filename == "" {
+		return nil, errorFlagRequired(flag)
+	}
+
+	var pp pppb.ProtoPackage
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", flag, err)
+	}
+	if err := proto.Unmarshal(data, &pp); err != nil {
+		return nil, fmt.Errorf("unmarshaling %s: %w", flag, err)
+	}
+	return &pp, nil
+}
+
+func readProtoPackageSetFile(flag flagName, filename string) (*pppb.ProtoPackageSet, error) {
+	var ps pppb.ProtoPackageSet
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", flag, err)
+	}
+	if err := proto.Unmarshal(data, &ps); err != nil {
+		return nil, fmt.Errorf("unmarshaling %s: %w", flag, err)
+	}
+	return &ps, nil
+}
+
+func writeProtoOutputFile(msg proto.Message, filename string) error {
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("marshaling generated data: %v", err)
+	}
+	if err := os.WriteFile(filename, data, os.ModePerm); err != nil {
+		return fmt.Errorf("writing proto file: %w", err)
+	}
+	return nil
+}
+
+func writeJsonOutputFile(msg proto.Message, filename string) error {
+	marshaler := protojson.MarshalOptions{
+		Multiline: true,
+		Indent:    "  ",
+	}
+	jsonstr, err := marshaler.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("marshaling json: %w", err)
+	}
+	if err := os.WriteFile(filename, []byte(jsonstr), os.ModePerm); err != nil {
+		return fmt.Errorf("writing json file: %w", err)
+	}
+	return nil
+}
+
+// writeReportOutputFile writes the report as json.  The file is written even
+// if the report is empty, since it is a declared output of the rule.
+func writeReportOutputFile(report *packageReport, filename string) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling report: %w", err)
+	}
+	if err := os.WriteFile(filename, data, os.ModePerm); err != nil {
+		return fmt.Errorf("writing report file: %w", err)
+	}
+	return nil
+}
+
+// makeProtoPackageSet groups the files of the packages by proto package name,
+// and the files without a package by archive.  The filters are applied before the dependencies are resolved.  A retained
+// package that imports a file of a package removed by the filters depends on
+// that package by ref: it is expected to be published separately (e.g.
+// 'include_packages = ["google.api"]' keeps depending on google.protobuf).  It
+// is an error if a retained package imports a file that the file filters
+// removed, since there is no package to refer to.
+func makeProtoPackageSet(directPkgs, transitivePkgs []*pppb.ProtoPackage, filter *packageFilter) (*pppb.ProtoPackageSet, *packageReport, error) {
+	report := &packageReport{}
+	byPackageKey := make(map[string][]*protoPackageFile)
+	collectProtoPackageFiles(byPackageKey, directPkgs, filter, report)
+	collectProtoPackageFiles(byPackageKey, transitivePkgs, filter, report)
+
+	packageKeys := make([]string, 0, len(byPackageKey))
+	for packageKey := range byPackageKey {
+		packageKeys = append(packageKeys, packageKey)
+	}
+	sort.Strings(packageKeys)
+
+	var pkgs []*pppb.ProtoPackage
+	// filtered maps the files of the packages removed by the filters to
+	// their package.
+	filtered := make(map[string]*pppb.ProtoPackage)
+	for _, packageKey := range packageKeys {
+		pkgFiles := byPackageKey[packageKey]
+		files := make([]*pppb.ProtoFile, len(pkgFiles))
+		rep := pkgFiles[0]
+		for i, pkgFile := range pkgFiles {
+			files[i] = pkgFile.file
+		}
+		pkg, err := makeProtoPackage(rep.pkg.Archive, rep.pkg.Compiler, rep.name, files)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !filter.keepPackage(pkg) {
+			log.Println("filtered package:", store.Key(pkg))
+			report.FilteredPackages = append(report.FilteredPackages, store.Key(pkg))
+			for _, file := range pkg.Files {
+				filtered[*file.File.Name] = pkg
+			}
+			continue
+		}
+		pkgs = append(pkgs, pkg)
+	}
+
+	providesFile := make(map[string]*pppb.ProtoPackage)
+	for _, dep := range pkgs {
+		for _, file := range dep.Files {
+			providesFile[*file.File.Name] = dep
+		}
+	}
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			for _, dep := range file.File.Dependency {
+				provider, ok := providesFile[dep]
+				if !ok {
+					removed, ok := filtered[dep]
+					if !ok {
+						if !filter.keepFile(dep) {
+							return nil, nil, fmt.Errorf("%s: dependency %s was filtered out", *file.File.Name, dep)
+						}
+						return nil, nil, fmt.Errorf("%s: unknown provider for %s", *file.File.Name, dep)
+					}
+					log.Printf("%s: dependency %s is provided by filtered package %s", *file.File.Name, dep, removed.Name)
+					provider = removed
+				}
+				log.Println(pkg.Name, dep, "provider:", provider.Name)
+
+				if provider == pkg {
+					continue
+				}
+				pkg.Dependencies = append(pkg.Dependencies, store.Ref(provider))
+				log.Println(pkg.Name, "deps:", pkg.Dependencies)
+			}
+		}
+		pkg.Dependencies = deduplicateAndSort(pkg.Dependencies)
+	}
+
+	return &pppb.ProtoPackageSet{Packages: pkgs}, report, nil
+}
+
+// collectProtoPackageFiles groups the files of the given packages by package
+// key (see store.Key), such that the files without a package are grouped by
+// the archive they come from.  Packages without files are skipped, and the
+// files removed by the filter are added to the report.
+func collectProtoPackageFiles(byPackageKey map[string][]*protoPackageFile, pkgs []*pppb.ProtoPackage, filter *packageFilter, report *packageReport) {
+	for _, pkg := range pkgs {
+		if len(pkg.Files) == 0 {
+			log.Println("package has no files:", pkg.Name)
+			continue
+		}
+		for _, file := range pkg.Files {
+			if !filter.keepFile(*file.File.Name) {
+				log.Println("filtered file:", *file.File.Name)
+				report.FilteredFiles = append(report.FilteredFiles, *file.File.Name)
+				continue
+			}
+			name := protoPackageName(file.File)
+			key := store.Key(&pppb.ProtoPackage{Name: name, Archive: pkg.Archive})
+			byPackageKey[key] = append(byPackageKey[key], &protoPackageFile{
+				name: name,
+				file: file,
+				pkg:  pkg,
+			})
+		}
+	}
+}
+
+// protoPackageName returns the proto package name of the file, or
+// store.DefaultPackageName if the file does not declare one.
+func protoPackageName(file *descriptorpb.FileDescriptorProto) string {
+	if name := file.GetPackage(); name != "" {
+		return name
+	}
+	log.Println("file has no package:", file.GetName())
+	return store.DefaultPackageName
+}
+
+func makeProtoPackage(archive *pppb.ProtoArchive, compiler *pppb.ProtoCompiler, name string, files []*pppb.ProtoFile) (*pppb.ProtoPackage, error) {
+	sort.Slice(files, func(i, j int) bool {
+		a := files[i]
+		b := files[j]
+		return *a.File.Name < *b.File.Name
+	})
+
+	hash, err := protohash.Package(files)
+	if err != nil {
+		return nil, fmt.Errorf("calculating proto package hash: %w", err)
+	}
+
+	return &pppb.ProtoPackage{
+		Name:     name,
+		Archive:  archive,
+		Compiler: compiler,
+		Files:    files,
+		Hash:     hash,
+	}, nil
+}
+
+func errorFlagRequired(name flagName) error {
+	return cli.UsageErrorf("flag required but not provided: -%s", name)
+}
+
+// deduplicateAndSort removes duplicate entries and sorts the list
+func deduplicateAndSort(in []string) (out []string) {
+	if len(in) == 0 {
+		return in
+	}
+	seen := make(map[string]bool)
+	for _, v := range in {
+		if seen[v] {
+			continue
+		}
+		seen[v] = true
+		out = append(out, v)
+	}
+	sort.Strings(out)
+	return
+}
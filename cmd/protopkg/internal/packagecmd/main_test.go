@@ -0,0 +1,285 @@
+package packagecmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/protopkg/apis/pkg/protohash"
+	"github.com/protopkg/apis/pkg/store"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// testArchive returns the archive of a commit of the repository.
+func testArchive(repository string) *pppb.ProtoArchive {
+	return &pppb.ProtoArchive{
+		Repository: &pppb.ProtoRepository{FullName: repository},
+		CommitSha1: "0123456789abcdef0123456789abcdef01234567",
+		ShortSha1:  "0123456",
+	}
+}
+
+// testFilePackage returns the output of 'protopkg file' for a proto file of
+// the proto package, in the archive.
+func testFilePackage(t *testing.T, archive *pppb.ProtoArchive, filename, packageName string, deps ...string) *pppb.ProtoPackage {
+	t.Helper()
+	desc := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String(filename),
+		Dependency: deps,
+		Syntax:     proto.String("proto3"),
+	}
+	if packageName != "" {
+		desc.Package = proto.String(packageName)
+	}
+	hash, err := protohash.File(desc, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &pppb.ProtoPackage{
+		Name:    filename,
+		Archive: archive,
+		Files:   []*pppb.ProtoFile{{File: desc, Hash: hash}},
+	}
+}
+
+func newTestFilter(t *testing.T, cfg *config) *packageFilter {
+	t.Helper()
+	filter, err := newPackageFilter(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filter
+}
+
+// packageDeps returns the dependencies of the packages of the set, by name.
+func packageDeps(pkgset *pppb.ProtoPackageSet) map[string]string {
+	deps := make(map[string]string)
+	for _, pkg := range pkgset.Packages {
+		deps[pkg.Name] = strings.Join(pkg.Dependencies, ",")
+	}
+	return deps
+}
+
+func TestMakeProtoPackageSet(t *testing.T) {
+	googleapis := testArchive("github.com/googleapis/googleapis")
+	protobuf := testArchive("github.com/protocolbuffers/protobuf")
+	descriptor := testFilePackage(t, protobuf, "google/protobuf/descriptor.proto", "google.protobuf")
+	any := testFilePackage(t, protobuf, "google/protobuf/any.proto", "google.protobuf")
+	annotations := testFilePackage(t, googleapis, "google/api/annotations.proto", "google.api", "google/api/http.proto", "google/protobuf/descriptor.proto")
+	http := testFilePackage(t, googleapis, "google/api/http.proto", "google.api")
+	status := testFilePackage(t, googleapis, "google/rpc/status.proto", "google.rpc", "google/protobuf/any.proto")
+	protobufRef := "github.com/protocolbuffers/protobuf/0123456/~:google.protobuf"
+
+	for _, tc := range []struct {
+		name string
+		cfg  config
+		// want maps the names of the packages of the set to their
+		// dependencies.
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name: "all",
+			want: map[string]string{
+				"google.api":      protobufRef,
+				"google.protobuf": "",
+				"google.rpc":      protobufRef,
+			},
+		},
+		{
+			// the filtered package is published separately, and is still
+			// a dependency
+			name: "include packages",
+			cfg:  config{IncludePackages: []string{"google.api"}},
+			want: map[string]string{
+				"google.api": protobufRef,
+			},
+		},
+		{
+			name: "exclude packages",
+			cfg:  config{ExcludePackages: []string{"google.r*"}},
+			want: map[string]string{
+				"google.api":      protobufRef,
+				"google.protobuf": "",
+			},
+		},
+		{
+			name: "exclude repositories",
+			cfg:  config{ExcludeRepositories: []string{"github.com/protocolbuffers/*"}},
+			want: map[string]string{
+				"google.api": protobufRef,
+				"google.rpc": protobufRef,
+			},
+		},
+		{
+			name: "exclude files",
+			cfg:  config{ExcludeFiles: []string{"google/rpc/*"}},
+			want: map[string]string{
+				"google.api":      protobufRef,
+				"google.protobuf": "",
+			},
+		},
+		{
+			// there is no package to refer to
+			name:    "excluded dependency file",
+			cfg:     config{ExcludeFiles: []string{"any.proto"}},
+			wantErr: "google/rpc/status.proto: dependency google/protobuf/any.proto was filtered out",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			pkgset, _, err := makeProtoPackageSet(
+				[]*pppb.ProtoPackage{annotations, status},
+				[]*pppb.ProtoPackage{http, descriptor, any},
+				newTestFilter(t, &tc.cfg),
+			)
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("got error %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := packageDeps(pkgset)
+			if len(got) != len(tc.want) {
+				t.Errorf("got packages %v, want %v", got, tc.want)
+			}
+			for name, deps := range tc.want {
+				if got[name] != deps {
+					t.Errorf("%s: got dependencies %q, want %q", name, got[name], deps)
+				}
+			}
+			for _, pkg := range pkgset.Packages {
+				hash, err := protohash.Package(pkg.Files)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if pkg.Hash != hash {
+					t.Errorf("%s: got hash %s, want %s", pkg.Name, pkg.Hash, hash)
+				}
+				if store.Ref(pkg) == "" {
+					t.Errorf("%s: no ref", pkg.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestMakeProtoPackageSetUnknownProvider(t *testing.T) {
+	archive := testArchive("github.com/googleapis/googleapis")
+	annotations := testFilePackage(t, archive, "google/api/annotations.proto", "google.api", "google/protobuf/descriptor.proto")
+	_, _, err := makeProtoPackageSet([]*pppb.ProtoPackage{annotations}, nil, newTestFilter(t, &config{}))
+	if err == nil || !strings.Contains(err.Error(), "unknown provider for google/protobuf/descriptor.proto") {
+		t.Errorf("got error %v", err)
+	}
+}
+
+func TestMakeProtoPackageSetReport(t *testing.T) {
+	archive := testArchive("github.com/googleapis/googleapis")
+	http := testFilePackage(t, archive, "google/api/http.proto", "google.api")
+	httpTest := testFilePackage(t, archive, "google/api/http_test.proto", "google.api")
+	status := testFilePackage(t, archive, "google/rpc/status.proto", "google.rpc")
+	_, report, err := makeProtoPackageSet(
+		[]*pppb.ProtoPackage{http, httpTest, status}, nil,
+		newTestFilter(t, &config{ExcludePackages: []string{"google.rpc"}, ExcludeFiles: []string{"*_test.proto"}}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &packageReport{
+		FilteredFiles:    []string{"google/api/http_test.proto"},
+		FilteredPackages: []string{"google.rpc"},
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("got report %+v, want %+v", report, want)
+	}
+}
+
+func TestWriteOutputFiles(t *testing.T) {
+	archive := testArchive("github.com/googleapis/googleapis")
+	pkgset, report, err := makeProtoPackageSet(
+		[]*pppb.ProtoPackage{testFilePackage(t, archive, "google/api/http.proto", "google.api")}, nil,
+		newTestFilter(t, &config{}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report.DefaultPackageFiles = []string{"nopackage.proto"}
+
+	dir := t.TempDir()
+	jsonFile := filepath.Join(dir, "pkg.json")
+	reportFile := filepath.Join(dir, "pkg.report.json")
+	if err := writeJsonOutputFile(pkgset, jsonFile); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeReportOutputFile(report, reportFile); err != nil {
+		t.Fatal(err)
+	}
+
+	// the json output is a plain package set, readable without
+	// DiscardUnknown
+	data, err := os.ReadFile(jsonFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gotSet pppb.ProtoPackageSet
+	if err := protojson.Unmarshal(data, &gotSet); err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(&gotSet, pkgset) {
+		t.Errorf("got package set %v, want %v", &gotSet, pkgset)
+	}
+
+	data, err = os.ReadFile(reportFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gotReport packageReport
+	if err := json.Unmarshal(data, &gotReport); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&gotReport, report) {
+		t.Errorf("got report %+v, want %+v", &gotReport, report)
+	}
+}
+
+func TestMakeProtoPackageSetDefaultPackages(t *testing.T) {
+	acme := testArchive("github.com/acme/apis")
+	vendor := testArchive("github.com/vendor/protos")
+	widget := testFilePackage(t, acme, "acme/widget.proto", "", "vendor/gadget.proto")
+	gadget := testFilePackage(t, vendor, "vendor/gadget.proto", "")
+	part := testFilePackage(t, vendor, "vendor/part.proto", "")
+
+	pkgset, report, err := makeProtoPackageSet([]*pppb.ProtoPackage{widget}, []*pppb.ProtoPackage{gadget, part}, newTestFilter(t, &config{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	acmeRef := "github.com/acme/apis/0123456/~:~default"
+	vendorRef := "github.com/vendor/protos/0123456/~:~default"
+	byRef := make(map[string]*pppb.ProtoPackage)
+	for _, pkg := range pkgset.Packages {
+		if pkg.Name != store.DefaultPackageName {
+			t.Errorf("got package %s, want %s", pkg.Name, store.DefaultPackageName)
+		}
+		byRef[store.Ref(pkg)] = pkg
+	}
+	if len(byRef) != 2 || byRef[acmeRef] == nil || byRef[vendorRef] == nil {
+		t.Fatalf("got packages %v, want %s and %s", byRef, acmeRef, vendorRef)
+	}
+	if got := byRef[acmeRef].Dependencies; len(got) != 1 || got[0] != vendorRef {
+		t.Errorf("got dependencies %v, want [%s]", got, vendorRef)
+	}
+	if got := len(byRef[vendorRef].Files); got != 2 {
+		t.Errorf("got %d vendor files, want 2", got)
+	}
+	if len(report.FilteredFiles) != 0 || len(report.FilteredPackages) != 0 {
+		t.Errorf("got report %+v, want nothing filtered", report)
+	}
+}
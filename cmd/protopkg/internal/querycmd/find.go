@@ -0,0 +1,74 @@
+package querycmd
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/protopkg/apis/cmd/protopkg/internal/cli"
+	regpb "github.com/protopkg/apis/protopkg/registry/v1alpha1"
+)
+
+const (
+	fileFlagName   flagName = "file"
+	symbolFlagName flagName = "symbol"
+)
+
+// FindCommand is the 'find' subcommand.
+var FindCommand = &cli.Command{
+	Name:    "find",
+	Summary: "Find the packages of a packages server that provide a file or symbol",
+	Flags:   findFlags,
+	Run:     runFind,
+}
+
+var findFlags = flag.NewFlagSet("find", flag.ContinueOnError)
+
+var (
+	findFile   = findFlags.String(string(fileFlagName), "", "name of a proto file, as imported (e.g. 'google/api/http.proto')")
+	findSymbol = findFlags.String(string(symbolFlagName), "", "fully-qualified name of a message, field, enum, enum value, service, method or extension (e.g. 'google.api.HttpRule')")
+	findQuery  queryFlags
+	findPages  pageFlags
+)
+
+func init() {
+	findQuery.register(findFlags)
+	findPages.register(findFlags)
+}
+
+func runFind() error {
+	if err := findQuery.validate(); err != nil {
+		return err
+	}
+	if (*findFile == "") == (*findSymbol == "") {
+		return cli.UsageErrorf("exactly one of -%s or -%s is required", fileFlagName, symbolFlagName)
+	}
+
+	registry, conn, err := createRegistryClient(findQuery.packagesServerAddress, &findQuery.dialOptions)
+	if err != nil {
+		return fmt.Errorf("find failed: %v", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := findQuery.context()
+	defer cancel()
+	resp, err := findPages.listPages(func(pageToken string) (*regpb.ListProtoPackagesResponse, error) {
+		if *findFile != "" {
+			return registry.FindProtoPackagesByFile(ctx, &regpb.FindProtoPackagesByFileRequest{
+				File:      *findFile,
+				PageSize:  int32(findPages.pageSize),
+				PageToken: pageToken,
+				ReadMask:  findQuery.fieldMask(),
+			})
+		}
+		return registry.FindProtoPackagesBySymbol(ctx, &regpb.FindProtoPackagesBySymbolRequest{
+			Symbol:    *findSymbol,
+			PageSize:  int32(findPages.pageSize),
+			PageToken: pageToken,
+			ReadMask:  findQuery.fieldMask(),
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("find failed: %w", err)
+	}
+	return writePackages(resp, findQuery.format)
+}
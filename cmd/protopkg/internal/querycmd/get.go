@@ -0,0 +1,70 @@
+package querycmd
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/protopkg/apis/cmd/protopkg/internal/cli"
+	regpb "github.com/protopkg/apis/protopkg/registry/v1alpha1"
+)
+
+const (
+	refFlagName  flagName = "ref"
+	idFlagName   flagName = "id"
+	hashFlagName flagName = "hash"
+)
+
+// GetCommand is the 'get' subcommand.
+var GetCommand = &cli.Command{
+	Name:    "get",
+	Summary: "Get a package from a packages server by ref, id or hash",
+	Flags:   getFlags,
+	Run:     runGet,
+}
+
+var getFlags = flag.NewFlagSet("get", flag.ContinueOnError)
+
+var (
+	getRef   = getFlags.String(string(refFlagName), "", "ref of the package (e.g. 'github.com/googleapis/googleapis/0123456/~:google.api')")
+	getID    = getFlags.String(string(idFlagName), "", "server-side identity of the package (NAME@HASH)")
+	getHash  = getFlags.String(string(hashFlagName), "", "hash of the package; the most recently committed package with the hash is returned")
+	getQuery queryFlags
+)
+
+func init() {
+	getQuery.register(getFlags)
+}
+
+func runGet() error {
+	if err := getQuery.validate(); err != nil {
+		return err
+	}
+	n := 0
+	for _, v := range []string{*getRef, *getID, *getHash} {
+		if v != "" {
+			n++
+		}
+	}
+	if n != 1 {
+		return cli.UsageErrorf("exactly one of -%s, -%s or -%s is required", refFlagName, idFlagName, hashFlagName)
+	}
+
+	registry, conn, err := createRegistryClient(getQuery.packagesServerAddress, &getQuery.dialOptions)
+	if err != nil {
+		return fmt.Errorf("get failed: %v", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := getQuery.context()
+	defer cancel()
+	pkg, err := registry.LookupProtoPackage(ctx, &regpb.LookupProtoPackageRequest{
+		Ref:      *getRef,
+		Id:       *getID,
+		Hash:     *getHash,
+		ReadMask: getQuery.fieldMask(),
+	})
+	if err != nil {
+		return fmt.Errorf("get failed: %w", err)
+	}
+	return writePackage(pkg, getQuery.format)
+}
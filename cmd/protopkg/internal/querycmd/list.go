@@ -0,0 +1,67 @@
+package querycmd
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/protopkg/apis/cmd/protopkg/internal/cli"
+	regpb "github.com/protopkg/apis/protopkg/registry/v1alpha1"
+)
+
+const (
+	nameFlagName       flagName = "name"
+	repositoryFlagName flagName = "repository"
+	commitFlagName     flagName = "commit"
+)
+
+// ListCommand is the 'list' subcommand.
+var ListCommand = &cli.Command{
+	Name:    "list",
+	Summary: "List the packages of a packages server, most recently committed first",
+	Flags:   listFlags,
+	Run:     runList,
+}
+
+var listFlags = flag.NewFlagSet("list", flag.ContinueOnError)
+
+var (
+	listName       = listFlags.String(string(nameFlagName), "", "list the versions of the package with this name (e.g. 'google.api')")
+	listRepository = listFlags.String(string(repositoryFlagName), "", "list the packages of the repository (e.g. 'github.com/googleapis/googleapis')")
+	listCommit     = listFlags.String(string(commitFlagName), "", "list the packages of the full or short commit sha1")
+	listQuery      queryFlags
+	listPages      pageFlags
+)
+
+func init() {
+	listQuery.register(listFlags)
+	listPages.register(listFlags)
+}
+
+func runList() error {
+	if err := listQuery.validate(); err != nil {
+		return err
+	}
+
+	registry, conn, err := createRegistryClient(listQuery.packagesServerAddress, &listQuery.dialOptions)
+	if err != nil {
+		return fmt.Errorf("list failed: %v", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := listQuery.context()
+	defer cancel()
+	resp, err := listPages.listPages(func(pageToken string) (*regpb.ListProtoPackagesResponse, error) {
+		return registry.ListProtoPackages(ctx, &regpb.ListProtoPackagesRequest{
+			Name:       *listName,
+			Repository: *listRepository,
+			Commit:     *listCommit,
+			PageSize:   int32(listPages.pageSize),
+			PageToken:  pageToken,
+			ReadMask:   listQuery.fieldMask(),
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("list failed: %w", err)
+	}
+	return writePackages(resp, listQuery.format)
+}
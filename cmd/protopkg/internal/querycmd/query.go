@@ -0,0 +1,191 @@
+// Package querycmd implements the 'get', 'list' and 'find' subcommands, which
+// query the packages stored by a packages server.
+package querycmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/protopkg/apis/cmd/protopkg/internal/cli"
+	"github.com/protopkg/apis/pkg/dial"
+	regpb "github.com/protopkg/apis/protopkg/registry/v1alpha1"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+type flagName string
+
+const (
+	packagesServerAddressFlagName flagName = "packages_server_address"
+	readMaskFlagName              flagName = "read_mask"
+	formatFlagName                flagName = "format"
+	timeoutFlagName               flagName = "timeout"
+	pageSizeFlagName              flagName = "page_size"
+	pageTokenFlagName             flagName = "page_token"
+	allFlagName                   flagName = "all"
+)
+
+const (
+	// jsonFormat prints the packages as json.
+	jsonFormat = "json"
+	// textFormat prints one line per package: its ref and id.
+	textFormat = "text"
+)
+
+// queryFlags are the flags shared by the query subcommands.
+type queryFlags struct {
+	packagesServerAddress string
+	readMask              string
+	format                string
+	timeout               time.Duration
+	dialOptions           dial.Options
+}
+
+func (f *queryFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.packagesServerAddress, string(packagesServerAddressFlagName), "", "address of the packages server")
+	fs.StringVar(&f.readMask, string(readMaskFlagName), "", "comma-separated fields of the packages to return (e.g. 'name,hash,archive,files.file.name'); all fields by default")
+	fs.StringVar(&f.format, string(formatFlagName), jsonFormat, "output format ('json' or 'text')")
+	fs.DurationVar(&f.timeout, string(timeoutFlagName), time.Minute, "deadline of the query (0 for none)")
+	f.dialOptions.RegisterFlags(fs)
+}
+
+// validate checks the shared flags.
+func (f *queryFlags) validate() error {
+	if f.packagesServerAddress == "" {
+		return errorFlagRequired(packagesServerAddressFlagName)
+	}
+	if f.format != jsonFormat && f.format != textFormat {
+		return cli.UsageErrorf("invalid -%s: %q (must be one of %q, %q)", formatFlagName, f.format, jsonFormat, textFormat)
+	}
+	return nil
+}
+
+// fieldMask returns the -read_mask field mask, or nil if not set.
+func (f *queryFlags) fieldMask() *fieldmaskpb.FieldMask {
+	if f.readMask == "" {
+		return nil
+	}
+	mask := &fieldmaskpb.FieldMask{}
+	for _, path := range strings.Split(f.readMask, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			mask.Paths = append(mask.Paths, path)
+		}
+	}
+	return mask
+}
+
+// context returns the context of the query, with the -timeout deadline.
+func (f *queryFlags) context() (context.Context, context.CancelFunc) {
+	if f.timeout > 0 {
+		return context.WithTimeout(context.Background(), f.timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// pageFlags are the paging flags of the list subcommands.
+type pageFlags struct {
+	pageSize  int
+	pageToken string
+	all       bool
+}
+
+func (f *pageFlags) register(fs *flag.FlagSet) {
+	fs.IntVar(&f.pageSize, string(pageSizeFlagName), 0, "maximum number of packages to return (default is chosen by the server)")
+	fs.StringVar(&f.pageToken, string(pageTokenFlagName), "", "next_page_token of a previous query")
+	fs.BoolVar(&f.all, string(allFlagName), false, "follow the page tokens and return all the packages")
+}
+
+// listPages calls list for the pages of a query.  Unless -all is set, only
+// the first page is returned.
+func (f *pageFlags) listPages(list func(pageToken string) (*regpb.ListProtoPackagesResponse, error)) (*regpb.ListProtoPackagesResponse, error) {
+	result := &regpb.ListProtoPackagesResponse{}
+	pageToken := f.pageToken
+	for {
+		resp, err := list(pageToken)
+		if err != nil {
+			return nil, err
+		}
+		result.Packages = append(result.Packages, resp.Packages...)
+		if !f.all || resp.NextPageToken == "" {
+			result.NextPageToken = resp.NextPageToken
+			return result, nil
+		}
+		pageToken = resp.NextPageToken
+	}
+}
+
+func createRegistryClient(address string, opts *dial.Options) (regpb.RegistryClient, *grpc.ClientConn, error) {
+	conn, err := dial.Dial(address, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return regpb.NewRegistryClient(conn), conn, nil
+}
+
+// writeMessage writes the message as json.
+func writeMessage(w io.Writer, msg proto.Message) error {
+	marshaler := protojson.MarshalOptions{
+		Multiline: true,
+		Indent:    "  ",
+	}
+	data, err := marshaler.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("marshaling json: %w", err)
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+// writePackages writes the packages of the response in the given format.  In
+// text format, the next page token (if any) is written last, as
+// 'next_page_token: <token>'.
+func writePackages(resp *regpb.ListProtoPackagesResponse, format string) error {
+	if format == jsonFormat {
+		return writeMessage(os.Stdout, resp)
+	}
+	for _, pkg := range resp.Packages {
+		if err := writePackageLine(os.Stdout, pkg); err != nil {
+			return err
+		}
+	}
+	if resp.NextPageToken != "" {
+		if _, err := fmt.Fprintln(os.Stdout, "next_page_token:", resp.NextPageToken); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writePackage writes the package in the given format.
+func writePackage(pkg *pppb.ProtoPackage, format string) error {
+	if format == jsonFormat {
+		return writeMessage(os.Stdout, pkg)
+	}
+	return writePackageLine(os.Stdout, pkg)
+}
+
+// writePackageLine writes the ref and id of the package.
+func writePackageLine(w io.Writer, pkg *pppb.ProtoPackage) error {
+	ref := fmt.Sprintf("%s/%s/%s:%s", pkg.GetArchive().GetRepository().GetFullName(), pkg.GetArchive().GetShortSha1(), archiveRoot(pkg), pkg.GetName())
+	_, err := fmt.Fprintf(w, "%s\t%s@%s\n", ref, pkg.GetName(), pkg.GetHash())
+	return err
+}
+
+func archiveRoot(pkg *pppb.ProtoPackage) string {
+	if root := pkg.GetArchive().GetRoot(); root != "" {
+		return root
+	}
+	return "~"
+}
+
+func errorFlagRequired(name flagName) error {
+	return cli.UsageErrorf("flag required but not provided: -%s", name)
+}
@@ -0,0 +1,143 @@
+package sbomcmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/protopkg/apis/pkg/store"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+)
+
+// The following types model the subset of the CycloneDX 1.5 json schema
+// (https://cyclonedx.org/docs/1.5/json/) that is used to describe a
+// ProtoPackageSet.
+
+type cycloneDxBom struct {
+	BomFormat    string                 `json:"bomFormat"`
+	SpecVersion  string                 `json:"specVersion"`
+	SerialNumber string                 `json:"serialNumber"`
+	Version      int                    `json:"version"`
+	Metadata     *cycloneDxMetadata     `json:"metadata"`
+	Components   []*cycloneDxComponent  `json:"components"`
+	Dependencies []*cycloneDxDependency `json:"dependencies"`
+}
+
+type cycloneDxMetadata struct {
+	Tools     *cycloneDxTools     `json:"tools"`
+	Component *cycloneDxComponent `json:"component"`
+}
+
+type cycloneDxTools struct {
+	Components []*cycloneDxComponent `json:"components"`
+}
+
+type cycloneDxComponent struct {
+	Type               string                        `json:"type"`
+	BomRef             string                        `json:"bom-ref,omitempty"`
+	Name               string                        `json:"name"`
+	Version            string                        `json:"version,omitempty"`
+	Purl               string                        `json:"purl,omitempty"`
+	ExternalReferences []*cycloneDxExternalReference `json:"externalReferences,omitempty"`
+	Properties         []*cycloneDxProperty          `json:"properties,omitempty"`
+}
+
+type cycloneDxExternalReference struct {
+	Type string `json:"type"`
+	Url  string `json:"url"`
+}
+
+type cycloneDxProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cycloneDxDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// makeCycloneDxBom creates a CycloneDX document where each package is a
+// component.  The bom-ref of a component is the package reference used in
+// ProtoPackage.Dependencies, such that the dependency graph maps directly.
+func makeCycloneDxBom(name string, pkgset *pppb.ProtoPackageSet) *cycloneDxBom {
+	digest := makeProtoPackageSetDigest(pkgset)
+
+	bom := &cycloneDxBom{
+		BomFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: "urn:uuid:" + makeUuid(digest),
+		Version:      1,
+		Metadata: &cycloneDxMetadata{
+			Tools: &cycloneDxTools{
+				Components: []*cycloneDxComponent{{Type: "application", Name: toolName}},
+			},
+			Component: &cycloneDxComponent{
+				Type:    "data",
+				BomRef:  name,
+				Name:    name,
+				Version: digest,
+			},
+		},
+	}
+
+	known := make(map[string]bool)
+	for _, pkg := range pkgset.Packages {
+		known[store.Ref(pkg)] = true
+	}
+
+	root := &cycloneDxDependency{Ref: name, DependsOn: []string{}}
+	bom.Dependencies = append(bom.Dependencies, root)
+
+	for _, pkg := range pkgset.Packages {
+		ref := store.Ref(pkg)
+		bom.Components = append(bom.Components, makeCycloneDxComponent(ref, pkg))
+		root.DependsOn = append(root.DependsOn, ref)
+
+		dep := &cycloneDxDependency{Ref: ref, DependsOn: []string{}}
+		for _, d := range pkg.Dependencies {
+			if !known[d] {
+				log.Printf("%s: dependency %s is not part of the package set, skipping", pkg.Name, d)
+				continue
+			}
+			dep.DependsOn = append(dep.DependsOn, d)
+		}
+		bom.Dependencies = append(bom.Dependencies, dep)
+	}
+
+	return bom
+}
+
+// makeCycloneDxComponent returns the component of the package.  The vcs
+// reference is omitted if the package has no archive.
+func makeCycloneDxComponent(ref string, pkg *pppb.ProtoPackage) *cycloneDxComponent {
+	archive := pkg.GetArchive()
+	component := &cycloneDxComponent{
+		Type:    "data",
+		BomRef:  ref,
+		Name:    pkg.Name,
+		Version: archive.GetCommitSha1(),
+		Purl:    makePackageUrl(pkg),
+		Properties: []*cycloneDxProperty{
+			{Name: "protopkg:hash", Value: pkg.Hash},
+			{Name: "protopkg:repository", Value: archive.GetRepository().GetFullName()},
+			{Name: "protopkg:commit", Value: archive.GetCommitSha1()},
+			{Name: "protopkg:root", Value: archive.GetRoot()},
+			{Name: "protopkg:files", Value: fmt.Sprintf("%d", len(pkg.Files))},
+		},
+	}
+	if url := makeVcsUrl(pkg); url != "" {
+		component.ExternalReferences = []*cycloneDxExternalReference{
+			{Type: "vcs", Url: url},
+		}
+	}
+	return component
+}
+
+// makeUuid formats the leading 16 bytes of the hex digest as a (version 4
+// layout) uuid, such that the serial number is stable for a given package set.
+func makeUuid(digest string) string {
+	b := []byte(digest[:32])
+	b[12] = '4'
+	b[16] = "89ab"[b[16]%4]
+	return fmt.Sprintf("%s-%s-%s-%s-%s", b[0:8], b[8:12], b[12:16], b[16:20], b[20:32])
+}
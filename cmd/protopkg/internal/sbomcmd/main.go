@@ -0,0 +1,140 @@
+package sbomcmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/protopkg/apis/cmd/protopkg/internal/cli"
+	"github.com/protopkg/apis/pkg/store"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	"google.golang.org/protobuf/proto"
+)
+
+type flagName string
+
+const (
+	protoPackageSetFileFlagName flagName = "pkgset_file"
+	nameFlagName                flagName = "name"
+	cycloneDxOutputFileFlagName flagName = "cyclonedx_out"
+	spdxOutputFileFlagName      flagName = "spdx_out"
+)
+
+// Command is the 'sbom' subcommand.
+var Command = &cli.Command{
+	Name:    "sbom",
+	Summary: "Write a software bill of materials for a proto package set",
+	Flags:   flags,
+	Run:     run,
+}
+
+var flags = flag.NewFlagSet("sbom", flag.ContinueOnError)
+
+var (
+	protoPackageSetFile = flags.String(string(protoPackageSetFileFlagName), "", "path to the proto package set file")
+	name                = flags.String(string(nameFlagName), "protopkg", "name of the generated bill of materials")
+	cycloneDxOutputFile = flags.String(string(cycloneDxOutputFileFlagName), "", "path of file to write the CycloneDX json document")
+	spdxOutputFile      = flags.String(string(spdxOutputFileFlagName), "", "path of file to write the SPDX json document")
+)
+
+const toolName = "protopkg"
+
+func run() error {
+	pkgset, err := readProtoPackageFileSet(protoPackageSetFileFlagName, *protoPackageSetFile)
+	if err != nil {
+		return err
+	}
+
+	if *cycloneDxOutputFile != "" {
+		if err := writeJsonOutputFile(makeCycloneDxBom(*name, pkgset), *cycloneDxOutputFile); err != nil {
+			return err
+		}
+	}
+	if *spdxOutputFile != "" {
+		doc, err := makeSpdxDocument(*name, pkgset)
+		if err != nil {
+			return err
+		}
+		if err := writeJsonOutputFile(doc, *spdxOutputFile); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func readProtoPackageFileSet(flag flagName, filename string) (*pppb.ProtoPackageSet, error) {
+	if filename == "" {
+		return nil, errorFlagRequired(flag)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", flag, err)
+	}
+	var msg pppb.ProtoPackageSet
+	if err := proto.Unmarshal(data, &msg); err != nil {
+		return nil, fmt.Errorf("unmarshaling %s: %w", flag, err)
+	}
+	return &msg, nil
+}
+
+func writeJsonOutputFile(v interface{}, filename string) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling json: %w", err)
+	}
+	if err := os.WriteFile(filename, append(data, '\n'), os.ModePerm); err != nil {
+		return fmt.Errorf("writing json file: %w", err)
+	}
+	log.Println("wrote:", filename)
+	return nil
+}
+
+// makePackageUrl returns the package-url (purl) of the archive that contains
+// the package.  Github repositories use the 'github' type, others are
+// 'generic' with a vcs_url qualifier.  It is empty if the package has no
+// archive repository.
+func makePackageUrl(pkg *pppb.ProtoPackage) string {
+	archive := pkg.GetArchive()
+	repo := archive.GetRepository()
+	if repo.GetFullName() == "" {
+		return ""
+	}
+	if repo.GetHost() == "github.com" {
+		purl := fmt.Sprintf("pkg:github/%s/%s@%s", repo.GetOwner(), repo.GetName(), archive.GetCommitSha1())
+		if archive.GetRoot() != "" {
+			purl += "#" + archive.GetRoot()
+		}
+		return purl
+	}
+	return fmt.Sprintf("pkg:generic/%s@%s?vcs_url=%s", repo.GetName(), archive.GetCommitSha1(), makeVcsUrl(pkg))
+}
+
+// makeVcsUrl returns the git url of the archive that contains the package, or
+// the empty string if the package has no archive repository.
+func makeVcsUrl(pkg *pppb.ProtoPackage) string {
+	archive := pkg.GetArchive()
+	if archive.GetRepository().GetFullName() == "" {
+		return ""
+	}
+	return fmt.Sprintf("git+https://%s@%s", archive.GetRepository().GetFullName(), archive.GetCommitSha1())
+}
+
+// makeProtoPackageSetDigest returns a digest of the package hashes, used to
+// derive stable document identifiers.
+func makeProtoPackageSetDigest(pkgset *pppb.ProtoPackageSet) string {
+	h := sha256.New()
+	for _, pkg := range pkgset.Packages {
+		h.Write([]byte(store.Ref(pkg)))
+		h.Write([]byte(pkg.Hash))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func errorFlagRequired(name flagName) error {
+	return cli.UsageErrorf("flag required but not provided: -%s", name)
+}
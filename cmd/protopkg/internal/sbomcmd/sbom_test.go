@@ -0,0 +1,134 @@
+package sbomcmd
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of the tests")
+
+// newTestProtoPackageSet returns a set of google.api, which depends on
+// google.protobuf (of another repository) and on a package outside of the
+// set, and of a package without an archive.
+func newTestProtoPackageSet() *pppb.ProtoPackageSet {
+	protobuf := &pppb.ProtoPackage{
+		Name: "google.protobuf",
+		Archive: &pppb.ProtoArchive{
+			Repository: &pppb.ProtoRepository{
+				Host:     "github.com",
+				Owner:    "protocolbuffers",
+				Name:     "protobuf",
+				FullName: "github.com/protocolbuffers/protobuf",
+			},
+			CommitSha1: "89abcdef0123456789abcdef0123456789abcdef",
+			ShortSha1:  "89abcde",
+			Root:       "src",
+			CommitTime: timestamppb.New(time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)),
+		},
+		Files: []*pppb.ProtoFile{{}, {}},
+		Hash:  "protoreflecthash.v0:1111",
+	}
+	api := &pppb.ProtoPackage{
+		Name: "google.api",
+		Archive: &pppb.ProtoArchive{
+			Repository: &pppb.ProtoRepository{
+				Host:     "gitlab.com",
+				Owner:    "googleapis",
+				Name:     "googleapis",
+				FullName: "gitlab.com/googleapis/googleapis",
+			},
+			CommitSha1: "0123456789abcdef0123456789abcdef01234567",
+			ShortSha1:  "0123456",
+			CommitTime: timestamppb.New(time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)),
+		},
+		Dependencies: []string{
+			"github.com/protocolbuffers/protobuf/89abcde/src:google.protobuf",
+			"github.com/other/protos/0123456/~:other.v1",
+		},
+		Files: []*pppb.ProtoFile{{}},
+		Hash:  "protoreflecthash.v0:2222",
+	}
+	local := &pppb.ProtoPackage{
+		Name:  "local.v1",
+		Files: []*pppb.ProtoFile{{}},
+		Hash:  "protoreflecthash.v0:3333",
+	}
+	return &pppb.ProtoPackageSet{Packages: []*pppb.ProtoPackage{protobuf, api, local}}
+}
+
+// checkGolden compares the json encoding of v with the golden file, or
+// rewrites it with -update.
+func checkGolden(t *testing.T, v interface{}, name string) {
+	t.Helper()
+	filename := filepath.Join("testdata", name)
+	got, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = append(got, '\n')
+	if *update {
+		if err := os.WriteFile(filename, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("%s differs (run with -update to accept):\n%s", filename, got)
+	}
+}
+
+func TestCycloneDx(t *testing.T) {
+	checkGolden(t, makeCycloneDxBom("apis", newTestProtoPackageSet()), "cyclonedx.golden.json")
+}
+
+func TestSpdx(t *testing.T) {
+	t.Setenv(sourceDateEpochEnv, "1700000000")
+	doc, err := makeSpdxDocument("apis", newTestProtoPackageSet())
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, doc, "spdx.golden.json")
+}
+
+func TestSpdxCreated(t *testing.T) {
+	pkgset := newTestProtoPackageSet()
+	t.Setenv(sourceDateEpochEnv, "")
+	os.Unsetenv(sourceDateEpochEnv)
+
+	for _, tc := range []struct {
+		name string
+		pkgs []*pppb.ProtoPackage
+		want time.Time
+	}{
+		{name: "latest commit", pkgs: pkgset.Packages, want: time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)},
+		{name: "no commit time", pkgs: pkgset.Packages[2:], want: time.Unix(0, 0).UTC()},
+	} {
+		got, err := makeSpdxCreated(&pppb.ProtoPackageSet{Packages: tc.pkgs})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Equal(tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	t.Setenv(sourceDateEpochEnv, "1700000000")
+	if got, err := makeSpdxCreated(pkgset); err != nil || !got.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("%s: got %v, %v", sourceDateEpochEnv, got, err)
+	}
+	t.Setenv(sourceDateEpochEnv, "yesterday")
+	if _, err := makeSpdxCreated(pkgset); err == nil || err.Error() != `invalid SOURCE_DATE_EPOCH: "yesterday"` {
+		t.Errorf("got error %v", err)
+	}
+}
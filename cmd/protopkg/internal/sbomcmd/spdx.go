@@ -0,0 +1,191 @@
+package sbomcmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/protopkg/apis/pkg/store"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+)
+
+// The following types model the subset of the SPDX 2.3 json schema
+// (https://spdx.github.io/spdx-spec/v2.3/) that is used to describe a
+// ProtoPackageSet.
+
+type spdxDocument struct {
+	SpdxVersion       string              `json:"spdxVersion"`
+	DataLicense       string              `json:"dataLicense"`
+	SpdxId            string              `json:"SPDXID"`
+	Name              string              `json:"name"`
+	DocumentNamespace string              `json:"documentNamespace"`
+	CreationInfo      *spdxCreationInfo   `json:"creationInfo"`
+	Packages          []*spdxPackage      `json:"packages"`
+	Relationships     []*spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	SpdxId           string             `json:"SPDXID"`
+	Name             string             `json:"name"`
+	VersionInfo      string             `json:"versionInfo,omitempty"`
+	DownloadLocation string             `json:"downloadLocation"`
+	FilesAnalyzed    bool               `json:"filesAnalyzed"`
+	LicenseConcluded string             `json:"licenseConcluded"`
+	LicenseDeclared  string             `json:"licenseDeclared"`
+	CopyrightText    string             `json:"copyrightText"`
+	SourceInfo       string             `json:"sourceInfo,omitempty"`
+	Comment          string             `json:"comment,omitempty"`
+	ExternalRefs     []*spdxExternalRef `json:"externalRefs,omitempty"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SpdxElementId      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSpdxElement string `json:"relatedSpdxElement"`
+}
+
+const (
+	spdxDocumentId = "SPDXRef-DOCUMENT"
+	spdxNoAssert   = "NOASSERTION"
+)
+
+// sourceDateEpochEnv is the environment variable of the reproducible builds
+// convention that fixes the timestamps of build outputs
+// (https://reproducible-builds.org/specs/source-date-epoch/).
+const sourceDateEpochEnv = "SOURCE_DATE_EPOCH"
+
+var spdxIdInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9.-]+`)
+
+// makeSpdxDocument creates an SPDX document where each package is an SPDX
+// package, related by DEPENDS_ON relationships.  The creation time is stable
+// for a given package set (see makeSpdxCreated).
+func makeSpdxDocument(name string, pkgset *pppb.ProtoPackageSet) (*spdxDocument, error) {
+	digest := makeProtoPackageSetDigest(pkgset)
+	created, err := makeSpdxCreated(pkgset)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &spdxDocument{
+		SpdxVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SpdxId:            spdxDocumentId,
+		Name:              name,
+		DocumentNamespace: fmt.Sprintf("https://protopkg.com/spdx/%s-%s", name, digest),
+		CreationInfo: &spdxCreationInfo{
+			Created:  created.Format(time.RFC3339),
+			Creators: []string{"Tool: " + toolName},
+		},
+	}
+
+	ids := make(map[string]string)
+	used := make(map[string]bool)
+	for _, pkg := range pkgset.Packages {
+		ref := store.Ref(pkg)
+		id := makeSpdxId(pkg, used)
+		ids[ref] = id
+		doc.Packages = append(doc.Packages, makeSpdxPackage(id, pkg))
+		doc.Relationships = append(doc.Relationships, &spdxRelationship{
+			SpdxElementId:      spdxDocumentId,
+			RelationshipType:   "DESCRIBES",
+			RelatedSpdxElement: id,
+		})
+	}
+
+	for _, pkg := range pkgset.Packages {
+		id := ids[store.Ref(pkg)]
+		for _, dep := range pkg.Dependencies {
+			depId, ok := ids[dep]
+			if !ok {
+				log.Printf("%s: dependency %s is not part of the package set, skipping", pkg.Name, dep)
+				continue
+			}
+			doc.Relationships = append(doc.Relationships, &spdxRelationship{
+				SpdxElementId:      id,
+				RelationshipType:   "DEPENDS_ON",
+				RelatedSpdxElement: depId,
+			})
+		}
+	}
+
+	return doc, nil
+}
+
+// makeSpdxPackage returns the SPDX package of the package.  The download
+// location is NOASSERTION and the purl is omitted if the package has no
+// archive.
+func makeSpdxPackage(id string, pkg *pppb.ProtoPackage) *spdxPackage {
+	archive := pkg.GetArchive()
+	spdxPkg := &spdxPackage{
+		SpdxId:           id,
+		Name:             pkg.Name,
+		VersionInfo:      archive.GetCommitSha1(),
+		DownloadLocation: spdxNoAssert,
+		LicenseConcluded: spdxNoAssert,
+		LicenseDeclared:  spdxNoAssert,
+		CopyrightText:    spdxNoAssert,
+		Comment:          "protopkg hash: " + pkg.Hash,
+	}
+	if url := makeVcsUrl(pkg); url != "" {
+		spdxPkg.DownloadLocation = url
+		spdxPkg.SourceInfo = fmt.Sprintf("repository %s, root %q, commit %s", archive.GetRepository().GetFullName(), archive.GetRoot(), archive.GetCommitSha1())
+		spdxPkg.ExternalRefs = []*spdxExternalRef{
+			{
+				ReferenceCategory: "PACKAGE-MANAGER",
+				ReferenceType:     "purl",
+				ReferenceLocator:  makePackageUrl(pkg),
+			},
+		}
+	}
+	return spdxPkg
+}
+
+// makeSpdxId returns a unique SPDX identifier for the package.
+func makeSpdxId(pkg *pppb.ProtoPackage, used map[string]bool) string {
+	name := pkg.Name
+	if shortSha1 := pkg.GetArchive().GetShortSha1(); shortSha1 != "" {
+		name += "-" + shortSha1
+	}
+	base := "SPDXRef-Package-" + spdxIdInvalidChars.ReplaceAllString(name, "-")
+	id := base
+	for i := 2; used[id]; i++ {
+		id = fmt.Sprintf("%s-%d", base, i)
+	}
+	used[id] = true
+	return id
+}
+
+// makeSpdxCreated returns the creation time of the document.  It is
+// SOURCE_DATE_EPOCH if set, otherwise the most recent commit time of the
+// packages, or the unix epoch if none has one, such that the document never
+// depends on when it is generated.
+func makeSpdxCreated(pkgset *pppb.ProtoPackageSet) (time.Time, error) {
+	if epoch, ok := os.LookupEnv(sourceDateEpochEnv); ok {
+		seconds, err := strconv.ParseInt(epoch, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid %s: %q", sourceDateEpochEnv, epoch)
+		}
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+	created := time.Unix(0, 0)
+	for _, pkg := range pkgset.Packages {
+		if t := pkg.GetArchive().GetCommitTime(); t != nil && t.AsTime().After(created) {
+			created = t.AsTime()
+		}
+	}
+	return created.UTC(), nil
+}
@@ -0,0 +1,167 @@
+package servecmd
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/protopkg/apis/cmd/protopkg/internal/cli"
+	"github.com/protopkg/apis/pkg/chunk"
+	"github.com/protopkg/apis/pkg/server"
+	"github.com/protopkg/apis/pkg/store"
+	"github.com/protopkg/apis/pkg/web"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+type flagName string
+
+const (
+	listenAddressFlagName      flagName = "listen_address"
+	httpListenAddressFlagName  flagName = "http_listen_address"
+	storeFlagName              flagName = "store"
+	storeDirFlagName           flagName = "store_dir"
+	tlsCertFileFlagName        flagName = "tls_cert_file"
+	tlsKeyFileFlagName         flagName = "tls_key_file"
+	maxRecvMessageSizeFlagName flagName = "max_recv_message_size"
+)
+
+const (
+	// memoryStore keeps the packages in memory.
+	memoryStore = "memory"
+	// fsStore keeps the packages in the -store_dir directory.
+	fsStore = "fs"
+)
+
+// Command is the 'serve' subcommand.
+var Command = &cli.Command{
+	Name:    "serve",
+	Summary: "Run a packages server",
+	Flags:   flags,
+	Run:     run,
+}
+
+var flags = flag.NewFlagSet("serve", flag.ContinueOnError)
+
+var (
+	listenAddress      = flags.String(string(listenAddressFlagName), "localhost:1080", "address to listen on")
+	httpListenAddress  = flags.String(string(httpListenAddressFlagName), "", "address to serve the read-only json API and HTML browser on (default is disabled); uses the TLS certificate of the server, if any")
+	storeKind          = flags.String(string(storeFlagName), memoryStore, "where the packages are stored ('memory' or 'fs')")
+	storeDir           = flags.String(string(storeDirFlagName), "", "directory of the 'fs' store")
+	tlsCertFile        = flags.String(string(tlsCertFileFlagName), "", "path to the PEM server certificate (default is plaintext)")
+	tlsKeyFile         = flags.String(string(tlsKeyFileFlagName), "", "path to the PEM private key of the server certificate")
+	maxRecvMessageSize = flags.Int(string(maxRecvMessageSizeFlagName), chunk.DefaultMaxMessageSize, "maximum size in bytes of a received message")
+)
+
+func run() error {
+	st, err := openStore(*storeKind, *storeDir)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	opts := []grpc.ServerOption{
+		grpc.MaxRecvMsgSize(*maxRecvMessageSize),
+	}
+	creds, err := serverCredentials(*tlsCertFile, *tlsKeyFile)
+	if err != nil {
+		return err
+	}
+	if creds != nil {
+		opts = append(opts, grpc.Creds(creds))
+	}
+
+	lis, err := net.Listen("tcp", *listenAddress)
+	if err != nil {
+		return err
+	}
+
+	srv := server.New(st)
+	grpcServer := grpc.NewServer(opts...)
+	srv.Register(grpcServer)
+
+	var httpServer *http.Server
+	if *httpListenAddress != "" {
+		httpLis, err := net.Listen("tcp", *httpListenAddress)
+		if err != nil {
+			lis.Close()
+			return err
+		}
+		httpServer = &http.Server{Handler: web.New(srv)}
+		go func() {
+			var err error
+			if creds != nil {
+				err = httpServer.ServeTLS(httpLis, *tlsCertFile, *tlsKeyFile)
+			} else {
+				err = httpServer.Serve(httpLis)
+			}
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("http server failed: %v", err)
+			}
+		}()
+		log.Printf("serving http on %s", httpLis.Addr())
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		log.Printf("received %v: shutting down", sig)
+		if httpServer != nil {
+			httpServer.Shutdown(context.Background())
+		}
+		grpcServer.GracefulStop()
+	}()
+
+	log.Printf("serving on %s (store: %s)", lis.Addr(), *storeKind)
+	if err := grpcServer.Serve(lis); err != nil {
+		return err
+	}
+	srv.Wait()
+	return nil
+}
+
+// openStore returns the store of the given kind.
+func openStore(kind, dir string) (store.Store, error) {
+	switch kind {
+	case memoryStore:
+		return store.NewMemory(), nil
+	case fsStore:
+		if dir == "" {
+			return nil, errorFlagRequired(storeDirFlagName)
+		}
+		return store.OpenFS(dir)
+	default:
+		return nil, cli.UsageErrorf("invalid -%s: %q (must be one of %q, %q)", storeFlagName, kind, memoryStore, fsStore)
+	}
+}
+
+// serverCredentials returns the TLS credentials of the server, or nil if no
+// certificate is given.
+func serverCredentials(certFile, keyFile string) (credentials.TransportCredentials, error) {
+	if certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+	if certFile == "" {
+		return nil, errorFlagRequired(tlsCertFileFlagName)
+	}
+	if keyFile == "" {
+		return nil, errorFlagRequired(tlsKeyFileFlagName)
+	}
+	creds, err := credentials.NewServerTLSFromFile(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading server certificate: %w", err)
+	}
+	return creds, nil
+}
+
+func errorFlagRequired(name flagName) error {
+	return cli.UsageErrorf("flag required but not provided: -%s", name)
+}
@@ -0,0 +1,137 @@
+package signcmd
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/protopkg/apis/cmd/protopkg/internal/cli"
+	"github.com/protopkg/apis/pkg/signature"
+	"github.com/protopkg/apis/pkg/store"
+	regpb "github.com/protopkg/apis/protopkg/registry/v1alpha1"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	"google.golang.org/protobuf/proto"
+)
+
+type flagName string
+
+const (
+	protoPackageSetFileFlagName  flagName = "pkgset_file"
+	keyFileFlagName              flagName = "key_file"
+	signaturesOutputFileFlagName flagName = "signatures_out"
+	generateKeyFlagName          flagName = "generate_key"
+	principalFlagName            flagName = "principal"
+)
+
+// Command is the 'sign' subcommand.
+var Command = &cli.Command{
+	Name:    "sign",
+	Summary: "Sign the packages of a proto package set",
+	Flags:   flags,
+	Run:     run,
+}
+
+var flags = flag.NewFlagSet("sign", flag.ContinueOnError)
+
+var (
+	protoPackageSetFile  = flags.String(string(protoPackageSetFileFlagName), "", "path to the proto package set file")
+	keyFile              = flags.String(string(keyFileFlagName), "", "path to the signing key (ed25519 PKCS#8 PEM or OpenSSH private key)")
+	signaturesOutputFile = flags.String(string(signaturesOutputFileFlagName), "", "path of the signatures file; signatures by other keys already in the file are kept")
+	generateKey          = flags.String(string(generateKeyFlagName), "", "generate a new ed25519 key at the given path, print its trusted keys line and exit")
+	principal            = flags.String(string(principalFlagName), "protopkg", "principal of the trusted keys line printed by -generate_key")
+)
+
+func run() error {
+	if *generateKey != "" {
+		pub, err := signature.GenerateKey(*generateKey)
+		if err != nil {
+			return err
+		}
+		log.Println("wrote:", *generateKey)
+		fmt.Println(signature.FormatTrustedKey(*principal, pub))
+		return nil
+	}
+
+	pkgset, err := readProtoPackageFileSet(protoPackageSetFileFlagName, *protoPackageSetFile)
+	if err != nil {
+		return err
+	}
+	if *keyFile == "" {
+		return errorFlagRequired(keyFileFlagName)
+	}
+	if *signaturesOutputFile == "" {
+		return errorFlagRequired(signaturesOutputFileFlagName)
+	}
+
+	signer, err := signature.LoadSigner(*keyFile)
+	if err != nil {
+		return err
+	}
+	existing, err := signature.ReadEnvelopes(*signaturesOutputFile)
+	if err != nil {
+		return err
+	}
+
+	list, err := signProtoPackageSet(pkgset, signer, existing)
+	if err != nil {
+		return err
+	}
+	if err := signature.WriteEnvelopes(list, *signaturesOutputFile); err != nil {
+		return err
+	}
+	log.Println("wrote:", *signaturesOutputFile)
+	return nil
+}
+
+// signProtoPackageSet signs each package of the set.  Envelopes of the
+// existing list that sign the same statement are extended with the new
+// signature; stale envelopes (and those of packages not in the set) are
+// dropped.
+func signProtoPackageSet(pkgset *pppb.ProtoPackageSet, signer *signature.Signer, existing *regpb.SignatureEnvelopeList) (*regpb.SignatureEnvelopeList, error) {
+	byRef := make(map[string]*regpb.SignatureEnvelope)
+	for _, env := range existing.Envelopes {
+		if stmt, err := signature.ParseStatement(env); err == nil {
+			byRef[stmt.Ref] = env
+		}
+	}
+
+	list := &regpb.SignatureEnvelopeList{}
+	for _, pkg := range pkgset.Packages {
+		stmt := &signature.Statement{
+			Name: pkg.Name,
+			Ref:  store.Ref(pkg),
+			Hash: pkg.Hash,
+		}
+		env := byRef[stmt.Ref]
+		if prev, err := signature.ParseStatement(env); err != nil || *prev != *stmt {
+			env = nil
+		}
+		env, err := signer.Sign(env, stmt)
+		if err != nil {
+			return nil, err
+		}
+		list.Envelopes = append(list.Envelopes, env)
+		log.Printf("signed: %s (%s)", pkg.Name, signer.KeyID())
+	}
+	return list, nil
+}
+
+func readProtoPackageFileSet(flag flagName, filename string) (*pppb.ProtoPackageSet, error) {
+	if filename == "" {
+		return nil, errorFlagRequired(flag)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", flag, err)
+	}
+	var msg pppb.ProtoPackageSet
+	if err := proto.Unmarshal(data, &msg); err != nil {
+		return nil, fmt.Errorf("unmarshaling %s: %w", flag, err)
+	}
+	return &msg, nil
+}
+
+func errorFlagRequired(name flagName) error {
+	return cli.UsageErrorf("flag required but not provided: -%s", name)
+}
@@ -0,0 +1,143 @@
+package verifysignaturecmd
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/protopkg/apis/cmd/protopkg/internal/cli"
+	"github.com/protopkg/apis/pkg/protohash"
+	"github.com/protopkg/apis/pkg/signature"
+	"github.com/protopkg/apis/pkg/store"
+	regpb "github.com/protopkg/apis/protopkg/registry/v1alpha1"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	"google.golang.org/protobuf/proto"
+)
+
+type flagName string
+
+const (
+	protoPackageSetFileFlagName flagName = "pkgset_file"
+	signaturesFileFlagName      flagName = "signatures_file"
+	trustedKeysFileFlagName     flagName = "trusted_keys_file"
+)
+
+// Command is the 'verify-signature' subcommand.
+var Command = &cli.Command{
+	Name:    "verify-signature",
+	Summary: "Verify the signatures of a proto package set",
+	Flags:   flags,
+	Run:     run,
+}
+
+var flags = flag.NewFlagSet("verify-signature", flag.ContinueOnError)
+
+var (
+	protoPackageSetFile = flags.String(string(protoPackageSetFileFlagName), "", "path to the proto package set file")
+	signaturesFile      = flags.String(string(signaturesFileFlagName), "", "path of the signatures file written by 'protopkg sign'")
+	trustedKeysFile     = flags.String(string(trustedKeysFileFlagName), "", "path of the trusted keys file ('<principal> <ssh public key>' per line)")
+)
+
+func run() error {
+	pkgset, err := readProtoPackageFileSet(protoPackageSetFileFlagName, *protoPackageSetFile)
+	if err != nil {
+		return err
+	}
+	if *signaturesFile == "" {
+		return errorFlagRequired(signaturesFileFlagName)
+	}
+	if *trustedKeysFile == "" {
+		return errorFlagRequired(trustedKeysFileFlagName)
+	}
+	envelopes, err := signature.ReadEnvelopes(*signaturesFile)
+	if err != nil {
+		return err
+	}
+	trusted, err := signature.ReadTrustedKeys(*trustedKeysFile)
+	if err != nil {
+		return err
+	}
+
+	return verifyProtoPackageSet(pkgset, envelopes, trusted)
+}
+
+// verifyProtoPackageSet checks that every package of the set has a valid
+// signature by a trusted key, and that the package files match the signed
+// hash.
+func verifyProtoPackageSet(pkgset *pppb.ProtoPackageSet, envelopes *regpb.SignatureEnvelopeList, trusted signature.TrustedKeys) error {
+	byKey := make(map[string][]*regpb.SignatureEnvelope)
+	for _, env := range envelopes.Envelopes {
+		if stmt, err := signature.ParseStatement(env); err == nil {
+			key := statementKey(stmt)
+			byKey[key] = append(byKey[key], env)
+		}
+	}
+
+	var problems []string
+	for _, pkg := range pkgset.Packages {
+		if err := verifyProtoPackage(pkg, byKey[store.Key(pkg)], trusted); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", pkg.Name, err))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("signature verification failed (%d problem(s)):\n  %s", len(problems), strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// statementKey returns the package key (see store.Key) of the signed package.
+func statementKey(stmt *signature.Statement) string {
+	if stmt.Name == store.DefaultPackageName {
+		return stmt.Ref
+	}
+	return stmt.Name
+}
+
+func verifyProtoPackage(pkg *pppb.ProtoPackage, envelopes []*regpb.SignatureEnvelope, trusted signature.TrustedKeys) error {
+	if err := protohash.Verify(pkg); err != nil {
+		return err
+	}
+	if len(envelopes) == 0 {
+		return fmt.Errorf("not signed")
+	}
+
+	ref := store.Ref(pkg)
+	var problems []string
+	for _, env := range envelopes {
+		stmt, signers, err := trusted.Verify(env)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		if stmt.Hash != pkg.Hash || stmt.Ref != ref {
+			problems = append(problems, fmt.Sprintf("signature is for %s (%s)", stmt.Ref, stmt.Hash))
+			continue
+		}
+		for _, signer := range signers {
+			log.Printf("verified: %s signed by %s (%s)", pkg.Name, signer.Principal, signature.KeyID(signer.Key))
+		}
+		return nil
+	}
+	return fmt.Errorf("%s", strings.Join(problems, "; "))
+}
+
+func readProtoPackageFileSet(flag flagName, filename string) (*pppb.ProtoPackageSet, error) {
+	if filename == "" {
+		return nil, errorFlagRequired(flag)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", flag, err)
+	}
+	var msg pppb.ProtoPackageSet
+	if err := proto.Unmarshal(data, &msg); err != nil {
+		return nil, fmt.Errorf("unmarshaling %s: %w", flag, err)
+	}
+	return &msg, nil
+}
+
+func errorFlagRequired(name flagName) error {
+	return cli.UsageErrorf("flag required but not provided: -%s", name)
+}
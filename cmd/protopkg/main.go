@@ -0,0 +1,50 @@
+// Command protopkg creates, signs, publishes, uploads, serves and queries
+// proto packages.
+//
+//	protopkg [global flags] <command> [flags]
+//
+// Run 'protopkg help' for the list of commands.
+package main
+
+import (
+	"os"
+
+	"github.com/protopkg/apis/cmd/protopkg/internal/checkoutcmd"
+	"github.com/protopkg/apis/cmd/protopkg/internal/cli"
+	"github.com/protopkg/apis/cmd/protopkg/internal/createcmd"
+	"github.com/protopkg/apis/cmd/protopkg/internal/exportcmd"
+	"github.com/protopkg/apis/cmd/protopkg/internal/filecmd"
+	"github.com/protopkg/apis/cmd/protopkg/internal/fsckcmd"
+	"github.com/protopkg/apis/cmd/protopkg/internal/generatecmd"
+	"github.com/protopkg/apis/cmd/protopkg/internal/graphcmd"
+	"github.com/protopkg/apis/cmd/protopkg/internal/mirrorcmd"
+	"github.com/protopkg/apis/cmd/protopkg/internal/ocicmd"
+	"github.com/protopkg/apis/cmd/protopkg/internal/packagecmd"
+	"github.com/protopkg/apis/cmd/protopkg/internal/querycmd"
+	"github.com/protopkg/apis/cmd/protopkg/internal/sbomcmd"
+	"github.com/protopkg/apis/cmd/protopkg/internal/servecmd"
+	"github.com/protopkg/apis/cmd/protopkg/internal/signcmd"
+	"github.com/protopkg/apis/cmd/protopkg/internal/verifysignaturecmd"
+)
+
+func main() {
+	os.Exit(cli.Main("protopkg", os.Args[1:],
+		filecmd.Command,
+		packagecmd.Command,
+		createcmd.Command,
+		sbomcmd.Command,
+		signcmd.Command,
+		verifysignaturecmd.Command,
+		ocicmd.Command,
+		servecmd.Command,
+		fsckcmd.Command,
+		querycmd.GetCommand,
+		querycmd.ListCommand,
+		querycmd.FindCommand,
+		graphcmd.Command,
+		exportcmd.Command,
+		checkoutcmd.Command,
+		generatecmd.Command,
+		mirrorcmd.Command,
+	))
+}
@@ -3,17 +3,19 @@ module github.com/protopkg/apis
 go 1.18
 
 require (
+	cloud.google.com/go/longrunning v0.5.0
 	github.com/bazelbuild/bazel-gazelle v0.31.1
 	github.com/google/go-github v17.0.0+incompatible
 	github.com/gregjones/httpcache v0.0.0-20190611155906-901d90724c79
 	github.com/stackb/apis v0.0.0-20230723215715-f7969e56f12b
 	github.com/stackb/protoreflecthash v0.0.0-20230622204848-b7269c7fa663
+	golang.org/x/crypto v0.9.0
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20230530153820-e85fd2cbaebc
 	google.golang.org/grpc v1.56.0
-	google.golang.org/protobuf v1.30.0
+	google.golang.org/protobuf v1.33.0
 )
 
 require (
-	cloud.google.com/go/longrunning v0.5.0 // indirect
 	github.com/bazelbuild/buildtools v0.0.0-20230510134650-37bd1811516d // indirect
 	github.com/golang/protobuf v1.5.3 // indirect
 	github.com/google/btree v1.1.2 // indirect
@@ -26,5 +28,4 @@ require (
 	golang.org/x/tools v0.9.1 // indirect
 	google.golang.org/genproto v0.0.0-20230530153820-e85fd2cbaebc // indirect
 	google.golang.org/genproto/googleapis/api v0.0.0-20230530153820-e85fd2cbaebc // indirect
-	google.golang.org/genproto/googleapis/rpc v0.0.0-20230530153820-e85fd2cbaebc // indirect
 )
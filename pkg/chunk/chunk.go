@@ -0,0 +1,127 @@
+// Package chunk splits proto packages that exceed the grpc message size limit
+// into file-level chunks, and reassembles them on the receiving side.
+//
+// A chunk is a regular ProtoPackage that carries a subset of the files of the
+// package; all other fields (name, hash, archive, dependencies, ...) are
+// identical in every chunk.  Chunks of a package are sent as consecutive
+// CreateProtoPackageRequest messages on the same stream, and the client marks
+// the stream with the MetadataKey header such that the server knows to
+// reassemble them (by name and hash) before processing the packages.
+package chunk
+
+import (
+	"fmt"
+
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// MetadataKey is the grpc metadata header that marks a stream as
+	// containing chunked packages.
+	MetadataKey = "x-protopkg-chunked"
+	// MetadataValue is the value of the MetadataKey header for file-level
+	// chunking.
+	MetadataValue = "files"
+)
+
+// DefaultMaxMessageSize is the default maximum size of a received grpc
+// message (4 MiB).
+const DefaultMaxMessageSize = 4 * 1024 * 1024
+
+// Split returns the package as a list of chunks, such that each
+// CreateProtoPackageRequest carrying a chunk is at most maxSize bytes.  A
+// package that fits is returned as is (a single chunk).  It is an error if a
+// single file does not fit.
+func Split(pkg *pppb.ProtoPackage, maxSize int) ([]*pppb.ProtoPackage, error) {
+	if maxSize <= 0 || requestSize(proto.Size(pkg)) <= maxSize {
+		return []*pppb.ProtoPackage{pkg}, nil
+	}
+
+	base := proto.Clone(pkg).(*pppb.ProtoPackage)
+	base.Files = nil
+	baseSize := proto.Size(base)
+	if requestSize(baseSize) > maxSize {
+		return nil, fmt.Errorf("package %s: metadata alone (%d bytes) exceeds the maximum message size (%d bytes)", pkg.Name, baseSize, maxSize)
+	}
+
+	var chunks []*pppb.ProtoPackage
+	var current *pppb.ProtoPackage
+	var currentSize int
+	for _, file := range pkg.Files {
+		size := fileEntrySize(file)
+		if requestSize(baseSize+size) > maxSize {
+			return nil, fmt.Errorf("package %s: file %s (%d bytes) exceeds the maximum message size (%d bytes)", pkg.Name, file.GetFile().GetName(), size, maxSize)
+		}
+		if current == nil || requestSize(currentSize+size) > maxSize {
+			current = proto.Clone(base).(*pppb.ProtoPackage)
+			currentSize = baseSize
+			chunks = append(chunks, current)
+		}
+		current.Files = append(current.Files, file)
+		currentSize += size
+	}
+	return chunks, nil
+}
+
+// Reassemble merges the chunks of each package (identified by name and hash)
+// into a single package, in the order in which the packages first appear.
+// Chunks of the same package must agree on everything but the files, and may
+// not repeat a file.
+func Reassemble(chunks []*pppb.ProtoPackage) ([]*pppb.ProtoPackage, error) {
+	type assembly struct {
+		pkg   *pppb.ProtoPackage
+		base  *pppb.ProtoPackage
+		files map[string]bool
+	}
+
+	var pkgs []*pppb.ProtoPackage
+	assemblies := make(map[string]*assembly)
+	for _, chunk := range chunks {
+		base := proto.Clone(chunk).(*pppb.ProtoPackage)
+		base.Files = nil
+
+		key := chunk.Name + "@" + chunk.Hash
+		a, ok := assemblies[key]
+		if !ok {
+			a = &assembly{
+				pkg:   proto.Clone(base).(*pppb.ProtoPackage),
+				base:  base,
+				files: make(map[string]bool),
+			}
+			assemblies[key] = a
+			pkgs = append(pkgs, a.pkg)
+		} else if !proto.Equal(a.base, base) {
+			return nil, fmt.Errorf("package %s: chunks disagree on package metadata", chunk.Name)
+		}
+
+		for _, file := range chunk.Files {
+			name := file.GetFile().GetName()
+			if a.files[name] {
+				return nil, fmt.Errorf("package %s: file %s was sent more than once", chunk.Name, name)
+			}
+			a.files[name] = true
+			a.pkg.Files = append(a.pkg.Files, file)
+		}
+	}
+	return pkgs, nil
+}
+
+// filesFieldNumber is the field number of ProtoPackage.files.
+var filesFieldNumber = (&pppb.ProtoPackage{}).ProtoReflect().Descriptor().Fields().ByName("files").Number()
+
+// pkgFieldNumber is the field number of CreateProtoPackageRequest.pkg.
+var pkgFieldNumber = (&pppb.CreateProtoPackageRequest{}).ProtoReflect().Descriptor().Fields().ByName("pkg").Number()
+
+// fileEntrySize returns the encoded size of the file as an element of
+// ProtoPackage.files.
+func fileEntrySize(file *pppb.ProtoFile) int {
+	return protowire.SizeTag(filesFieldNumber) + protowire.SizeBytes(proto.Size(file))
+}
+
+// requestSize returns the encoded size of a CreateProtoPackageRequest carrying
+// a package of the given encoded size.
+func requestSize(pkgSize int) int {
+	return protowire.SizeTag(pkgFieldNumber) + protowire.SizeBytes(pkgSize)
+}
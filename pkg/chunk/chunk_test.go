@@ -0,0 +1,180 @@
+package chunk
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/protopkg/apis/pkg/protohash"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// newTestPackage returns a valid package with the given number of files, each
+// of which has a comment of the given size.
+func newTestPackage(t *testing.T, numFiles, commentSize int) *pppb.ProtoPackage {
+	t.Helper()
+	pkg := &pppb.ProtoPackage{
+		Name: "acme.v1",
+		Archive: &pppb.ProtoArchive{
+			Repository: &pppb.ProtoRepository{FullName: "github.com/acme/protos"},
+			CommitSha1: "0123456789abcdef0123456789abcdef01234567",
+		},
+		Dependencies: []string{"github.com/acme/protos/0123456/~:acme.common"},
+	}
+	for i := 0; i < numFiles; i++ {
+		desc := &descriptorpb.FileDescriptorProto{
+			Name:    proto.String(fmt.Sprintf("acme/v1/file%d.proto", i)),
+			Package: proto.String("acme.v1"),
+			Syntax:  proto.String("proto3"),
+			SourceCodeInfo: &descriptorpb.SourceCodeInfo{
+				Location: []*descriptorpb.SourceCodeInfo_Location{{
+					LeadingComments: proto.String(strings.Repeat("x", commentSize)),
+				}},
+			},
+		}
+		hash, err := protohash.File(desc, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pkg.Files = append(pkg.Files, &pppb.ProtoFile{File: desc, Hash: hash})
+	}
+	hash, err := protohash.Package(pkg.Files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg.Hash = hash
+	return pkg
+}
+
+func TestSplitReassemble(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		maxSize    int
+		wantChunks int
+	}{
+		{name: "fits", maxSize: DefaultMaxMessageSize, wantChunks: 1},
+		{name: "no limit", maxSize: 0, wantChunks: 1},
+		{name: "file per chunk", maxSize: 1500, wantChunks: 5},
+		{name: "two files per chunk", maxSize: 2500, wantChunks: 3},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			pkg := newTestPackage(t, 5, 1000)
+			chunks, err := Split(pkg, tc.maxSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(chunks) != tc.wantChunks {
+				t.Errorf("got %d chunks, want %d", len(chunks), tc.wantChunks)
+			}
+			for i, chunk := range chunks {
+				size := proto.Size(&pppb.CreateProtoPackageRequest{Pkg: chunk})
+				if tc.maxSize > 0 && size > tc.maxSize {
+					t.Errorf("chunk #%d: got %d bytes, want at most %d", i, size, tc.maxSize)
+				}
+				if chunk.Name != pkg.Name || chunk.Hash != pkg.Hash || len(chunk.Dependencies) != 1 {
+					t.Errorf("chunk #%d: metadata differs from the package", i)
+				}
+			}
+
+			got, err := Reassemble(chunks)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 1 {
+				t.Fatalf("got %d packages, want 1", len(got))
+			}
+			if !proto.Equal(got[0], pkg) {
+				t.Errorf("reassembled package differs from the original")
+			}
+		})
+	}
+}
+
+func TestReassembleInterleaved(t *testing.T) {
+	a := newTestPackage(t, 2, 10)
+	b := newTestPackage(t, 2, 20)
+	b.Name = "acme.v2"
+	split := func(pkg *pppb.ProtoPackage) (first, second *pppb.ProtoPackage) {
+		first = proto.Clone(pkg).(*pppb.ProtoPackage)
+		first.Files = pkg.Files[:1]
+		second = proto.Clone(pkg).(*pppb.ProtoPackage)
+		second.Files = pkg.Files[1:]
+		return
+	}
+	a1, a2 := split(a)
+	b1, b2 := split(b)
+
+	got, err := Reassemble([]*pppb.ProtoPackage{a1, b1, a2, b2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || !proto.Equal(got[0], a) || !proto.Equal(got[1], b) {
+		t.Errorf("got %v, want [%v %v]", got, a, b)
+	}
+}
+
+func TestReassembleProblems(t *testing.T) {
+	pkg := newTestPackage(t, 3, 1000)
+	chunks, err := Split(pkg, 1500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 3 {
+		t.Fatalf("got %d chunks, want 3", len(chunks))
+	}
+
+	t.Run("duplicate chunk", func(t *testing.T) {
+		_, err := Reassemble([]*pppb.ProtoPackage{chunks[0], chunks[1], chunks[1], chunks[2]})
+		want := "package acme.v1: file acme/v1/file1.proto was sent more than once"
+		if err == nil || err.Error() != want {
+			t.Errorf("got error %v, want %q", err, want)
+		}
+	})
+
+	t.Run("metadata mismatch", func(t *testing.T) {
+		other := proto.Clone(chunks[1]).(*pppb.ProtoPackage)
+		other.Dependencies = nil
+		_, err := Reassemble([]*pppb.ProtoPackage{chunks[0], other, chunks[2]})
+		want := "package acme.v1: chunks disagree on package metadata"
+		if err == nil || err.Error() != want {
+			t.Errorf("got error %v, want %q", err, want)
+		}
+	})
+
+	// a missing chunk cannot be told apart from a smaller package, but the
+	// reassembled package no longer matches its hash
+	t.Run("missing chunk", func(t *testing.T) {
+		got, err := Reassemble([]*pppb.ProtoPackage{chunks[0], chunks[2]})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 1 || len(got[0].Files) != 2 {
+			t.Fatalf("got %v, want a single package with 2 files", got)
+		}
+		if err := protohash.Verify(got[0]); err == nil || !strings.Contains(err.Error(), "hash mismatch: package has") {
+			t.Errorf("verify: got %v, want a package hash mismatch", err)
+		}
+	})
+}
+
+func TestSplitTooLarge(t *testing.T) {
+	t.Run("file", func(t *testing.T) {
+		pkg := newTestPackage(t, 3, 1000)
+		pkg.Files[1].File.SourceCodeInfo.Location[0].LeadingComments = proto.String(strings.Repeat("x", 5000))
+		_, err := Split(pkg, 2000)
+		if err == nil || !strings.Contains(err.Error(), "package acme.v1: file acme/v1/file1.proto (") || !strings.Contains(err.Error(), "exceeds the maximum message size (2000 bytes)") {
+			t.Errorf("got error %v", err)
+		}
+	})
+
+	t.Run("metadata", func(t *testing.T) {
+		pkg := newTestPackage(t, 1, 10)
+		pkg.Dependencies = []string{strings.Repeat("x", 3000)}
+		_, err := Split(pkg, 2000)
+		if err == nil || !strings.Contains(err.Error(), "package acme.v1: metadata alone") {
+			t.Errorf("got error %v", err)
+		}
+	})
+}
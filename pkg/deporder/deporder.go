@@ -0,0 +1,62 @@
+// Package deporder sorts proto packages in dependency order, the order in
+// which a server accepts them.
+package deporder
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/protopkg/apis/pkg/store"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+)
+
+// Packages sorts the packages such that every package comes after the
+// packages of the list it depends on.  Packages that do not depend on each
+// other keep their relative order.  Dependencies outside the list are assumed
+// to be known by the server, and do not affect the order.  It is an error if
+// the packages depend on each other in a cycle.
+func Packages(pkgs []*pppb.ProtoPackage) ([]*pppb.ProtoPackage, error) {
+	byRef := make(map[string]*pppb.ProtoPackage)
+	for _, pkg := range pkgs {
+		byRef[store.Ref(pkg)] = pkg
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[*pppb.ProtoPackage]int)
+	ordered := make([]*pppb.ProtoPackage, 0, len(pkgs))
+	var path []string
+
+	var visit func(pkg *pppb.ProtoPackage) error
+	visit = func(pkg *pppb.ProtoPackage) error {
+		switch state[pkg] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle: %s -> %s", strings.Join(path, " -> "), pkg.Name)
+		}
+		state[pkg] = visiting
+		path = append(path, pkg.Name)
+		for _, dep := range pkg.Dependencies {
+			if other, ok := byRef[dep]; ok {
+				if err := visit(other); err != nil {
+					return err
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[pkg] = visited
+		ordered = append(ordered, pkg)
+		return nil
+	}
+
+	for _, pkg := range pkgs {
+		if err := visit(pkg); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
@@ -0,0 +1,106 @@
+package deporder
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/protopkg/apis/pkg/store"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+)
+
+// newTestPackages returns a package of github.com/acme/protos per name, with
+// the dependencies of deps (by name).
+func newTestPackages(names []string, deps map[string][]string) map[string]*pppb.ProtoPackage {
+	pkgs := make(map[string]*pppb.ProtoPackage)
+	for _, name := range names {
+		pkgs[name] = &pppb.ProtoPackage{
+			Name: name,
+			Archive: &pppb.ProtoArchive{
+				Repository: &pppb.ProtoRepository{FullName: "github.com/acme/protos"},
+				ShortSha1:  "0123456",
+			},
+		}
+	}
+	for name, list := range deps {
+		for _, dep := range list {
+			ref := "github.com/other/protos/0123456/~:" + dep
+			if pkg, ok := pkgs[dep]; ok {
+				ref = store.Ref(pkg)
+			}
+			pkgs[name].Dependencies = append(pkgs[name].Dependencies, ref)
+		}
+	}
+	return pkgs
+}
+
+func TestPackages(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		order   []string
+		deps    map[string][]string
+		want    []string
+		wantErr string
+	}{
+		{
+			name:  "no dependencies",
+			order: []string{"c", "a", "b"},
+			want:  []string{"c", "a", "b"},
+		},
+		{
+			name:  "chain",
+			order: []string{"api", "common", "base"},
+			deps:  map[string][]string{"api": {"common"}, "common": {"base"}},
+			want:  []string{"base", "common", "api"},
+		},
+		{
+			name:  "diamond",
+			order: []string{"api", "left", "right", "base"},
+			deps:  map[string][]string{"api": {"left", "right"}, "left": {"base"}, "right": {"base"}},
+			want:  []string{"base", "left", "right", "api"},
+		},
+		{
+			// dependencies outside of the list do not affect the order
+			name:  "external dependency",
+			order: []string{"api", "common"},
+			deps:  map[string][]string{"api": {"common", "google.protobuf"}, "common": {"google.protobuf"}},
+			want:  []string{"common", "api"},
+		},
+		{
+			name:    "cycle",
+			order:   []string{"api", "a", "b"},
+			deps:    map[string][]string{"api": {"a"}, "a": {"b"}, "b": {"a"}},
+			wantErr: "dependency cycle: api -> a -> b -> a",
+		},
+		{
+			name:    "self",
+			order:   []string{"a"},
+			deps:    map[string][]string{"a": {"a"}},
+			wantErr: "dependency cycle: a -> a",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			pkgs := newTestPackages(tc.order, tc.deps)
+			var list []*pppb.ProtoPackage
+			for _, name := range tc.order {
+				list = append(list, pkgs[name])
+			}
+			got, err := Packages(list)
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("got error %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, pkg := range got {
+				names = append(names, pkg.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(tc.want, ",") {
+				t.Errorf("got %v, want %v", names, tc.want)
+			}
+		})
+	}
+}
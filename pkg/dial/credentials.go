@@ -0,0 +1,207 @@
+package dial
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// tokenRefreshMargin is the time before expiry at which a token is considered
+// expired, such that it does not expire while a call is in flight.
+const tokenRefreshMargin = 30 * time.Second
+
+// token is a bearer token and its (optional) expiry.  It is also the json
+// format of token files and credential helper responses:
+//
+//	{"token": "...", "expires_at": "2023-07-01T00:00:00Z"}
+type token struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// valid reports whether the token can be used at the given time.
+func (t *token) valid(now time.Time) bool {
+	if t == nil || t.Token == "" {
+		return false
+	}
+	return t.ExpiresAt.IsZero() || now.Add(tokenRefreshMargin).Before(t.ExpiresAt)
+}
+
+// tokenSource produces tokens.
+type tokenSource interface {
+	token(ctx context.Context) (*token, error)
+}
+
+// PerRPCCredentials returns the per-RPC credentials for calls to the given
+// server address, or nil if no token source is configured.
+func (o *Options) PerRPCCredentials(address string) (credentials.PerRPCCredentials, error) {
+	var sources []tokenSource
+	if o.TokenEnv != "" {
+		value := strings.TrimSpace(os.Getenv(o.TokenEnv))
+		if value == "" {
+			return nil, fmt.Errorf("environment variable %s (%s) is empty or not set", o.TokenEnv, o.flag("auth_token_env"))
+		}
+		sources = append(sources, &staticTokenSource{&token{Token: value}})
+	}
+	if o.TokenFile != "" {
+		sources = append(sources, &fileTokenSource{filename: o.TokenFile})
+	}
+	if o.CredentialHelper != "" {
+		sources = append(sources, &helperTokenSource{helper: o.CredentialHelper, address: address})
+	}
+	switch len(sources) {
+	case 0:
+		return nil, nil
+	case 1:
+		return &bearerCredentials{source: &cachingTokenSource{source: sources[0]}}, nil
+	default:
+		return nil, fmt.Errorf("only one of %s, %s and %s may be given", o.flag("auth_token_env"), o.flag("auth_token_file"), o.flag("auth_credential_helper"))
+	}
+}
+
+// hasToken reports whether a token source is configured.
+func (o *Options) hasToken() bool {
+	return o.TokenEnv != "" || o.TokenFile != "" || o.CredentialHelper != ""
+}
+
+// bearerCredentials sends the token as 'authorization: Bearer <token>'
+// metadata.
+type bearerCredentials struct {
+	source tokenSource
+}
+
+// GetRequestMetadata implements credentials.PerRPCCredentials.
+func (c *bearerCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	tok, err := c.source.token(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting auth token: %w", err)
+	}
+	return map[string]string{
+		"authorization": "Bearer " + tok.Token,
+	}, nil
+}
+
+// RequireTransportSecurity implements credentials.PerRPCCredentials.  Tokens
+// are never sent over plaintext connections.
+func (c *bearerCredentials) RequireTransportSecurity() bool {
+	return true
+}
+
+// staticTokenSource always returns the same token.
+type staticTokenSource struct {
+	tok *token
+}
+
+func (s *staticTokenSource) token(ctx context.Context) (*token, error) {
+	return s.tok, nil
+}
+
+// fileTokenSource reads the token from a file, either as plain text or in the
+// json token format.  A plain text token does not expire, but the file is
+// re-read when it is modified.
+type fileTokenSource struct {
+	filename string
+	modTime  time.Time
+}
+
+func (s *fileTokenSource) token(ctx context.Context) (*token, error) {
+	info, err := os.Stat(s.filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading token file: %w", err)
+	}
+	data, err := os.ReadFile(s.filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading token file: %w", err)
+	}
+	tok, err := parseToken(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", s.filename, err)
+	}
+	s.modTime = info.ModTime()
+	return tok, nil
+}
+
+// modified reports whether the file has changed since it was last read.
+func (s *fileTokenSource) modified() bool {
+	info, err := os.Stat(s.filename)
+	return err != nil || !info.ModTime().Equal(s.modTime)
+}
+
+// helperTokenSource runs an external credential helper.  The helper is invoked
+// as '<helper> get' with the server address on stdin, and must print a token
+// in the json token format on stdout (similar to git and docker credential
+// helpers).
+type helperTokenSource struct {
+	helper  string
+	address string
+}
+
+func (s *helperTokenSource) token(ctx context.Context) (*token, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, s.helper, "get")
+	cmd.Stdin = strings.NewReader(s.address + "\n")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("credential helper %s: %w: %s", s.helper, err, strings.TrimSpace(stderr.String()))
+	}
+	var tok token
+	if err := json.Unmarshal(stdout.Bytes(), &tok); err != nil {
+		return nil, fmt.Errorf("credential helper %s: invalid response: %w", s.helper, err)
+	}
+	if tok.Token == "" {
+		return nil, fmt.Errorf("credential helper %s: no token for %s", s.helper, s.address)
+	}
+	return &tok, nil
+}
+
+// cachingTokenSource caches the token of the underlying source until it
+// expires (or the token file is modified).
+type cachingTokenSource struct {
+	source tokenSource
+
+	mu  sync.Mutex
+	tok *token
+}
+
+func (s *cachingTokenSource) token(ctx context.Context) (*token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tok.valid(time.Now()) {
+		if file, ok := s.source.(*fileTokenSource); !ok || !file.modified() {
+			return s.tok, nil
+		}
+	}
+	tok, err := s.source.token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !tok.valid(time.Now()) {
+		return nil, errors.New("token is empty or expired")
+	}
+	s.tok = tok
+	return tok, nil
+}
+
+// parseToken parses a token either in the json format or as plain text.
+func parseToken(data []byte) (*token, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '{' {
+		var tok token
+		if err := json.Unmarshal(data, &tok); err != nil {
+			return nil, fmt.Errorf("invalid token: %w", err)
+		}
+		return &tok, nil
+	}
+	return &token{Token: string(data)}, nil
+}
@@ -0,0 +1,216 @@
+package dial
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseToken(t *testing.T) {
+	expiry := time.Date(2030, 7, 1, 0, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name    string
+		data    string
+		want    token
+		wantErr string
+	}{
+		{name: "plain", data: "secret\n", want: token{Token: "secret"}},
+		{name: "json", data: `{"token": "secret"}`, want: token{Token: "secret"}},
+		{name: "json expiry", data: `{"token": "secret", "expires_at": "2030-07-01T00:00:00Z"}`, want: token{Token: "secret", ExpiresAt: expiry}},
+		{name: "invalid json", data: `{"token": `, wantErr: "invalid token: "},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseToken([]byte(tc.data))
+			if tc.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tc.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Token != tc.want.Token || !got.ExpiresAt.Equal(tc.want.ExpiresAt) {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTokenValid(t *testing.T) {
+	now := time.Now()
+	for _, tc := range []struct {
+		name string
+		tok  *token
+		want bool
+	}{
+		{name: "nil"},
+		{name: "empty", tok: &token{}},
+		{name: "no expiry", tok: &token{Token: "secret"}, want: true},
+		{name: "expires later", tok: &token{Token: "secret", ExpiresAt: now.Add(time.Hour)}, want: true},
+		{name: "expires soon", tok: &token{Token: "secret", ExpiresAt: now.Add(tokenRefreshMargin / 2)}},
+		{name: "expired", tok: &token{Token: "secret", ExpiresAt: now.Add(-time.Minute)}},
+	} {
+		if got := tc.tok.valid(now); got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestFileTokenReload(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	filename := writeFile(t, dir, "token", []byte("first\n"))
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	setModTime := func(mtime time.Time) {
+		t.Helper()
+		if err := os.Chtimes(filename, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	setModTime(modTime)
+	source := &cachingTokenSource{source: &fileTokenSource{filename: filename}}
+	get := func(want string) {
+		t.Helper()
+		tok, err := source.token(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tok.Token != want {
+			t.Errorf("got token %q, want %q", tok.Token, want)
+		}
+	}
+	get("first")
+
+	// the file is not read again while it is unmodified
+	writeFile(t, dir, "token", []byte("unseen\n"))
+	setModTime(modTime)
+	get("first")
+
+	// a modified file is read again
+	writeFile(t, dir, "token", []byte("second\n"))
+	setModTime(modTime.Add(time.Minute))
+	get("second")
+
+	// an expired token is read again, even if the file is unmodified
+	expiresAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	writeFile(t, dir, "token", []byte(`{"token": "third", "expires_at": "`+expiresAt+`"}`))
+	setModTime(modTime.Add(2 * time.Minute))
+	get("third")
+	source.tok.ExpiresAt = time.Now().Add(-time.Minute)
+	get("third")
+
+	// a token that expires within the margin is rejected
+	expiresAt = time.Now().Add(tokenRefreshMargin / 2).UTC().Format(time.RFC3339)
+	writeFile(t, dir, "token", []byte(`{"token": "fourth", "expires_at": "`+expiresAt+`"}`))
+	setModTime(modTime.Add(3 * time.Minute))
+	if _, err := source.token(ctx); err == nil || err.Error() != "token is empty or expired" {
+		t.Errorf("got error %v, want expired", err)
+	}
+
+	if err := os.Remove(filename); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := source.token(ctx); err == nil || !strings.HasPrefix(err.Error(), "reading token file: ") {
+		t.Errorf("got error %v, want reading token file", err)
+	}
+}
+
+// writeHelper writes a credential helper shell script with the given body.
+func writeHelper(t *testing.T, body string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("credential helper scripts require a unix shell")
+	}
+	filename := writeFile(t, t.TempDir(), "helper", []byte("#!/bin/sh\n"+body+"\n"))
+	if err := os.Chmod(filename, 0700); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestHelperTokenSource(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		body    string
+		want    string
+		wantErr string
+	}{
+		{
+			// the helper is invoked as '<helper> get' with the address on
+			// stdin
+			name: "token",
+			body: `read address
+[ "$1" = get ] || exit 2
+echo "{\"token\": \"token for $address\", \"expires_at\": \"2030-07-01T00:00:00Z\"}"`,
+			want: "token for packages.example.com:443",
+		},
+		{
+			name:    "failure",
+			body:    `echo "not logged in" >&2; exit 1`,
+			wantErr: "exit status 1: not logged in",
+		},
+		{
+			name:    "invalid response",
+			body:    `echo "secret"`,
+			wantErr: "invalid response: ",
+		},
+		{
+			name:    "no token",
+			body:    `echo "{}"`,
+			wantErr: "no token for packages.example.com:443",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			helper := writeHelper(t, tc.body)
+			source := &helperTokenSource{helper: helper, address: "packages.example.com:443"}
+			tok, err := source.token(context.Background())
+			if tc.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), "credential helper "+helper+": ") || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tok.Token != tc.want {
+				t.Errorf("got token %q, want %q", tok.Token, tc.want)
+			}
+			if want := time.Date(2030, 7, 1, 0, 0, 0, 0, time.UTC); !tok.ExpiresAt.Equal(want) {
+				t.Errorf("got expiry %v, want %v", tok.ExpiresAt, want)
+			}
+		})
+	}
+}
+
+func TestPerRPCCredentials(t *testing.T) {
+	t.Setenv("PROTOPKG_TEST_TOKEN", " secret\n")
+	creds, err := (&Options{TokenEnv: "PROTOPKG_TEST_TOKEN"}).PerRPCCredentials("localhost:1080")
+	if err != nil {
+		t.Fatal(err)
+	}
+	md, err := creds.GetRequestMetadata(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := md["authorization"]; got != "Bearer secret" {
+		t.Errorf("got authorization %q, want %q", got, "Bearer secret")
+	}
+	if !creds.RequireTransportSecurity() {
+		t.Error("tokens are sent over plaintext")
+	}
+
+	if creds, err := (&Options{}).PerRPCCredentials("localhost:1080"); creds != nil || err != nil {
+		t.Errorf("no source: got %v, %v", creds, err)
+	}
+	if _, err := (&Options{TokenEnv: "PROTOPKG_TEST_UNSET"}).PerRPCCredentials("localhost:1080"); err == nil || !strings.Contains(err.Error(), "is empty or not set") {
+		t.Errorf("unset variable: got error %v", err)
+	}
+	if _, err := (&Options{TokenEnv: "PROTOPKG_TEST_TOKEN", TokenFile: "token"}).PerRPCCredentials("localhost:1080"); err == nil || !strings.Contains(err.Error(), "only one of") {
+		t.Errorf("two sources: got error %v", err)
+	}
+}
@@ -0,0 +1,191 @@
+// Package dial establishes grpc client connections to protopkg servers.  It
+// provides the transport security (plaintext, TLS or mutual TLS) and
+// authentication (bearer token) options shared by the client commands.
+package dial
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Options configures how a client connection is established.  The zero value
+// connects without transport security, as the clients always did; TLS is
+// enabled by TLS or any of the other TLS options.
+type Options struct {
+	// TLS enables transport security, verifying the server against the
+	// system cert pool unless CACertFile is given.
+	TLS bool
+	// CACertFile is an optional path to a PEM bundle of root certificates used
+	// to verify the server, instead of the system cert pool.
+	CACertFile string
+	// ClientCertFile is an optional path to a PEM client certificate,
+	// presented to the server for mutual TLS.  Requires ClientKeyFile.
+	ClientCertFile string
+	// ClientKeyFile is the path to the PEM private key of ClientCertFile.
+	ClientKeyFile string
+	// ServerName overrides the name used to verify the server certificate
+	// (by default, the host part of the address).
+	ServerName string
+	// TokenEnv is the name of an environment variable that holds a static
+	// bearer token.
+	TokenEnv string
+	// TokenFile is the path of a file that holds a bearer token.  The file is
+	// re-read when the token expires or the file changes.
+	TokenFile string
+	// CredentialHelper is the name or path of an executable that produces a
+	// bearer token for the server address (see helperTokenSource).
+	CredentialHelper string
+
+	// flagPrefix is the prefix of the flag names, used in error messages.
+	flagPrefix string
+}
+
+// RegisterFlags installs the flags for the options in the given flagset.
+func (o *Options) RegisterFlags(fs *flag.FlagSet) {
+	o.RegisterPrefixedFlags(fs, "")
+}
+
+// RegisterPrefixedFlags installs the flags for the options in the given
+// flagset, with names that start with the prefix (e.g. 'source_' for
+// -source_tls).  It allows a command to connect to several servers
+// with different options.
+func (o *Options) RegisterPrefixedFlags(fs *flag.FlagSet, prefix string) {
+	o.flagPrefix = prefix
+	fs.BoolVar(&o.TLS, prefix+"tls", false, "connect with TLS (implied by the other tls flags; default is plaintext)")
+	fs.StringVar(&o.CACertFile, prefix+"tls_ca_cert_file", "", "path to a PEM bundle of CA certificates that verify the server (default is the system cert pool)")
+	fs.StringVar(&o.ClientCertFile, prefix+"tls_client_cert_file", "", "path to a PEM client certificate for mutual TLS")
+	fs.StringVar(&o.ClientKeyFile, prefix+"tls_client_key_file", "", "path to the PEM private key of the client certificate")
+	fs.StringVar(&o.ServerName, prefix+"tls_server_name", "", "override the server name used to verify the server certificate")
+	fs.StringVar(&o.TokenEnv, prefix+"auth_token_env", "", "name of the environment variable that holds a bearer token")
+	fs.StringVar(&o.TokenFile, prefix+"auth_token_file", "", "path of a file that holds a bearer token (plain text or json)")
+	fs.StringVar(&o.CredentialHelper, prefix+"auth_credential_helper", "", "executable that prints a bearer token for the server address")
+}
+
+// flag returns the name of a flag of the options, for error messages.
+func (o *Options) flag(name string) string {
+	return "-" + o.flagPrefix + name
+}
+
+// useTLS reports whether the connection uses transport security.
+func (o *Options) useTLS() bool {
+	return o.TLS || o.CACertFile != "" || o.ClientCertFile != "" || o.ClientKeyFile != "" || o.ServerName != ""
+}
+
+// Validate checks the options for consistency.
+func (o *Options) Validate() error {
+	if !o.useTLS() {
+		if o.hasToken() {
+			return fmt.Errorf("TLS is required to send an auth token, set %s", o.flag("tls"))
+		}
+		return nil
+	}
+	if (o.ClientCertFile == "") != (o.ClientKeyFile == "") {
+		return fmt.Errorf("%s and %s must be given together", o.flag("tls_client_cert_file"), o.flag("tls_client_key_file"))
+	}
+	return nil
+}
+
+// TransportCredentials returns the transport credentials for the options.
+func (o *Options) TransportCredentials() (credentials.TransportCredentials, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	if !o.useTLS() {
+		return insecure.NewCredentials(), nil
+	}
+	config, err := o.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	return credentials.NewTLS(config), nil
+}
+
+// TLSConfig returns the client tls configuration for the options.
+func (o *Options) TLSConfig() (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName: o.ServerName,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if o.CACertFile != "" {
+		pool, err := readCertPool(o.CACertFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = pool
+	} else {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			return nil, fmt.Errorf("getting system x509 cert pool: %w", err)
+		}
+		config.RootCAs = pool
+	}
+
+	if o.ClientCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.ClientCertFile, o.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+// DialOptions returns the grpc dial options to connect to the given address.
+func (o *Options) DialOptions(address string) ([]grpc.DialOption, error) {
+	creds, err := o.TransportCredentials()
+	if err != nil {
+		return nil, err
+	}
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+	}
+
+	perRPC, err := o.PerRPCCredentials(address)
+	if err != nil {
+		return nil, err
+	}
+	if perRPC != nil {
+		opts = append(opts, grpc.WithPerRPCCredentials(perRPC))
+	}
+
+	return opts, nil
+}
+
+// Dial creates a client connection to the given address.  Additional dial
+// options are appended to the ones derived from the options.
+func Dial(address string, o *Options, extra ...grpc.DialOption) (*grpc.ClientConn, error) {
+	if address == "" {
+		return nil, errors.New("server address is required")
+	}
+	opts, err := o.DialOptions(address)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := grpc.Dial(address, append(opts, extra...)...)
+	if err != nil {
+		return nil, fmt.Errorf("dialing connection: %w", err)
+	}
+	return conn, nil
+}
+
+func readCertPool(filename string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading CA certificates: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no PEM certificates found in %s", filename)
+	}
+	return pool, nil
+}
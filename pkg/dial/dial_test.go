@@ -0,0 +1,258 @@
+package dial
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+)
+
+// testCA is a certificate authority generated for the test.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "protopkg test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{
+		cert: cert,
+		key:  key,
+		pem:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+// issue returns a certificate signed by the CA, for the server ('localhost')
+// or a client.
+func (ca *testCA) issue(t *testing.T, serial int64, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+}
+
+func writeFile(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+	filename := filepath.Join(dir, name)
+	if err := os.WriteFile(filename, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+// startServer starts a grpc server on a loopback port with the given tls
+// configuration, serving the health service.  The authorization metadata of
+// the calls is sent to the returned channel.
+func startServer(t *testing.T, config *tls.Config) (string, <-chan string) {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := make(chan string, 10)
+	server := grpc.NewServer(
+		grpc.Creds(credentials.NewTLS(config)),
+		grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			md, _ := metadata.FromIncomingContext(ctx)
+			auth <- firstValue(md.Get("authorization"))
+			return handler(ctx, req)
+		}),
+	)
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	_, port, err := net.SplitHostPort(lis.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return net.JoinHostPort("localhost", port), auth
+}
+
+func firstValue(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func check(address string, o *Options) error {
+	conn, err := Dial(address, o)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	return err
+}
+
+func TestDialTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	caFile := writeFile(t, dir, "ca.pem", ca.pem)
+	serverCert, serverKey := ca.issue(t, 2, x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := ca.issue(t, 3, x509.ExtKeyUsageClientAuth)
+	clientCertFile := writeFile(t, dir, "client.pem", clientCert)
+	clientKeyFile := writeFile(t, dir, "client.key", clientKey)
+
+	pair, err := tls.X509KeyPair(serverCert, serverKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(ca.pem)
+
+	address, auth := startServer(t, &tls.Config{
+		Certificates: []tls.Certificate{pair},
+	})
+	mtlsAddress, _ := startServer(t, &tls.Config{
+		Certificates: []tls.Certificate{pair},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+	})
+
+	t.Setenv("PROTOPKG_TEST_TOKEN", "s3cret")
+
+	for _, tc := range []struct {
+		name    string
+		address string
+		opts    Options
+		wantErr bool
+		// wantAuth is the expected authorization metadata of a successful
+		// call.
+		wantAuth string
+	}{
+		{
+			name:    "plaintext by default",
+			address: address,
+			wantErr: true,
+		},
+		{
+			name:    "system cert pool",
+			address: address,
+			opts:    Options{TLS: true},
+			wantErr: true,
+		},
+		{
+			name:    "ca cert implies tls",
+			address: address,
+			opts:    Options{CACertFile: caFile},
+		},
+		{
+			name:     "token",
+			address:  address,
+			opts:     Options{CACertFile: caFile, TokenEnv: "PROTOPKG_TEST_TOKEN"},
+			wantAuth: "Bearer s3cret",
+		},
+		{
+			name:    "mutual tls without client certificate",
+			address: mtlsAddress,
+			opts:    Options{CACertFile: caFile},
+			wantErr: true,
+		},
+		{
+			name:    "mutual tls",
+			address: mtlsAddress,
+			opts:    Options{CACertFile: caFile, ClientCertFile: clientCertFile, ClientKeyFile: clientKeyFile},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := check(tc.address, &tc.opts)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.address != address {
+				return
+			}
+			if got := <-auth; got != tc.wantAuth {
+				t.Errorf("authorization: got %q, want %q", got, tc.wantAuth)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		opts    Options
+		wantErr bool
+	}{
+		{name: "plaintext"},
+		{name: "tls", opts: Options{TLS: true}},
+		{name: "token without tls", opts: Options{TokenFile: "token"}, wantErr: true},
+		{name: "token with tls", opts: Options{TLS: true, TokenFile: "token"}},
+		{name: "client certificate without key", opts: Options{ClientCertFile: "cert.pem"}, wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.opts.Validate(); (err != nil) != tc.wantErr {
+				t.Errorf("got error %v, want error: %v", err, tc.wantErr)
+			}
+		})
+	}
+}
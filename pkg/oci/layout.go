@@ -0,0 +1,97 @@
+package oci
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Layout is an OCI image layout directory
+// (https://github.com/opencontainers/image-spec/blob/main/image-layout.md).
+// Manifests are listed in index.json, named '<repository>:<tag>' by the
+// org.opencontainers.image.ref.name annotation.
+type Layout struct {
+	dir string
+}
+
+// OpenLayout opens the layout in dir, creating it if needed.
+func OpenLayout(dir string) (*Layout, error) {
+	if err := os.MkdirAll(filepath.Join(dir, "blobs", "sha256"), 0755); err != nil {
+		return nil, fmt.Errorf("creating layout: %w", err)
+	}
+	marker := filepath.Join(dir, "oci-layout")
+	if _, err := os.Stat(marker); errors.Is(err, os.ErrNotExist) {
+		if err := os.WriteFile(marker, []byte(`{"imageLayoutVersion":"1.0.0"}`), 0644); err != nil {
+			return nil, fmt.Errorf("creating layout: %w", err)
+		}
+	}
+	return &Layout{dir: dir}, nil
+}
+
+// PushBlob implements Target.
+func (l *Layout) PushBlob(ctx context.Context, repository string, blob *Blob) error {
+	filename := l.blobPath(blob.Digest)
+	if _, err := os.Stat(filename); err == nil {
+		return nil
+	}
+	return os.WriteFile(filename, blob.Data, 0644)
+}
+
+// PushManifest implements Target.  A previous manifest with the same name is
+// replaced in the index.
+func (l *Layout) PushManifest(ctx context.Context, repository, tag string, manifest *Blob) error {
+	if err := l.PushBlob(ctx, repository, manifest); err != nil {
+		return err
+	}
+	index, err := l.readIndex()
+	if err != nil {
+		return err
+	}
+
+	name := repository + ":" + tag
+	desc := manifest.Descriptor
+	desc.Annotations = map[string]string{AnnotationRefName: name}
+
+	manifests := []Descriptor{}
+	for _, other := range index.Manifests {
+		if other.Annotations[AnnotationRefName] != name {
+			manifests = append(manifests, other)
+		}
+	}
+	index.Manifests = append(manifests, desc)
+	return l.writeIndex(index)
+}
+
+func (l *Layout) blobPath(digest string) string {
+	return filepath.Join(l.dir, "blobs", strings.Replace(digest, ":", string(filepath.Separator), 1))
+}
+
+func (l *Layout) readIndex() (*Index, error) {
+	index := &Index{SchemaVersion: 2, MediaType: MediaTypeImageIndex}
+	data, err := os.ReadFile(filepath.Join(l.dir, "index.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading index: %w", err)
+	}
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("unmarshaling index: %w", err)
+	}
+	return index, nil
+}
+
+func (l *Layout) writeIndex(index *Index) error {
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling index: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(l.dir, "index.json"), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("writing index: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+// Package oci writes OCI artifacts, either to an on-disk OCI image layout or
+// to a registry over the OCI distribution API.  Only what is needed to
+// publish artifacts is implemented (no pulling, no multi-arch images).
+package oci
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	// MediaTypeImageManifest is the media type of an OCI image manifest.
+	MediaTypeImageManifest = "application/vnd.oci.image.manifest.v1+json"
+	// MediaTypeImageIndex is the media type of an OCI image index.
+	MediaTypeImageIndex = "application/vnd.oci.image.index.v1+json"
+	// MediaTypeEmptyJSON is the media type of the empty config of artifacts
+	// that have no config.
+	MediaTypeEmptyJSON = "application/vnd.oci.empty.v1+json"
+	// MediaTypeImageLayerGzip is the media type of a gzipped tar layer.
+	MediaTypeImageLayerGzip = "application/vnd.oci.image.layer.v1.tar+gzip"
+)
+
+// Well-known annotation keys.
+const (
+	AnnotationTitle    = "org.opencontainers.image.title"
+	AnnotationCreated  = "org.opencontainers.image.created"
+	AnnotationSource   = "org.opencontainers.image.source"
+	AnnotationRevision = "org.opencontainers.image.revision"
+	AnnotationRefName  = "org.opencontainers.image.ref.name"
+)
+
+// Descriptor describes a blob.
+type Descriptor struct {
+	MediaType    string            `json:"mediaType"`
+	Digest       string            `json:"digest"`
+	Size         int64             `json:"size"`
+	ArtifactType string            `json:"artifactType,omitempty"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+}
+
+// Manifest is an OCI image manifest.
+type Manifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType"`
+	ArtifactType  string            `json:"artifactType,omitempty"`
+	Config        Descriptor        `json:"config"`
+	Layers        []Descriptor      `json:"layers"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+// Index is an OCI image index.
+type Index struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	MediaType     string       `json:"mediaType,omitempty"`
+	Manifests     []Descriptor `json:"manifests"`
+}
+
+// Blob is a blob and its descriptor.
+type Blob struct {
+	Descriptor
+	Data []byte
+}
+
+// NewBlob returns the blob of the given content.
+func NewBlob(mediaType string, data []byte, annotations map[string]string) *Blob {
+	return &Blob{
+		Descriptor: Descriptor{
+			MediaType:   mediaType,
+			Digest:      Digest(data),
+			Size:        int64(len(data)),
+			Annotations: annotations,
+		},
+		Data: data,
+	}
+}
+
+// Digest returns the sha256 digest of the data.
+func Digest(data []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(data))
+}
+
+// Artifact is a manifest with its config and layers.
+type Artifact struct {
+	Manifest *Manifest
+	Config   *Blob
+	Layers   []*Blob
+}
+
+// NewArtifact creates an artifact with an empty config, following the OCI
+// 1.1 guidance for artifacts.
+func NewArtifact(artifactType string, layers []*Blob, annotations map[string]string) *Artifact {
+	config := NewBlob(MediaTypeEmptyJSON, []byte("{}"), nil)
+	manifest := &Manifest{
+		SchemaVersion: 2,
+		MediaType:     MediaTypeImageManifest,
+		ArtifactType:  artifactType,
+		Config:        config.Descriptor,
+		Annotations:   annotations,
+	}
+	for _, layer := range layers {
+		manifest.Layers = append(manifest.Layers, layer.Descriptor)
+	}
+	return &Artifact{Manifest: manifest, Config: config, Layers: layers}
+}
+
+// ManifestBlob returns the encoded manifest.
+func (a *Artifact) ManifestBlob() (*Blob, error) {
+	data, err := json.Marshal(a.Manifest)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling manifest: %w", err)
+	}
+	blob := NewBlob(MediaTypeImageManifest, data, nil)
+	blob.ArtifactType = a.Manifest.ArtifactType
+	return blob, nil
+}
+
+// Target is a place artifacts are written to.
+type Target interface {
+	// PushBlob stores a blob in the repository, unless it already exists.
+	PushBlob(ctx context.Context, repository string, blob *Blob) error
+	// PushManifest stores the manifest in the repository under the given
+	// tag.
+	PushManifest(ctx context.Context, repository, tag string, manifest *Blob) error
+}
+
+// Push writes the artifact to the target, tagged in the repository.  It
+// returns the descriptor of the manifest.
+func Push(ctx context.Context, target Target, repository, tag string, artifact *Artifact) (*Descriptor, error) {
+	blobs := append([]*Blob{artifact.Config}, artifact.Layers...)
+	for _, blob := range blobs {
+		if err := target.PushBlob(ctx, repository, blob); err != nil {
+			return nil, fmt.Errorf("pushing blob %s: %w", blob.Digest, err)
+		}
+	}
+	manifest, err := artifact.ManifestBlob()
+	if err != nil {
+		return nil, err
+	}
+	if err := target.PushManifest(ctx, repository, tag, manifest); err != nil {
+		return nil, fmt.Errorf("pushing manifest %s: %w", manifest.Digest, err)
+	}
+	return &manifest.Descriptor, nil
+}
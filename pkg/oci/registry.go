@@ -0,0 +1,225 @@
+package oci
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Registry pushes to a registry over the OCI distribution API.
+type Registry struct {
+	// Host is the registry host (e.g. 'ghcr.io' or 'localhost:5000').
+	Host string
+	// Insecure uses plain http instead of https.
+	Insecure bool
+	// Username and Password are optional basic credentials, used directly
+	// or to obtain a bearer token.
+	Username string
+	Password string
+	// Client is the http client (http.DefaultClient if nil).
+	Client *http.Client
+
+	mu     sync.Mutex
+	tokens map[string]string // by scope
+}
+
+// PushBlob implements Target.
+func (r *Registry) PushBlob(ctx context.Context, repository string, blob *Blob) error {
+	scope := "repository:" + repository + ":pull,push"
+
+	resp, err := r.do(ctx, scope, http.MethodHead, r.url("/v2/%s/blobs/%s", repository, blob.Digest), "", nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	resp, err = r.do(ctx, scope, http.MethodPost, r.url("/v2/%s/blobs/uploads/", repository), "", nil)
+	if err != nil {
+		return err
+	}
+	if err := checkResponse(resp, http.StatusAccepted); err != nil {
+		return fmt.Errorf("starting upload: %w", err)
+	}
+	resp.Body.Close()
+	location, err := r.resolveLocation(resp)
+	if err != nil {
+		return err
+	}
+	query := location.Query()
+	query.Set("digest", blob.Digest)
+	location.RawQuery = query.Encode()
+
+	resp, err = r.do(ctx, scope, http.MethodPut, location.String(), "application/octet-stream", blob.Data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp, http.StatusCreated); err != nil {
+		return fmt.Errorf("uploading: %w", err)
+	}
+	return nil
+}
+
+// PushManifest implements Target.
+func (r *Registry) PushManifest(ctx context.Context, repository, tag string, manifest *Blob) error {
+	scope := "repository:" + repository + ":pull,push"
+	resp, err := r.do(ctx, scope, http.MethodPut, r.url("/v2/%s/manifests/%s", repository, tag), manifest.MediaType, manifest.Data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return checkResponse(resp, http.StatusCreated)
+}
+
+func (r *Registry) url(format string, args ...interface{}) string {
+	scheme := "https"
+	if r.Insecure {
+		scheme = "http"
+	}
+	return scheme + "://" + r.Host + fmt.Sprintf(format, args...)
+}
+
+// resolveLocation returns the absolute upload location of the response.
+func (r *Registry) resolveLocation(resp *http.Response) (*url.URL, error) {
+	location := resp.Header.Get("Location")
+	if location == "" {
+		return nil, fmt.Errorf("registry did not return an upload location")
+	}
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid upload location %q: %w", location, err)
+	}
+	return resp.Request.URL.ResolveReference(u), nil
+}
+
+// do sends the request, authenticating as required by the registry.
+func (r *Registry) do(ctx context.Context, scope, method, rawurl, contentType string, body []byte) (*http.Response, error) {
+	send := func() (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, method, rawurl, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		req.ContentLength = int64(len(body))
+		if token := r.token(scope); token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		} else if r.Username != "" {
+			req.SetBasicAuth(r.Username, r.Password)
+		}
+		return r.client().Do(req)
+	}
+
+	resp, err := send()
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	challenge := resp.Header.Get("WWW-Authenticate")
+	if !strings.HasPrefix(strings.ToLower(challenge), "bearer ") {
+		return nil, fmt.Errorf("%s %s: unauthorized (challenge %q)", method, rawurl, challenge)
+	}
+	if err := r.fetchToken(ctx, scope, parseChallenge(challenge[len("bearer "):])); err != nil {
+		return nil, err
+	}
+	return send()
+}
+
+func (r *Registry) client() *http.Client {
+	if r.Client != nil {
+		return r.Client
+	}
+	return http.DefaultClient
+}
+
+func (r *Registry) token(scope string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.tokens[scope]
+}
+
+// fetchToken obtains a bearer token from the realm of the challenge.
+func (r *Registry) fetchToken(ctx context.Context, scope string, params map[string]string) error {
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return fmt.Errorf("invalid bearer challenge realm %q", params["realm"])
+	}
+	query := realm.Query()
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	query.Set("scope", scope)
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return err
+	}
+	if r.Username != "" {
+		req.SetBasicAuth(r.Username, r.Password)
+	}
+	resp, err := r.client().Do(req)
+	if err != nil {
+		return fmt.Errorf("fetching token: %w", err)
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return fmt.Errorf("fetching token: %w", err)
+	}
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("decoding token: %w", err)
+	}
+	token := body.Token
+	if token == "" {
+		token = body.AccessToken
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.tokens == nil {
+		r.tokens = make(map[string]string)
+	}
+	r.tokens[scope] = token
+	return nil
+}
+
+// parseChallenge parses the 'key="value", ...' parameters of a
+// WWW-Authenticate challenge.
+func parseChallenge(s string) map[string]string {
+	params := make(map[string]string)
+	for _, part := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if ok {
+			params[strings.ToLower(key)] = strings.Trim(value, `"`)
+		}
+	}
+	return params
+}
+
+// checkResponse returns an error if the response does not have the expected
+// status, including the error message of the registry.
+func checkResponse(resp *http.Response, want int) error {
+	if resp.StatusCode == want {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	return fmt.Errorf("%s %s: %s: %s", resp.Request.Method, resp.Request.URL.Redacted(), resp.Status, strings.TrimSpace(string(body)))
+}
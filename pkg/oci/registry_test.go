@@ -0,0 +1,227 @@
+package oci
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeRegistry implements the blob and manifest push endpoints of the OCI
+// distribution API.  If token is set, the registry requires it as a bearer
+// token, issued by its /token endpoint to the given user.
+type fakeRegistry struct {
+	token    string
+	user     string
+	password string
+
+	mu        sync.Mutex
+	server    *httptest.Server
+	blobs     map[string][]byte // by repository and digest
+	manifests map[string]*Blob  // by repository and tag
+	uploads   int
+	// scopes are the scopes that tokens were requested for.
+	scopes []string
+}
+
+func newFakeRegistry(t *testing.T, token, user, password string) *fakeRegistry {
+	r := &fakeRegistry{
+		token:     token,
+		user:      user,
+		password:  password,
+		blobs:     make(map[string][]byte),
+		manifests: make(map[string]*Blob),
+	}
+	r.server = httptest.NewServer(r)
+	t.Cleanup(r.server.Close)
+	return r
+}
+
+// registry returns a client of the fake registry.
+func (r *fakeRegistry) registry() *Registry {
+	return &Registry{
+		Host:     strings.TrimPrefix(r.server.URL, "http://"),
+		Insecure: true,
+		Client:   r.server.Client(),
+	}
+}
+
+func (r *fakeRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if req.URL.Path == "/token" {
+		r.serveToken(w, req)
+		return
+	}
+	if r.token != "" && req.Header.Get("Authorization") != "Bearer "+r.token {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="fake"`, r.server.URL))
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	path := strings.TrimPrefix(req.URL.Path, "/v2/")
+	switch {
+	case req.Method == http.MethodPost && strings.HasSuffix(path, "/blobs/uploads/"):
+		r.uploads++
+		repo := strings.TrimSuffix(path, "/blobs/uploads/")
+		w.Header().Set("Location", fmt.Sprintf("/v2/%s/blobs/uploads/%d", repo, r.uploads))
+		w.WriteHeader(http.StatusAccepted)
+	case req.Method == http.MethodPut && strings.Contains(path, "/blobs/uploads/"):
+		repo, _, _ := strings.Cut(path, "/blobs/uploads/")
+		data, err := io.ReadAll(req.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		digest := req.URL.Query().Get("digest")
+		if Digest(data) != digest {
+			http.Error(w, "digest mismatch", http.StatusBadRequest)
+			return
+		}
+		r.blobs[repo+"@"+digest] = data
+		w.WriteHeader(http.StatusCreated)
+	case req.Method == http.MethodHead && strings.Contains(path, "/blobs/"):
+		repo, digest, _ := strings.Cut(path, "/blobs/")
+		if _, ok := r.blobs[repo+"@"+digest]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	case req.Method == http.MethodPut && strings.Contains(path, "/manifests/"):
+		repo, tag, _ := strings.Cut(path, "/manifests/")
+		data, err := io.ReadAll(req.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var manifest Manifest
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, desc := range append([]Descriptor{manifest.Config}, manifest.Layers...) {
+			if _, ok := r.blobs[repo+"@"+desc.Digest]; !ok {
+				http.Error(w, "blob unknown: "+desc.Digest, http.StatusBadRequest)
+				return
+			}
+		}
+		r.manifests[repo+":"+tag] = NewBlob(req.Header.Get("Content-Type"), data, nil)
+		w.WriteHeader(http.StatusCreated)
+	default:
+		http.Error(w, "unsupported", http.StatusMethodNotAllowed)
+	}
+}
+
+func (r *fakeRegistry) serveToken(w http.ResponseWriter, req *http.Request) {
+	user, password, ok := req.BasicAuth()
+	if !ok || user != r.user || password != r.password {
+		http.Error(w, "bad credentials", http.StatusUnauthorized)
+		return
+	}
+	if req.URL.Query().Get("service") != "fake" {
+		http.Error(w, "unknown service", http.StatusBadRequest)
+		return
+	}
+	r.scopes = append(r.scopes, req.URL.Query().Get("scope"))
+	json.NewEncoder(w).Encode(map[string]string{"access_token": r.token})
+}
+
+func testArtifact() *Artifact {
+	layer := NewBlob(MediaTypeImageLayerGzip, []byte("layer"), map[string]string{AnnotationTitle: "layer.tar.gz"})
+	return NewArtifact("application/vnd.protopkg.test", []*Blob{layer}, nil)
+}
+
+func TestRegistryPush(t *testing.T) {
+	fake := newFakeRegistry(t, "", "", "")
+	registry := fake.registry()
+	artifact := testArtifact()
+
+	desc, err := Push(context.Background(), registry, "protos/google", "v1", artifact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest, ok := fake.manifests["protos/google:v1"]
+	if !ok {
+		t.Fatal("manifest was not pushed")
+	}
+	if manifest.MediaType != MediaTypeImageManifest {
+		t.Errorf("manifest media type: got %q, want %q", manifest.MediaType, MediaTypeImageManifest)
+	}
+	if manifest.Digest != desc.Digest {
+		t.Errorf("manifest digest: got %s, want %s", manifest.Digest, desc.Digest)
+	}
+	if fake.uploads != 2 {
+		t.Errorf("got %d blob uploads, want 2 (config and layer)", fake.uploads)
+	}
+
+	// the blobs exist: pushing again only replaces the manifest
+	if _, err := Push(context.Background(), registry, "protos/google", "v2", artifact); err != nil {
+		t.Fatal(err)
+	}
+	if fake.uploads != 2 {
+		t.Errorf("got %d blob uploads after the second push, want 2", fake.uploads)
+	}
+	if _, ok := fake.manifests["protos/google:v2"]; !ok {
+		t.Error("second manifest was not pushed")
+	}
+}
+
+func TestRegistryPushBearer(t *testing.T) {
+	fake := newFakeRegistry(t, "s3cret", "user", "password")
+	registry := fake.registry()
+	registry.Username = "user"
+	registry.Password = "password"
+
+	if _, err := Push(context.Background(), registry, "protos/google", "v1", testArtifact()); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fake.manifests["protos/google:v1"]; !ok {
+		t.Fatal("manifest was not pushed")
+	}
+	// the token is requested once, and reused for the following requests
+	want := []string{"repository:protos/google:pull,push"}
+	if strings.Join(fake.scopes, " ") != strings.Join(want, " ") {
+		t.Errorf("token scopes: got %q, want %q", fake.scopes, want)
+	}
+}
+
+func TestRegistryPushBearerBadCredentials(t *testing.T) {
+	fake := newFakeRegistry(t, "s3cret", "user", "password")
+	registry := fake.registry()
+	registry.Username = "user"
+	registry.Password = "wrong"
+
+	_, err := Push(context.Background(), registry, "protos/google", "v1", testArtifact())
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if !strings.Contains(err.Error(), "fetching token") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(fake.manifests) != 0 {
+		t.Error("manifest was pushed")
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	got := parseChallenge(`realm="https://auth.example.com/token", service="registry.example.com",scope="repository:a/b:pull"`)
+	want := map[string]string{
+		"realm":   "https://auth.example.com/token",
+		"service": "registry.example.com",
+		"scope":   "repository:a/b:pull",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s: got %q, want %q", k, got[k], v)
+		}
+	}
+}
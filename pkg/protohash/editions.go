@@ -0,0 +1,397 @@
+package protohash
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// EditionDefaults returns the fully resolved FeatureSet defaults for the given
+// edition.  The defaults are read from the edition_defaults options of the
+// FeatureSet fields, as declared by the descriptor.proto that ships with
+// google.golang.org/protobuf.  Editions outside of the range supported by
+// protodesc are an error.
+func EditionDefaults(edition descriptorpb.Edition) (*descriptorpb.FeatureSet, error) {
+	if edition < protodesc.SupportedEditionsMinimum || edition > protodesc.SupportedEditionsMaximum {
+		return nil, fmt.Errorf("unsupported edition: %v", edition)
+	}
+	defaults := &descriptorpb.FeatureSet{}
+	msg := defaults.ProtoReflect()
+	fields := msg.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		opts, ok := fd.Options().(*descriptorpb.FieldOptions)
+		if !ok {
+			continue
+		}
+		// the defaults apply from their edition onwards: the one in effect
+		// is the most recent one that is not after the edition.
+		var value *descriptorpb.FieldOptions_EditionDefault
+		for _, d := range opts.GetEditionDefaults() {
+			if d.GetEdition() <= edition && (value == nil || d.GetEdition() > value.GetEdition()) {
+				value = d
+			}
+		}
+		if value == nil {
+			continue
+		}
+		if fd.Kind() != protoreflect.EnumKind {
+			return nil, fmt.Errorf("feature %s: unsupported default of kind %v", fd.Name(), fd.Kind())
+		}
+		ev := fd.Enum().Values().ByName(protoreflect.Name(value.GetValue()))
+		if ev == nil {
+			return nil, fmt.Errorf("feature %s: unknown default %q", fd.Name(), value.GetValue())
+		}
+		msg.Set(fd, protoreflect.ValueOfEnum(ev.Number()))
+	}
+	return defaults, nil
+}
+
+// fileEdition returns the edition of the file, mapping the proto2 and proto3
+// syntax onto their equivalent pseudo-editions.
+func fileEdition(f *descriptorpb.FileDescriptorProto) descriptorpb.Edition {
+	switch f.GetSyntax() {
+	case "proto3":
+		return descriptorpb.Edition_EDITION_PROTO3
+	case "editions":
+		return f.GetEdition()
+	default:
+		return descriptorpb.Edition_EDITION_PROTO2
+	}
+}
+
+// mergeFeatures returns a new FeatureSet where the features of the child
+// override those of the (resolved) parent.
+func mergeFeatures(parent, child *descriptorpb.FeatureSet) *descriptorpb.FeatureSet {
+	resolved := proto.Clone(parent).(*descriptorpb.FeatureSet)
+	if child != nil {
+		proto.Merge(resolved, child)
+	}
+	return resolved
+}
+
+// pruneFeatures resolves the features of an element against its parent and
+// clears the overrides that are redundant with the inherited value.  If no
+// overrides remain the FeatureSet is removed.  The resolved features are
+// returned.
+func pruneFeatures(parent *descriptorpb.FeatureSet, features **descriptorpb.FeatureSet) *descriptorpb.FeatureSet {
+	if *features == nil {
+		return parent
+	}
+	resolved := mergeFeatures(parent, *features)
+
+	msg := (*features).ProtoReflect()
+	inherited := parent.ProtoReflect()
+	msg.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if inherited.Has(fd) && inherited.Get(fd).Equal(v) {
+			msg.Clear(fd)
+		}
+		return true
+	})
+	if proto.Size(*features) == 0 {
+		*features = nil
+	}
+
+	return resolved
+}
+
+// NormalizeFeatures removes explicit feature overrides that restate the value
+// already in effect, such that a redundant 'option features.x = DEFAULT;' does
+// not change the hash of the file.
+func NormalizeFeatures(f *descriptorpb.FileDescriptorProto) error {
+	defaults, err := EditionDefaults(fileEdition(f))
+	if err != nil {
+		return fmt.Errorf("%s: %w", f.GetName(), err)
+	}
+	resolved := defaults
+	if f.Options != nil {
+		resolved = pruneFeatures(defaults, &f.Options.Features)
+		f.Options = emptyToNil(f.Options)
+	}
+	for _, m := range f.MessageType {
+		normalizeMessageFeatures(resolved, m)
+	}
+	for _, e := range f.EnumType {
+		normalizeEnumFeatures(resolved, e)
+	}
+	for _, e := range f.Extension {
+		normalizeFieldFeatures(resolved, e)
+	}
+	for _, s := range f.Service {
+		features := resolved
+		if s.Options != nil {
+			features = pruneFeatures(resolved, &s.Options.Features)
+			s.Options = emptyToNil(s.Options)
+		}
+		for _, m := range s.Method {
+			if m.Options != nil {
+				pruneFeatures(features, &m.Options.Features)
+				m.Options = emptyToNil(m.Options)
+			}
+		}
+	}
+	return nil
+}
+
+func normalizeMessageFeatures(parent *descriptorpb.FeatureSet, m *descriptorpb.DescriptorProto) {
+	resolved := parent
+	if m.Options != nil {
+		resolved = pruneFeatures(parent, &m.Options.Features)
+		m.Options = emptyToNil(m.Options)
+	}
+	oneofs := make([]*descriptorpb.FeatureSet, len(m.OneofDecl))
+	for i, o := range m.OneofDecl {
+		oneofs[i] = resolved
+		if o.Options != nil {
+			oneofs[i] = pruneFeatures(resolved, &o.Options.Features)
+			o.Options = emptyToNil(o.Options)
+		}
+	}
+	for _, f := range m.Field {
+		if f.OneofIndex != nil && int(*f.OneofIndex) < len(oneofs) {
+			normalizeFieldFeatures(oneofs[*f.OneofIndex], f)
+		} else {
+			normalizeFieldFeatures(resolved, f)
+		}
+	}
+	for _, e := range m.Extension {
+		normalizeFieldFeatures(resolved, e)
+	}
+	for _, r := range m.ExtensionRange {
+		if r.Options != nil {
+			pruneFeatures(resolved, &r.Options.Features)
+			r.Options = emptyToNil(r.Options)
+		}
+	}
+	for _, n := range m.NestedType {
+		normalizeMessageFeatures(resolved, n)
+	}
+	for _, e := range m.EnumType {
+		normalizeEnumFeatures(resolved, e)
+	}
+}
+
+func normalizeEnumFeatures(parent *descriptorpb.FeatureSet, e *descriptorpb.EnumDescriptorProto) {
+	resolved := parent
+	if e.Options != nil {
+		resolved = pruneFeatures(parent, &e.Options.Features)
+		e.Options = emptyToNil(e.Options)
+	}
+	for _, v := range e.Value {
+		if v.Options != nil {
+			pruneFeatures(resolved, &v.Options.Features)
+			v.Options = emptyToNil(v.Options)
+		}
+	}
+}
+
+func normalizeFieldFeatures(parent *descriptorpb.FeatureSet, f *descriptorpb.FieldDescriptorProto) {
+	if f.Options != nil {
+		pruneFeatures(parent, &f.Options.Features)
+		f.Options = emptyToNil(f.Options)
+	}
+}
+
+// WireFile returns a copy of the file projected onto the properties that
+// determine its wire compatibility.  The syntax and edition are removed and the
+// features in effect are made explicit on the fields and enums that they
+// apply to, such that a proto2 or proto3 file and its equivalent editions file
+// have the same projection.  Source code info is removed.
+func WireFile(f *descriptorpb.FileDescriptorProto) (*descriptorpb.FileDescriptorProto, error) {
+	defaults, err := EditionDefaults(fileEdition(f))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", f.GetName(), err)
+	}
+	wire := proto.Clone(f).(*descriptorpb.FileDescriptorProto)
+	wire.Syntax = nil
+	wire.Edition = nil
+	wire.SourceCodeInfo = nil
+
+	resolved := defaults
+	if wire.Options != nil {
+		resolved = mergeFeatures(defaults, wire.Options.Features)
+		wire.Options.Features = nil
+		wire.Options = emptyToNil(wire.Options)
+	}
+	for _, m := range wire.MessageType {
+		wireCompatMessage(resolved, m)
+	}
+	for _, e := range wire.EnumType {
+		wireCompatEnum(resolved, e)
+	}
+	for _, e := range wire.Extension {
+		wireCompatField(resolved, e, true)
+	}
+	for _, s := range wire.Service {
+		if s.Options != nil {
+			s.Options.Features = nil
+			s.Options = emptyToNil(s.Options)
+		}
+		for _, m := range s.Method {
+			if m.Options != nil {
+				m.Options.Features = nil
+				m.Options = emptyToNil(m.Options)
+			}
+		}
+	}
+
+	return wire, nil
+}
+
+func wireCompatMessage(parent *descriptorpb.FeatureSet, m *descriptorpb.DescriptorProto) {
+	resolved := parent
+	if m.Options != nil {
+		resolved = mergeFeatures(parent, m.Options.Features)
+		m.Options.Features = nil
+		m.Options = emptyToNil(m.Options)
+	}
+
+	// proto3 optional fields are represented by a synthetic oneof, which does
+	// not exist in editions (the field has explicit presence instead).  The
+	// synthetic oneofs always follow the real ones.
+	oneofs := make([]*descriptorpb.FeatureSet, len(m.OneofDecl))
+	var synthetic int
+	for i, o := range m.OneofDecl {
+		oneofs[i] = resolved
+		if o.Options != nil {
+			oneofs[i] = mergeFeatures(resolved, o.Options.Features)
+			o.Options.Features = nil
+			o.Options = emptyToNil(o.Options)
+		}
+	}
+	for _, f := range m.Field {
+		if f.GetProto3Optional() {
+			synthetic++
+			f.Proto3Optional = nil
+			f.OneofIndex = nil
+			if f.Options == nil {
+				f.Options = &descriptorpb.FieldOptions{}
+			}
+			f.Options.Features = mergeFeatures(resolved, f.Options.Features)
+			f.Options.Features.FieldPresence = descriptorpb.FeatureSet_EXPLICIT.Enum()
+			wireCompatField(f.Options.Features, f, false)
+			continue
+		}
+		if f.OneofIndex != nil && int(*f.OneofIndex) < len(oneofs) {
+			wireCompatField(oneofs[*f.OneofIndex], f, true)
+		} else {
+			wireCompatField(resolved, f, false)
+		}
+	}
+	if synthetic > 0 && synthetic <= len(m.OneofDecl) {
+		m.OneofDecl = m.OneofDecl[:len(m.OneofDecl)-synthetic]
+	}
+
+	for _, e := range m.Extension {
+		wireCompatField(resolved, e, true)
+	}
+	for _, r := range m.ExtensionRange {
+		if r.Options != nil {
+			r.Options.Features = nil
+			r.Options = emptyToNil(r.Options)
+		}
+	}
+	for _, n := range m.NestedType {
+		wireCompatMessage(resolved, n)
+	}
+	for _, e := range m.EnumType {
+		wireCompatEnum(resolved, e)
+	}
+}
+
+func wireCompatEnum(parent *descriptorpb.FeatureSet, e *descriptorpb.EnumDescriptorProto) {
+	resolved := parent
+	if e.Options != nil {
+		resolved = mergeFeatures(parent, e.Options.Features)
+	} else {
+		e.Options = &descriptorpb.EnumOptions{}
+	}
+	e.Options.Features = &descriptorpb.FeatureSet{
+		EnumType: resolved.EnumType,
+	}
+	for _, v := range e.Value {
+		if v.Options != nil {
+			v.Options.Features = nil
+			v.Options = emptyToNil(v.Options)
+		}
+	}
+}
+
+// wireCompatField replaces the legacy proto2/proto3 representation of field
+// properties (required label, group type, packed option) by their feature
+// equivalent, and records the resolved features that are relevant for the
+// field.  Fields that always have explicit presence (oneof members and
+// extensions) do not record their presence.
+func wireCompatField(parent *descriptorpb.FeatureSet, f *descriptorpb.FieldDescriptorProto, alwaysExplicit bool) {
+	var resolved *descriptorpb.FeatureSet
+	if f.Options != nil {
+		resolved = mergeFeatures(parent, f.Options.Features)
+	} else {
+		resolved = mergeFeatures(parent, nil)
+		f.Options = &descriptorpb.FieldOptions{}
+	}
+
+	if f.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_REQUIRED {
+		f.Label = descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+		resolved.FieldPresence = descriptorpb.FeatureSet_LEGACY_REQUIRED.Enum()
+	}
+	if f.GetType() == descriptorpb.FieldDescriptorProto_TYPE_GROUP {
+		f.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+		resolved.MessageEncoding = descriptorpb.FeatureSet_DELIMITED.Enum()
+	}
+	if f.Options.Packed != nil {
+		if f.Options.GetPacked() {
+			resolved.RepeatedFieldEncoding = descriptorpb.FeatureSet_PACKED.Enum()
+		} else {
+			resolved.RepeatedFieldEncoding = descriptorpb.FeatureSet_EXPANDED.Enum()
+		}
+		f.Options.Packed = nil
+	}
+
+	repeated := f.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+	message := f.GetType() == descriptorpb.FieldDescriptorProto_TYPE_MESSAGE
+
+	features := &descriptorpb.FeatureSet{}
+	switch {
+	case resolved.GetFieldPresence() == descriptorpb.FeatureSet_LEGACY_REQUIRED:
+		features.FieldPresence = resolved.FieldPresence
+	case !repeated && !message && !alwaysExplicit:
+		features.FieldPresence = resolved.FieldPresence
+	}
+	if repeated && isPackableType(f.GetType()) {
+		features.RepeatedFieldEncoding = resolved.RepeatedFieldEncoding
+	}
+	if f.GetType() == descriptorpb.FieldDescriptorProto_TYPE_STRING {
+		features.Utf8Validation = resolved.Utf8Validation
+	}
+	if message {
+		features.MessageEncoding = resolved.MessageEncoding
+	}
+	f.Options.Features = emptyToNil(features)
+	f.Options = emptyToNil(f.Options)
+}
+
+// isPackableType reports whether repeated fields of the given type may use the
+// packed encoding.
+func isPackableType(t descriptorpb.FieldDescriptorProto_Type) bool {
+	switch t {
+	case descriptorpb.FieldDescriptorProto_TYPE_STRING,
+		descriptorpb.FieldDescriptorProto_TYPE_BYTES,
+		descriptorpb.FieldDescriptorProto_TYPE_MESSAGE,
+		descriptorpb.FieldDescriptorProto_TYPE_GROUP:
+		return false
+	}
+	return true
+}
+
+// emptyToNil returns nil if the message has no fields set.
+func emptyToNil[T proto.Message](msg T) T {
+	if proto.Size(msg) == 0 {
+		var zero T
+		return zero
+	}
+	return msg
+}
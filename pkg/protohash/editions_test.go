@@ -0,0 +1,144 @@
+package protohash
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestEditionDefaults(t *testing.T) {
+	for _, tc := range []struct {
+		edition descriptorpb.Edition
+		want    *descriptorpb.FeatureSet
+	}{
+		{
+			edition: descriptorpb.Edition_EDITION_PROTO2,
+			want: &descriptorpb.FeatureSet{
+				FieldPresence:         descriptorpb.FeatureSet_EXPLICIT.Enum(),
+				EnumType:              descriptorpb.FeatureSet_CLOSED.Enum(),
+				RepeatedFieldEncoding: descriptorpb.FeatureSet_EXPANDED.Enum(),
+				Utf8Validation:        descriptorpb.FeatureSet_NONE.Enum(),
+				MessageEncoding:       descriptorpb.FeatureSet_LENGTH_PREFIXED.Enum(),
+				JsonFormat:            descriptorpb.FeatureSet_LEGACY_BEST_EFFORT.Enum(),
+			},
+		},
+		{
+			edition: descriptorpb.Edition_EDITION_PROTO3,
+			want: &descriptorpb.FeatureSet{
+				FieldPresence:         descriptorpb.FeatureSet_IMPLICIT.Enum(),
+				EnumType:              descriptorpb.FeatureSet_OPEN.Enum(),
+				RepeatedFieldEncoding: descriptorpb.FeatureSet_PACKED.Enum(),
+				Utf8Validation:        descriptorpb.FeatureSet_VERIFY.Enum(),
+				MessageEncoding:       descriptorpb.FeatureSet_LENGTH_PREFIXED.Enum(),
+				JsonFormat:            descriptorpb.FeatureSet_ALLOW.Enum(),
+			},
+		},
+		{
+			edition: descriptorpb.Edition_EDITION_2023,
+			want: &descriptorpb.FeatureSet{
+				FieldPresence:         descriptorpb.FeatureSet_EXPLICIT.Enum(),
+				EnumType:              descriptorpb.FeatureSet_OPEN.Enum(),
+				RepeatedFieldEncoding: descriptorpb.FeatureSet_PACKED.Enum(),
+				Utf8Validation:        descriptorpb.FeatureSet_VERIFY.Enum(),
+				MessageEncoding:       descriptorpb.FeatureSet_LENGTH_PREFIXED.Enum(),
+				JsonFormat:            descriptorpb.FeatureSet_ALLOW.Enum(),
+			},
+		},
+	} {
+		t.Run(tc.edition.String(), func(t *testing.T) {
+			got, err := EditionDefaults(tc.edition)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !proto.Equal(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	if _, err := EditionDefaults(descriptorpb.Edition_EDITION_99999_TEST_ONLY); err == nil {
+		t.Error("expected an error for an unsupported edition")
+	}
+}
+
+// testFile returns a file with a single message M, which has an int32 field
+// a, a repeated int32 field b and a string field s.
+func testFile(syntax string, edition descriptorpb.Edition, fileFeatures *descriptorpb.FeatureSet, fieldOptions ...*descriptorpb.FieldOptions) *descriptorpb.FileDescriptorProto {
+	label := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+	repeated := descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	f := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("test.proto"),
+		Package: proto.String("test"),
+		Syntax:  proto.String(syntax),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("M"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("a"), Number: proto.Int32(1), Label: label, Type: descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum(), JsonName: proto.String("a")},
+				{Name: proto.String("b"), Number: proto.Int32(2), Label: repeated, Type: descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum(), JsonName: proto.String("b")},
+				{Name: proto.String("s"), Number: proto.Int32(3), Label: label, Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(), JsonName: proto.String("s")},
+			},
+		}},
+	}
+	if syntax == "editions" {
+		f.Edition = edition.Enum()
+	}
+	if fileFeatures != nil {
+		f.Options = &descriptorpb.FileOptions{Features: fileFeatures}
+	}
+	for i, opts := range fieldOptions {
+		f.MessageType[0].Field[i].Options = opts
+	}
+	return f
+}
+
+func TestWireCompatFile(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		file       *descriptorpb.FileDescriptorProto
+		equivalent *descriptorpb.FileDescriptorProto
+	}{
+		{
+			name: "proto2",
+			file: testFile("proto2", 0, nil,
+				nil,
+				&descriptorpb.FieldOptions{Packed: proto.Bool(true)},
+			),
+			equivalent: testFile("editions", descriptorpb.Edition_EDITION_2023, nil,
+				nil,
+				nil,
+				&descriptorpb.FieldOptions{Features: &descriptorpb.FeatureSet{Utf8Validation: descriptorpb.FeatureSet_NONE.Enum()}},
+			),
+		},
+		{
+			name: "proto3",
+			file: testFile("proto3", 0, nil),
+			equivalent: testFile("editions", descriptorpb.Edition_EDITION_2023,
+				&descriptorpb.FeatureSet{FieldPresence: descriptorpb.FeatureSet_IMPLICIT.Enum()},
+			),
+		},
+		{
+			name: "2023",
+			file: testFile("editions", descriptorpb.Edition_EDITION_2023, nil),
+			equivalent: testFile("proto2", 0, nil,
+				nil,
+				&descriptorpb.FieldOptions{Packed: proto.Bool(true)},
+				&descriptorpb.FieldOptions{Features: &descriptorpb.FeatureSet{Utf8Validation: descriptorpb.FeatureSet_VERIFY.Enum()}},
+			),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := WireFile(tc.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := WireFile(tc.equivalent)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !proto.Equal(got, want) {
+				t.Errorf("wire projections differ:\n%v\n%v", got, want)
+			}
+		})
+	}
+}
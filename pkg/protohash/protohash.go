@@ -0,0 +1,133 @@
+// Package protohash calculates the hashes of proto packages.
+package protohash
+
+import (
+	"fmt"
+	"strings"
+
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	"github.com/stackb/protoreflecthash"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// WirePrefix is the prefix of the hashes of the wire-compatible projection
+// of a file (see 'protopkg file -hash_mode=wire'), and of the packages made
+// of such files.
+const WirePrefix = "wire."
+
+// Package calculates the hash of the package files, the same way as
+// 'protopkg package' does.  Source code, source info and the file size and
+// sha256 do not contribute to the hash.
+//
+// If every file has a wire hash, the package hash is a wire hash of the file
+// names, hashes and dependencies only: the descriptors still record the
+// syntax and edition of the files, which must not distinguish a proto3
+// package from its equivalent editions package.
+func Package(files []*pppb.ProtoFile) (string, error) {
+	if isWire(files) {
+		return wirePackage(files)
+	}
+	stripped := make([]*pppb.ProtoFile, len(files))
+	for i, file := range files {
+		strip := proto.Clone(file).(*pppb.ProtoFile)
+		strip.SourceCode = ""
+		strip.FileSha256 = ""
+		strip.FileSize = 0
+		if strip.File != nil {
+			strip.File.SourceCodeInfo = nil
+		}
+		stripped[i] = strip
+	}
+	return Message(&pppb.ProtoPackage{
+		Files: stripped,
+	})
+}
+
+// isWire reports whether the files all have a wire hash.
+func isWire(files []*pppb.ProtoFile) bool {
+	for _, file := range files {
+		if !strings.HasPrefix(file.Hash, WirePrefix) {
+			return false
+		}
+	}
+	return len(files) > 0
+}
+
+func wirePackage(files []*pppb.ProtoFile) (string, error) {
+	projected := make([]*pppb.ProtoFile, len(files))
+	for i, file := range files {
+		projected[i] = &pppb.ProtoFile{
+			File:         &descriptorpb.FileDescriptorProto{Name: file.GetFile().Name},
+			Hash:         file.Hash,
+			Dependencies: file.Dependencies,
+		}
+	}
+	hash, err := Message(&pppb.ProtoPackage{
+		Files: projected,
+	})
+	if err != nil {
+		return "", err
+	}
+	return WirePrefix + hash, nil
+}
+
+// File calculates the hash of the file descriptor, the same way as 'protopkg
+// file' does: the object hash of the descriptor, or with wire, the wire hash
+// of its wire-compatible projection (see WireFile).
+func File(desc *descriptorpb.FileDescriptorProto, wire bool) (string, error) {
+	if !wire {
+		return Message(desc)
+	}
+	projected, err := WireFile(desc)
+	if err != nil {
+		return "", err
+	}
+	hash, err := Message(projected)
+	if err != nil {
+		return "", err
+	}
+	return WirePrefix + hash, nil
+}
+
+// Verify checks the hashes of the package: the descriptor of each file must
+// hash to the file hash, recalculated in the mode of that hash (object or
+// wire), and the files must hash to the package hash.  A wire package hash
+// does not cover the descriptors, so its files must be verified to bind the
+// package hash to their content.
+func Verify(pkg *pppb.ProtoPackage) error {
+	for i, file := range pkg.Files {
+		name := file.GetFile().GetName()
+		if name == "" {
+			name = fmt.Sprintf("file #%d", i)
+		}
+		if file.Hash == "" {
+			return fmt.Errorf("%s: no hash", name)
+		}
+		hash, err := File(file.File, strings.HasPrefix(file.Hash, WirePrefix))
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if hash != file.Hash {
+			return fmt.Errorf("%s: hash mismatch: file has %s, descriptor hashes to %s", name, file.Hash, hash)
+		}
+	}
+	hash, err := Package(pkg.Files)
+	if err != nil {
+		return err
+	}
+	if hash != pkg.Hash {
+		return fmt.Errorf("hash mismatch: package has %s, files hash to %s", pkg.Hash, hash)
+	}
+	return nil
+}
+
+// Message calculates the object hash of a message.
+func Message(msg proto.Message) (string, error) {
+	hasher := protoreflecthash.NewHasher()
+	data, err := hasher.HashProto(msg.ProtoReflect())
+	if err != nil {
+		return "", fmt.Errorf("hashing proto: %w", err)
+	}
+	return fmt.Sprintf("protoreflecthash.v0:%x", data), nil
+}
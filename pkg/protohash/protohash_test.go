@@ -0,0 +1,117 @@
+package protohash
+
+import (
+	"testing"
+
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// testPackage returns a package of the test file, with its file and package
+// hashes in the given mode.
+func testPackage(t *testing.T, wire bool) *pppb.ProtoPackage {
+	t.Helper()
+	desc := testFile("proto3", 0, nil)
+	hash, err := File(desc, wire)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := []*pppb.ProtoFile{{File: desc, Hash: hash, SourceCode: "syntax = \"proto3\";"}}
+	pkgHash, err := Package(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &pppb.ProtoPackage{Name: "test", Files: files, Hash: pkgHash}
+}
+
+func TestVerify(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		wire    bool
+		tamper  func(pkg *pppb.ProtoPackage)
+		wantErr bool
+	}{
+		{name: "object"},
+		{name: "wire", wire: true},
+		{
+			name: "source code",
+			wire: true,
+			tamper: func(pkg *pppb.ProtoPackage) {
+				pkg.Files[0].SourceCode = "// not hashed"
+			},
+		},
+		{
+			name: "object descriptor",
+			tamper: func(pkg *pppb.ProtoPackage) {
+				pkg.Files[0].File.MessageType[0].Name = proto.String("Other")
+			},
+			wantErr: true,
+		},
+		{
+			// the wire package hash does not cover the descriptor: only the
+			// file hash does
+			name: "wire descriptor",
+			wire: true,
+			tamper: func(pkg *pppb.ProtoPackage) {
+				pkg.Files[0].File.MessageType[0].Name = proto.String("Other")
+			},
+			wantErr: true,
+		},
+		{
+			name: "wire file hash",
+			wire: true,
+			tamper: func(pkg *pppb.ProtoPackage) {
+				pkg.Files[0].Hash = WirePrefix + "protoreflecthash.v0:00"
+			},
+			wantErr: true,
+		},
+		{
+			name: "no file hash",
+			tamper: func(pkg *pppb.ProtoPackage) {
+				pkg.Files[0].Hash = ""
+			},
+			wantErr: true,
+		},
+		{
+			name: "package hash",
+			tamper: func(pkg *pppb.ProtoPackage) {
+				pkg.Hash = "protoreflecthash.v0:00"
+			},
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			pkg := testPackage(t, tc.wire)
+			if tc.tamper != nil {
+				tc.tamper(pkg)
+			}
+			if err := Verify(pkg); (err != nil) != tc.wantErr {
+				t.Errorf("got error %v, want error: %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestWireFileHash(t *testing.T) {
+	proto3, err := File(testFile("proto3", 0, nil), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	editions, err := File(testFile("editions", descriptorpb.Edition_EDITION_2023,
+		&descriptorpb.FeatureSet{FieldPresence: descriptorpb.FeatureSet_IMPLICIT.Enum()},
+	), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proto3 != editions {
+		t.Errorf("proto3 file hashes to %s, editions file to %s", proto3, editions)
+	}
+	object, err := File(testFile("proto3", 0, nil), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if object == proto3 {
+		t.Errorf("object and wire hashes are both %s", object)
+	}
+}
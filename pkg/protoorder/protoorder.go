@@ -0,0 +1,371 @@
+// Package protoorder restores the declaration order of the files of proto
+// packages, which 'protopkg file' sorts canonically before hashing.
+package protoorder
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// The field numbers of the repeated fields that 'protopkg file' sorts, as
+// used in the paths of the source code info.
+const (
+	fileDependencyField    = 3
+	fileMessageTypeField   = 4
+	fileEnumTypeField      = 5
+	fileServiceField       = 6
+	fileExtensionField     = 7
+	messageFieldField      = 2
+	messageNestedTypeField = 3
+	messageEnumTypeField   = 4
+	messageExtRangeField   = 5
+	messageExtensionField  = 6
+	messageResRangeField   = 9
+	messageResNameField    = 10
+	enumValueField         = 2
+	serviceMethodField     = 2
+	nameField              = 1
+	fieldNumberField       = 3
+	rangeStartField        = 1
+	// wholeLocation reads the key of an element from its own location.
+	wholeLocation = -1
+)
+
+// The kinds of the keys of the elements in the source code.
+const (
+	// nameKey is an identifier.
+	nameKey = iota
+	// numberKey is an integer literal, compared in decimal.
+	numberKey
+	// stringKey is a string literal (or an identifier), compared unquoted.
+	stringKey
+)
+
+// tabWidth is the width of a tab in the columns of the spans.
+const tabWidth = 8
+
+// Restore undoes the canonical sorting of the file by 'protopkg file', such
+// that the elements are in the order of the source file, and match the paths
+// of the source code info.  The source code info is not sorted, so its paths
+// hold the declaration indexes of the elements; the declaration index of an
+// element is found by reading its name (or number) from the source code at
+// the span of the location.  The file may be partially restored on error.
+func Restore(file *descriptorpb.FileDescriptorProto, sourceCode string) error {
+	if file.SourceCodeInfo == nil {
+		return fmt.Errorf("no source code info")
+	}
+	if sourceCode == "" {
+		return fmt.Errorf("no source code")
+	}
+	r := &reorderer{
+		lines:     strings.Split(sourceCode, "\n"),
+		locations: make(map[string]*descriptorpb.SourceCodeInfo_Location),
+	}
+	for _, loc := range file.SourceCodeInfo.Location {
+		r.locations[pathKey(loc.Path)] = loc
+	}
+
+	if err := r.reorder(file.Dependency, []int32{fileDependencyField}, wholeLocation, stringKey, func(i int) string {
+		return file.Dependency[i]
+	}, nil); err != nil {
+		return fmt.Errorf("dependencies: %w", err)
+	}
+	if err := r.reorder(file.MessageType, []int32{fileMessageTypeField}, nameField, nameKey, func(i int) string {
+		return file.MessageType[i].GetName()
+	}, nil); err != nil {
+		return fmt.Errorf("messages: %w", err)
+	}
+	if err := r.reorder(file.EnumType, []int32{fileEnumTypeField}, nameField, nameKey, func(i int) string {
+		return file.EnumType[i].GetName()
+	}, nil); err != nil {
+		return fmt.Errorf("enums: %w", err)
+	}
+	if err := r.reorder(file.Service, []int32{fileServiceField}, nameField, nameKey, func(i int) string {
+		return file.Service[i].GetName()
+	}, nil); err != nil {
+		return fmt.Errorf("services: %w", err)
+	}
+	if err := r.reorder(file.Extension, []int32{fileExtensionField}, nameField, nameKey, func(i int) string {
+		return file.Extension[i].GetName()
+	}, nil); err != nil {
+		return fmt.Errorf("extensions: %w", err)
+	}
+
+	for i, m := range file.MessageType {
+		if err := r.reorderMessage(m, []int32{fileMessageTypeField, int32(i)}); err != nil {
+			return fmt.Errorf("message %s: %w", m.GetName(), err)
+		}
+	}
+	for i, e := range file.EnumType {
+		if err := r.reorderEnum(e, []int32{fileEnumTypeField, int32(i)}); err != nil {
+			return fmt.Errorf("enum %s: %w", e.GetName(), err)
+		}
+	}
+	for i, s := range file.Service {
+		path := []int32{fileServiceField, int32(i)}
+		if err := r.reorder(s.Method, append(path, serviceMethodField), nameField, nameKey, func(i int) string {
+			return s.Method[i].GetName()
+		}, nil); err != nil {
+			return fmt.Errorf("service %s: %w", s.GetName(), err)
+		}
+	}
+	return nil
+}
+
+func (r *reorderer) reorderMessage(m *descriptorpb.DescriptorProto, path []int32) error {
+	sub := func(field int32) []int32 {
+		return append(append([]int32(nil), path...), field)
+	}
+	if err := r.reorder(m.Field, sub(messageFieldField), fieldNumberField, numberKey, func(i int) string {
+		return strconv.Itoa(int(m.Field[i].GetNumber()))
+	}, nil); err != nil {
+		return fmt.Errorf("fields: %w", err)
+	}
+	// map entries are synthesized, and have no location: they are ordered as
+	// the map fields that refer to them.
+	mapFields := make(map[string]int)
+	for i, f := range m.Field {
+		typeName := f.GetTypeName()
+		mapFields[typeName[strings.LastIndexByte(typeName, '.')+1:]] = i
+	}
+	if err := r.reorder(m.NestedType, sub(messageNestedTypeField), nameField, nameKey, func(i int) string {
+		return m.NestedType[i].GetName()
+	}, func(i int) int {
+		if m.NestedType[i].GetOptions().GetMapEntry() {
+			if j, ok := mapFields[m.NestedType[i].GetName()]; ok {
+				return j
+			}
+		}
+		return len(m.Field)
+	}); err != nil {
+		return fmt.Errorf("nested messages: %w", err)
+	}
+	if err := r.reorder(m.EnumType, sub(messageEnumTypeField), nameField, nameKey, func(i int) string {
+		return m.EnumType[i].GetName()
+	}, nil); err != nil {
+		return fmt.Errorf("enums: %w", err)
+	}
+	if err := r.reorder(m.Extension, sub(messageExtensionField), nameField, nameKey, func(i int) string {
+		return m.Extension[i].GetName()
+	}, nil); err != nil {
+		return fmt.Errorf("extensions: %w", err)
+	}
+	if err := r.reorder(m.ExtensionRange, sub(messageExtRangeField), rangeStartField, numberKey, func(i int) string {
+		return strconv.Itoa(int(m.ExtensionRange[i].GetStart()))
+	}, nil); err != nil {
+		return fmt.Errorf("extension ranges: %w", err)
+	}
+	if err := r.reorder(m.ReservedRange, sub(messageResRangeField), rangeStartField, numberKey, func(i int) string {
+		return strconv.Itoa(int(m.ReservedRange[i].GetStart()))
+	}, nil); err != nil {
+		return fmt.Errorf("reserved ranges: %w", err)
+	}
+	if err := r.reorder(m.ReservedName, sub(messageResNameField), wholeLocation, stringKey, func(i int) string {
+		return m.ReservedName[i]
+	}, nil); err != nil {
+		return fmt.Errorf("reserved names: %w", err)
+	}
+
+	for i, nested := range m.NestedType {
+		if err := r.reorderMessage(nested, append(sub(messageNestedTypeField), int32(i))); err != nil {
+			return fmt.Errorf("message %s: %w", nested.GetName(), err)
+		}
+	}
+	for i, e := range m.EnumType {
+		if err := r.reorderEnum(e, append(sub(messageEnumTypeField), int32(i))); err != nil {
+			return fmt.Errorf("enum %s: %w", e.GetName(), err)
+		}
+	}
+	return nil
+}
+
+func (r *reorderer) reorderEnum(e *descriptorpb.EnumDescriptorProto, path []int32) error {
+	if err := r.reorder(e.Value, append(append([]int32(nil), path...), enumValueField), nameField, nameKey, func(i int) string {
+		return e.Value[i].GetName()
+	}, nil); err != nil {
+		return fmt.Errorf("values: %w", err)
+	}
+	return nil
+}
+
+// reorderer reads the declaration indexes of the elements from the source
+// code info.
+type reorderer struct {
+	lines     []string
+	locations map[string]*descriptorpb.SourceCodeInfo_Location
+}
+
+// reorder sorts the list (a slice) into declaration order.  The location of
+// the element with declaration index i is path+[i]; its key is read from the
+// span of path+[i, field], or of path+[i] if field is wholeLocation, and
+// matched (according to its kind) with the key of the elements.  The elements without a location are assigned the
+// remaining indexes, in the order given by hint (if not nil), else in their
+// current order.  The list is not changed on error.
+func (r *reorderer) reorder(list interface{}, path []int32, field int32, kind int, key func(i int) string, hint func(i int) int) error {
+	n := reflect.ValueOf(list).Len()
+	if n < 2 {
+		return nil
+	}
+	declared := make(map[string]int)
+	var unused []int
+	for i := 0; i < n; i++ {
+		locPath := append(append([]int32(nil), path...), int32(i))
+		if field != wholeLocation {
+			locPath = append(locPath, field)
+		}
+		text, ok := r.text(locPath)
+		if !ok {
+			unused = append(unused, i)
+			continue
+		}
+		k, err := normalizeKey(text, kind)
+		if err != nil {
+			return err
+		}
+		if _, ok := declared[k]; ok {
+			return fmt.Errorf("duplicate declaration of %q", k)
+		}
+		declared[k] = i
+	}
+
+	index := make([]int, n)
+	var unmatched []int
+	for i := 0; i < n; i++ {
+		j, ok := declared[key(i)]
+		if !ok {
+			unmatched = append(unmatched, i)
+			continue
+		}
+		index[i] = j
+		delete(declared, key(i))
+	}
+	if len(declared) > 0 {
+		keys := make([]string, 0, len(declared))
+		for k := range declared {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		return fmt.Errorf("no element for the declaration of %q", keys[0])
+	}
+	if len(unmatched) != len(unused) {
+		return fmt.Errorf("%d elements without a declaration", len(unmatched)-len(unused))
+	}
+	if hint != nil {
+		sort.SliceStable(unmatched, func(a, b int) bool { return hint(unmatched[a]) < hint(unmatched[b]) })
+	}
+	for k, i := range unmatched {
+		index[i] = unused[k]
+	}
+
+	swap := reflect.Swapper(list)
+	sort.Sort(&byIndex{index, swap})
+	return nil
+}
+
+// text returns the source code at the span of the location of the path.  Only
+// single-line spans are read.
+func (r *reorderer) text(path []int32) (string, bool) {
+	loc, ok := r.locations[pathKey(path)]
+	if !ok {
+		return "", false
+	}
+	span := loc.Span
+	var line, start, end int32
+	switch len(span) {
+	case 3:
+		line, start, end = span[0], span[1], span[2]
+	case 4:
+		if span[0] != span[2] {
+			return "", false
+		}
+		line, start, end = span[0], span[1], span[3]
+	default:
+		return "", false
+	}
+	if int(line) >= len(r.lines) {
+		return "", false
+	}
+	text := r.lines[line]
+	from, to := byteOffset(text, int(start)), byteOffset(text, int(end))
+	if from < 0 || to < from {
+		return "", false
+	}
+	return text[from:to], true
+}
+
+// byteOffset returns the byte offset of the column of the line, where tabs
+// advance the column to the next multiple of 8 (as protoc does), or -1 if the
+// line is too short.
+func byteOffset(line string, column int) int {
+	col := 0
+	for i := 0; i < len(line); i++ {
+		if col >= column {
+			return i
+		}
+		if line[i] == '\t' {
+			col += tabWidth - col%tabWidth
+		} else {
+			col++
+		}
+	}
+	if col >= column {
+		return len(line)
+	}
+	return -1
+}
+
+// normalizeKey returns the key of the source text of a location: numbers are
+// formatted in decimal, and string literals are unquoted (the location of an
+// import statement spans the whole statement).
+func normalizeKey(text string, kind int) (string, error) {
+	text = strings.TrimSpace(text)
+	switch kind {
+	case numberKey:
+		n, err := strconv.ParseInt(text, 0, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid number: %q", text)
+		}
+		return strconv.FormatInt(n, 10), nil
+	case stringKey:
+		if start := strings.IndexAny(text, `"'`); start >= 0 {
+			if end := strings.LastIndexByte(text, text[start]); end > start {
+				if unquoted, err := strconv.Unquote(`"` + text[start+1:end] + `"`); err == nil {
+					return unquoted, nil
+				}
+				return text[start+1 : end], nil
+			}
+		}
+		return strings.TrimSuffix(text, ";"), nil
+	default:
+		return text, nil
+	}
+}
+
+func pathKey(path []int32) string {
+	var b strings.Builder
+	for i, p := range path {
+		if i > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(strconv.Itoa(int(p)))
+	}
+	return b.String()
+}
+
+// byIndex sorts a list by the declaration indexes of its elements.
+type byIndex struct {
+	index []int
+	swap  func(i, j int)
+}
+
+func (s *byIndex) Len() int           { return len(s.index) }
+func (s *byIndex) Less(i, j int) bool { return s.index[i] < s.index[j] }
+func (s *byIndex) Swap(i, j int) {
+	s.index[i], s.index[j] = s.index[j], s.index[i]
+	s.swap(i, j)
+}
@@ -0,0 +1,270 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	longrunningpb "cloud.google.com/go/longrunning/autogen/longrunningpb"
+	regpb "github.com/protopkg/apis/protopkg/registry/v1alpha1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// operationPrefix is the prefix of operation names.
+const operationPrefix = "operations/"
+
+// defaultListPageSize is the page size of ListOperations when the request
+// does not give one.
+const defaultListPageSize = 100
+
+// Finished operations are forgotten after operationRetention, and the oldest
+// ones beyond maxFinishedOperations, such that a long-running server does not
+// accumulate them.  Running operations are always kept.
+const (
+	operationRetention    = time.Hour
+	maxFinishedOperations = 1000
+)
+
+// operation is a long-running operation of the server.
+type operation struct {
+	name string
+	// done is closed when the operation completes.
+	done chan struct{}
+
+	mu sync.Mutex
+	op *longrunningpb.Operation
+	// refs are the packages of a successful operation.
+	refs []*regpb.ProtoPackageRef
+	// finishedAt is when the operation completed, zero while it runs.
+	finishedAt time.Time
+}
+
+// snapshot returns a copy of the operation message.
+func (o *operation) snapshot() *longrunningpb.Operation {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return proto.Clone(o.op).(*longrunningpb.Operation)
+}
+
+// packages returns the packages of the operation, and the operation itself.
+func (o *operation) packages() ([]*regpb.ProtoPackageRef, *longrunningpb.Operation) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.refs, proto.Clone(o.op).(*longrunningpb.Operation)
+}
+
+// finished returns when the operation completed, or the zero time if it is
+// still running.
+func (o *operation) finished() time.Time {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.finishedAt
+}
+
+// operations is the table of operations, in creation order.
+type operations struct {
+	mu    sync.Mutex
+	ops   map[string]*operation
+	names []string
+
+	// retention and maxFinished limit the finished operations (see prune).
+	retention   time.Duration
+	maxFinished int
+	now         func() time.Time
+}
+
+func newOperations() *operations {
+	return &operations{
+		ops:         make(map[string]*operation),
+		retention:   operationRetention,
+		maxFinished: maxFinishedOperations,
+		now:         time.Now,
+	}
+}
+
+// start creates a new running operation.
+func (t *operations) start() *operation {
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		panic(fmt.Sprintf("reading random operation id: %v", err))
+	}
+	name := operationPrefix + hex.EncodeToString(id[:])
+	o := &operation{
+		name: name,
+		done: make(chan struct{}),
+		op:   &longrunningpb.Operation{Name: name},
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.prune()
+	t.ops[name] = o
+	t.names = append(t.names, name)
+	return o
+}
+
+// prune removes the finished operations that are older than the retention,
+// and the oldest finished operations beyond maxFinished.  t.mu must be held.
+func (t *operations) prune() {
+	now := t.now()
+	finished := make(map[string]time.Time)
+	for _, name := range t.names {
+		if at := t.ops[name].finished(); !at.IsZero() {
+			finished[name] = at
+		}
+	}
+	excess := len(finished) - t.maxFinished
+	kept := t.names[:0]
+	for _, name := range t.names {
+		if at, ok := finished[name]; ok && (excess > 0 || now.Sub(at) > t.retention) {
+			delete(t.ops, name)
+			excess--
+			continue
+		}
+		kept = append(kept, name)
+	}
+	t.names = kept
+}
+
+// finish completes the operation with either the response or the error.
+func (o *operation) finish(response proto.Message, refs []*regpb.ProtoPackageRef, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	defer close(o.done)
+
+	o.op.Done = true
+	o.finishedAt = time.Now()
+	if err != nil {
+		o.op.Result = &longrunningpb.Operation_Error{Error: status.Convert(err).Proto()}
+		return
+	}
+	resp, err := anypb.New(response)
+	if err != nil {
+		o.op.Result = &longrunningpb.Operation_Error{Error: status.Convert(err).Proto()}
+		return
+	}
+	o.op.Result = &longrunningpb.Operation_Response{Response: resp}
+	o.refs = refs
+}
+
+func (t *operations) get(name string) (*operation, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	o, ok := t.ops[name]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "operation not found: %s", name)
+	}
+	return o, nil
+}
+
+func (t *operations) delete(name string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.ops[name]; !ok {
+		return status.Errorf(codes.NotFound, "operation not found: %s", name)
+	}
+	delete(t.ops, name)
+	for i, other := range t.names {
+		if other == name {
+			t.names = append(t.names[:i], t.names[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// list returns a page of operations starting at the given offset, and the
+// offset of the next page (0 if there are no more).
+func (t *operations) list(offset, size int) ([]*operation, int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if offset >= len(t.names) {
+		return nil, 0
+	}
+	end := offset + size
+	next := end
+	if end >= len(t.names) {
+		end = len(t.names)
+		next = 0
+	}
+	page := make([]*operation, 0, end-offset)
+	for _, name := range t.names[offset:end] {
+		page = append(page, t.ops[name])
+	}
+	return page, next
+}
+
+// GetOperation implements longrunningpb.OperationsServer.
+func (s *Server) GetOperation(ctx context.Context, req *longrunningpb.GetOperationRequest) (*longrunningpb.Operation, error) {
+	o, err := s.ops.get(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	return o.snapshot(), nil
+}
+
+// ListOperations implements longrunningpb.OperationsServer.  The filter is
+// not supported.
+func (s *Server) ListOperations(ctx context.Context, req *longrunningpb.ListOperationsRequest) (*longrunningpb.ListOperationsResponse, error) {
+	if req.Filter != "" {
+		return nil, status.Error(codes.InvalidArgument, "filter is not supported")
+	}
+	offset := 0
+	if req.PageToken != "" {
+		n, err := strconv.Atoi(req.PageToken)
+		if err != nil || n < 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid page token: %q", req.PageToken)
+		}
+		offset = n
+	}
+	size := int(req.PageSize)
+	if size <= 0 {
+		size = defaultListPageSize
+	}
+
+	page, next := s.ops.list(offset, size)
+	resp := &longrunningpb.ListOperationsResponse{}
+	for _, o := range page {
+		resp.Operations = append(resp.Operations, o.snapshot())
+	}
+	if next > 0 {
+		resp.NextPageToken = strconv.Itoa(next)
+	}
+	return resp, nil
+}
+
+// DeleteOperation implements longrunningpb.OperationsServer.
+func (s *Server) DeleteOperation(ctx context.Context, req *longrunningpb.DeleteOperationRequest) (*emptypb.Empty, error) {
+	if err := s.ops.delete(req.Name); err != nil {
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// WaitOperation implements longrunningpb.OperationsServer.  It returns the
+// operation when it is done, or when the timeout (or the deadline of the
+// call) expires.
+func (s *Server) WaitOperation(ctx context.Context, req *longrunningpb.WaitOperationRequest) (*longrunningpb.Operation, error) {
+	o, err := s.ops.get(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	if timeout := req.Timeout.AsDuration(); req.Timeout != nil && timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	select {
+	case <-o.done:
+	case <-ctx.Done():
+	}
+	return o.snapshot(), nil
+}
@@ -0,0 +1,208 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+
+	longrunningpb "cloud.google.com/go/longrunning/autogen/longrunningpb"
+	"github.com/protopkg/apis/pkg/chunk"
+	"github.com/protopkg/apis/pkg/store"
+	"github.com/protopkg/apis/pkg/validate"
+	regpb "github.com/protopkg/apis/protopkg/registry/v1alpha1"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/runtime/protoiface"
+)
+
+// CreateProtoPackage implements pppb.PackagesServer.  It receives the
+// packages of the stream (reassembling chunked packages), and returns an
+// operation that stores them.
+func (s *Server) CreateProtoPackage(stream pppb.Packages_CreateProtoPackageServer) error {
+	var pkgs []*pppb.ProtoPackage
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if req.Pkg == nil {
+			return status.Error(codes.InvalidArgument, "request has no package")
+		}
+		pkgs = append(pkgs, req.Pkg)
+	}
+	if len(pkgs) == 0 {
+		return status.Error(codes.InvalidArgument, "no packages received")
+	}
+
+	if md, ok := metadata.FromIncomingContext(stream.Context()); ok {
+		if values := md.Get(chunk.MetadataKey); len(values) > 0 {
+			if values[0] != chunk.MetadataValue {
+				return status.Errorf(codes.InvalidArgument, "unsupported %s: %q", chunk.MetadataKey, values[0])
+			}
+			var err error
+			if pkgs, err = chunk.Reassemble(pkgs); err != nil {
+				return status.Error(codes.InvalidArgument, err.Error())
+			}
+		}
+	}
+
+	op := s.ops.start()
+	s.pending.Add(1)
+	go func() {
+		defer s.pending.Done()
+		resp, refs, err := s.createProtoPackages(context.Background(), pkgs)
+		if err != nil {
+			log.Printf("%s failed: %v", op.name, err)
+		}
+		op.finish(resp, refs, err)
+	}()
+
+	return stream.SendAndClose(op.snapshot())
+}
+
+// createProtoPackages validates and stores the packages.  Nothing is stored
+// unless all packages are valid, all dependencies are resolved (by the store
+// or by other packages of the list), and no package conflicts with a stored
+// package.
+func (s *Server) createProtoPackages(ctx context.Context, pkgs []*pppb.ProtoPackage) (*regpb.CreateProtoPackageResponse, []*regpb.ProtoPackageRef, error) {
+	if err := validateProtoPackages(pkgs); err != nil {
+		return nil, nil, err
+	}
+	if err := s.checkProtoPackageDependencies(ctx, pkgs); err != nil {
+		return nil, nil, err
+	}
+	for _, pkg := range pkgs {
+		other, err := s.store.Resolve(ctx, store.Ref(pkg))
+		if errors.Is(err, store.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, storeError(err)
+		}
+		if other.Hash != pkg.Hash {
+			return nil, nil, status.Errorf(codes.AlreadyExists, "%s: the server has hash %s (received %s)", store.Ref(pkg), other.Hash, pkg.Hash)
+		}
+	}
+
+	resp := &regpb.CreateProtoPackageResponse{}
+	var refs []*regpb.ProtoPackageRef
+	for _, pkg := range pkgs {
+		ref := &regpb.ProtoPackageRef{
+			Ref:  store.Ref(pkg),
+			Name: pkg.Name,
+			Hash: pkg.Hash,
+		}
+		created, err := s.store.Put(ctx, pkg)
+		if err != nil {
+			return nil, nil, storeError(err)
+		}
+		if created {
+			log.Printf("stored: %s (%s)", ref.Ref, store.ID(pkg.Name, pkg.Hash))
+		} else {
+			log.Printf("exists: %s (%s)", ref.Ref, store.ID(pkg.Name, pkg.Hash))
+		}
+		resp.Packages = append(resp.Packages, &regpb.ProtoPackageStatus{
+			Pkg:        ref,
+			State:      regpb.ProtoPackageStatus_EXISTS,
+			Id:         store.ID(pkg.Name, pkg.Hash),
+			Hash:       pkg.Hash,
+			PackageUrl: pkg.PackageUrl,
+		})
+		refs = append(refs, ref)
+	}
+	return resp, refs, nil
+}
+
+// validateProtoPackages returns an InvalidArgument error with the problems
+// of the packages as BadRequest details, or nil if they are valid.
+func validateProtoPackages(pkgs []*pppb.ProtoPackage) error {
+	var violations []*errdetails.BadRequest_FieldViolation
+	for _, problem := range validate.Packages(pkgs) {
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{
+			Field:       problem.Package,
+			Description: problem.Description,
+		})
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	return withDetails(status.Newf(codes.InvalidArgument, "invalid proto package (%d problem(s))", len(violations)),
+		&errdetails.BadRequest{FieldViolations: violations})
+}
+
+// checkProtoPackageDependencies returns a FailedPrecondition error listing
+// the dependencies that are neither stored nor in the list, or nil if all
+// are resolved.
+func (s *Server) checkProtoPackageDependencies(ctx context.Context, pkgs []*pppb.ProtoPackage) error {
+	received := make(map[string]bool)
+	for _, pkg := range pkgs {
+		received[store.Ref(pkg)] = true
+	}
+	var violations []*errdetails.PreconditionFailure_Violation
+	for _, pkg := range pkgs {
+		for _, dep := range pkg.Dependencies {
+			if received[dep] {
+				continue
+			}
+			_, err := s.store.Resolve(ctx, dep)
+			if errors.Is(err, store.ErrNotFound) {
+				violations = append(violations, &errdetails.PreconditionFailure_Violation{
+					Type:        "MISSING_DEPENDENCY",
+					Subject:     dep,
+					Description: fmt.Sprintf("%s depends on %s, which is not on the server", pkg.Name, dep),
+				})
+				continue
+			}
+			if err != nil {
+				return storeError(err)
+			}
+		}
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	return withDetails(status.Newf(codes.FailedPrecondition, "%d missing dependencies", len(violations)),
+		&errdetails.PreconditionFailure{Violations: violations})
+}
+
+// GetProtoPackage implements pppb.PackagesServer.  It returns the package
+// stored by the given operation; the operation must be done and must have
+// created a single package.
+func (s *Server) GetProtoPackage(ctx context.Context, req *pppb.GetProtoPackageRequest) (*pppb.ProtoPackage, error) {
+	o, err := s.ops.get(req.OperationName)
+	if err != nil {
+		return nil, err
+	}
+	refs, op := o.packages()
+	if !op.Done {
+		return nil, status.Errorf(codes.FailedPrecondition, "operation %s is not done", op.Name)
+	}
+	if result, ok := op.Result.(*longrunningpb.Operation_Error); ok {
+		return nil, status.ErrorProto(result.Error)
+	}
+	if len(refs) != 1 {
+		return nil, status.Errorf(codes.FailedPrecondition, "operation %s created %d packages (use the Registry service to get them)", op.Name, len(refs))
+	}
+	pkg, err := s.store.Get(ctx, refs[0].Name, refs[0].Hash)
+	if err != nil {
+		return nil, storeError(err)
+	}
+	return pkg, nil
+}
+
+// withDetails returns the status as an error, with the given details.
+func withDetails(st *status.Status, details ...protoiface.MessageV1) error {
+	withDetails, err := st.WithDetails(details...)
+	if err != nil {
+		return st.Err()
+	}
+	return withDetails.Err()
+}
@@ -0,0 +1,252 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/protopkg/apis/pkg/store"
+	regpb "github.com/protopkg/apis/protopkg/registry/v1alpha1"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// maxQueryPageSize is the largest page size of the query methods.
+const maxQueryPageSize = 1000
+
+// headerPaths are the paths of the file fields that the headers returned by
+// store.List have; other file fields need the stored files.
+var headerPaths = map[string]bool{
+	"files.hash":         true,
+	"files.file.name":    true,
+	"files.file.package": true,
+}
+
+// LookupProtoPackage implements regpb.RegistryServer.
+func (s *Server) LookupProtoPackage(ctx context.Context, req *regpb.LookupProtoPackageRequest) (*pppb.ProtoPackage, error) {
+	mask, err := parseReadMask(req.ReadMask)
+	if err != nil {
+		return nil, err
+	}
+
+	var pkg *pppb.ProtoPackage
+	switch {
+	case req.Ref != "" && req.Id == "" && req.Hash == "":
+		pkg, err = s.store.Resolve(ctx, req.Ref)
+	case req.Id != "" && req.Ref == "" && req.Hash == "":
+		name, hash, ok := strings.Cut(req.Id, "@")
+		if !ok || name == "" || hash == "" {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid package id: %q (want NAME@HASH)", req.Id)
+		}
+		pkg, err = s.store.Get(ctx, name, hash)
+	case req.Hash != "" && req.Ref == "" && req.Id == "":
+		var hdrs []*pppb.ProtoPackage
+		hdrs, err = s.store.List(ctx, &store.Filter{Hash: req.Hash})
+		if err != nil {
+			break
+		}
+		if len(hdrs) == 0 {
+			return nil, status.Errorf(codes.NotFound, "%s: %v", req.Hash, store.ErrNotFound)
+		}
+		pkg, err = s.store.Resolve(ctx, store.Ref(hdrs[0]))
+	default:
+		return nil, status.Error(codes.InvalidArgument, "request must have exactly one of ref, id or hash")
+	}
+	if err != nil {
+		return nil, storeError(err)
+	}
+	mask.apply(pkg)
+	return pkg, nil
+}
+
+// ListProtoPackages implements regpb.RegistryServer.
+func (s *Server) ListProtoPackages(ctx context.Context, req *regpb.ListProtoPackagesRequest) (*regpb.ListProtoPackagesResponse, error) {
+	return s.listProtoPackages(ctx, &store.Filter{
+		Name:       req.Name,
+		Repository: req.Repository,
+		Commit:     req.Commit,
+	}, req.PageSize, req.PageToken, req.ReadMask)
+}
+
+// FindProtoPackagesByFile implements regpb.RegistryServer.
+func (s *Server) FindProtoPackagesByFile(ctx context.Context, req *regpb.FindProtoPackagesByFileRequest) (*regpb.ListProtoPackagesResponse, error) {
+	if req.File == "" {
+		return nil, status.Error(codes.InvalidArgument, "file is required")
+	}
+	return s.listProtoPackages(ctx, &store.Filter{File: req.File}, req.PageSize, req.PageToken, req.ReadMask)
+}
+
+// FindProtoPackagesBySymbol implements regpb.RegistryServer.
+func (s *Server) FindProtoPackagesBySymbol(ctx context.Context, req *regpb.FindProtoPackagesBySymbolRequest) (*regpb.ListProtoPackagesResponse, error) {
+	symbol := strings.TrimPrefix(req.Symbol, ".")
+	if symbol == "" {
+		return nil, status.Error(codes.InvalidArgument, "symbol is required")
+	}
+	return s.listProtoPackages(ctx, &store.Filter{Symbol: symbol}, req.PageSize, req.PageToken, req.ReadMask)
+}
+
+// listProtoPackages returns a page of the packages that match the filter.
+// The stored files are only read if the read mask selects file fields that
+// the headers do not have.
+func (s *Server) listProtoPackages(ctx context.Context, filter *store.Filter, pageSize int32, pageToken string, readMask *fieldmaskpb.FieldMask) (*regpb.ListProtoPackagesResponse, error) {
+	mask, err := parseReadMask(readMask)
+	if err != nil {
+		return nil, err
+	}
+	offset := 0
+	if pageToken != "" {
+		n, err := strconv.Atoi(pageToken)
+		if err != nil || n < 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid page token: %q", pageToken)
+		}
+		offset = n
+	}
+	size := int(pageSize)
+	switch {
+	case size < 0:
+		return nil, status.Errorf(codes.InvalidArgument, "invalid page size: %d", pageSize)
+	case size == 0:
+		size = defaultListPageSize
+	case size > maxQueryPageSize:
+		size = maxQueryPageSize
+	}
+
+	hdrs, err := s.store.List(ctx, filter)
+	if err != nil {
+		return nil, storeError(err)
+	}
+	resp := &regpb.ListProtoPackagesResponse{}
+	if offset >= len(hdrs) {
+		return resp, nil
+	}
+	end := offset + size
+	if end < len(hdrs) {
+		resp.NextPageToken = strconv.Itoa(end)
+	} else {
+		end = len(hdrs)
+	}
+	for _, hdr := range hdrs[offset:end] {
+		pkg := hdr
+		if mask.needsFiles() {
+			pkg, err = s.store.Resolve(ctx, store.Ref(hdr))
+			if err != nil {
+				return nil, storeError(err)
+			}
+		}
+		mask.apply(pkg)
+		resp.Packages = append(resp.Packages, pkg)
+	}
+	return resp, nil
+}
+
+// readMask is a parsed field mask of ProtoPackage: each field name maps to
+// the mask of its subfields, or to nil if the whole field is selected.  A nil
+// readMask selects all fields.
+type readMask map[string]readMask
+
+// parseReadMask parses and validates the field mask.  A path may go through
+// repeated message fields (e.g. 'files.source_code'), in which case it
+// applies to each element.
+func parseReadMask(fm *fieldmaskpb.FieldMask) (readMask, error) {
+	if len(fm.GetPaths()) == 0 {
+		return nil, nil
+	}
+	mask := make(readMask)
+	for _, path := range fm.Paths {
+		if err := mask.add(path, (&pppb.ProtoPackage{}).ProtoReflect().Descriptor()); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid read mask: %v", err)
+		}
+	}
+	return mask, nil
+}
+
+// add adds the path, relative to the message descriptor, to the mask.
+func (m readMask) add(path string, md protoreflect.MessageDescriptor) error {
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		fd := md.Fields().ByName(protoreflect.Name(name))
+		if fd == nil {
+			return fmt.Errorf("%q: %s has no field %q", path, md.FullName(), name)
+		}
+		if i == len(names)-1 {
+			m[name] = nil
+			return nil
+		}
+		if fd.Message() == nil || fd.IsMap() {
+			return fmt.Errorf("%q: field %s has no subfields", path, fd.FullName())
+		}
+		sub, ok := m[name]
+		if ok && sub == nil {
+			// the whole field is already selected.
+			return nil
+		}
+		if !ok {
+			sub = make(readMask)
+			m[name] = sub
+		}
+		m, md = sub, fd.Message()
+	}
+	return nil
+}
+
+// needsFiles reports whether the mask selects file fields that the headers
+// returned by store.List do not have.
+func (m readMask) needsFiles() bool {
+	if m == nil {
+		return true
+	}
+	return m.selectsOther("", headerPaths)
+}
+
+// selectsOther reports whether the mask selects fields of files other than
+// the given paths.
+func (m readMask) selectsOther(prefix string, paths map[string]bool) bool {
+	for name, sub := range m {
+		path := prefix + name
+		if path != "files" && !strings.HasPrefix(path, "files.") {
+			continue
+		}
+		if paths[path] {
+			continue
+		}
+		if sub == nil || sub.selectsOther(path+".", paths) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply clears the fields of the message that the mask does not select.
+func (m readMask) apply(msg interface{ ProtoReflect() protoreflect.Message }) {
+	if m == nil {
+		return
+	}
+	m.prune(msg.ProtoReflect())
+}
+
+func (m readMask) prune(msg protoreflect.Message) {
+	var clear []protoreflect.FieldDescriptor
+	msg.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		sub, ok := m[string(fd.Name())]
+		switch {
+		case !ok:
+			clear = append(clear, fd)
+		case sub == nil:
+		case fd.IsList():
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				sub.prune(list.Get(i).Message())
+			}
+		default:
+			sub.prune(v.Message())
+		}
+		return true
+	})
+	for _, fd := range clear {
+		msg.Clear(fd)
+	}
+}
@@ -0,0 +1,387 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/protopkg/apis/pkg/store"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	rpbalpha "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// ReflectionPackageMetadataKey is the metadata key that scopes a reflection
+// stream to stored packages.  Each value is a package ref (e.g.
+// 'github.com/googleapis/googleapis/0123456/~:google.api') or ID
+// (NAME@HASH); the packages and their dependencies are visible to the
+// stream.
+//
+// Without the metadata, ListServices returns the services of the most
+// recently committed version of each package, and file and symbol requests
+// load the most recently committed package that provides the file or
+// symbol, with its dependencies.  Extensions are only found in the packages
+// loaded by the stream.
+const ReflectionPackageMetadataKey = "protopkg-package"
+
+// reflectionServer implements the grpc.reflection.v1alpha
+// ServerReflection service from the descriptors of the stored packages.  The
+// stream is served by the reflection package of grpc, with a resolver scoped
+// to the packages of the stream.
+type reflectionServer struct {
+	rpbalpha.UnimplementedServerReflectionServer
+
+	store store.Store
+}
+
+// ServerReflectionInfo implements rpbalpha.ServerReflectionServer.
+func (r *reflectionServer) ServerReflectionInfo(stream rpbalpha.ServerReflection_ServerReflectionInfoServer) error {
+	scope, err := newReflectionScope(stream.Context(), r.store)
+	if err != nil {
+		return err
+	}
+	srv := reflection.NewServer(reflection.ServerOptions{
+		Services:           scope,
+		DescriptorResolver: scope,
+		ExtensionResolver:  scope,
+	})
+	return srv.ServerReflectionInfo(stream)
+}
+
+// reflectionServerV1 implements the grpc.reflection.v1 ServerReflection
+// service.  The v1 messages are wire-compatible with the v1alpha ones, the
+// stream is converted and served by reflectionServer.
+type reflectionServerV1 struct {
+	rpb.UnimplementedServerReflectionServer
+
+	alpha *reflectionServer
+}
+
+// ServerReflectionInfo implements rpb.ServerReflectionServer.
+func (r *reflectionServerV1) ServerReflectionInfo(stream rpb.ServerReflection_ServerReflectionInfoServer) error {
+	return r.alpha.ServerReflectionInfo(&v1alphaStream{stream})
+}
+
+// v1alphaStream adapts a v1 reflection stream to the v1alpha messages.
+type v1alphaStream struct {
+	rpb.ServerReflection_ServerReflectionInfoServer
+}
+
+func (s *v1alphaStream) Send(resp *rpbalpha.ServerReflectionResponse) error {
+	var out rpb.ServerReflectionResponse
+	if err := convertMessage(resp, &out); err != nil {
+		return err
+	}
+	return s.ServerReflection_ServerReflectionInfoServer.Send(&out)
+}
+
+func (s *v1alphaStream) Recv() (*rpbalpha.ServerReflectionRequest, error) {
+	req, err := s.ServerReflection_ServerReflectionInfoServer.Recv()
+	if err != nil {
+		return nil, err
+	}
+	var out rpbalpha.ServerReflectionRequest
+	if err := convertMessage(req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// convertMessage copies a message into a wire-compatible message of another
+// type.
+func convertMessage(from, to proto.Message) error {
+	data, err := proto.Marshal(from)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	if err := proto.Unmarshal(data, to); err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	return nil
+}
+
+// reflectionScope holds the descriptors of the packages visible to a
+// reflection stream.  It implements reflection.ServiceInfoProvider,
+// protodesc.Resolver and reflection.ExtensionResolver.  Packages are loaded
+// from the store as needed; a stream is served sequentially, so the scope is
+// not safe for concurrent use.
+type reflectionScope struct {
+	ctx   context.Context
+	store store.Store
+	// pinned are the refs of the packages given in the metadata, if any.
+	pinned []string
+	files  *protoregistry.Files
+	// loaded are the refs of the packages whose files are in files.
+	loaded map[string]bool
+}
+
+func newReflectionScope(ctx context.Context, st store.Store) (*reflectionScope, error) {
+	scope := &reflectionScope{
+		ctx:    ctx,
+		store:  st,
+		files:  new(protoregistry.Files),
+		loaded: make(map[string]bool),
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get(ReflectionPackageMetadataKey) {
+		pkg, err := scope.resolve(value)
+		if err != nil {
+			return nil, err
+		}
+		ref := store.Ref(pkg)
+		scope.pinned = append(scope.pinned, ref)
+		if err := scope.load(ref, pkg); err != nil {
+			return nil, err
+		}
+	}
+	return scope, nil
+}
+
+// resolve returns the package of a ref or ID.
+func (s *reflectionScope) resolve(value string) (*pppb.ProtoPackage, error) {
+	var pkg *pppb.ProtoPackage
+	var err error
+	if name, hash, ok := strings.Cut(value, "@"); ok {
+		pkg, err = s.store.Get(s.ctx, name, hash)
+	} else {
+		pkg, err = s.store.Resolve(s.ctx, value)
+	}
+	if err != nil {
+		return nil, storeError(fmt.Errorf("%s metadata: %w", ReflectionPackageMetadataKey, err))
+	}
+	return pkg, nil
+}
+
+// load adds the files of the package and of its dependencies.  The package
+// is resolved if nil.  Files whose path is already known are skipped: the
+// first package that provides a file wins.
+func (s *reflectionScope) load(ref string, pkg *pppb.ProtoPackage) error {
+	if s.loaded[ref] {
+		return nil
+	}
+	s.loaded[ref] = true
+	if pkg == nil {
+		var err error
+		if pkg, err = s.store.Resolve(s.ctx, ref); err != nil {
+			return storeError(err)
+		}
+	}
+	for _, dep := range pkg.Dependencies {
+		// a missing dependency leaves unresolved types in the descriptors.
+		if err := s.load(dep, nil); err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+	}
+
+	pending := make(map[string]*descriptorpb.FileDescriptorProto)
+	for _, file := range pkg.Files {
+		pending[file.GetFile().GetName()] = file.GetFile()
+	}
+	var register func(name string) error
+	register = func(name string) error {
+		fdp, ok := pending[name]
+		if !ok {
+			return nil
+		}
+		delete(pending, name)
+		if _, err := s.files.FindFileByPath(name); err == nil {
+			return nil
+		}
+		for _, dep := range fdp.Dependency {
+			if err := register(dep); err != nil {
+				return err
+			}
+		}
+		fd, err := protodesc.FileOptions{AllowUnresolvable: true}.New(fdp, fileResolver{s.files})
+		if err != nil {
+			return status.Errorf(codes.Internal, "%s: %s: %v", ref, name, err)
+		}
+		if err := s.files.RegisterFile(fd); err != nil {
+			return status.Errorf(codes.Internal, "%s: %s: %v", ref, name, err)
+		}
+		return nil
+	}
+	for _, file := range pkg.Files {
+		if err := register(file.GetFile().GetName()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadLatest loads the most recently committed package that matches the
+// filter, unless the scope is pinned.
+func (s *reflectionScope) loadLatest(filter *store.Filter) error {
+	if len(s.pinned) > 0 {
+		return nil
+	}
+	hdrs, err := s.store.List(s.ctx, filter)
+	if err != nil {
+		return storeError(err)
+	}
+	if len(hdrs) == 0 {
+		return nil
+	}
+	return s.load(store.Ref(hdrs[0]), nil)
+}
+
+// GetServiceInfo implements reflection.ServiceInfoProvider.  Only the
+// service names are set.
+func (s *reflectionScope) GetServiceInfo() map[string]grpc.ServiceInfo {
+	services := make(map[string]grpc.ServiceInfo)
+	add := func(file *descriptorpb.FileDescriptorProto) {
+		for _, service := range file.Service {
+			name := service.GetName()
+			if file.GetPackage() != "" {
+				name = file.GetPackage() + "." + name
+			}
+			services[name] = grpc.ServiceInfo{}
+		}
+	}
+	if len(s.pinned) > 0 {
+		for _, ref := range s.pinned {
+			pkg, err := s.store.Resolve(s.ctx, ref)
+			if err != nil {
+				continue
+			}
+			for _, file := range pkg.Files {
+				add(file.GetFile())
+			}
+		}
+		return services
+	}
+	hdrs, err := s.store.List(s.ctx, &store.Filter{})
+	if err != nil {
+		return services
+	}
+	seen := make(map[string]bool)
+	for _, hdr := range hdrs {
+		if seen[hdr.Name] {
+			continue
+		}
+		seen[hdr.Name] = true
+		for _, file := range hdr.Files {
+			add(file.GetFile())
+		}
+	}
+	return services
+}
+
+// FindFileByPath implements protodesc.Resolver.
+func (s *reflectionScope) FindFileByPath(path string) (protoreflect.FileDescriptor, error) {
+	if fd, err := s.files.FindFileByPath(path); err == nil {
+		return fd, nil
+	}
+	if err := s.loadLatest(&store.Filter{File: path}); err != nil {
+		return nil, err
+	}
+	return fileResolver{s.files}.FindFileByPath(path)
+}
+
+// FindDescriptorByName implements protodesc.Resolver.
+func (s *reflectionScope) FindDescriptorByName(name protoreflect.FullName) (protoreflect.Descriptor, error) {
+	if d, err := s.files.FindDescriptorByName(name); err == nil {
+		return d, nil
+	}
+	if err := s.loadLatest(&store.Filter{Symbol: string(name)}); err != nil {
+		return nil, err
+	}
+	return fileResolver{s.files}.FindDescriptorByName(name)
+}
+
+// FindExtensionByName implements reflection.ExtensionResolver.
+func (s *reflectionScope) FindExtensionByName(field protoreflect.FullName) (protoreflect.ExtensionType, error) {
+	d, err := s.FindDescriptorByName(field)
+	if err != nil {
+		return nil, err
+	}
+	xd, ok := d.(protoreflect.ExtensionDescriptor)
+	if !ok || !xd.IsExtension() {
+		return nil, protoregistry.NotFound
+	}
+	return dynamicpb.NewExtensionType(xd), nil
+}
+
+// FindExtensionByNumber implements reflection.ExtensionResolver.
+func (s *reflectionScope) FindExtensionByNumber(message protoreflect.FullName, field protoreflect.FieldNumber) (protoreflect.ExtensionType, error) {
+	var found protoreflect.ExtensionType
+	s.RangeExtensionsByMessage(message, func(xt protoreflect.ExtensionType) bool {
+		if xt.TypeDescriptor().Number() == field {
+			found = xt
+			return false
+		}
+		return true
+	})
+	if found == nil {
+		return nil, protoregistry.NotFound
+	}
+	return found, nil
+}
+
+// RangeExtensionsByMessage implements reflection.ExtensionResolver.  The
+// package of the message is loaded first.
+func (s *reflectionScope) RangeExtensionsByMessage(message protoreflect.FullName, f func(protoreflect.ExtensionType) bool) {
+	if _, err := s.FindDescriptorByName(message); err != nil {
+		return
+	}
+	s.files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		return rangeExtensions(fd.Extensions(), fd.Messages(), message, f)
+	})
+}
+
+// rangeExtensions calls f for the extensions of the message, among the given
+// extensions and those of the given messages (recursively).  It returns false
+// if f stops the iteration.
+func rangeExtensions(xds protoreflect.ExtensionDescriptors, mds protoreflect.MessageDescriptors, message protoreflect.FullName, f func(protoreflect.ExtensionType) bool) bool {
+	for i := 0; i < xds.Len(); i++ {
+		xd := xds.Get(i)
+		if xd.ContainingMessage().FullName() == message && !f(dynamicpb.NewExtensionType(xd)) {
+			return false
+		}
+	}
+	for i := 0; i < mds.Len(); i++ {
+		md := mds.Get(i)
+		if !rangeExtensions(md.Extensions(), md.Messages(), message, f) {
+			return false
+		}
+	}
+	return true
+}
+
+// fileResolver resolves descriptors from the files, then from the
+// well-known types linked in the binary, which packages usually import
+// without providing them.
+type fileResolver struct {
+	files *protoregistry.Files
+}
+
+func (r fileResolver) FindFileByPath(path string) (protoreflect.FileDescriptor, error) {
+	if fd, err := r.files.FindFileByPath(path); err == nil {
+		return fd, nil
+	}
+	if !strings.HasPrefix(path, "google/protobuf/") {
+		return nil, protoregistry.NotFound
+	}
+	return protoregistry.GlobalFiles.FindFileByPath(path)
+}
+
+func (r fileResolver) FindDescriptorByName(name protoreflect.FullName) (protoreflect.Descriptor, error) {
+	if d, err := r.files.FindDescriptorByName(name); err == nil {
+		return d, nil
+	}
+	if !strings.HasPrefix(string(name), "google.protobuf.") {
+		return nil, protoregistry.NotFound
+	}
+	return protoregistry.GlobalFiles.FindDescriptorByName(name)
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/protopkg/apis/pkg/signature"
+	"github.com/protopkg/apis/pkg/store"
+	regpb "github.com/protopkg/apis/protopkg/registry/v1alpha1"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CheckProtoPackages implements regpb.RegistryServer.  Packages are looked
+// up by ref, or by name and hash if the request has no ref.
+func (s *Server) CheckProtoPackages(ctx context.Context, req *regpb.CheckProtoPackagesRequest) (*regpb.CheckProtoPackagesResponse, error) {
+	resp := &regpb.CheckProtoPackagesResponse{}
+	for _, ref := range req.Packages {
+		st, err := s.checkProtoPackage(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+		resp.Packages = append(resp.Packages, st)
+	}
+	return resp, nil
+}
+
+func (s *Server) checkProtoPackage(ctx context.Context, ref *regpb.ProtoPackageRef) (*regpb.ProtoPackageStatus, error) {
+	st := &regpb.ProtoPackageStatus{
+		Pkg:   ref,
+		State: regpb.ProtoPackageStatus_MISSING,
+	}
+	var err error
+	var pkg *pppb.ProtoPackage
+	switch {
+	case ref.Ref != "":
+		pkg, err = s.store.Resolve(ctx, ref.Ref)
+	case ref.Name != "" && ref.Hash != "":
+		pkg, err = s.store.Get(ctx, ref.Name, ref.Hash)
+	default:
+		return nil, status.Error(codes.InvalidArgument, "package ref must have either a ref, or a name and hash")
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		return st, nil
+	}
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	st.Hash = pkg.Hash
+	if ref.Hash != "" && ref.Hash != pkg.Hash {
+		st.State = regpb.ProtoPackageStatus_CONFLICT
+		return st, nil
+	}
+	st.State = regpb.ProtoPackageStatus_EXISTS
+	st.Id = store.ID(pkg.Name, pkg.Hash)
+	st.PackageUrl = pkg.PackageUrl
+	return st, nil
+}
+
+// PutProtoPackageSignatures implements regpb.RegistryServer.  The envelopes
+// are stored with the package named by their statement; signatures are not
+// verified, as trust is decided by the clients that read them.
+func (s *Server) PutProtoPackageSignatures(ctx context.Context, req *regpb.PutProtoPackageSignaturesRequest) (*regpb.PutProtoPackageSignaturesResponse, error) {
+	type key struct{ name, hash string }
+	byPackage := make(map[key][]*regpb.SignatureEnvelope)
+	var order []key
+	for _, env := range req.Envelopes {
+		stmt, err := signature.ParseStatement(env)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if len(env.Signatures) == 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "%s: envelope has no signatures", stmt.Name)
+		}
+		k := key{stmt.Name, stmt.Hash}
+		if _, ok := byPackage[k]; !ok {
+			order = append(order, k)
+		}
+		byPackage[k] = append(byPackage[k], env)
+	}
+
+	resp := &regpb.PutProtoPackageSignaturesResponse{}
+	for _, k := range order {
+		envs := byPackage[k]
+		if err := s.store.PutSignatures(ctx, k.name, k.hash, envs...); err != nil {
+			return nil, storeError(err)
+		}
+		log.Printf("signatures: %s (%d envelope(s))", store.ID(k.name, k.hash), len(envs))
+		resp.Stored += int32(len(envs))
+	}
+	return resp, nil
+}
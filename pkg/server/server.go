@@ -0,0 +1,79 @@
+// Package server implements the packages server: the Packages, Operations
+// and Registry grpc services on top of a store.Store.  The server also
+// implements grpc server reflection (v1 and v1alpha) from the descriptors of
+// the stored packages, such that reflection clients can browse the stored
+// APIs (see ReflectionPackageMetadataKey).
+//
+// Packages received by CreateProtoPackage are validated and stored in the
+// background; the call returns a long-running operation that completes when
+// the packages are stored (or rejected).  Operations are held in memory and
+// do not survive a restart of the server; finished operations are forgotten
+// after an hour, or once a thousand newer ones have finished.
+package server
+
+import (
+	"errors"
+	"sync"
+
+	longrunningpb "cloud.google.com/go/longrunning/autogen/longrunningpb"
+	"github.com/protopkg/apis/pkg/store"
+	regpb "github.com/protopkg/apis/protopkg/registry/v1alpha1"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	rpbalpha "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+
+	// register the gzip compressor, such that clients may compress the
+	// upload stream.
+	_ "google.golang.org/grpc/encoding/gzip"
+)
+
+// Server implements the Packages, Operations and Registry services.
+type Server struct {
+	pppb.UnimplementedPackagesServer
+	longrunningpb.UnimplementedOperationsServer
+	regpb.UnimplementedRegistryServer
+
+	store store.Store
+	ops   *operations
+
+	// pending tracks the operations that are still being processed.
+	pending sync.WaitGroup
+}
+
+// New returns a server that keeps the packages in the given store.
+func New(st store.Store) *Server {
+	return &Server{
+		store: st,
+		ops:   newOperations(),
+	}
+}
+
+// Register registers the services of the server.
+func (s *Server) Register(r *grpc.Server) {
+	pppb.RegisterPackagesServer(r, s)
+	longrunningpb.RegisterOperationsServer(r, s)
+	regpb.RegisterRegistryServer(r, s)
+	reflection := &reflectionServer{store: s.store}
+	rpbalpha.RegisterServerReflectionServer(r, reflection)
+	rpb.RegisterServerReflectionServer(r, &reflectionServerV1{alpha: reflection})
+}
+
+// Wait waits for the pending operations to complete.
+func (s *Server) Wait() {
+	s.pending.Wait()
+}
+
+// storeError converts an error of the store to a grpc status error.
+func storeError(err error) error {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, store.ErrConflict):
+		return status.Error(codes.AlreadyExists, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
@@ -0,0 +1,209 @@
+package server
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	longrunningpb "cloud.google.com/go/longrunning/autogen/longrunningpb"
+	"github.com/protopkg/apis/pkg/protohash"
+	"github.com/protopkg/apis/pkg/store"
+	regpb "github.com/protopkg/apis/protopkg/registry/v1alpha1"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// newTestConn starts a server on the in-memory store, and returns a client
+// connection to it.
+func newTestConn(t *testing.T) *grpc.ClientConn {
+	t.Helper()
+	lis := bufconn.Listen(1024 * 1024)
+	srv := New(store.NewMemory())
+	grpcServer := grpc.NewServer()
+	srv.Register(grpcServer)
+	go grpcServer.Serve(lis)
+	t.Cleanup(func() {
+		grpcServer.Stop()
+		srv.Wait()
+	})
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// newTestPackage returns a valid package with a single file.
+func newTestPackage(t *testing.T, name, file string) *pppb.ProtoPackage {
+	t.Helper()
+	files := []*pppb.ProtoFile{{
+		File: &descriptorpb.FileDescriptorProto{
+			Name:    proto.String(file),
+			Package: proto.String(name),
+			Syntax:  proto.String("proto3"),
+		},
+	}}
+	fileHash, err := protohash.File(files[0].File, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files[0].Hash = fileHash
+	hash, err := protohash.Package(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &pppb.ProtoPackage{
+		Name: name,
+		Archive: &pppb.ProtoArchive{
+			Repository: &pppb.ProtoRepository{
+				Host:     "github.com",
+				Owner:    "example",
+				Name:     "protos",
+				FullName: "github.com/example/protos",
+			},
+			CommitSha1: "0123456789abcdef0123456789abcdef01234567",
+			ShortSha1:  "0123456",
+		},
+		Files: files,
+		Hash:  hash,
+	}
+}
+
+func createProtoPackages(t *testing.T, client pppb.PackagesClient, pkgs ...*pppb.ProtoPackage) *longrunningpb.Operation {
+	t.Helper()
+	stream, err := client.CreateProtoPackage(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pkg := range pkgs {
+		if err := stream.Send(&pppb.CreateProtoPackageRequest{Pkg: pkg}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	op, err := stream.CloseAndRecv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return op
+}
+
+func waitOperation(t *testing.T, ops longrunningpb.OperationsClient, name string) *longrunningpb.Operation {
+	t.Helper()
+	op, err := ops.WaitOperation(context.Background(), &longrunningpb.WaitOperationRequest{
+		Name:    name,
+		Timeout: durationpb.New(10 * time.Second),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !op.Done {
+		t.Fatalf("operation %s is not done", name)
+	}
+	return op
+}
+
+func TestCreateWaitGet(t *testing.T) {
+	conn := newTestConn(t)
+	packages := pppb.NewPackagesClient(conn)
+	ops := longrunningpb.NewOperationsClient(conn)
+	registry := regpb.NewRegistryClient(conn)
+	pkg := newTestPackage(t, "example.v1", "example/v1/example.proto")
+
+	op := createProtoPackages(t, packages, pkg)
+	if op.Name == "" {
+		t.Fatal("operation has no name")
+	}
+	op = waitOperation(t, ops, op.Name)
+	if rpcStatus := op.GetError(); rpcStatus != nil {
+		t.Fatalf("operation failed: %v", rpcStatus)
+	}
+
+	got, err := packages.GetProtoPackage(context.Background(), &pppb.GetProtoPackageRequest{OperationName: op.Name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(got, pkg) {
+		t.Errorf("got %v, want %v", got, pkg)
+	}
+
+	resp, err := registry.CheckProtoPackages(context.Background(), &regpb.CheckProtoPackagesRequest{
+		Packages: []*regpb.ProtoPackageRef{{Ref: store.Ref(pkg)}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state := resp.Packages[0].State; state != regpb.ProtoPackageStatus_EXISTS {
+		t.Errorf("check: got %v, want EXISTS", state)
+	}
+}
+
+func TestCreateMissingDependency(t *testing.T) {
+	conn := newTestConn(t)
+	packages := pppb.NewPackagesClient(conn)
+	ops := longrunningpb.NewOperationsClient(conn)
+	pkg := newTestPackage(t, "example.v1", "example/v1/example.proto")
+	pkg.Dependencies = []string{"github.com/example/protos/0123456/~:example.common"}
+
+	op := waitOperation(t, ops, createProtoPackages(t, packages, pkg).Name)
+	if code := codes.Code(op.GetError().GetCode()); code != codes.FailedPrecondition {
+		t.Fatalf("operation error: got %v, want FailedPrecondition", code)
+	}
+	_, err := packages.GetProtoPackage(context.Background(), &pppb.GetProtoPackageRequest{OperationName: op.Name})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("get: got %v, want FailedPrecondition", err)
+	}
+}
+
+func TestOperationsPrune(t *testing.T) {
+	now := time.Now()
+	table := newOperations()
+	table.maxFinished = 2
+	table.now = func() time.Time { return now }
+
+	var started []*operation
+	for i := 0; i < 4; i++ {
+		started = append(started, table.start())
+	}
+	for _, o := range started[:3] {
+		o.finish(&regpb.CreateProtoPackageResponse{}, nil, nil)
+	}
+
+	// the oldest of the three finished operations exceeds the cap
+	table.start()
+	if _, err := table.get(started[0].name); status.Code(err) != codes.NotFound {
+		t.Errorf("oldest finished operation: got %v, want NotFound", err)
+	}
+	for _, o := range started[1:] {
+		if _, err := table.get(o.name); err != nil {
+			t.Errorf("operation %s: %v", o.name, err)
+		}
+	}
+
+	// after the retention, only the running operations remain
+	now = now.Add(operationRetention + time.Minute)
+	table.start()
+	if _, err := table.get(started[1].name); status.Code(err) != codes.NotFound {
+		t.Errorf("expired operation: got %v, want NotFound", err)
+	}
+	if _, err := table.get(started[3].name); err != nil {
+		t.Errorf("running operation: %v", err)
+	}
+	if got, _ := table.list(0, 100); len(got) != 3 {
+		t.Errorf("got %d operations, want 3 (running)", len(got))
+	}
+}
@@ -0,0 +1,69 @@
+package signature
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	regpb "github.com/protopkg/apis/protopkg/registry/v1alpha1"
+	"golang.org/x/crypto/ssh"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// ReadEnvelopes reads a signatures file (a json SignatureEnvelopeList).  A
+// missing file is treated as an empty list.
+func ReadEnvelopes(filename string) (*regpb.SignatureEnvelopeList, error) {
+	var list regpb.SignatureEnvelopeList
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return &list, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading signatures: %w", err)
+	}
+	if err := protojson.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("unmarshaling signatures %s: %w", filename, err)
+	}
+	return &list, nil
+}
+
+// WriteEnvelopes writes a signatures file.
+func WriteEnvelopes(list *regpb.SignatureEnvelopeList, filename string) error {
+	data, err := protojson.MarshalOptions{Multiline: true}.Marshal(list)
+	if err != nil {
+		return fmt.Errorf("marshaling signatures: %w", err)
+	}
+	if err := os.WriteFile(filename, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("writing signatures: %w", err)
+	}
+	return nil
+}
+
+// GenerateKey creates a new ed25519 private key in PKCS#8 PEM form.  The
+// file must not exist.
+func GenerateKey(filename string) (ssh.PublicKey, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generating key: %w", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling key: %w", err)
+	}
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("creating key file: %w", err)
+	}
+	if err := pem.Encode(f, &pem.Block{Type: "PRIVATE KEY", Bytes: der}); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("writing key file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return nil, fmt.Errorf("writing key file: %w", err)
+	}
+	return ssh.NewPublicKey(pub)
+}
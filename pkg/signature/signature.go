@@ -0,0 +1,128 @@
+// Package signature signs and verifies proto packages with detached DSSE
+// envelopes.  The envelope payload is a Statement naming the package and its
+// hash; the signature is made with an ssh signer, which covers both plain
+// ed25519 keys (PKCS#8 PEM) and ssh signing keys (OpenSSH format).
+package signature
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	regpb "github.com/protopkg/apis/protopkg/registry/v1alpha1"
+	"golang.org/x/crypto/ssh"
+)
+
+// PayloadType is the DSSE payload type of package signature envelopes.
+const PayloadType = "application/vnd.protopkg.signature+json"
+
+// Statement is the signed payload of an envelope.
+type Statement struct {
+	// Name is the package name (e.g. 'google.api').
+	Name string `json:"name"`
+	// Ref is the package reference, in the same form as
+	// ProtoPackage.Dependencies.
+	Ref string `json:"ref"`
+	// Hash is the ProtoPackage hash.
+	Hash string `json:"hash"`
+}
+
+// Signer signs payloads.
+type Signer struct {
+	signer ssh.Signer
+}
+
+// LoadSigner reads an unencrypted private key, either an ed25519 key in
+// PKCS#8 PEM form or an OpenSSH private key.
+func LoadSigner(filename string) (*Signer, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading key file: %w", err)
+	}
+	signer, err := ssh.ParsePrivateKey(data)
+	if err != nil {
+		var missing *ssh.PassphraseMissingError
+		if errors.As(err, &missing) {
+			return nil, fmt.Errorf("%s: encrypted keys are not supported", filename)
+		}
+		return nil, fmt.Errorf("%s: parsing private key: %w", filename, err)
+	}
+	return &Signer{signer: signer}, nil
+}
+
+// KeyID returns the id of the signing key: the ssh SHA256 fingerprint of the
+// public key.
+func (s *Signer) KeyID() string {
+	return KeyID(s.signer.PublicKey())
+}
+
+// PublicKey returns the public key of the signer.
+func (s *Signer) PublicKey() ssh.PublicKey {
+	return s.signer.PublicKey()
+}
+
+// Sign signs the statement, adding the signature to the envelope.  If env is
+// nil a new envelope is created.  An existing signature of the same key is
+// replaced.
+func (s *Signer) Sign(env *regpb.SignatureEnvelope, stmt *Statement) (*regpb.SignatureEnvelope, error) {
+	payload, err := json.Marshal(stmt)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling statement: %w", err)
+	}
+	if env == nil {
+		env = &regpb.SignatureEnvelope{PayloadType: PayloadType, Payload: payload}
+	} else if env.PayloadType != PayloadType || !bytes.Equal(env.Payload, payload) {
+		return nil, fmt.Errorf("%s: existing envelope signs a different statement", stmt.Name)
+	}
+
+	sig, err := s.sign(pae(env.PayloadType, env.Payload))
+	if err != nil {
+		return nil, fmt.Errorf("signing %s: %w", stmt.Name, err)
+	}
+	keyID := s.KeyID()
+	signatures := []*regpb.Signature{{Keyid: keyID, Sig: ssh.Marshal(sig)}}
+	for _, other := range env.Signatures {
+		if other.Keyid != keyID {
+			signatures = append(signatures, other)
+		}
+	}
+	env.Signatures = signatures
+	return env, nil
+}
+
+func (s *Signer) sign(data []byte) (*ssh.Signature, error) {
+	// prefer SHA-256 over the legacy SHA-1 ssh-rsa signatures
+	if as, ok := s.signer.(ssh.AlgorithmSigner); ok && s.signer.PublicKey().Type() == ssh.KeyAlgoRSA {
+		return as.SignWithAlgorithm(rand.Reader, data, ssh.SigAlgoRSASHA2256)
+	}
+	return s.signer.Sign(rand.Reader, data)
+}
+
+// KeyID returns the id of the public key.
+func KeyID(key ssh.PublicKey) string {
+	return ssh.FingerprintSHA256(key)
+}
+
+// ParseStatement returns the statement of the envelope, without verifying
+// the signatures.
+func ParseStatement(env *regpb.SignatureEnvelope) (*Statement, error) {
+	if env.GetPayloadType() != PayloadType {
+		return nil, fmt.Errorf("unsupported payload type %q", env.GetPayloadType())
+	}
+	var stmt Statement
+	if err := json.Unmarshal(env.GetPayload(), &stmt); err != nil {
+		return nil, fmt.Errorf("invalid payload: %w", err)
+	}
+	return &stmt, nil
+}
+
+// pae returns the DSSE pre-authentication encoding of the payload.
+func pae(payloadType string, payload []byte) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "DSSEv1 %d %s %d ", len(payloadType), payloadType, len(payload))
+	b.Write(payload)
+	return b.Bytes()
+}
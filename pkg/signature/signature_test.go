@@ -0,0 +1,165 @@
+package signature
+
+import (
+	"crypto/ed25519"
+	"path/filepath"
+	"testing"
+
+	regpb "github.com/protopkg/apis/protopkg/registry/v1alpha1"
+	"golang.org/x/crypto/ssh"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestPAE(t *testing.T) {
+	// the example of the DSSE protocol specification
+	got := string(pae("http://example.com/HelloWorld", []byte("hello world")))
+	want := "DSSEv1 29 http://example.com/HelloWorld 11 hello world"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// newTestSigner generates an ed25519 key and loads it as a signer.
+func newTestSigner(t *testing.T) *Signer {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "key.pem")
+	pub, err := GenerateKey(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := LoadSigner(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signer.KeyID() != KeyID(pub) {
+		t.Fatalf("signer key %s, generated key %s", signer.KeyID(), KeyID(pub))
+	}
+	return signer
+}
+
+var testStatement = &Statement{
+	Name: "google.api",
+	Ref:  "github.com/googleapis/googleapis/0123456/~:google.api",
+	Hash: "protoreflecthash.v0:0e24bad9",
+}
+
+func TestSignVerify(t *testing.T) {
+	signer := newTestSigner(t)
+	env, err := signer.Sign(nil, testStatement)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the signature is a plain ed25519 signature of the PAE
+	if len(env.Signatures) != 1 {
+		t.Fatalf("got %d signatures, want 1", len(env.Signatures))
+	}
+	var sig ssh.Signature
+	if err := ssh.Unmarshal(env.Signatures[0].Sig, &sig); err != nil {
+		t.Fatal(err)
+	}
+	pub := signer.PublicKey().(ssh.CryptoPublicKey).CryptoPublicKey().(ed25519.PublicKey)
+	if !ed25519.Verify(pub, pae(PayloadType, env.Payload), sig.Blob) {
+		t.Error("ed25519 signature does not verify against the PAE")
+	}
+
+	// the envelope survives the signatures file
+	filename := filepath.Join(t.TempDir(), "signatures.json")
+	if err := WriteEnvelopes(&regpb.SignatureEnvelopeList{Envelopes: []*regpb.SignatureEnvelope{env}}, filename); err != nil {
+		t.Fatal(err)
+	}
+	list, err := ReadEnvelopes(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Envelopes) != 1 || !proto.Equal(list.Envelopes[0], env) {
+		t.Fatalf("read %v, want %v", list.Envelopes, env)
+	}
+
+	trusted := TrustedKeys{signer.KeyID(): {Principal: "release@example.com", Key: signer.PublicKey()}}
+	stmt, signers, err := trusted.Verify(list.Envelopes[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *stmt != *testStatement {
+		t.Errorf("statement: got %+v, want %+v", stmt, testStatement)
+	}
+	if len(signers) != 1 || signers[0].Principal != "release@example.com" {
+		t.Errorf("signers: got %v", signers)
+	}
+}
+
+func TestVerifyTampered(t *testing.T) {
+	signer := newTestSigner(t)
+	other := newTestSigner(t)
+	trusted := TrustedKeys{signer.KeyID(): {Principal: "release@example.com", Key: signer.PublicKey()}}
+
+	for _, tc := range []struct {
+		name   string
+		tamper func(env *regpb.SignatureEnvelope)
+	}{
+		{
+			name: "payload",
+			tamper: func(env *regpb.SignatureEnvelope) {
+				env.Payload = []byte(`{"name":"google.api","ref":"github.com/googleapis/googleapis/0123456/~:google.api","hash":"protoreflecthash.v0:ffffffff"}`)
+			},
+		},
+		{
+			name: "payload type",
+			tamper: func(env *regpb.SignatureEnvelope) {
+				env.PayloadType = "application/json"
+			},
+		},
+		{
+			name: "signature",
+			tamper: func(env *regpb.SignatureEnvelope) {
+				var sig ssh.Signature
+				if err := ssh.Unmarshal(env.Signatures[0].Sig, &sig); err != nil {
+					t.Fatal(err)
+				}
+				sig.Blob[0] ^= 0xff
+				env.Signatures[0].Sig = ssh.Marshal(&sig)
+			},
+		},
+		{
+			name: "untrusted key",
+			tamper: func(env *regpb.SignatureEnvelope) {
+				signed, err := other.Sign(nil, testStatement)
+				if err != nil {
+					t.Fatal(err)
+				}
+				env.Signatures = signed.Signatures
+			},
+		},
+		{
+			name: "signature of another key",
+			tamper: func(env *regpb.SignatureEnvelope) {
+				signed, err := other.Sign(nil, testStatement)
+				if err != nil {
+					t.Fatal(err)
+				}
+				env.Signatures[0].Sig = signed.Signatures[0].Sig
+			},
+		},
+		{
+			name: "no signatures",
+			tamper: func(env *regpb.SignatureEnvelope) {
+				env.Signatures = nil
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			env, err := signer.Sign(nil, testStatement)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := trusted.Verify(env); err != nil {
+				t.Fatalf("untampered envelope: %v", err)
+			}
+			tc.tamper(env)
+			if _, signers, err := trusted.Verify(env); err == nil {
+				t.Errorf("tampered envelope verified (signers %v)", signers)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package signature
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	regpb "github.com/protopkg/apis/protopkg/registry/v1alpha1"
+	"golang.org/x/crypto/ssh"
+)
+
+// TrustedKey is a public key that is trusted to sign packages.
+type TrustedKey struct {
+	// Principal names the owner of the key (e.g. 'release@example.com').
+	Principal string
+	Key       ssh.PublicKey
+}
+
+// TrustedKeys is a set of trusted keys, by key id.
+type TrustedKeys map[string]*TrustedKey
+
+// ReadTrustedKeys reads a trusted keys file.  Each line names a principal
+// and its public key in authorized_keys form, similar to the ssh
+// allowed_signers file:
+//
+//	release@example.com ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAA... comment
+//
+// Empty lines and lines starting with '#' are ignored.
+func ReadTrustedKeys(filename string) (TrustedKeys, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading trusted keys: %w", err)
+	}
+	keys := make(TrustedKeys)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("%s:%d: expected '<principal> <key-type> <key>'", filename, lineno)
+		}
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(strings.Join(fields[1:], " ")))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", filename, lineno, err)
+		}
+		keys[KeyID(key)] = &TrustedKey{Principal: fields[0], Key: key}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading trusted keys: %w", err)
+	}
+	return keys, nil
+}
+
+// FormatTrustedKey returns the trusted keys file line for the key.
+func FormatTrustedKey(principal string, key ssh.PublicKey) string {
+	return principal + " " + strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))
+}
+
+// Verify checks that the envelope carries at least one valid signature by a
+// trusted key.  It returns the signed statement and the trusted keys that
+// signed it.
+func (keys TrustedKeys) Verify(env *regpb.SignatureEnvelope) (*Statement, []*TrustedKey, error) {
+	stmt, err := ParseStatement(env)
+	if err != nil {
+		return nil, nil, err
+	}
+	data := pae(env.PayloadType, env.Payload)
+
+	var signers []*TrustedKey
+	var problems []string
+	for _, s := range env.Signatures {
+		trusted, ok := keys[s.Keyid]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("key %s is not trusted", s.Keyid))
+			continue
+		}
+		var sig ssh.Signature
+		if err := ssh.Unmarshal(s.Sig, &sig); err != nil {
+			problems = append(problems, fmt.Sprintf("key %s: malformed signature: %v", s.Keyid, err))
+			continue
+		}
+		if err := trusted.Key.Verify(data, &sig); err != nil {
+			problems = append(problems, fmt.Sprintf("key %s (%s): invalid signature", s.Keyid, trusted.Principal))
+			continue
+		}
+		signers = append(signers, trusted)
+	}
+	if len(signers) == 0 {
+		if len(problems) == 0 {
+			return stmt, nil, errors.New("no signatures")
+		}
+		return stmt, nil, errors.New(strings.Join(problems, "; "))
+	}
+	return stmt, signers, nil
+}
@@ -0,0 +1,392 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	regpb "github.com/protopkg/apis/protopkg/registry/v1alpha1"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	"google.golang.org/protobuf/proto"
+)
+
+// subdirectories of the FS store.
+const (
+	objectsDir    = "objects"
+	refsDir       = "refs"
+	signaturesDir = "signatures"
+	tmpDir        = "tmp"
+	lostDir       = "lost"
+)
+
+// fileExt is the extension of the files of the FS store.
+const fileExt = ".pb"
+
+// objectAlgorithm is the digest algorithm of the objects.
+const objectAlgorithm = "sha256"
+
+var objectDigestPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// FS is a Store that keeps the packages in a directory, in a
+// content-addressed layout:
+//
+//	objects/sha256/<xx>/<digest>.pb    a file of a package, by the sha256 of its encoding
+//	refs/<xx>/<sha256 of ref>.pb       the package without file contents, by ref
+//	signatures/<xx>/<sha256 of id>.pb  the signature envelopes, by package ID
+//	tmp/                               files being written
+//
+// The files of a ref record the digest of their object in their file_url
+// ('sha256:<digest>').  Objects are addressed by their exact content, not by
+// the package hash: the hash does not cover the source code, nor (for wire
+// hashes) the syntax and edition of the descriptors, so two refs with the
+// same package hash may have different files.
+//
+// Every file is written to tmp and atomically renamed into place, and the
+// objects are written before the refs that use them.  After a crash the store
+// is therefore consistent, except for temporary files (removed by OpenFS) and
+// objects that no ref uses (reported, and removed on repair, by Check).
+// Files that are already stored are not written again.
+//
+// The refs are indexed in memory when the store is opened.
+type FS struct {
+	dir string
+
+	mu    sync.RWMutex
+	index *index
+}
+
+// OpenFS opens (or creates) the store in the given directory.  Temporary
+// files left by an interrupted write are removed, and unreadable refs are
+// skipped (see Check).
+func OpenFS(dir string) (*FS, error) {
+	for _, sub := range []string{objectsDir, refsDir, signaturesDir, tmpDir} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			return nil, fmt.Errorf("creating store: %w", err)
+		}
+	}
+	s := &FS{dir: dir}
+	if _, err := s.removeTemporaryFiles(); err != nil {
+		return nil, err
+	}
+	index, problems, err := s.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+	for _, problem := range problems {
+		log.Printf("store: skipped %s: %s", problem.Path, problem.Description)
+	}
+	s.index = index
+	return s, nil
+}
+
+// Dir returns the directory of the store.
+func (s *FS) Dir() string {
+	return s.dir
+}
+
+// Put implements Store.
+func (s *FS) Put(ctx context.Context, pkg *pppb.ProtoPackage) (bool, error) {
+	ref := Ref(pkg)
+	hdr := makeHeader(pkg)
+	objects := make([][]byte, len(pkg.Files))
+	for i, file := range pkg.Files {
+		data, err := proto.MarshalOptions{Deterministic: true}.Marshal(file)
+		if err != nil {
+			return false, fmt.Errorf("%s: marshaling %s: %w", ref, file.GetFile().GetName(), err)
+		}
+		objects[i] = data
+		hdr.Files[i].FileUrl = objectDigest(data)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if other, ok := s.index.lookup(ref); ok {
+		if other.Hash != pkg.Hash {
+			return false, fmt.Errorf("%s: %w (stored: %s)", ref, ErrConflict, other.Hash)
+		}
+		return false, nil
+	}
+
+	for i, data := range objects {
+		path, err := s.objectPath(hdr.Files[i].FileUrl)
+		if err != nil {
+			return false, err
+		}
+		if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+			if err := s.writeData(path, data); err != nil {
+				return false, err
+			}
+		} else if err != nil {
+			return false, fmt.Errorf("%s: %w", ref, err)
+		}
+	}
+
+	if err := s.writeFile(s.refPath(ref), hdr); err != nil {
+		return false, err
+	}
+	s.index.add(hdr)
+	return true, nil
+}
+
+// Get implements Store.
+func (s *FS) Get(ctx context.Context, name, hash string) (*pppb.ProtoPackage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id := ID(name, hash)
+	hdr, ok := s.index.latest(id)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", id, ErrNotFound)
+	}
+	return s.readPackage(hdr)
+}
+
+// Resolve implements Store.
+func (s *FS) Resolve(ctx context.Context, ref string) (*pppb.ProtoPackage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	hdr, ok := s.index.lookup(ref)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", ref, ErrNotFound)
+	}
+	return s.readPackage(hdr)
+}
+
+// List implements Store.
+func (s *FS) List(ctx context.Context, filter *Filter) ([]*pppb.ProtoPackage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	hdrs := cloneHeaders(s.index.list(filter))
+	for _, hdr := range hdrs {
+		for _, file := range hdr.Files {
+			file.FileUrl = ""
+		}
+	}
+	return hdrs, nil
+}
+
+// PutSignatures implements Store.
+func (s *FS) PutSignatures(ctx context.Context, name, hash string, envs ...*regpb.SignatureEnvelope) error {
+	path := s.signaturesPath(ID(name, hash))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list, err := s.readSignatures(path)
+	if err != nil {
+		return err
+	}
+	list.Envelopes = mergeEnvelopes(list.Envelopes, envs...)
+	return s.writeFile(path, list)
+}
+
+// Signatures implements Store.
+func (s *FS) Signatures(ctx context.Context, name, hash string) ([]*regpb.SignatureEnvelope, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list, err := s.readSignatures(s.signaturesPath(ID(name, hash)))
+	if err != nil {
+		return nil, err
+	}
+	return list.Envelopes, nil
+}
+
+// Close implements Store.
+func (s *FS) Close() error {
+	return nil
+}
+
+// readPackage returns the header with the files of its objects.
+func (s *FS) readPackage(hdr *pppb.ProtoPackage) (*pppb.ProtoPackage, error) {
+	files := make([]*pppb.ProtoFile, len(hdr.Files))
+	for i, file := range hdr.Files {
+		path, err := s.objectPath(file.FileUrl)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", Ref(hdr), err)
+		}
+		var object pppb.ProtoFile
+		if err := readFile(path, &object); err != nil {
+			return nil, fmt.Errorf("%s: %w", Ref(hdr), err)
+		}
+		files[i] = &object
+	}
+	return withFiles(hdr, files), nil
+}
+
+func (s *FS) readSignatures(path string) (*regpb.SignatureEnvelopeList, error) {
+	var list regpb.SignatureEnvelopeList
+	if err := readFile(path, &list); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// loadIndex indexes the refs of the store.  Refs that cannot be read, or are
+// not stored at the path of their ref, are returned as problems.
+func (s *FS) loadIndex() (*index, []*Problem, error) {
+	index := newIndex()
+	var problems []*Problem
+	err := walkFiles(filepath.Join(s.dir, refsDir), func(path string) error {
+		var hdr pppb.ProtoPackage
+		if err := readFile(path, &hdr); err != nil {
+			problems = append(problems, &Problem{Path: s.rel(path), Description: err.Error()})
+			return nil
+		}
+		ref := Ref(&hdr)
+		if s.refPath(ref) != path {
+			problems = append(problems, &Problem{Path: s.rel(path), Description: fmt.Sprintf("ref %s is not stored at its path", ref)})
+			return nil
+		}
+		index.add(&hdr)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading refs: %w", err)
+	}
+	return index, problems, nil
+}
+
+// removeTemporaryFiles removes the files of interrupted writes, and returns
+// their paths.
+func (s *FS) removeTemporaryFiles() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(s.dir, tmpDir))
+	if err != nil {
+		return nil, fmt.Errorf("reading temporary files: %w", err)
+	}
+	var removed []string
+	for _, entry := range entries {
+		path := filepath.Join(s.dir, tmpDir, entry.Name())
+		if err := os.RemoveAll(path); err != nil {
+			return nil, fmt.Errorf("removing temporary file: %w", err)
+		}
+		removed = append(removed, s.rel(path))
+	}
+	return removed, nil
+}
+
+// writeFile atomically writes the message to the path.
+func (s *FS) writeFile(path string, msg proto.Message) error {
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("marshaling %s: %w", s.rel(path), err)
+	}
+	return s.writeData(path, data)
+}
+
+// writeData atomically writes the data to the path.
+func (s *FS) writeData(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Join(s.dir, tmpDir), "write-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing %s: %w", s.rel(path), err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing %s: %w", s.rel(path), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing %s: %w", s.rel(path), err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("writing %s: %w", s.rel(path), err)
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// objectDigest returns the digest that addresses the encoded file in the
+// objects.
+func objectDigest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return objectAlgorithm + ":" + hex.EncodeToString(sum[:])
+}
+
+// objectPath returns the path of the object with the digest.
+func (s *FS) objectPath(objectDigest string) (string, error) {
+	algorithm, digest, ok := strings.Cut(objectDigest, ":")
+	if !ok || algorithm != objectAlgorithm || !objectDigestPattern.MatchString(digest) {
+		return "", fmt.Errorf("invalid object digest: %q", objectDigest)
+	}
+	return filepath.Join(s.dir, objectsDir, algorithm, digest[:2], digest+fileExt), nil
+}
+
+func (s *FS) refPath(ref string) string {
+	return s.keyPath(refsDir, ref)
+}
+
+func (s *FS) signaturesPath(id string) string {
+	return s.keyPath(signaturesDir, id)
+}
+
+// keyPath returns the path of a key in the subdirectory, by the sha256 of the
+// key.
+func (s *FS) keyPath(sub, key string) string {
+	sum := sha256.Sum256([]byte(key))
+	digest := hex.EncodeToString(sum[:])
+	return filepath.Join(s.dir, sub, digest[:2], digest+fileExt)
+}
+
+// rel returns the path relative to the store directory.
+func (s *FS) rel(path string) string {
+	if rel, err := filepath.Rel(s.dir, path); err == nil {
+		return rel
+	}
+	return path
+}
+
+func readFile(path string, msg proto.Message) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return fmt.Errorf("unmarshaling %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// walkFiles calls fn for each regular file under the directory.
+func walkFiles(dir string, fn func(path string) error) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			return fn(path)
+		}
+		return nil
+	})
+}
+
+// syncDir flushes the directory entries, such that a rename survives a
+// crash.
+func syncDir(dir string) error {
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("syncing %s: %w", dir, err)
+	}
+	return nil
+}
@@ -0,0 +1,350 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/protopkg/apis/pkg/protohash"
+	regpb "github.com/protopkg/apis/protopkg/registry/v1alpha1"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// newTestPackage returns a package with a single file, at the given commit.
+func newTestPackage(t *testing.T, name, commit string) *pppb.ProtoPackage {
+	t.Helper()
+	files := []*pppb.ProtoFile{{
+		File: &descriptorpb.FileDescriptorProto{
+			Name:    proto.String(strings.ReplaceAll(name, ".", "/") + "/test.proto"),
+			Package: proto.String(name),
+			Syntax:  proto.String("proto3"),
+			MessageType: []*descriptorpb.DescriptorProto{{
+				Name: proto.String("Message"),
+			}},
+		},
+	}}
+	fileHash, err := protohash.File(files[0].File, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files[0].Hash = fileHash
+	hash, err := protohash.Package(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &pppb.ProtoPackage{
+		Name: name,
+		Archive: &pppb.ProtoArchive{
+			Repository: &pppb.ProtoRepository{
+				Host:     "github.com",
+				Owner:    "example",
+				Name:     "protos",
+				FullName: "github.com/example/protos",
+			},
+			CommitSha1: commit,
+			ShortSha1:  commit[:7],
+		},
+		Files: files,
+		Hash:  hash,
+	}
+}
+
+func openTestFS(t *testing.T, dir string) *FS {
+	t.Helper()
+	s, err := OpenFS(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func putTestPackage(t *testing.T, s *FS, pkg *pppb.ProtoPackage) {
+	t.Helper()
+	if _, err := s.Put(context.Background(), pkg); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testObjectPath returns the path of the object of the file.
+func testObjectPath(t *testing.T, s *FS, file *pppb.ProtoFile) string {
+	t.Helper()
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path, err := s.objectPath(objectDigest(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const (
+	commit1 = "1111111111111111111111111111111111111111"
+	commit2 = "2222222222222222222222222222222222222222"
+)
+
+func TestFSReopen(t *testing.T) {
+	dir := t.TempDir()
+	s := openTestFS(t, dir)
+	pkg := newTestPackage(t, "example.v1", commit1)
+	putTestPackage(t, s, pkg)
+	// the same files under another ref share the object
+	other := proto.Clone(pkg).(*pppb.ProtoPackage)
+	other.Archive.CommitSha1 = commit2
+	other.Archive.ShortSha1 = commit2[:7]
+	putTestPackage(t, s, other)
+
+	s = openTestFS(t, dir)
+	for _, want := range []*pppb.ProtoPackage{pkg, other} {
+		got, err := s.Resolve(context.Background(), Ref(want))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !proto.Equal(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+	problems, err := s.Check(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 0 {
+		t.Errorf("unexpected problems: %v", problems)
+	}
+}
+
+func TestOpenFSRemovesTemporaryFiles(t *testing.T) {
+	dir := t.TempDir()
+	s := openTestFS(t, dir)
+	pkg := newTestPackage(t, "example.v1", commit1)
+	putTestPackage(t, s, pkg)
+
+	// a write interrupted before its rename
+	tmp := filepath.Join(dir, tmpDir, "write-123")
+	if err := os.WriteFile(tmp, []byte("partial"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	removed, err := s.removeTemporaryFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 1 || removed[0] != filepath.Join(tmpDir, "write-123") {
+		t.Errorf("removed %v, want [%s]", removed, filepath.Join(tmpDir, "write-123"))
+	}
+
+	if err := os.WriteFile(tmp, []byte("partial"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s = openTestFS(t, dir)
+	if _, err := os.Stat(tmp); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("temporary file was not removed: %v", err)
+	}
+	if _, err := s.Resolve(context.Background(), Ref(pkg)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLoadIndexProblems(t *testing.T) {
+	dir := t.TempDir()
+	s := openTestFS(t, dir)
+	good := newTestPackage(t, "example.v1", commit1)
+	moved := newTestPackage(t, "example.v2", commit1)
+	putTestPackage(t, s, good)
+	putTestPackage(t, s, moved)
+
+	// a truncated ref, and a ref stored at the path of another ref
+	corrupt := s.refPath("github.com/example/protos/1111111/~:example.v3")
+	if err := os.MkdirAll(filepath.Dir(corrupt), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(corrupt, []byte{0xff}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	misplaced := s.refPath("github.com/example/protos/1111111/~:example.v4")
+	if err := os.MkdirAll(filepath.Dir(misplaced), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(s.refPath(Ref(moved)), misplaced); err != nil {
+		t.Fatal(err)
+	}
+
+	index, problems, err := s.loadIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string)
+	for _, problem := range problems {
+		got[problem.Path] = problem.Description
+	}
+	if len(got) != 2 {
+		t.Fatalf("got problems %v, want 2", problems)
+	}
+	if _, ok := got[s.rel(corrupt)]; !ok {
+		t.Errorf("corrupt ref %s was not reported: %v", s.rel(corrupt), problems)
+	}
+	if desc := got[s.rel(misplaced)]; !strings.Contains(desc, "not stored at its path") {
+		t.Errorf("misplaced ref %s: got %q", s.rel(misplaced), desc)
+	}
+	if _, ok := index.lookup(Ref(good)); !ok {
+		t.Errorf("%s is not indexed", Ref(good))
+	}
+	if _, ok := index.lookup(Ref(moved)); ok {
+		t.Errorf("misplaced %s is indexed", Ref(moved))
+	}
+
+	// the store still opens, without the skipped refs
+	s = openTestFS(t, dir)
+	if _, err := s.Resolve(context.Background(), Ref(moved)); !errors.Is(err, ErrNotFound) {
+		t.Errorf("resolving %s: got %v, want ErrNotFound", Ref(moved), err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	s := openTestFS(t, dir)
+	ctx := context.Background()
+
+	good := newTestPackage(t, "example.good", commit1)
+	putTestPackage(t, s, good)
+	if err := s.PutSignatures(ctx, good.Name, good.Hash, &regpb.SignatureEnvelope{PayloadType: "test", Payload: []byte("{}")}); err != nil {
+		t.Fatal(err)
+	}
+
+	// a Put interrupted after writing the object, before the ref
+	unused := newTestPackage(t, "example.unused", commit1)
+	unusedPath := testObjectPath(t, s, unused.Files[0])
+	if err := s.writeFile(unusedPath, unused.Files[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	// an object whose file was modified, and the ref that uses it
+	tampered := newTestPackage(t, "example.tampered", commit1)
+	putTestPackage(t, s, tampered)
+	tamperedPath := testObjectPath(t, s, tampered.Files[0])
+	modified := proto.Clone(tampered.Files[0]).(*pppb.ProtoFile)
+	modified.File.MessageType[0].Name = proto.String("Modified")
+	if err := s.writeFile(tamperedPath, modified); err != nil {
+		t.Fatal(err)
+	}
+
+	// a ref whose object is missing
+	missing := newTestPackage(t, "example.missing", commit1)
+	putTestPackage(t, s, missing)
+	if err := os.Remove(testObjectPath(t, s, missing.Files[0])); err != nil {
+		t.Fatal(err)
+	}
+
+	// a ref whose files do not hash to its package hash: its object is only
+	// used by the ref
+	mislabeled := newTestPackage(t, "example.mislabeled", commit1)
+	mislabeled.Hash = good.Hash
+	putTestPackage(t, s, mislabeled)
+	mislabeledPath := testObjectPath(t, s, mislabeled.Files[0])
+
+	// an unreadable signatures file, and a temporary file
+	badSignatures := s.signaturesPath(ID(missing.Name, missing.Hash))
+	if err := os.MkdirAll(filepath.Dir(badSignatures), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(badSignatures, []byte{0xff}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	tmp := filepath.Join(dir, tmpDir, "write-123")
+	if err := os.WriteFile(tmp, []byte("partial"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		s.rel(unusedPath):                  "object is not used by any ref",
+		s.rel(tamperedPath):                "object has digest",
+		s.rel(s.refPath(Ref(tampered))):    "is invalid",
+		s.rel(s.refPath(Ref(missing))):     "is missing",
+		s.rel(s.refPath(Ref(mislabeled))):  "hash mismatch",
+		s.rel(mislabeledPath):              "object is not used by any ref",
+		s.rel(badSignatures):               "unmarshaling",
+		filepath.Join(tmpDir, "write-123"): "temporary file",
+	}
+	checkProblems := func(problems []*Problem, repaired bool) {
+		t.Helper()
+		if len(problems) != len(want) {
+			t.Errorf("got %d problems, want %d: %v", len(problems), len(want), problems)
+		}
+		for _, problem := range problems {
+			desc, ok := want[problem.Path]
+			if !ok {
+				t.Errorf("unexpected problem: %v", problem)
+				continue
+			}
+			if !strings.Contains(problem.Description, desc) {
+				t.Errorf("%s: got %q, want %q", problem.Path, problem.Description, desc)
+			}
+			if problem.Repaired != repaired {
+				t.Errorf("%s: repaired %v, want %v", problem.Path, problem.Repaired, repaired)
+			}
+		}
+	}
+
+	problems, err := s.Check(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkProblems(problems, false)
+	if _, err := os.Stat(unusedPath); err != nil {
+		t.Errorf("check without repair removed the unused object: %v", err)
+	}
+
+	problems, err = s.Check(ctx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkProblems(problems, true)
+
+	// invalid files are kept in the lost directory, unused objects and
+	// temporary files are removed
+	for _, path := range []string{tamperedPath, s.refPath(Ref(tampered)), s.refPath(Ref(missing)), s.refPath(Ref(mislabeled)), badSignatures} {
+		if _, err := os.Stat(filepath.Join(dir, lostDir, s.rel(path))); err != nil {
+			t.Errorf("%s was not moved to %s: %v", s.rel(path), lostDir, err)
+		}
+	}
+	for _, path := range []string{unusedPath, mislabeledPath, tmp} {
+		if _, err := os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("%s was not removed: %v", s.rel(path), err)
+		}
+	}
+
+	// the index was rebuilt: only the good package remains
+	for _, pkg := range []*pppb.ProtoPackage{tampered, missing, mislabeled} {
+		if _, err := s.Resolve(ctx, Ref(pkg)); !errors.Is(err, ErrNotFound) {
+			t.Errorf("resolving %s: got %v, want ErrNotFound", Ref(pkg), err)
+		}
+	}
+	got, err := s.Resolve(ctx, Ref(good))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(got, good) {
+		t.Errorf("got %v, want %v", got, good)
+	}
+	envs, err := s.Signatures(ctx, good.Name, good.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(envs) != 1 {
+		t.Errorf("got %d signature envelopes, want 1", len(envs))
+	}
+
+	problems, err = s.Check(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 0 {
+		t.Errorf("problems after repair: %v", problems)
+	}
+}
@@ -0,0 +1,210 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/protopkg/apis/pkg/protohash"
+	regpb "github.com/protopkg/apis/protopkg/registry/v1alpha1"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	"google.golang.org/protobuf/proto"
+)
+
+// Problem is an inconsistency of an FS store.
+type Problem struct {
+	// Path is the path of the file, relative to the store directory.
+	Path string
+	// Description describes the problem.
+	Description string
+	// Repaired reports whether the problem was repaired.
+	Repaired bool
+}
+
+func (p *Problem) String() string {
+	if p.Repaired {
+		return fmt.Sprintf("%s: %s (repaired)", p.Path, p.Description)
+	}
+	return fmt.Sprintf("%s: %s", p.Path, p.Description)
+}
+
+// Check verifies the consistency of the store, and returns the problems
+// found:
+//
+//   - objects that cannot be read, or whose content does not match the digest
+//     of their path;
+//   - refs that cannot be read, are not stored at the path of their ref,
+//     whose objects are missing or invalid, or whose files do not hash to the
+//     package hash (see protohash.Verify);
+//   - objects that no ref uses (left by an interrupted Put);
+//   - signature files that cannot be read;
+//   - temporary files.
+//
+// With repair, invalid objects and refs are moved to the 'lost' directory of
+// the store, unused objects and temporary files are removed, and the index
+// is rebuilt.
+func (s *FS) Check(ctx context.Context, repair bool) ([]*Problem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var problems []*Problem
+	report := func(path, description string, fix func() error) error {
+		problem := &Problem{Path: s.rel(path), Description: description}
+		problems = append(problems, problem)
+		if repair && fix != nil {
+			if err := fix(); err != nil {
+				return fmt.Errorf("repairing %s: %w", problem.Path, err)
+			}
+			problem.Repaired = true
+		}
+		return nil
+	}
+	lose := func(path string) func() error {
+		return func() error { return s.moveToLost(path) }
+	}
+
+	// objects: by path, whether the object is valid.
+	objects := make(map[string]bool)
+	err := walkFiles(filepath.Join(s.dir, objectsDir), func(path string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		valid, description := s.checkObject(path)
+		objects[path] = valid
+		if !valid {
+			return report(path, description, lose(path))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("checking objects: %w", err)
+	}
+
+	used := make(map[string]bool)
+	err = walkFiles(filepath.Join(s.dir, refsDir), func(path string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var hdr pppb.ProtoPackage
+		if err := readFile(path, &hdr); err != nil {
+			return report(path, err.Error(), lose(path))
+		}
+		ref := Ref(&hdr)
+		if s.refPath(ref) != path {
+			return report(path, fmt.Sprintf("ref %s is not stored at its path", ref), lose(path))
+		}
+		var objectPaths []string
+		for _, file := range hdr.Files {
+			objectPath, err := s.objectPath(file.FileUrl)
+			if err != nil {
+				return report(path, fmt.Sprintf("ref %s: %v", ref, err), lose(path))
+			}
+			valid, ok := objects[objectPath]
+			if !ok {
+				return report(path, fmt.Sprintf("ref %s: object %s is missing", ref, s.rel(objectPath)), lose(path))
+			}
+			if !valid {
+				return report(path, fmt.Sprintf("ref %s: object %s is invalid", ref, s.rel(objectPath)), lose(path))
+			}
+			objectPaths = append(objectPaths, objectPath)
+		}
+		pkg, err := s.readPackage(&hdr)
+		if err != nil {
+			return report(path, err.Error(), lose(path))
+		}
+		if err := protohash.Verify(pkg); err != nil {
+			return report(path, fmt.Sprintf("ref %s: %v", ref, err), lose(path))
+		}
+		for _, objectPath := range objectPaths {
+			used[objectPath] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("checking refs: %w", err)
+	}
+
+	for path, valid := range objects {
+		if valid && !used[path] {
+			if err := report(path, "object is not used by any ref", func() error { return os.Remove(path) }); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	err = walkFiles(filepath.Join(s.dir, signaturesDir), func(path string) error {
+		var list regpb.SignatureEnvelopeList
+		if err := readFile(path, &list); err != nil {
+			return report(path, err.Error(), lose(path))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("checking signatures: %w", err)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(s.dir, tmpDir))
+	if err != nil {
+		return nil, fmt.Errorf("checking temporary files: %w", err)
+	}
+	for _, entry := range entries {
+		path := filepath.Join(s.dir, tmpDir, entry.Name())
+		if err := report(path, "temporary file", func() error { return os.RemoveAll(path) }); err != nil {
+			return nil, err
+		}
+	}
+
+	if repair {
+		index, _, err := s.loadIndex()
+		if err != nil {
+			return nil, err
+		}
+		s.index = index
+	}
+
+	sortProblems(problems)
+	return problems, nil
+}
+
+// checkObject reports whether the object at the path is valid, and if not,
+// why.
+func (s *FS) checkObject(path string) (bool, string) {
+	rel, _ := filepath.Rel(filepath.Join(s.dir, objectsDir), path)
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	if len(parts) != 3 || !strings.HasSuffix(parts[2], fileExt) {
+		return false, "unexpected file"
+	}
+	want := parts[0] + ":" + strings.TrimSuffix(parts[2], fileExt)
+	if wantPath, err := s.objectPath(want); err != nil || wantPath != path {
+		return false, "unexpected file"
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err.Error()
+	}
+	if digest := objectDigest(data); digest != want {
+		return false, fmt.Sprintf("object has digest %s", digest)
+	}
+	var file pppb.ProtoFile
+	if err := proto.Unmarshal(data, &file); err != nil {
+		return false, fmt.Sprintf("unmarshaling %s: %v", filepath.Base(path), err)
+	}
+	return true, ""
+}
+
+// moveToLost moves the file to the lost directory, keeping its path.
+func (s *FS) moveToLost(path string) error {
+	dst := filepath.Join(s.dir, lostDir, s.rel(path))
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	return os.Rename(path, dst)
+}
+
+func sortProblems(problems []*Problem) {
+	sort.Slice(problems, func(i, j int) bool { return problems[i].Path < problems[j].Path })
+}
@@ -0,0 +1,312 @@
+package store
+
+import (
+	"sort"
+
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// index holds the stored refs in memory, and indexes them by ID, name, hash,
+// repository, commit, file name and symbol.  It is not safe for concurrent
+// use.
+type index struct {
+	// refs maps each ref to the header of the package.
+	refs         map[string]*pppb.ProtoPackage
+	byID         map[string][]string
+	byName       map[string][]string
+	byHash       map[string][]string
+	byRepository map[string][]string
+	byCommit     map[string][]string
+	byFile       map[string][]string
+	bySymbol     map[string][]string
+}
+
+func newIndex() *index {
+	return &index{
+		refs:         make(map[string]*pppb.ProtoPackage),
+		byID:         make(map[string][]string),
+		byName:       make(map[string][]string),
+		byHash:       make(map[string][]string),
+		byRepository: make(map[string][]string),
+		byCommit:     make(map[string][]string),
+		byFile:       make(map[string][]string),
+		bySymbol:     make(map[string][]string),
+	}
+}
+
+// add indexes the header of a package.  The ref must not be indexed yet.
+func (x *index) add(hdr *pppb.ProtoPackage) {
+	ref := Ref(hdr)
+	x.refs[ref] = hdr
+	x.byID[ID(hdr.Name, hdr.Hash)] = append(x.byID[ID(hdr.Name, hdr.Hash)], ref)
+	x.byName[hdr.Name] = append(x.byName[hdr.Name], ref)
+	x.byHash[hdr.Hash] = append(x.byHash[hdr.Hash], ref)
+	x.byRepository[hdr.Archive.GetRepository().GetFullName()] = append(x.byRepository[hdr.Archive.GetRepository().GetFullName()], ref)
+	x.byCommit[hdr.Archive.GetCommitSha1()] = append(x.byCommit[hdr.Archive.GetCommitSha1()], ref)
+	if short := hdr.Archive.GetShortSha1(); short != "" && short != hdr.Archive.GetCommitSha1() {
+		x.byCommit[short] = append(x.byCommit[short], ref)
+	}
+	for _, file := range hdr.Files {
+		x.byFile[file.GetFile().GetName()] = append(x.byFile[file.GetFile().GetName()], ref)
+	}
+	seen := make(map[string]bool)
+	for _, symbol := range symbols(hdr) {
+		if !seen[symbol] {
+			seen[symbol] = true
+			x.bySymbol[symbol] = append(x.bySymbol[symbol], ref)
+		}
+	}
+}
+
+// lookup returns the header stored under the ref.
+func (x *index) lookup(ref string) (*pppb.ProtoPackage, bool) {
+	hdr, ok := x.refs[ref]
+	return hdr, ok
+}
+
+// latest returns the header of the most recently committed ref of the
+// package with the given ID.
+func (x *index) latest(id string) (*pppb.ProtoPackage, bool) {
+	hdrs := x.headers(x.byID[id])
+	if len(hdrs) == 0 {
+		return nil, false
+	}
+	return hdrs[0], true
+}
+
+// list returns the headers that match the filter, most recently committed
+// first.
+func (x *index) list(filter *Filter) []*pppb.ProtoPackage {
+	var candidates []string
+	switch {
+	case filter.Name != "":
+		candidates = x.byName[filter.Name]
+	case filter.Hash != "":
+		candidates = x.byHash[filter.Hash]
+	case filter.Symbol != "":
+		candidates = x.bySymbol[filter.Symbol]
+	case filter.File != "":
+		candidates = x.byFile[filter.File]
+	case filter.Commit != "":
+		candidates = x.byCommit[filter.Commit]
+	case filter.Repository != "":
+		candidates = x.byRepository[filter.Repository]
+	default:
+		for ref := range x.refs {
+			candidates = append(candidates, ref)
+		}
+	}
+	var matches []string
+	for _, ref := range candidates {
+		if filter.matches(x.refs[ref]) {
+			matches = append(matches, ref)
+		}
+	}
+	return x.headers(matches)
+}
+
+// headers returns the headers of the refs, most recently committed first.
+func (x *index) headers(refs []string) []*pppb.ProtoPackage {
+	hdrs := make([]*pppb.ProtoPackage, 0, len(refs))
+	for _, ref := range refs {
+		hdrs = append(hdrs, x.refs[ref])
+	}
+	sortByCommitTime(hdrs)
+	return hdrs
+}
+
+// matches reports whether the header is selected by the filter.
+func (f *Filter) matches(hdr *pppb.ProtoPackage) bool {
+	if f.Name != "" && hdr.Name != f.Name {
+		return false
+	}
+	if f.Hash != "" && hdr.Hash != f.Hash {
+		return false
+	}
+	if f.Repository != "" && hdr.Archive.GetRepository().GetFullName() != f.Repository {
+		return false
+	}
+	if f.Commit != "" && hdr.Archive.GetCommitSha1() != f.Commit && hdr.Archive.GetShortSha1() != f.Commit {
+		return false
+	}
+	if f.File != "" {
+		found := false
+		for _, file := range hdr.Files {
+			if file.GetFile().GetName() == f.File {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.Symbol != "" {
+		found := false
+		for _, symbol := range symbols(hdr) {
+			if symbol == f.Symbol {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// sortByCommitTime sorts the packages most recently committed first, then by
+// ref.
+func sortByCommitTime(pkgs []*pppb.ProtoPackage) {
+	sort.SliceStable(pkgs, func(i, j int) bool {
+		ti := pkgs[i].Archive.GetCommitTime().AsTime()
+		tj := pkgs[j].Archive.GetCommitTime().AsTime()
+		if !ti.Equal(tj) {
+			return ti.After(tj)
+		}
+		return Ref(pkgs[i]) < Ref(pkgs[j])
+	})
+}
+
+// makeHeader returns the package without the file contents: the files only
+// have a name, a hash, and the names of their declarations (see skeleton).
+func makeHeader(pkg *pppb.ProtoPackage) *pppb.ProtoPackage {
+	hdr := proto.Clone(pkg).(*pppb.ProtoPackage)
+	hdr.Files = make([]*pppb.ProtoFile, len(pkg.Files))
+	for i, file := range pkg.Files {
+		hdr.Files[i] = &pppb.ProtoFile{
+			File: skeleton(file.GetFile()),
+			Hash: file.Hash,
+		}
+	}
+	return hdr
+}
+
+// skeleton returns the name and package of the file, and the names of its
+// messages, fields, enums, enum values, services, methods and extensions.
+func skeleton(file *descriptorpb.FileDescriptorProto) *descriptorpb.FileDescriptorProto {
+	s := &descriptorpb.FileDescriptorProto{
+		Name:        file.Name,
+		Package:     file.Package,
+		MessageType: skeletonMessages(file.MessageType),
+		EnumType:    skeletonEnums(file.EnumType),
+		Extension:   skeletonFields(file.Extension),
+	}
+	for _, service := range file.Service {
+		ss := &descriptorpb.ServiceDescriptorProto{Name: service.Name}
+		for _, method := range service.Method {
+			ss.Method = append(ss.Method, &descriptorpb.MethodDescriptorProto{Name: method.Name})
+		}
+		s.Service = append(s.Service, ss)
+	}
+	return s
+}
+
+func skeletonMessages(messages []*descriptorpb.DescriptorProto) []*descriptorpb.DescriptorProto {
+	var s []*descriptorpb.DescriptorProto
+	for _, message := range messages {
+		s = append(s, &descriptorpb.DescriptorProto{
+			Name:       message.Name,
+			Field:      skeletonFields(message.Field),
+			NestedType: skeletonMessages(message.NestedType),
+			EnumType:   skeletonEnums(message.EnumType),
+			Extension:  skeletonFields(message.Extension),
+		})
+	}
+	return s
+}
+
+func skeletonEnums(enums []*descriptorpb.EnumDescriptorProto) []*descriptorpb.EnumDescriptorProto {
+	var s []*descriptorpb.EnumDescriptorProto
+	for _, enum := range enums {
+		se := &descriptorpb.EnumDescriptorProto{Name: enum.Name}
+		for _, value := range enum.Value {
+			se.Value = append(se.Value, &descriptorpb.EnumValueDescriptorProto{Name: value.Name})
+		}
+		s = append(s, se)
+	}
+	return s
+}
+
+func skeletonFields(fields []*descriptorpb.FieldDescriptorProto) []*descriptorpb.FieldDescriptorProto {
+	var s []*descriptorpb.FieldDescriptorProto
+	for _, field := range fields {
+		s = append(s, &descriptorpb.FieldDescriptorProto{Name: field.Name})
+	}
+	return s
+}
+
+// symbols returns the fully-qualified names, without leading dot, of the
+// declarations of the files of the package.  As in protobuf, enum values are
+// scoped by the parent of their enum (e.g. 'google.api.REQUIRED').
+func symbols(pkg *pppb.ProtoPackage) []string {
+	var names []string
+	add := func(scope, name string) string {
+		fullName := name
+		if scope != "" {
+			fullName = scope + "." + name
+		}
+		names = append(names, fullName)
+		return fullName
+	}
+	var addEnums func(scope string, enums []*descriptorpb.EnumDescriptorProto)
+	addEnums = func(scope string, enums []*descriptorpb.EnumDescriptorProto) {
+		for _, enum := range enums {
+			add(scope, enum.GetName())
+			for _, value := range enum.Value {
+				add(scope, value.GetName())
+			}
+		}
+	}
+	addFields := func(scope string, fields []*descriptorpb.FieldDescriptorProto) {
+		for _, field := range fields {
+			add(scope, field.GetName())
+		}
+	}
+	var addMessages func(scope string, messages []*descriptorpb.DescriptorProto)
+	addMessages = func(scope string, messages []*descriptorpb.DescriptorProto) {
+		for _, message := range messages {
+			name := add(scope, message.GetName())
+			addFields(name, message.Field)
+			addFields(name, message.Extension)
+			addMessages(name, message.NestedType)
+			addEnums(name, message.EnumType)
+		}
+	}
+	for _, file := range pkg.Files {
+		fd := file.GetFile()
+		scope := fd.GetPackage()
+		addMessages(scope, fd.MessageType)
+		addEnums(scope, fd.EnumType)
+		addFields(scope, fd.Extension)
+		for _, service := range fd.Service {
+			name := add(scope, service.GetName())
+			for _, method := range service.Method {
+				add(name, method.GetName())
+			}
+		}
+	}
+	return names
+}
+
+// withFiles returns a copy of the header with the given files.
+func withFiles(hdr *pppb.ProtoPackage, files []*pppb.ProtoFile) *pppb.ProtoPackage {
+	pkg := proto.Clone(hdr).(*pppb.ProtoPackage)
+	pkg.Files = make([]*pppb.ProtoFile, len(files))
+	for i, file := range files {
+		pkg.Files[i] = proto.Clone(file).(*pppb.ProtoFile)
+	}
+	return pkg
+}
+
+// cloneHeaders returns copies of the headers.
+func cloneHeaders(hdrs []*pppb.ProtoPackage) []*pppb.ProtoPackage {
+	clones := make([]*pppb.ProtoPackage, len(hdrs))
+	for i, hdr := range hdrs {
+		clones[i] = proto.Clone(hdr).(*pppb.ProtoPackage)
+	}
+	return clones
+}
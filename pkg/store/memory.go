@@ -0,0 +1,110 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	regpb "github.com/protopkg/apis/protopkg/registry/v1alpha1"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	"google.golang.org/protobuf/proto"
+)
+
+// Memory is a Store that holds the packages in memory.  It is intended for
+// tests and short-lived servers; the packages are lost when the process
+// exits.
+type Memory struct {
+	mu         sync.RWMutex
+	index      *index
+	files      map[string][]*pppb.ProtoFile // by ref
+	signatures map[string][]*regpb.SignatureEnvelope
+}
+
+// NewMemory returns an empty in-memory store.
+func NewMemory() *Memory {
+	return &Memory{
+		index:      newIndex(),
+		files:      make(map[string][]*pppb.ProtoFile),
+		signatures: make(map[string][]*regpb.SignatureEnvelope),
+	}
+}
+
+// Put implements Store.
+func (m *Memory) Put(ctx context.Context, pkg *pppb.ProtoPackage) (bool, error) {
+	ref := Ref(pkg)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if other, ok := m.index.lookup(ref); ok {
+		if other.Hash != pkg.Hash {
+			return false, fmt.Errorf("%s: %w (stored: %s)", ref, ErrConflict, other.Hash)
+		}
+		return false, nil
+	}
+	// the package hash does not cover the source code: refs with the same
+	// hash keep their own files.
+	m.files[ref] = withFiles(&pppb.ProtoPackage{}, pkg.Files).Files
+	m.index.add(makeHeader(pkg))
+	return true, nil
+}
+
+// Get implements Store.
+func (m *Memory) Get(ctx context.Context, name, hash string) (*pppb.ProtoPackage, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	id := ID(name, hash)
+	hdr, ok := m.index.latest(id)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", id, ErrNotFound)
+	}
+	return withFiles(hdr, m.files[Ref(hdr)]), nil
+}
+
+// Resolve implements Store.
+func (m *Memory) Resolve(ctx context.Context, ref string) (*pppb.ProtoPackage, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	hdr, ok := m.index.lookup(ref)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", ref, ErrNotFound)
+	}
+	return withFiles(hdr, m.files[Ref(hdr)]), nil
+}
+
+// List implements Store.
+func (m *Memory) List(ctx context.Context, filter *Filter) ([]*pppb.ProtoPackage, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return cloneHeaders(m.index.list(filter)), nil
+}
+
+// PutSignatures implements Store.
+func (m *Memory) PutSignatures(ctx context.Context, name, hash string, envs ...*regpb.SignatureEnvelope) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := ID(name, hash)
+	m.signatures[id] = mergeEnvelopes(m.signatures[id], envs...)
+	return nil
+}
+
+// Signatures implements Store.
+func (m *Memory) Signatures(ctx context.Context, name, hash string) ([]*regpb.SignatureEnvelope, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var envs []*regpb.SignatureEnvelope
+	for _, env := range m.signatures[ID(name, hash)] {
+		envs = append(envs, proto.Clone(env).(*regpb.SignatureEnvelope))
+	}
+	return envs, nil
+}
+
+// Close implements Store.
+func (m *Memory) Close() error {
+	return nil
+}
@@ -0,0 +1,151 @@
+// Package store defines the storage of the packages server, and implements
+// an in-memory store and a filesystem store.
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	regpb "github.com/protopkg/apis/protopkg/registry/v1alpha1"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	// ErrNotFound is returned when a package is not in the store.
+	ErrNotFound = errors.New("package not found")
+	// ErrConflict is returned when a different package is already stored
+	// under the same ref.
+	ErrConflict = errors.New("package conflicts with a stored package")
+)
+
+// Store holds proto packages, and the signatures of the packages.
+//
+// A package is identified by its name and hash.  Packages are also addressed
+// by ref (see Ref), the form used by ProtoPackage.Dependencies; the same
+// package may be stored under several refs (e.g. when it did not change
+// between two commits).  Each ref keeps its own archive, compiler,
+// dependencies and files: the package hash does not cover the source code,
+// so the files of two refs with the same hash may differ.
+//
+// Implementations must be safe for concurrent use, and must not retain or
+// modify the messages passed in, nor expect the returned messages to be left
+// unmodified.
+type Store interface {
+	// Put stores the package.  Storing a package that is already stored
+	// under the same ref is a no-op; created reports whether the package was
+	// new.  It returns an error wrapping ErrConflict if a package with a
+	// different hash is stored under the same ref.
+	Put(ctx context.Context, pkg *pppb.ProtoPackage) (created bool, err error)
+	// Get returns the package with the given name and hash, or an error
+	// wrapping ErrNotFound.  If the package is stored under several refs,
+	// the most recently committed one is returned.
+	Get(ctx context.Context, name, hash string) (*pppb.ProtoPackage, error)
+	// Resolve returns the package stored under the ref, or an error wrapping
+	// ErrNotFound.
+	Resolve(ctx context.Context, ref string) (*pppb.ProtoPackage, error)
+	// List returns the packages that match the filter, most recently
+	// committed first.  The returned packages do not have the file contents:
+	// the files only have a name, a hash, and the package and names of their
+	// declarations.
+	List(ctx context.Context, filter *Filter) ([]*pppb.ProtoPackage, error)
+	// PutSignatures stores signature envelopes of the package with the given
+	// name and hash.  Envelopes with the same payload as a stored envelope
+	// are merged into it.  The package does not need to be stored (yet).
+	PutSignatures(ctx context.Context, name, hash string, envs ...*regpb.SignatureEnvelope) error
+	// Signatures returns the signature envelopes of the package with the
+	// given name and hash.
+	Signatures(ctx context.Context, name, hash string) ([]*regpb.SignatureEnvelope, error)
+	// Close releases the resources of the store.
+	Close() error
+}
+
+// Filter selects packages in Store.List.  Empty fields match all packages.
+type Filter struct {
+	// Name is the package name (e.g. 'google.api').
+	Name string
+	// Hash is the package hash.
+	Hash string
+	// Repository is the full name of the archive repository (e.g.
+	// 'github.com/googleapis/googleapis').
+	Repository string
+	// Commit is the full or short commit sha1 of the archive.
+	Commit string
+	// File is the name of a proto file of the package (e.g.
+	// 'google/api/http.proto').
+	File string
+	// Symbol is the fully-qualified name, without leading dot, of a message,
+	// field, enum, enum value, service, method or extension of the package
+	// (e.g. 'google.api.HttpRule').
+	Symbol string
+}
+
+// DefaultPackageName is the name of the package that groups the proto files
+// of an archive that have no package statement (e.g.
+// 'github.com/acme/apis/0123456/~:~default').  The leading '~' cannot occur
+// in a proto package name, so it never collides with a real package.
+const DefaultPackageName = "~default"
+
+// Ref returns the reference of a package in the same form used by
+// ProtoPackage.Dependencies: REPOSITORY/SHORT_SHA1/ROOT:NAME, where ROOT is
+// '~' for the root of the archive and NAME is the proto package name, or
+// DefaultPackageName for the files without one (e.g.
+// 'github.com/googleapis/googleapis/0123456/~:google.api').
+func Ref(pkg *pppb.ProtoPackage) string {
+	root := "~"
+	if pkg.GetArchive().GetRoot() != "" {
+		root = pkg.Archive.Root
+	}
+	return fmt.Sprintf("%s/%s/%s:%s", pkg.GetArchive().GetRepository().GetFullName(), pkg.GetArchive().GetShortSha1(), root, pkg.GetName())
+}
+
+// Key returns the key of a package within a package set: its name, or its
+// ref for the default package, since the files without a package of each
+// archive form a default package of their own.
+func Key(pkg *pppb.ProtoPackage) string {
+	if pkg.GetName() == DefaultPackageName {
+		return Ref(pkg)
+	}
+	return pkg.GetName()
+}
+
+// ID returns the server-side identity of a package (e.g.
+// 'google.api@protoreflecthash.v0:0e24bad9...').
+func ID(name, hash string) string {
+	return name + "@" + hash
+}
+
+// mergeEnvelopes adds the envelopes to the list.  An envelope with the same
+// payload as one in the list contributes the signatures of keys that did not
+// already sign it.
+func mergeEnvelopes(list []*regpb.SignatureEnvelope, envs ...*regpb.SignatureEnvelope) []*regpb.SignatureEnvelope {
+	for _, env := range envs {
+		var existing *regpb.SignatureEnvelope
+		for _, other := range list {
+			if other.PayloadType == env.PayloadType && string(other.Payload) == string(env.Payload) {
+				existing = other
+				break
+			}
+		}
+		if existing == nil {
+			list = append(list, proto.Clone(env).(*regpb.SignatureEnvelope))
+			continue
+		}
+		for _, sig := range env.Signatures {
+			if !hasSignature(existing, sig.Keyid) {
+				existing.Signatures = append(existing.Signatures, proto.Clone(sig).(*regpb.Signature))
+			}
+		}
+	}
+	return list
+}
+
+func hasSignature(env *regpb.SignatureEnvelope, keyid string) bool {
+	for _, sig := range env.Signatures {
+		if sig.Keyid == keyid {
+			return true
+		}
+	}
+	return false
+}
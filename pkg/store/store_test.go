@@ -0,0 +1,75 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestSameHashDifferentSource(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		store func(t *testing.T) Store
+	}{
+		{name: "memory", store: func(t *testing.T) Store { return NewMemory() }},
+		{name: "fs", store: func(t *testing.T) Store { return openTestFS(t, t.TempDir()) }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := tc.store(t)
+			ctx := context.Background()
+
+			// the source code does not contribute to the package hash: a
+			// comment-only change hashes the same
+			first := newTestPackage(t, "example.v1", commit1)
+			first.Files[0].SourceCode = "syntax = \"proto3\";\n"
+			second := newTestPackage(t, "example.v1", commit2)
+			second.Files[0].SourceCode = "// a comment\nsyntax = \"proto3\";\n"
+			second.Archive.CommitTime = timestamppb.New(time.Unix(1700000000, 0))
+			if first.Hash != second.Hash {
+				t.Fatalf("hashes differ: %s, %s", first.Hash, second.Hash)
+			}
+			for _, pkg := range []*pppb.ProtoPackage{first, second} {
+				if _, err := s.Put(ctx, pkg); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			for _, want := range []*pppb.ProtoPackage{first, second} {
+				got, err := s.Resolve(ctx, Ref(want))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !proto.Equal(got, want) {
+					t.Errorf("resolve %s: got source %q, want %q", Ref(want), got.Files[0].SourceCode, want.Files[0].SourceCode)
+				}
+			}
+			// both commits are the same package: Get returns the latest one
+			got, err := s.Get(ctx, second.Name, second.Hash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Files[0].SourceCode != second.Files[0].SourceCode {
+				t.Errorf("get: got source %q, want %q", got.Files[0].SourceCode, second.Files[0].SourceCode)
+			}
+		})
+	}
+}
+
+func TestKey(t *testing.T) {
+	archive := &pppb.ProtoArchive{
+		Repository: &pppb.ProtoRepository{FullName: "github.com/acme/apis"},
+		ShortSha1:  "0123456",
+	}
+	for name, want := range map[string]string{
+		"acme.v1":          "acme.v1",
+		DefaultPackageName: "github.com/acme/apis/0123456/~:~default",
+	} {
+		if got := Key(&pppb.ProtoPackage{Name: name, Archive: archive}); got != want {
+			t.Errorf("Key(%s): got %s, want %s", name, got, want)
+		}
+	}
+}
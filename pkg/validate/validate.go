@@ -0,0 +1,112 @@
+// Package validate checks proto packages before they are stored.  It is used
+// both by the client, before a package set is uploaded, and by the server,
+// on the packages it receives, such that both report the same problems.
+package validate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/protopkg/apis/pkg/protohash"
+	"github.com/protopkg/apis/pkg/store"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+)
+
+// Problem is a problem found in a package.
+type Problem struct {
+	// Package identifies the package: its key (see store.Key), or its
+	// position in the list if it has no name (e.g. 'package #2').
+	Package string
+	// Description describes the problem (e.g. 'package has no files').
+	Description string
+}
+
+func (p *Problem) String() string {
+	return p.Package + ": " + p.Description
+}
+
+// Packages checks a list of packages that are stored together: each package
+// must be valid on its own, and no two packages may have the same key or
+// provide the same file.  It returns the list of problems found.
+func Packages(pkgs []*pppb.ProtoPackage) []*Problem {
+	var problems []*Problem
+	keys := make(map[string]bool)
+	files := make(map[string]string)
+	for i, pkg := range pkgs {
+		field := store.Key(pkg)
+		if pkg.Name == "" {
+			field = fmt.Sprintf("package #%d", i)
+		}
+		addf := func(format string, args ...interface{}) {
+			problems = append(problems, &Problem{Package: field, Description: fmt.Sprintf(format, args...)})
+		}
+
+		if pkg.Name != "" {
+			if keys[field] {
+				addf("duplicate package name")
+			}
+			keys[field] = true
+		}
+		for _, file := range pkg.Files {
+			name := file.GetFile().GetName()
+			if name == "" {
+				continue
+			}
+			if other, ok := files[name]; ok && other != field {
+				addf("file %s is also provided by %s", name, other)
+			}
+			files[name] = field
+		}
+		for _, problem := range Package(pkg) {
+			addf("%s", problem)
+		}
+	}
+	return problems
+}
+
+// Package checks a single package: its name, archive, files, dependencies and
+// hashes.  It returns the list of problems found.
+func Package(pkg *pppb.ProtoPackage) []string {
+	var problems []string
+	addf := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if pkg.Name == "" {
+		addf("name is empty")
+	}
+	if pkg.Archive.GetRepository().GetFullName() == "" {
+		addf("archive repository is missing")
+	}
+	if pkg.Archive.GetCommitSha1() == "" {
+		addf("archive commit is missing")
+	}
+	if len(pkg.Files) == 0 {
+		addf("package has no files")
+	}
+	files := make(map[string]bool)
+	for i, file := range pkg.Files {
+		name := file.GetFile().GetName()
+		if name == "" {
+			addf("file #%d: descriptor name is missing", i)
+			continue
+		}
+		if files[name] {
+			addf("file %s: duplicate file", name)
+		}
+		files[name] = true
+	}
+	for _, dep := range pkg.Dependencies {
+		if !strings.Contains(dep, ":") {
+			addf("malformed dependency %q", dep)
+		}
+	}
+
+	if pkg.Hash == "" {
+		addf("hash is empty")
+	} else if err := protohash.Verify(pkg); err != nil {
+		addf("%v", err)
+	}
+
+	return problems
+}
@@ -0,0 +1,167 @@
+package validate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/protopkg/apis/pkg/protohash"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// newTestPackage returns a valid package of the repository with the given
+// files.
+func newTestPackage(t *testing.T, name, repository string, filenames ...string) *pppb.ProtoPackage {
+	t.Helper()
+	var files []*pppb.ProtoFile
+	for _, filename := range filenames {
+		desc := &descriptorpb.FileDescriptorProto{
+			Name:   proto.String(filename),
+			Syntax: proto.String("proto3"),
+		}
+		if name != "~default" {
+			desc.Package = proto.String(name)
+		}
+		hash, err := protohash.File(desc, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, &pppb.ProtoFile{File: desc, Hash: hash})
+	}
+	pkg := &pppb.ProtoPackage{
+		Name: name,
+		Archive: &pppb.ProtoArchive{
+			Repository: &pppb.ProtoRepository{FullName: repository},
+			CommitSha1: "0123456789abcdef0123456789abcdef01234567",
+			ShortSha1:  "0123456",
+		},
+		Files: files,
+	}
+	setHash(t, pkg)
+	return pkg
+}
+
+func setHash(t *testing.T, pkg *pppb.ProtoPackage) {
+	t.Helper()
+	hash, err := protohash.Package(pkg.Files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg.Hash = hash
+}
+
+func TestPackage(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		modify func(pkg *pppb.ProtoPackage)
+		// want are the prefixes of the expected problems.
+		want []string
+	}{
+		{
+			name:   "valid",
+			modify: func(pkg *pppb.ProtoPackage) {},
+		},
+		{
+			name: "no archive",
+			modify: func(pkg *pppb.ProtoPackage) {
+				pkg.Archive = nil
+			},
+			want: []string{"archive repository is missing", "archive commit is missing"},
+		},
+		{
+			name: "malformed dependency",
+			modify: func(pkg *pppb.ProtoPackage) {
+				pkg.Dependencies = []string{"google.protobuf"}
+			},
+			want: []string{`malformed dependency "google.protobuf"`},
+		},
+		{
+			name: "duplicate file",
+			modify: func(pkg *pppb.ProtoPackage) {
+				pkg.Files = append(pkg.Files, pkg.Files[0])
+				setHash(t, pkg)
+			},
+			want: []string{"file acme/v1/a.proto: duplicate file"},
+		},
+		{
+			name: "no hash",
+			modify: func(pkg *pppb.ProtoPackage) {
+				pkg.Hash = ""
+			},
+			want: []string{"hash is empty"},
+		},
+		{
+			name: "tampered file",
+			modify: func(pkg *pppb.ProtoPackage) {
+				pkg.Files[1].File.Syntax = proto.String("proto2")
+			},
+			want: []string{"acme/v1/b.proto: hash mismatch: file has "},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			pkg := newTestPackage(t, "acme.v1", "github.com/acme/apis", "acme/v1/a.proto", "acme/v1/b.proto")
+			tc.modify(pkg)
+			got := Package(pkg)
+			if len(got) != len(tc.want) {
+				t.Fatalf("got problems %q, want %q", got, tc.want)
+			}
+			for i := range got {
+				if !strings.HasPrefix(got[i], tc.want[i]) {
+					t.Errorf("problem #%d: got %q, want %q", i, got[i], tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPackages(t *testing.T) {
+	api := newTestPackage(t, "acme.v1", "github.com/acme/apis", "acme/v1/a.proto")
+	otherAPI := newTestPackage(t, "acme.v1", "github.com/acme/apis", "acme/v1/b.proto")
+	provides := newTestPackage(t, "acme.v2", "github.com/acme/apis", "acme/v1/a.proto")
+	defaultA := newTestPackage(t, "~default", "github.com/acme/apis", "a.proto")
+	defaultB := newTestPackage(t, "~default", "github.com/vendor/protos", "b.proto")
+	unnamed := newTestPackage(t, "acme.v3", "github.com/acme/apis", "acme/v3/c.proto")
+	unnamed.Name = ""
+
+	for _, tc := range []struct {
+		name string
+		pkgs []*pppb.ProtoPackage
+		want []string
+	}{
+		{
+			name: "valid",
+			pkgs: []*pppb.ProtoPackage{api},
+		},
+		{
+			name: "duplicate name",
+			pkgs: []*pppb.ProtoPackage{api, otherAPI},
+			want: []string{"acme.v1: duplicate package name"},
+		},
+		{
+			name: "file of another package",
+			pkgs: []*pppb.ProtoPackage{api, provides},
+			want: []string{"acme.v2: file acme/v1/a.proto is also provided by acme.v1"},
+		},
+		{
+			// the default packages of distinct archives are distinct
+			name: "default packages",
+			pkgs: []*pppb.ProtoPackage{defaultA, defaultB},
+		},
+		{
+			name: "unnamed",
+			pkgs: []*pppb.ProtoPackage{api, unnamed},
+			want: []string{"package #1: name is empty"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			for _, problem := range Packages(tc.pkgs) {
+				got = append(got, problem.String())
+			}
+			if strings.Join(got, "\n") != strings.Join(tc.want, "\n") {
+				t.Errorf("got problems %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
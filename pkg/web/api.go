@@ -0,0 +1,209 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	regpb "github.com/protopkg/apis/protopkg/registry/v1alpha1"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// apiPrefix is the path prefix of the json API.
+const apiPrefix = "/api/v1/"
+
+// serveAPI serves the routes of the json API.
+func (h *Handler) serveAPI(w http.ResponseWriter, r *http.Request) {
+	route, arg, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, apiPrefix), "/")
+	var msg proto.Message
+	var err error
+	switch {
+	case route == "packages" && arg == "":
+		msg, err = h.listPackages(r, &regpb.ListProtoPackagesRequest{
+			Name:       r.URL.Query().Get("name"),
+			Repository: r.URL.Query().Get("repository"),
+			Commit:     r.URL.Query().Get("commit"),
+		})
+	case route == "packages":
+		h.servePackage(w, r, arg)
+		return
+	case route == "versions" && arg != "":
+		msg, err = h.listPackages(r, &regpb.ListProtoPackagesRequest{Name: arg})
+	case route == "lookup" && arg == "":
+		msg, err = h.registry.LookupProtoPackage(r.Context(), &regpb.LookupProtoPackageRequest{
+			Ref:      r.URL.Query().Get("ref"),
+			Id:       r.URL.Query().Get("id"),
+			Hash:     r.URL.Query().Get("hash"),
+			ReadMask: readMask(r),
+		})
+	case route == "files" && arg != "":
+		msg, err = h.findPackagesByFile(r, arg)
+	case route == "symbols" && arg != "":
+		msg, err = h.findPackagesBySymbol(r, arg)
+	default:
+		err = status.Errorf(codes.NotFound, "no such route: %s", r.URL.Path)
+	}
+	if err != nil {
+		writeJSONError(w, err)
+		return
+	}
+	writeJSON(w, msg)
+}
+
+// servePackage serves a package, or one of its files, by id.
+func (h *Handler) servePackage(w http.ResponseWriter, r *http.Request, path string) {
+	id, rest, ok := cutID(path)
+	if !ok {
+		writeJSONError(w, status.Errorf(codes.InvalidArgument, "invalid package id: %q (want NAME@HASH)", path))
+		return
+	}
+	kind, filename, _ := strings.Cut(rest, "/")
+	switch {
+	case rest == "":
+		pkg, err := h.registry.LookupProtoPackage(r.Context(), &regpb.LookupProtoPackageRequest{Id: id, ReadMask: readMask(r)})
+		if err != nil {
+			writeJSONError(w, err)
+			return
+		}
+		writeJSON(w, pkg)
+	case kind == "files" && filename != "":
+		file, err := h.lookupFile(r, id, filename, readMask(r).GetPaths())
+		if err != nil {
+			writeJSONError(w, err)
+			return
+		}
+		writeJSON(w, file)
+	case kind == "raw" && filename != "":
+		file, err := h.lookupFile(r, id, filename, []string{"source_code"})
+		if err != nil {
+			writeJSONError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprint(w, file.SourceCode)
+	default:
+		writeJSONError(w, status.Errorf(codes.NotFound, "no such route: %s", r.URL.Path))
+	}
+}
+
+// lookupFile returns the file of the package with the given id.  The paths
+// select the fields of the file (e.g. 'source_code'); all fields are returned
+// if there are none.
+func (h *Handler) lookupFile(r *http.Request, id, filename string, paths []string) (*pppb.ProtoFile, error) {
+	var mask *fieldmaskpb.FieldMask
+	if len(paths) > 0 {
+		mask = &fieldmaskpb.FieldMask{Paths: []string{"files.file.name"}}
+		for _, path := range paths {
+			mask.Paths = append(mask.Paths, "files."+path)
+		}
+	}
+	pkg, err := h.registry.LookupProtoPackage(r.Context(), &regpb.LookupProtoPackageRequest{Id: id, ReadMask: mask})
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range pkg.Files {
+		if file.GetFile().GetName() == filename {
+			if mask != nil && !selectsFileName(paths) {
+				file.File = nil
+			}
+			return file, nil
+		}
+	}
+	return nil, status.Errorf(codes.NotFound, "%s: no such file: %s", id, filename)
+}
+
+// selectsFileName reports whether the paths of a file select its name.
+func selectsFileName(paths []string) bool {
+	for _, path := range paths {
+		if path == "file" || path == "file.name" {
+			return true
+		}
+	}
+	return false
+}
+
+// listPackages completes the request with the paging and read mask
+// parameters, and lists the packages.
+func (h *Handler) listPackages(r *http.Request, req *regpb.ListProtoPackagesRequest) (*regpb.ListProtoPackagesResponse, error) {
+	pageSize, err := pageSize(r)
+	if err != nil {
+		return nil, err
+	}
+	req.PageSize = pageSize
+	req.PageToken = r.URL.Query().Get("page_token")
+	req.ReadMask = readMask(r)
+	return h.registry.ListProtoPackages(r.Context(), req)
+}
+
+func (h *Handler) findPackagesByFile(r *http.Request, file string) (*regpb.ListProtoPackagesResponse, error) {
+	pageSize, err := pageSize(r)
+	if err != nil {
+		return nil, err
+	}
+	return h.registry.FindProtoPackagesByFile(r.Context(), &regpb.FindProtoPackagesByFileRequest{
+		File:      file,
+		PageSize:  pageSize,
+		PageToken: r.URL.Query().Get("page_token"),
+		ReadMask:  readMask(r),
+	})
+}
+
+func (h *Handler) findPackagesBySymbol(r *http.Request, symbol string) (*regpb.ListProtoPackagesResponse, error) {
+	pageSize, err := pageSize(r)
+	if err != nil {
+		return nil, err
+	}
+	return h.registry.FindProtoPackagesBySymbol(r.Context(), &regpb.FindProtoPackagesBySymbolRequest{
+		Symbol:    symbol,
+		PageSize:  pageSize,
+		PageToken: r.URL.Query().Get("page_token"),
+		ReadMask:  readMask(r),
+	})
+}
+
+// pageSize returns the page_size parameter, or 0 if not set.
+func pageSize(r *http.Request) (int32, error) {
+	value := r.URL.Query().Get("page_size")
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		return 0, status.Errorf(codes.InvalidArgument, "invalid page size: %q", value)
+	}
+	return int32(n), nil
+}
+
+// jsonMarshaler is the encoding of the json API.
+var jsonMarshaler = protojson.MarshalOptions{
+	Multiline: true,
+	Indent:    "  ",
+}
+
+func writeJSON(w http.ResponseWriter, msg proto.Message) {
+	data, err := jsonMarshaler.Marshal(msg)
+	if err != nil {
+		writeJSONError(w, status.Errorf(codes.Internal, "marshaling json: %v", err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(data, '\n'))
+}
+
+// writeJSONError writes the grpc status of the error as json.
+func writeJSONError(w http.ResponseWriter, err error) {
+	data, merr := jsonMarshaler.Marshal(status.Convert(err).Proto())
+	if merr != nil {
+		http.Error(w, err.Error(), httpStatus(err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus(err))
+	w.Write(append(data, '\n'))
+}
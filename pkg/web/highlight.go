@@ -0,0 +1,134 @@
+package web
+
+import (
+	"html/template"
+	"strings"
+	"unicode/utf8"
+)
+
+// protoKeywords are the keywords of the proto language.
+var protoKeywords = map[string]bool{
+	"syntax": true, "edition": true, "package": true, "import": true, "public": true, "weak": true,
+	"option": true, "message": true, "enum": true, "service": true, "rpc": true, "returns": true,
+	"stream": true, "extend": true, "extensions": true, "reserved": true, "oneof": true, "map": true,
+	"repeated": true, "optional": true, "required": true, "group": true, "to": true, "max": true,
+	"true": true, "false": true, "inf": true, "nan": true,
+}
+
+// protoScalarTypes are the scalar value types of the proto language.
+var protoScalarTypes = map[string]bool{
+	"double": true, "float": true, "int32": true, "int64": true, "uint32": true, "uint64": true,
+	"sint32": true, "sint64": true, "fixed32": true, "fixed64": true, "sfixed32": true,
+	"sfixed64": true, "bool": true, "string": true, "bytes": true,
+}
+
+// highlightProto returns the proto source code as HTML, with the comments,
+// strings, numbers, keywords and scalar types in spans of the classes c, s,
+// n, k and t.  The file names of the import statements are linked to the
+// URL returned by importURL, unless it returns "".
+func highlightProto(src string, importURL func(path string) string) template.HTML {
+	var b strings.Builder
+	span := func(class, text string) {
+		b.WriteString(`<span class="` + class + `">`)
+		b.WriteString(template.HTMLEscapeString(text))
+		b.WriteString(`</span>`)
+	}
+	// inImport is set after the import keyword, until the file name.
+	inImport := false
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case strings.HasPrefix(src[i:], "//"):
+			end := strings.IndexByte(src[i:], '\n')
+			if end < 0 {
+				end = len(src) - i
+			}
+			span("c", src[i:i+end])
+			i += end
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				end = len(src) - i
+			} else {
+				end += 4
+			}
+			span("c", src[i:i+end])
+			i += end
+		case c == '"' || c == '\'':
+			end := i + 1
+			for end < len(src) && src[end] != c && src[end] != '\n' {
+				if src[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end < len(src) && src[end] == c {
+				end++
+			}
+			if end > len(src) {
+				end = len(src)
+			}
+			text := src[i:end]
+			if url := ""; inImport {
+				inImport = false
+				if len(text) >= 2 {
+					url = importURL(text[1 : len(text)-1])
+				}
+				if url != "" {
+					b.WriteString(`<a href="` + template.HTMLEscapeString(url) + `">`)
+					span("s", text)
+					b.WriteString(`</a>`)
+					i = end
+					break
+				}
+			}
+			span("s", text)
+			i = end
+		case isDigit(c) || (c == '.' && i+1 < len(src) && isDigit(src[i+1])):
+			end := i + 1
+			for end < len(src) && (isIdentChar(src[end]) || src[end] == '.') {
+				end++
+			}
+			span("n", src[i:end])
+			i = end
+		case isIdentStart(c):
+			end := i + 1
+			for end < len(src) && isIdentChar(src[end]) {
+				end++
+			}
+			word := src[i:end]
+			switch {
+			case protoKeywords[word]:
+				span("k", word)
+				if word == "import" {
+					inImport = true
+				}
+			case protoScalarTypes[word]:
+				span("t", word)
+			default:
+				b.WriteString(template.HTMLEscapeString(word))
+			}
+			i = end
+		default:
+			if c == ';' {
+				inImport = false
+			}
+			_, size := utf8.DecodeRuneInString(src[i:])
+			b.WriteString(template.HTMLEscapeString(src[i : i+size]))
+			i += size
+		}
+	}
+	return template.HTML(b.String())
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || isDigit(c)
+}
@@ -0,0 +1,327 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/protopkg/apis/pkg/store"
+	regpb "github.com/protopkg/apis/protopkg/registry/v1alpha1"
+	pppb "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+//go:embed templates/*.html
+var templatesFS embed.FS
+
+var templates = template.Must(template.New("").Funcs(template.FuncMap{
+	"packageURL":  packageURL,
+	"fileURL":     fileURL,
+	"refURL":      refURL,
+	"rawURL":      rawURL,
+	"versionsURL": versionsURL,
+}).ParseFS(templatesFS, "templates/*.html"))
+
+// read masks of the pages: the browser only reads the source code of the
+// file it shows.
+var (
+	summaryMask = &fieldmaskpb.FieldMask{Paths: []string{"name", "hash", "archive", "files.file.name"}}
+	packageMask = &fieldmaskpb.FieldMask{Paths: []string{"name", "hash", "archive", "compiler", "package_url", "created_at", "dependencies", "files.hash", "files.file.name", "files.file.package"}}
+	fileMask    = &fieldmaskpb.FieldMask{Paths: []string{"name", "hash", "archive", "dependencies", "files.hash", "files.file.name", "files.file.dependency", "files.source_code"}}
+	filesMask   = &fieldmaskpb.FieldMask{Paths: []string{"name", "hash", "files.file.name"}}
+)
+
+// summary is a package in the lists of the browser.
+type summary struct {
+	Name       string
+	ID         string
+	Ref        string
+	Repository string
+	Commit     string
+	CommitTime string
+	Files      int
+}
+
+func makeSummary(pkg *pppb.ProtoPackage) *summary {
+	s := &summary{
+		Name:       pkg.Name,
+		ID:         pkg.Name + "@" + pkg.Hash,
+		Ref:        store.Ref(pkg),
+		Repository: pkg.Archive.GetRepository().GetFullName(),
+		Commit:     pkg.Archive.GetShortSha1(),
+		Files:      len(pkg.Files),
+	}
+	if t := pkg.Archive.GetCommitTime(); t != nil {
+		s.CommitTime = t.AsTime().UTC().Format(time.RFC3339)
+	}
+	return s
+}
+
+// serveHTML serves the pages of the browser:
+//
+//	/                               the latest version of each package
+//	/packages/NAME                  the versions of a package
+//	/packages/NAME@HASH             a package
+//	/packages/NAME@HASH/files/PATH  a file of a package
+//	/refs/REF                       redirects to the package of a ref
+//	/search?q=FILE|SYMBOL           the packages that provide a file or symbol
+func (h *Handler) serveHTML(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	path := r.URL.Path
+	switch {
+	case path == "/":
+		h.serveIndex(ctx, w)
+	case strings.HasPrefix(path, "/packages/"):
+		path = strings.TrimPrefix(path, "/packages/")
+		id, rest, ok := cutID(path)
+		kind, filename, _ := strings.Cut(rest, "/")
+		switch {
+		case !ok && path != "" && !strings.Contains(path, "/"):
+			h.serveVersions(ctx, w, path)
+		case ok && rest == "":
+			h.servePackagePage(ctx, w, id)
+		case ok && kind == "files" && filename != "":
+			h.serveFilePage(ctx, w, id, filename)
+		default:
+			writeHTMLError(w, status.Errorf(codes.NotFound, "no such page: %s", r.URL.Path))
+		}
+	case strings.HasPrefix(path, "/refs/"):
+		pkg, err := h.registry.LookupProtoPackage(ctx, &regpb.LookupProtoPackageRequest{
+			Ref:      strings.TrimPrefix(path, "/refs/"),
+			ReadMask: &fieldmaskpb.FieldMask{Paths: []string{"name", "hash"}},
+		})
+		if err != nil {
+			writeHTMLError(w, err)
+			return
+		}
+		http.Redirect(w, r, packageURL(pkg.Name+"@"+pkg.Hash), http.StatusFound)
+	case path == "/search":
+		h.serveSearch(ctx, w, strings.TrimSpace(r.URL.Query().Get("q")))
+	default:
+		writeHTMLError(w, status.Errorf(codes.NotFound, "no such page: %s", r.URL.Path))
+	}
+}
+
+func (h *Handler) serveIndex(ctx context.Context, w http.ResponseWriter) {
+	pkgs, err := h.listAll(ctx, &regpb.ListProtoPackagesRequest{ReadMask: summaryMask})
+	if err != nil {
+		writeHTMLError(w, err)
+		return
+	}
+	// the packages are listed most recently committed first.
+	var latest []*summary
+	seen := make(map[string]bool)
+	for _, pkg := range pkgs {
+		if !seen[pkg.Name] {
+			seen[pkg.Name] = true
+			latest = append(latest, makeSummary(pkg))
+		}
+	}
+	sort.Slice(latest, func(i, j int) bool { return latest[i].Name < latest[j].Name })
+	writeHTML(w, "index.html", map[string]interface{}{
+		"Title":    "Packages",
+		"Packages": latest,
+	})
+}
+
+func (h *Handler) serveVersions(ctx context.Context, w http.ResponseWriter, name string) {
+	pkgs, err := h.listAll(ctx, &regpb.ListProtoPackagesRequest{Name: name, ReadMask: summaryMask})
+	if err != nil {
+		writeHTMLError(w, err)
+		return
+	}
+	if len(pkgs) == 0 {
+		writeHTMLError(w, status.Errorf(codes.NotFound, "no such package: %s", name))
+		return
+	}
+	var versions []*summary
+	for _, pkg := range pkgs {
+		versions = append(versions, makeSummary(pkg))
+	}
+	writeHTML(w, "versions.html", map[string]interface{}{
+		"Title":    name,
+		"Name":     name,
+		"Versions": versions,
+	})
+}
+
+func (h *Handler) servePackagePage(ctx context.Context, w http.ResponseWriter, id string) {
+	pkg, err := h.registry.LookupProtoPackage(ctx, &regpb.LookupProtoPackageRequest{Id: id, ReadMask: packageMask})
+	if err != nil {
+		writeHTMLError(w, err)
+		return
+	}
+	var createdAt string
+	if pkg.CreatedAt != nil {
+		createdAt = pkg.CreatedAt.AsTime().UTC().Format(time.RFC3339)
+	}
+	writeHTML(w, "package.html", map[string]interface{}{
+		"Title":     pkg.Name,
+		"Package":   pkg,
+		"Summary":   makeSummary(pkg),
+		"CreatedAt": createdAt,
+	})
+}
+
+func (h *Handler) serveFilePage(ctx context.Context, w http.ResponseWriter, id, filename string) {
+	pkg, err := h.registry.LookupProtoPackage(ctx, &regpb.LookupProtoPackageRequest{Id: id, ReadMask: fileMask})
+	if err != nil {
+		writeHTMLError(w, err)
+		return
+	}
+	var file *pppb.ProtoFile
+	for _, f := range pkg.Files {
+		if f.GetFile().GetName() == filename {
+			file = f
+			break
+		}
+	}
+	if file == nil {
+		writeHTMLError(w, status.Errorf(codes.NotFound, "%s: no such file: %s", id, filename))
+		return
+	}
+
+	// providers maps the files of the package and of its dependencies to the
+	// id of their package.
+	providers := make(map[string]string)
+	for _, dep := range pkg.Dependencies {
+		other, err := h.registry.LookupProtoPackage(ctx, &regpb.LookupProtoPackageRequest{Ref: dep, ReadMask: filesMask})
+		if err != nil {
+			log.Printf("web: %s: dependency %s: %v", id, dep, err)
+			continue
+		}
+		for _, f := range other.Files {
+			providers[f.GetFile().GetName()] = other.Name + "@" + other.Hash
+		}
+	}
+	for _, f := range pkg.Files {
+		providers[f.GetFile().GetName()] = id
+	}
+	importURL := func(path string) string {
+		if provider, ok := providers[path]; ok {
+			return fileURL(provider, path)
+		}
+		return "/search?q=" + url.QueryEscape(path)
+	}
+
+	var imports []map[string]string
+	for _, dep := range file.GetFile().GetDependency() {
+		imports = append(imports, map[string]string{"Name": dep, "URL": importURL(dep)})
+	}
+	writeHTML(w, "file.html", map[string]interface{}{
+		"Title":   filename,
+		"Summary": makeSummary(pkg),
+		"File":    file,
+		"Imports": imports,
+		"Source":  highlightProto(file.SourceCode, importURL),
+	})
+}
+
+func (h *Handler) serveSearch(ctx context.Context, w http.ResponseWriter, q string) {
+	data := map[string]interface{}{
+		"Title": "Search",
+		"Query": q,
+	}
+	if q != "" {
+		var resp *regpb.ListProtoPackagesResponse
+		var err error
+		if strings.HasSuffix(q, ".proto") {
+			resp, err = h.registry.FindProtoPackagesByFile(ctx, &regpb.FindProtoPackagesByFileRequest{File: q, ReadMask: summaryMask})
+		} else {
+			resp, err = h.registry.FindProtoPackagesBySymbol(ctx, &regpb.FindProtoPackagesBySymbolRequest{Symbol: q, ReadMask: summaryMask})
+		}
+		if err != nil {
+			writeHTMLError(w, err)
+			return
+		}
+		var results []*summary
+		for _, pkg := range resp.Packages {
+			results = append(results, makeSummary(pkg))
+		}
+		data["Results"] = results
+		data["More"] = resp.NextPageToken != ""
+	}
+	writeHTML(w, "search.html", data)
+}
+
+// listAll returns the packages of all the pages of the request.
+func (h *Handler) listAll(ctx context.Context, req *regpb.ListProtoPackagesRequest) ([]*pppb.ProtoPackage, error) {
+	var pkgs []*pppb.ProtoPackage
+	for {
+		resp, err := h.registry.ListProtoPackages(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		pkgs = append(pkgs, resp.Packages...)
+		if resp.NextPageToken == "" {
+			return pkgs, nil
+		}
+		req.PageToken = resp.NextPageToken
+	}
+}
+
+// writeHTML renders the template.  The page is rendered before it is written,
+// such that a template error results in an error page.
+func writeHTML(w http.ResponseWriter, name string, data interface{}) {
+	writeHTMLStatus(w, http.StatusOK, name, data)
+}
+
+func writeHTMLStatus(w http.ResponseWriter, code int, name string, data interface{}) {
+	var buf bytes.Buffer
+	if err := templates.ExecuteTemplate(&buf, name, data); err != nil {
+		log.Printf("web: rendering %s: %v", name, err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(code)
+	w.Write(buf.Bytes())
+}
+
+// writeHTMLError renders the error page of a grpc error.
+func writeHTMLError(w http.ResponseWriter, err error) {
+	code := httpStatus(err)
+	writeHTMLStatus(w, code, "error.html", map[string]interface{}{
+		"Title":   http.StatusText(code),
+		"Message": status.Convert(err).Message(),
+	})
+}
+
+func packageURL(id string) string {
+	return "/packages/" + url.PathEscape(id)
+}
+
+func fileURL(id, path string) string {
+	return packageURL(id) + "/files/" + escapePath(path)
+}
+
+func versionsURL(name string) string {
+	return "/packages/" + url.PathEscape(name)
+}
+
+// rawURL returns the json API URL of the source code of a file.
+func rawURL(id, path string) string {
+	return apiPrefix + "packages/" + url.PathEscape(id) + "/raw/" + escapePath(path)
+}
+
+func refURL(ref string) string {
+	return "/refs/" + escapePath(ref)
+}
+
+// escapePath escapes the segments of a slash-separated path.
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
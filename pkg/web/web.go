@@ -0,0 +1,119 @@
+// Package web implements the HTTP interface of the packages server: a
+// read-only json API and a server-rendered HTML browser of the stored
+// packages.  Both are served from the Registry service of the server, called
+// in-process, such that they share its paging, read masks and errors.
+//
+// The json API (in the protojson encoding, as written by the -json_out flags
+// of the commands):
+//
+//	GET /api/v1/packages?name=&repository=&commit=   list packages (ListProtoPackagesResponse)
+//	GET /api/v1/versions/NAME                        list the versions of a package
+//	GET /api/v1/packages/NAME@HASH                   get a package (ProtoPackage)
+//	GET /api/v1/packages/NAME@HASH/files/PATH        get a file of a package (ProtoFile)
+//	GET /api/v1/packages/NAME@HASH/raw/PATH          get the source code of a file (text/plain)
+//	GET /api/v1/lookup?ref=|id=|hash=                get a package by ref, id or hash
+//	GET /api/v1/files/PATH                           find the packages that provide a file
+//	GET /api/v1/symbols/SYMBOL                       find the packages that define a symbol
+//
+// The list routes take the page_size and page_token parameters, and all
+// routes but raw take a comma-separated read_mask parameter (relative to the
+// ProtoFile for the files of a package, e.g. 'source_code').  Errors are
+// returned as a json google.rpc.Status, with the HTTP status of the grpc
+// code.
+//
+// The HTML browser is served under /.
+package web
+
+import (
+	"net/http"
+	"strings"
+
+	regpb "github.com/protopkg/apis/protopkg/registry/v1alpha1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// Handler serves the json API and the HTML browser.
+type Handler struct {
+	registry regpb.RegistryServer
+	mux      *http.ServeMux
+}
+
+// New returns a handler that serves the packages of the registry.
+func New(registry regpb.RegistryServer) *Handler {
+	h := &Handler{
+		registry: registry,
+		mux:      http.NewServeMux(),
+	}
+	h.mux.HandleFunc(apiPrefix, h.serveAPI)
+	h.mux.HandleFunc("/", h.serveHTML)
+	return h
+}
+
+// ServeHTTP implements http.Handler.  Only GET and HEAD requests are
+// allowed.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+// readMask returns the field mask of the comma-separated read_mask
+// parameter, or nil if not set.
+func readMask(r *http.Request) *fieldmaskpb.FieldMask {
+	value := r.URL.Query().Get("read_mask")
+	if value == "" {
+		return nil
+	}
+	mask := &fieldmaskpb.FieldMask{}
+	for _, path := range strings.Split(value, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			mask.Paths = append(mask.Paths, path)
+		}
+	}
+	return mask
+}
+
+// cutID splits a path of the form NAME@HASH[/REST] into the package id and
+// the rest of the path.
+func cutID(path string) (id, rest string, ok bool) {
+	id, rest, _ = strings.Cut(path, "/")
+	name, hash, ok := strings.Cut(id, "@")
+	if !ok || name == "" || hash == "" {
+		return "", "", false
+	}
+	return id, rest, true
+}
+
+// httpStatus returns the HTTP status of a grpc error.
+func httpStatus(err error) int {
+	switch status.Code(err) {
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.Canceled:
+		// the client closed the request, as in nginx.
+		return 499
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,1515 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.30.0
+// 	protoc        v3.14.0
+// source: protopkg/registry/v1alpha1/registry.proto
+
+package v1alpha1
+
+import (
+	v1alpha2 "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	fieldmaskpb "google.golang.org/protobuf/types/known/fieldmaskpb"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ProtoPackageStatus_State int32
+
+const (
+	ProtoPackageStatus_STATE_UNSPECIFIED ProtoPackageStatus_State = 0
+	// the server does not have the package.
+	ProtoPackageStatus_MISSING ProtoPackageStatus_State = 1
+	// the server has the package, with the same hash.
+	ProtoPackageStatus_EXISTS ProtoPackageStatus_State = 2
+	// the server has a package with the same ref, but a different hash.
+	ProtoPackageStatus_CONFLICT ProtoPackageStatus_State = 3
+)
+
+// Enum value maps for ProtoPackageStatus_State.
+var (
+	ProtoPackageStatus_State_name = map[int32]string{
+		0: "STATE_UNSPECIFIED",
+		1: "MISSING",
+		2: "EXISTS",
+		3: "CONFLICT",
+	}
+	ProtoPackageStatus_State_value = map[string]int32{
+		"STATE_UNSPECIFIED": 0,
+		"MISSING":           1,
+		"EXISTS":            2,
+		"CONFLICT":          3,
+	}
+)
+
+func (x ProtoPackageStatus_State) Enum() *ProtoPackageStatus_State {
+	p := new(ProtoPackageStatus_State)
+	*p = x
+	return p
+}
+
+func (x ProtoPackageStatus_State) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ProtoPackageStatus_State) Descriptor() protoreflect.EnumDescriptor {
+	return file_protopkg_registry_v1alpha1_registry_proto_enumTypes[0].Descriptor()
+}
+
+func (ProtoPackageStatus_State) Type() protoreflect.EnumType {
+	return &file_protopkg_registry_v1alpha1_registry_proto_enumTypes[0]
+}
+
+func (x ProtoPackageStatus_State) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ProtoPackageStatus_State.Descriptor instead.
+func (ProtoPackageStatus_State) EnumDescriptor() ([]byte, []int) {
+	return file_protopkg_registry_v1alpha1_registry_proto_rawDescGZIP(), []int{1, 0}
+}
+
+// ProtoPackageRef identifies a version of a package.
+type ProtoPackageRef struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// the package reference, in the same form as ProtoPackage.dependencies
+	// (e.g. 'github.com/googleapis/googleapis/0123456/~:google.api').  The
+	// files without a package statement are in the package named '~default'.
+	Ref string `protobuf:"bytes,1,opt,name=ref,proto3" json:"ref,omitempty"`
+	// the package name (e.g. 'google.api').
+	Name string `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	// the package hash (e.g. 'protoreflecthash.v0:0e24bad9...').
+	Hash string `protobuf:"bytes,3,opt,name=hash,proto3" json:"hash,omitempty"`
+}
+
+func (x *ProtoPackageRef) Reset() {
+	*x = ProtoPackageRef{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_protopkg_registry_v1alpha1_registry_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ProtoPackageRef) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProtoPackageRef) ProtoMessage() {}
+
+func (x *ProtoPackageRef) ProtoReflect() protoreflect.Message {
+	mi := &file_protopkg_registry_v1alpha1_registry_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProtoPackageRef.ProtoReflect.Descriptor instead.
+func (*ProtoPackageRef) Descriptor() ([]byte, []int) {
+	return file_protopkg_registry_v1alpha1_registry_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ProtoPackageRef) GetRef() string {
+	if x != nil {
+		return x.Ref
+	}
+	return ""
+}
+
+func (x *ProtoPackageRef) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ProtoPackageRef) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+// ProtoPackageStatus is the state of a package on the server.
+type ProtoPackageStatus struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// the package as given in the request.
+	Pkg   *ProtoPackageRef         `protobuf:"bytes,1,opt,name=pkg,proto3" json:"pkg,omitempty"`
+	State ProtoPackageStatus_State `protobuf:"varint,2,opt,name=state,proto3,enum=protopkg.registry.v1alpha1.ProtoPackageStatus_State" json:"state,omitempty"`
+	// the server-side identity of the package (e.g.
+	// 'google.api@protoreflecthash.v0:0e24bad9...'), if it exists.
+	Id string `protobuf:"bytes,3,opt,name=id,proto3" json:"id,omitempty"`
+	// the hash of the package on the server, if it exists.
+	Hash string `protobuf:"bytes,4,opt,name=hash,proto3" json:"hash,omitempty"`
+	// URL for the package on the server, if it exists.
+	PackageUrl string `protobuf:"bytes,5,opt,name=package_url,json=packageUrl,proto3" json:"package_url,omitempty"`
+}
+
+func (x *ProtoPackageStatus) Reset() {
+	*x = ProtoPackageStatus{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_protopkg_registry_v1alpha1_registry_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ProtoPackageStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProtoPackageStatus) ProtoMessage() {}
+
+func (x *ProtoPackageStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_protopkg_registry_v1alpha1_registry_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProtoPackageStatus.ProtoReflect.Descriptor instead.
+func (*ProtoPackageStatus) Descriptor() ([]byte, []int) {
+	return file_protopkg_registry_v1alpha1_registry_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ProtoPackageStatus) GetPkg() *ProtoPackageRef {
+	if x != nil {
+		return x.Pkg
+	}
+	return nil
+}
+
+func (x *ProtoPackageStatus) GetState() ProtoPackageStatus_State {
+	if x != nil {
+		return x.State
+	}
+	return ProtoPackageStatus_STATE_UNSPECIFIED
+}
+
+func (x *ProtoPackageStatus) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ProtoPackageStatus) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *ProtoPackageStatus) GetPackageUrl() string {
+	if x != nil {
+		return x.PackageUrl
+	}
+	return ""
+}
+
+type CheckProtoPackagesRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Packages []*ProtoPackageRef `protobuf:"bytes,1,rep,name=packages,proto3" json:"packages,omitempty"`
+}
+
+func (x *CheckProtoPackagesRequest) Reset() {
+	*x = CheckProtoPackagesRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_protopkg_registry_v1alpha1_registry_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CheckProtoPackagesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckProtoPackagesRequest) ProtoMessage() {}
+
+func (x *CheckProtoPackagesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protopkg_registry_v1alpha1_registry_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckProtoPackagesRequest.ProtoReflect.Descriptor instead.
+func (*CheckProtoPackagesRequest) Descriptor() ([]byte, []int) {
+	return file_protopkg_registry_v1alpha1_registry_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *CheckProtoPackagesRequest) GetPackages() []*ProtoPackageRef {
+	if x != nil {
+		return x.Packages
+	}
+	return nil
+}
+
+type CheckProtoPackagesResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// the status of each requested package, in the same order as the request.
+	Packages []*ProtoPackageStatus `protobuf:"bytes,1,rep,name=packages,proto3" json:"packages,omitempty"`
+}
+
+func (x *CheckProtoPackagesResponse) Reset() {
+	*x = CheckProtoPackagesResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_protopkg_registry_v1alpha1_registry_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CheckProtoPackagesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckProtoPackagesResponse) ProtoMessage() {}
+
+func (x *CheckProtoPackagesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_protopkg_registry_v1alpha1_registry_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckProtoPackagesResponse.ProtoReflect.Descriptor instead.
+func (*CheckProtoPackagesResponse) Descriptor() ([]byte, []int) {
+	return file_protopkg_registry_v1alpha1_registry_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *CheckProtoPackagesResponse) GetPackages() []*ProtoPackageStatus {
+	if x != nil {
+		return x.Packages
+	}
+	return nil
+}
+
+// CreateProtoPackageResponse is the response of the operation returned by
+// Packages.CreateProtoPackage.
+type CreateProtoPackageResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// the status of each package received on the stream, in order.
+	Packages []*ProtoPackageStatus `protobuf:"bytes,1,rep,name=packages,proto3" json:"packages,omitempty"`
+}
+
+func (x *CreateProtoPackageResponse) Reset() {
+	*x = CreateProtoPackageResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_protopkg_registry_v1alpha1_registry_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CreateProtoPackageResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateProtoPackageResponse) ProtoMessage() {}
+
+func (x *CreateProtoPackageResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_protopkg_registry_v1alpha1_registry_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateProtoPackageResponse.ProtoReflect.Descriptor instead.
+func (*CreateProtoPackageResponse) Descriptor() ([]byte, []int) {
+	return file_protopkg_registry_v1alpha1_registry_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CreateProtoPackageResponse) GetPackages() []*ProtoPackageStatus {
+	if x != nil {
+		return x.Packages
+	}
+	return nil
+}
+
+// Signature is a signature of a SignatureEnvelope.
+type Signature struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// identifies the key that made the signature (e.g. 'SHA256:...', the
+	// ssh fingerprint of the public key).
+	Keyid string `protobuf:"bytes,1,opt,name=keyid,proto3" json:"keyid,omitempty"`
+	// the signature over the pre-authentication encoding of the envelope
+	// payload (an ssh signature in wire format).
+	Sig []byte `protobuf:"bytes,2,opt,name=sig,proto3" json:"sig,omitempty"`
+}
+
+func (x *Signature) Reset() {
+	*x = Signature{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_protopkg_registry_v1alpha1_registry_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Signature) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Signature) ProtoMessage() {}
+
+func (x *Signature) ProtoReflect() protoreflect.Message {
+	mi := &file_protopkg_registry_v1alpha1_registry_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Signature.ProtoReflect.Descriptor instead.
+func (*Signature) Descriptor() ([]byte, []int) {
+	return file_protopkg_registry_v1alpha1_registry_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Signature) GetKeyid() string {
+	if x != nil {
+		return x.Keyid
+	}
+	return ""
+}
+
+func (x *Signature) GetSig() []byte {
+	if x != nil {
+		return x.Sig
+	}
+	return nil
+}
+
+// SignatureEnvelope is a detached signature of a package, in the DSSE
+// envelope format (https://github.com/secure-systems-lab/dsse).  The payload
+// is a json statement naming the package ref and hash.
+type SignatureEnvelope struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// the payload type (e.g. 'application/vnd.protopkg.signature+json').
+	PayloadType string `protobuf:"bytes,1,opt,name=payload_type,json=payloadType,proto3" json:"payload_type,omitempty"`
+	// the signed payload.
+	Payload []byte `protobuf:"bytes,2,opt,name=payload,proto3" json:"payload,omitempty"`
+	// one or more signatures of the payload.
+	Signatures []*Signature `protobuf:"bytes,3,rep,name=signatures,proto3" json:"signatures,omitempty"`
+}
+
+func (x *SignatureEnvelope) Reset() {
+	*x = SignatureEnvelope{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_protopkg_registry_v1alpha1_registry_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SignatureEnvelope) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SignatureEnvelope) ProtoMessage() {}
+
+func (x *SignatureEnvelope) ProtoReflect() protoreflect.Message {
+	mi := &file_protopkg_registry_v1alpha1_registry_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SignatureEnvelope.ProtoReflect.Descriptor instead.
+func (*SignatureEnvelope) Descriptor() ([]byte, []int) {
+	return file_protopkg_registry_v1alpha1_registry_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *SignatureEnvelope) GetPayloadType() string {
+	if x != nil {
+		return x.PayloadType
+	}
+	return ""
+}
+
+func (x *SignatureEnvelope) GetPayload() []byte {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *SignatureEnvelope) GetSignatures() []*Signature {
+	if x != nil {
+		return x.Signatures
+	}
+	return nil
+}
+
+// SignatureEnvelopeList is a list of envelopes, typically one per package of
+// a package set.
+type SignatureEnvelopeList struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Envelopes []*SignatureEnvelope `protobuf:"bytes,1,rep,name=envelopes,proto3" json:"envelopes,omitempty"`
+}
+
+func (x *SignatureEnvelopeList) Reset() {
+	*x = SignatureEnvelopeList{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_protopkg_registry_v1alpha1_registry_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SignatureEnvelopeList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SignatureEnvelopeList) ProtoMessage() {}
+
+func (x *SignatureEnvelopeList) ProtoReflect() protoreflect.Message {
+	mi := &file_protopkg_registry_v1alpha1_registry_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SignatureEnvelopeList.ProtoReflect.Descriptor instead.
+func (*SignatureEnvelopeList) Descriptor() ([]byte, []int) {
+	return file_protopkg_registry_v1alpha1_registry_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *SignatureEnvelopeList) GetEnvelopes() []*SignatureEnvelope {
+	if x != nil {
+		return x.Envelopes
+	}
+	return nil
+}
+
+type PutProtoPackageSignaturesRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Envelopes []*SignatureEnvelope `protobuf:"bytes,1,rep,name=envelopes,proto3" json:"envelopes,omitempty"`
+}
+
+func (x *PutProtoPackageSignaturesRequest) Reset() {
+	*x = PutProtoPackageSignaturesRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_protopkg_registry_v1alpha1_registry_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PutProtoPackageSignaturesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PutProtoPackageSignaturesRequest) ProtoMessage() {}
+
+func (x *PutProtoPackageSignaturesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protopkg_registry_v1alpha1_registry_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PutProtoPackageSignaturesRequest.ProtoReflect.Descriptor instead.
+func (*PutProtoPackageSignaturesRequest) Descriptor() ([]byte, []int) {
+	return file_protopkg_registry_v1alpha1_registry_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *PutProtoPackageSignaturesRequest) GetEnvelopes() []*SignatureEnvelope {
+	if x != nil {
+		return x.Envelopes
+	}
+	return nil
+}
+
+type PutProtoPackageSignaturesResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// the number of envelopes stored.
+	Stored int32 `protobuf:"varint,1,opt,name=stored,proto3" json:"stored,omitempty"`
+}
+
+func (x *PutProtoPackageSignaturesResponse) Reset() {
+	*x = PutProtoPackageSignaturesResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_protopkg_registry_v1alpha1_registry_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PutProtoPackageSignaturesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PutProtoPackageSignaturesResponse) ProtoMessage() {}
+
+func (x *PutProtoPackageSignaturesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_protopkg_registry_v1alpha1_registry_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PutProtoPackageSignaturesResponse.ProtoReflect.Descriptor instead.
+func (*PutProtoPackageSignaturesResponse) Descriptor() ([]byte, []int) {
+	return file_protopkg_registry_v1alpha1_registry_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *PutProtoPackageSignaturesResponse) GetStored() int32 {
+	if x != nil {
+		return x.Stored
+	}
+	return 0
+}
+
+type LookupProtoPackageRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// exactly one of ref, id or hash identifies the package.
+	//
+	// the package reference (e.g.
+	// 'github.com/googleapis/googleapis/0123456/~:google.api').
+	Ref string `protobuf:"bytes,1,opt,name=ref,proto3" json:"ref,omitempty"`
+	// the server-side identity of the package (e.g.
+	// 'google.api@protoreflecthash.v0:0e24bad9...').
+	Id string `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	// the package hash; if several packages have the hash, the most recently
+	// committed one is returned.
+	Hash string `protobuf:"bytes,3,opt,name=hash,proto3" json:"hash,omitempty"`
+	// the fields of the package to return (e.g. 'name,hash,files.file' to
+	// skip the source code).  All fields are returned if not set.
+	ReadMask *fieldmaskpb.FieldMask `protobuf:"bytes,4,opt,name=read_mask,json=readMask,proto3" json:"read_mask,omitempty"`
+}
+
+func (x *LookupProtoPackageRequest) Reset() {
+	*x = LookupProtoPackageRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_protopkg_registry_v1alpha1_registry_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *LookupProtoPackageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LookupProtoPackageRequest) ProtoMessage() {}
+
+func (x *LookupProtoPackageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protopkg_registry_v1alpha1_registry_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LookupProtoPackageRequest.ProtoReflect.Descriptor instead.
+func (*LookupProtoPackageRequest) Descriptor() ([]byte, []int) {
+	return file_protopkg_registry_v1alpha1_registry_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *LookupProtoPackageRequest) GetRef() string {
+	if x != nil {
+		return x.Ref
+	}
+	return ""
+}
+
+func (x *LookupProtoPackageRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *LookupProtoPackageRequest) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *LookupProtoPackageRequest) GetReadMask() *fieldmaskpb.FieldMask {
+	if x != nil {
+		return x.ReadMask
+	}
+	return nil
+}
+
+type ListProtoPackagesRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// the package name (e.g. 'google.api'); lists the versions of the
+	// package.
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// the full name of the archive repository (e.g.
+	// 'github.com/googleapis/googleapis').
+	Repository string `protobuf:"bytes,2,opt,name=repository,proto3" json:"repository,omitempty"`
+	// the full or short commit sha1 of the archive.
+	Commit string `protobuf:"bytes,3,opt,name=commit,proto3" json:"commit,omitempty"`
+	// the maximum number of packages to return (default 100, max 1000).
+	PageSize int32 `protobuf:"varint,4,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// the next_page_token of the previous response.
+	PageToken string `protobuf:"bytes,5,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	// the fields of the packages to return.  All fields are returned if not
+	// set.
+	ReadMask *fieldmaskpb.FieldMask `protobuf:"bytes,6,opt,name=read_mask,json=readMask,proto3" json:"read_mask,omitempty"`
+}
+
+func (x *ListProtoPackagesRequest) Reset() {
+	*x = ListProtoPackagesRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_protopkg_registry_v1alpha1_registry_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListProtoPackagesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListProtoPackagesRequest) ProtoMessage() {}
+
+func (x *ListProtoPackagesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protopkg_registry_v1alpha1_registry_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListProtoPackagesRequest.ProtoReflect.Descriptor instead.
+func (*ListProtoPackagesRequest) Descriptor() ([]byte, []int) {
+	return file_protopkg_registry_v1alpha1_registry_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ListProtoPackagesRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ListProtoPackagesRequest) GetRepository() string {
+	if x != nil {
+		return x.Repository
+	}
+	return ""
+}
+
+func (x *ListProtoPackagesRequest) GetCommit() string {
+	if x != nil {
+		return x.Commit
+	}
+	return ""
+}
+
+func (x *ListProtoPackagesRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListProtoPackagesRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+func (x *ListProtoPackagesRequest) GetReadMask() *fieldmaskpb.FieldMask {
+	if x != nil {
+		return x.ReadMask
+	}
+	return nil
+}
+
+type ListProtoPackagesResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// the packages, most recently committed first.
+	Packages []*v1alpha2.ProtoPackage `protobuf:"bytes,1,rep,name=packages,proto3" json:"packages,omitempty"`
+	// token of the next page, empty if there are no more packages.
+	NextPageToken string `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+}
+
+func (x *ListProtoPackagesResponse) Reset() {
+	*x = ListProtoPackagesResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_protopkg_registry_v1alpha1_registry_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListProtoPackagesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListProtoPackagesResponse) ProtoMessage() {}
+
+func (x *ListProtoPackagesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_protopkg_registry_v1alpha1_registry_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListProtoPackagesResponse.ProtoReflect.Descriptor instead.
+func (*ListProtoPackagesResponse) Descriptor() ([]byte, []int) {
+	return file_protopkg_registry_v1alpha1_registry_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ListProtoPackagesResponse) GetPackages() []*v1alpha2.ProtoPackage {
+	if x != nil {
+		return x.Packages
+	}
+	return nil
+}
+
+func (x *ListProtoPackagesResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+type FindProtoPackagesByFileRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// the name of the proto file, as imported (e.g. 'google/api/http.proto').
+	File string `protobuf:"bytes,1,opt,name=file,proto3" json:"file,omitempty"`
+	// the maximum number of packages to return (default 100, max 1000).
+	PageSize int32 `protobuf:"varint,2,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// the next_page_token of the previous response.
+	PageToken string `protobuf:"bytes,3,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	// the fields of the packages to return.  All fields are returned if not
+	// set.
+	ReadMask *fieldmaskpb.FieldMask `protobuf:"bytes,4,opt,name=read_mask,json=readMask,proto3" json:"read_mask,omitempty"`
+}
+
+func (x *FindProtoPackagesByFileRequest) Reset() {
+	*x = FindProtoPackagesByFileRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_protopkg_registry_v1alpha1_registry_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FindProtoPackagesByFileRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FindProtoPackagesByFileRequest) ProtoMessage() {}
+
+func (x *FindProtoPackagesByFileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protopkg_registry_v1alpha1_registry_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FindProtoPackagesByFileRequest.ProtoReflect.Descriptor instead.
+func (*FindProtoPackagesByFileRequest) Descriptor() ([]byte, []int) {
+	return file_protopkg_registry_v1alpha1_registry_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *FindProtoPackagesByFileRequest) GetFile() string {
+	if x != nil {
+		return x.File
+	}
+	return ""
+}
+
+func (x *FindProtoPackagesByFileRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *FindProtoPackagesByFileRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+func (x *FindProtoPackagesByFileRequest) GetReadMask() *fieldmaskpb.FieldMask {
+	if x != nil {
+		return x.ReadMask
+	}
+	return nil
+}
+
+type FindProtoPackagesBySymbolRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// the fully-qualified name of a message, field, enum, enum value,
+	// service, method or extension, without leading dot (e.g.
+	// 'google.api.HttpRule').
+	Symbol string `protobuf:"bytes,1,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	// the maximum number of packages to return (default 100, max 1000).
+	PageSize int32 `protobuf:"varint,2,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// the next_page_token of the previous response.
+	PageToken string `protobuf:"bytes,3,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	// the fields of the packages to return.  All fields are returned if not
+	// set.
+	ReadMask *fieldmaskpb.FieldMask `protobuf:"bytes,4,opt,name=read_mask,json=readMask,proto3" json:"read_mask,omitempty"`
+}
+
+func (x *FindProtoPackagesBySymbolRequest) Reset() {
+	*x = FindProtoPackagesBySymbolRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_protopkg_registry_v1alpha1_registry_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FindProtoPackagesBySymbolRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FindProtoPackagesBySymbolRequest) ProtoMessage() {}
+
+func (x *FindProtoPackagesBySymbolRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protopkg_registry_v1alpha1_registry_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FindProtoPackagesBySymbolRequest.ProtoReflect.Descriptor instead.
+func (*FindProtoPackagesBySymbolRequest) Descriptor() ([]byte, []int) {
+	return file_protopkg_registry_v1alpha1_registry_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *FindProtoPackagesBySymbolRequest) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *FindProtoPackagesBySymbolRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *FindProtoPackagesBySymbolRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+func (x *FindProtoPackagesBySymbolRequest) GetReadMask() *fieldmaskpb.FieldMask {
+	if x != nil {
+		return x.ReadMask
+	}
+	return nil
+}
+
+var File_protopkg_registry_v1alpha1_registry_proto protoreflect.FileDescriptor
+
+var file_protopkg_registry_v1alpha1_registry_proto_rawDesc = []byte{
+	0x0a, 0x29, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x70, 0x6b, 0x67, 0x2f, 0x72, 0x65, 0x67, 0x69, 0x73,
+	0x74, 0x72, 0x79, 0x2f, 0x76, 0x31, 0x61, 0x6c, 0x70, 0x68, 0x61, 0x31, 0x2f, 0x72, 0x65, 0x67,
+	0x69, 0x73, 0x74, 0x72, 0x79, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x1a, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x70, 0x6b, 0x67, 0x2e, 0x72, 0x65, 0x67, 0x69, 0x73, 0x74, 0x72, 0x79, 0x2e, 0x76,
+	0x31, 0x61, 0x6c, 0x70, 0x68, 0x61, 0x31, 0x1a, 0x33, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x2f, 0x73,
+	0x74, 0x61, 0x63, 0x6b, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x70, 0x61,
+	0x63, 0x6b, 0x61, 0x67, 0x65, 0x2f, 0x76, 0x31, 0x61, 0x6c, 0x70, 0x68, 0x61, 0x32, 0x2f, 0x70,
+	0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x20, 0x67, 0x6f,
+	0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x66, 0x69,
+	0x65, 0x6c, 0x64, 0x5f, 0x6d, 0x61, 0x73, 0x6b, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x4b,
+	0x0a, 0x0f, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x52, 0x65,
+	0x66, 0x12, 0x10, 0x0a, 0x03, 0x72, 0x65, 0x66, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03,
+	0x72, 0x65, 0x66, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x68, 0x61, 0x73, 0x68, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x68, 0x61, 0x73, 0x68, 0x22, 0xab, 0x02, 0x0a, 0x12,
+	0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x53, 0x74, 0x61, 0x74,
+	0x75, 0x73, 0x12, 0x3d, 0x0a, 0x03, 0x70, 0x6b, 0x67, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x2b, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x70, 0x6b, 0x67, 0x2e, 0x72, 0x65, 0x67, 0x69, 0x73,
+	0x74, 0x72, 0x79, 0x2e, 0x76, 0x31, 0x61, 0x6c, 0x70, 0x68, 0x61, 0x31, 0x2e, 0x50, 0x72, 0x6f,
+	0x74, 0x6f, 0x50, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x52, 0x65, 0x66, 0x52, 0x03, 0x70, 0x6b,
+	0x67, 0x12, 0x4a, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0e,
+	0x32, 0x34, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x70, 0x6b, 0x67, 0x2e, 0x72, 0x65, 0x67, 0x69,
+	0x73, 0x74, 0x72, 0x79, 0x2e, 0x76, 0x31, 0x61, 0x6c, 0x70, 0x68, 0x61, 0x31, 0x2e, 0x50, 0x72,
+	0x6f, 0x74, 0x6f, 0x50, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73,
+	0x2e, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x12, 0x0e, 0x0a,
+	0x02, 0x69, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12, 0x0a,
+	0x04, 0x68, 0x61, 0x73, 0x68, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x68, 0x61, 0x73,
+	0x68, 0x12, 0x1f, 0x0a, 0x0b, 0x70, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x5f, 0x75, 0x72, 0x6c,
+	0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x70, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x55,
+	0x72, 0x6c, 0x22, 0x45, 0x0a, 0x05, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x15, 0x0a, 0x11, 0x53,
+	0x54, 0x41, 0x54, 0x45, 0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49, 0x46, 0x49, 0x45, 0x44,
+	0x10, 0x00, 0x12, 0x0b, 0x0a, 0x07, 0x4d, 0x49, 0x53, 0x53, 0x49, 0x4e, 0x47, 0x10, 0x01, 0x12,
+	0x0a, 0x0a, 0x06, 0x45, 0x58, 0x49, 0x53, 0x54, 0x53, 0x10, 0x02, 0x12, 0x0c, 0x0a, 0x08, 0x43,
+	0x4f, 0x4e, 0x46, 0x4c, 0x49, 0x43, 0x54, 0x10, 0x03, 0x22, 0x64, 0x0a, 0x19, 0x43, 0x68, 0x65,
+	0x63, 0x6b, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x73, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x47, 0x0a, 0x08, 0x70, 0x61, 0x63, 0x6b, 0x61, 0x67,
+	0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x2b, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x70, 0x6b, 0x67, 0x2e, 0x72, 0x65, 0x67, 0x69, 0x73, 0x74, 0x72, 0x79, 0x2e, 0x76, 0x31, 0x61,
+	0x6c, 0x70, 0x68, 0x61, 0x31, 0x2e, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50, 0x61, 0x63, 0x6b, 0x61,
+	0x67, 0x65, 0x52, 0x65, 0x66, 0x52, 0x08, 0x70, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x73, 0x22,
+	0x68, 0x0a, 0x1a, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50, 0x61, 0x63,
+	0x6b, 0x61, 0x67, 0x65, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x4a, 0x0a,
+	0x08, 0x70, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x2e, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x70, 0x6b, 0x67, 0x2e, 0x72, 0x65, 0x67, 0x69, 0x73,
+	0x74, 0x72, 0x79, 0x2e, 0x76, 0x31, 0x61, 0x6c, 0x70, 0x68, 0x61, 0x31, 0x2e, 0x50, 0x72, 0x6f,
+	0x74, 0x6f, 0x50, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52,
+	0x08, 0x70, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x73, 0x22, 0x68, 0x0a, 0x1a, 0x43, 0x72, 0x65,
+	0x61, 0x74, 0x65, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x4a, 0x0a, 0x08, 0x70, 0x61, 0x63, 0x6b, 0x61,
+	0x67, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x2e, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x70, 0x6b, 0x67, 0x2e, 0x72, 0x65, 0x67, 0x69, 0x73, 0x74, 0x72, 0x79, 0x2e, 0x76, 0x31,
+	0x61, 0x6c, 0x70, 0x68, 0x61, 0x31, 0x2e, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50, 0x61, 0x63, 0x6b,
+	0x61, 0x67, 0x65, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x08, 0x70, 0x61, 0x63, 0x6b, 0x61,
+	0x67, 0x65, 0x73, 0x22, 0x33, 0x0a, 0x09, 0x53, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65,
+	0x12, 0x14, 0x0a, 0x05, 0x6b, 0x65, 0x79, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x05, 0x6b, 0x65, 0x79, 0x69, 0x64, 0x12, 0x10, 0x0a, 0x03, 0x73, 0x69, 0x67, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x0c, 0x52, 0x03, 0x73, 0x69, 0x67, 0x22, 0x97, 0x01, 0x0a, 0x11, 0x53, 0x69, 0x67,
+	0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x45, 0x6e, 0x76, 0x65, 0x6c, 0x6f, 0x70, 0x65, 0x12, 0x21,
+	0x0a, 0x0c, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x5f, 0x74, 0x79, 0x70, 0x65, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x54, 0x79, 0x70,
+	0x65, 0x12, 0x18, 0x0a, 0x07, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x0c, 0x52, 0x07, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x12, 0x45, 0x0a, 0x0a, 0x73,
+	0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x25, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x70, 0x6b, 0x67, 0x2e, 0x72, 0x65, 0x67, 0x69, 0x73,
+	0x74, 0x72, 0x79, 0x2e, 0x76, 0x31, 0x61, 0x6c, 0x70, 0x68, 0x61, 0x31, 0x2e, 0x53, 0x69, 0x67,
+	0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x52, 0x0a, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72,
+	0x65, 0x73, 0x22, 0x64, 0x0a, 0x15, 0x53, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x45,
+	0x6e, 0x76, 0x65, 0x6c, 0x6f, 0x70, 0x65, 0x4c, 0x69, 0x73, 0x74, 0x12, 0x4b, 0x0a, 0x09, 0x65,
+	0x6e, 0x76, 0x65, 0x6c, 0x6f, 0x70, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x2d,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x70, 0x6b, 0x67, 0x2e, 0x72, 0x65, 0x67, 0x69, 0x73, 0x74,
+	0x72, 0x79, 0x2e, 0x76, 0x31, 0x61, 0x6c, 0x70, 0x68, 0x61, 0x31, 0x2e, 0x53, 0x69, 0x67, 0x6e,
+	0x61, 0x74, 0x75, 0x72, 0x65, 0x45, 0x6e, 0x76, 0x65, 0x6c, 0x6f, 0x70, 0x65, 0x52, 0x09, 0x65,
+	0x6e, 0x76, 0x65, 0x6c, 0x6f, 0x70, 0x65, 0x73, 0x22, 0x6f, 0x0a, 0x20, 0x50, 0x75, 0x74, 0x50,
+	0x72, 0x6f, 0x74, 0x6f, 0x50, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x53, 0x69, 0x67, 0x6e, 0x61,
+	0x74, 0x75, 0x72, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x4b, 0x0a, 0x09,
+	0x65, 0x6e, 0x76, 0x65, 0x6c, 0x6f, 0x70, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x2d, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x70, 0x6b, 0x67, 0x2e, 0x72, 0x65, 0x67, 0x69, 0x73,
+	0x74, 0x72, 0x79, 0x2e, 0x76, 0x31, 0x61, 0x6c, 0x70, 0x68, 0x61, 0x31, 0x2e, 0x53, 0x69, 0x67,
+	0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x45, 0x6e, 0x76, 0x65, 0x6c, 0x6f, 0x70, 0x65, 0x52, 0x09,
+	0x65, 0x6e, 0x76, 0x65, 0x6c, 0x6f, 0x70, 0x65, 0x73, 0x22, 0x3b, 0x0a, 0x21, 0x50, 0x75, 0x74,
+	0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x53, 0x69, 0x67, 0x6e,
+	0x61, 0x74, 0x75, 0x72, 0x65, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x16,
+	0x0a, 0x06, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x06,
+	0x73, 0x74, 0x6f, 0x72, 0x65, 0x64, 0x22, 0x8a, 0x01, 0x0a, 0x19, 0x4c, 0x6f, 0x6f, 0x6b, 0x75,
+	0x70, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x72, 0x65, 0x66, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x03, 0x72, 0x65, 0x66, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x68, 0x61, 0x73, 0x68, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x68, 0x61, 0x73, 0x68, 0x12, 0x37, 0x0a, 0x09, 0x72, 0x65,
+	0x61, 0x64, 0x5f, 0x6d, 0x61, 0x73, 0x6b, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e,
+	0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e,
+	0x46, 0x69, 0x65, 0x6c, 0x64, 0x4d, 0x61, 0x73, 0x6b, 0x52, 0x08, 0x72, 0x65, 0x61, 0x64, 0x4d,
+	0x61, 0x73, 0x6b, 0x22, 0xdb, 0x01, 0x0a, 0x18, 0x4c, 0x69, 0x73, 0x74, 0x50, 0x72, 0x6f, 0x74,
+	0x6f, 0x50, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04,
+	0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1e, 0x0a, 0x0a, 0x72, 0x65, 0x70, 0x6f, 0x73, 0x69, 0x74, 0x6f,
+	0x72, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x72, 0x65, 0x70, 0x6f, 0x73, 0x69,
+	0x74, 0x6f, 0x72, 0x79, 0x12, 0x16, 0x0a, 0x06, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x12, 0x1b, 0x0a, 0x09,
+	0x70, 0x61, 0x67, 0x65, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52,
+	0x08, 0x70, 0x61, 0x67, 0x65, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x70, 0x61, 0x67,
+	0x65, 0x5f, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x70,
+	0x61, 0x67, 0x65, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x12, 0x37, 0x0a, 0x09, 0x72, 0x65, 0x61, 0x64,
+	0x5f, 0x6d, 0x61, 0x73, 0x6b, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f,
+	0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x46, 0x69,
+	0x65, 0x6c, 0x64, 0x4d, 0x61, 0x73, 0x6b, 0x52, 0x08, 0x72, 0x65, 0x61, 0x64, 0x4d, 0x61, 0x73,
+	0x6b, 0x22, 0x94, 0x01, 0x0a, 0x19, 0x4c, 0x69, 0x73, 0x74, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50,
+	0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12,
+	0x4f, 0x0a, 0x08, 0x70, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x33, 0x2e, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x2e, 0x73, 0x74, 0x61, 0x63, 0x6b, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x70, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65,
+	0x2e, 0x76, 0x31, 0x61, 0x6c, 0x70, 0x68, 0x61, 0x32, 0x2e, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50,
+	0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x52, 0x08, 0x70, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x73,
+	0x12, 0x26, 0x0a, 0x0f, 0x6e, 0x65, 0x78, 0x74, 0x5f, 0x70, 0x61, 0x67, 0x65, 0x5f, 0x74, 0x6f,
+	0x6b, 0x65, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x6e, 0x65, 0x78, 0x74, 0x50,
+	0x61, 0x67, 0x65, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x22, 0xa9, 0x01, 0x0a, 0x1e, 0x46, 0x69, 0x6e,
+	0x64, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x73, 0x42, 0x79,
+	0x46, 0x69, 0x6c, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x66,
+	0x69, 0x6c, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x66, 0x69, 0x6c, 0x65, 0x12,
+	0x1b, 0x0a, 0x09, 0x70, 0x61, 0x67, 0x65, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x05, 0x52, 0x08, 0x70, 0x61, 0x67, 0x65, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x1d, 0x0a, 0x0a,
+	0x70, 0x61, 0x67, 0x65, 0x5f, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x09, 0x70, 0x61, 0x67, 0x65, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x12, 0x37, 0x0a, 0x09, 0x72,
+	0x65, 0x61, 0x64, 0x5f, 0x6d, 0x61, 0x73, 0x6b, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a,
+	0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66,
+	0x2e, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x4d, 0x61, 0x73, 0x6b, 0x52, 0x08, 0x72, 0x65, 0x61, 0x64,
+	0x4d, 0x61, 0x73, 0x6b, 0x22, 0xaf, 0x01, 0x0a, 0x20, 0x46, 0x69, 0x6e, 0x64, 0x50, 0x72, 0x6f,
+	0x74, 0x6f, 0x50, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x73, 0x42, 0x79, 0x53, 0x79, 0x6d, 0x62,
+	0x6f, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x79, 0x6d,
+	0x62, 0x6f, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x79, 0x6d, 0x62, 0x6f,
+	0x6c, 0x12, 0x1b, 0x0a, 0x09, 0x70, 0x61, 0x67, 0x65, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x70, 0x61, 0x67, 0x65, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x1d,
+	0x0a, 0x0a, 0x70, 0x61, 0x67, 0x65, 0x5f, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x09, 0x70, 0x61, 0x67, 0x65, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x12, 0x37, 0x0a,
+	0x09, 0x72, 0x65, 0x61, 0x64, 0x5f, 0x6d, 0x61, 0x73, 0x6b, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62,
+	0x75, 0x66, 0x2e, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x4d, 0x61, 0x73, 0x6b, 0x52, 0x08, 0x72, 0x65,
+	0x61, 0x64, 0x4d, 0x61, 0x73, 0x6b, 0x32, 0xf1, 0x06, 0x0a, 0x08, 0x52, 0x65, 0x67, 0x69, 0x73,
+	0x74, 0x72, 0x79, 0x12, 0x88, 0x01, 0x0a, 0x12, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x50, 0x72, 0x6f,
+	0x74, 0x6f, 0x50, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x73, 0x12, 0x35, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x70, 0x6b, 0x67, 0x2e, 0x72, 0x65, 0x67, 0x69, 0x73, 0x74, 0x72, 0x79, 0x2e, 0x76,
+	0x31, 0x61, 0x6c, 0x70, 0x68, 0x61, 0x31, 0x2e, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x50, 0x72, 0x6f,
+	0x74, 0x6f, 0x50, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x36, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x70, 0x6b, 0x67, 0x2e, 0x72, 0x65, 0x67,
+	0x69, 0x73, 0x74, 0x72, 0x79, 0x2e, 0x76, 0x31, 0x61, 0x6c, 0x70, 0x68, 0x61, 0x31, 0x2e, 0x43,
+	0x68, 0x65, 0x63, 0x6b, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65,
+	0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x03, 0x90, 0x02, 0x01, 0x12, 0x85,
+	0x01, 0x0a, 0x12, 0x4c, 0x6f, 0x6f, 0x6b, 0x75, 0x70, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50, 0x61,
+	0x63, 0x6b, 0x61, 0x67, 0x65, 0x12, 0x35, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x70, 0x6b, 0x67,
+	0x2e, 0x72, 0x65, 0x67, 0x69, 0x73, 0x74, 0x72, 0x79, 0x2e, 0x76, 0x31, 0x61, 0x6c, 0x70, 0x68,
+	0x61, 0x31, 0x2e, 0x4c, 0x6f, 0x6f, 0x6b, 0x75, 0x70, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50, 0x61,
+	0x63, 0x6b, 0x61, 0x67, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x33, 0x2e, 0x62,
+	0x75, 0x69, 0x6c, 0x64, 0x2e, 0x73, 0x74, 0x61, 0x63, 0x6b, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x62, 0x75, 0x66, 0x2e, 0x70, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x2e, 0x76, 0x31, 0x61, 0x6c,
+	0x70, 0x68, 0x61, 0x32, 0x2e, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50, 0x61, 0x63, 0x6b, 0x61, 0x67,
+	0x65, 0x22, 0x03, 0x90, 0x02, 0x01, 0x12, 0x85, 0x01, 0x0a, 0x11, 0x4c, 0x69, 0x73, 0x74, 0x50,
+	0x72, 0x6f, 0x74, 0x6f, 0x50, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x73, 0x12, 0x34, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x70, 0x6b, 0x67, 0x2e, 0x72, 0x65, 0x67, 0x69, 0x73, 0x74, 0x72, 0x79,
+	0x2e, 0x76, 0x31, 0x61, 0x6c, 0x70, 0x68, 0x61, 0x31, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x50, 0x72,
+	0x6f, 0x74, 0x6f, 0x50, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x35, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x70, 0x6b, 0x67, 0x2e, 0x72, 0x65,
+	0x67, 0x69, 0x73, 0x74, 0x72, 0x79, 0x2e, 0x76, 0x31, 0x61, 0x6c, 0x70, 0x68, 0x61, 0x31, 0x2e,
+	0x4c, 0x69, 0x73, 0x74, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65,
+	0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x03, 0x90, 0x02, 0x01, 0x12, 0x91,
+	0x01, 0x0a, 0x17, 0x46, 0x69, 0x6e, 0x64, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50, 0x61, 0x63, 0x6b,
+	0x61, 0x67, 0x65, 0x73, 0x42, 0x79, 0x46, 0x69, 0x6c, 0x65, 0x12, 0x3a, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x70, 0x6b, 0x67, 0x2e, 0x72, 0x65, 0x67, 0x69, 0x73, 0x74, 0x72, 0x79, 0x2e, 0x76,
+	0x31, 0x61, 0x6c, 0x70, 0x68, 0x61, 0x31, 0x2e, 0x46, 0x69, 0x6e, 0x64, 0x50, 0x72, 0x6f, 0x74,
+	0x6f, 0x50, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x73, 0x42, 0x79, 0x46, 0x69, 0x6c, 0x65, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x35, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x70, 0x6b,
+	0x67, 0x2e, 0x72, 0x65, 0x67, 0x69, 0x73, 0x74, 0x72, 0x79, 0x2e, 0x76, 0x31, 0x61, 0x6c, 0x70,
+	0x68, 0x61, 0x31, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50, 0x61, 0x63,
+	0x6b, 0x61, 0x67, 0x65, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x03, 0x90,
+	0x02, 0x01, 0x12, 0x95, 0x01, 0x0a, 0x19, 0x46, 0x69, 0x6e, 0x64, 0x50, 0x72, 0x6f, 0x74, 0x6f,
+	0x50, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x73, 0x42, 0x79, 0x53, 0x79, 0x6d, 0x62, 0x6f, 0x6c,
+	0x12, 0x3c, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x70, 0x6b, 0x67, 0x2e, 0x72, 0x65, 0x67, 0x69,
+	0x73, 0x74, 0x72, 0x79, 0x2e, 0x76, 0x31, 0x61, 0x6c, 0x70, 0x68, 0x61, 0x31, 0x2e, 0x46, 0x69,
+	0x6e, 0x64, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x73, 0x42,
+	0x79, 0x53, 0x79, 0x6d, 0x62, 0x6f, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x35,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x70, 0x6b, 0x67, 0x2e, 0x72, 0x65, 0x67, 0x69, 0x73, 0x74,
+	0x72, 0x79, 0x2e, 0x76, 0x31, 0x61, 0x6c, 0x70, 0x68, 0x61, 0x31, 0x2e, 0x4c, 0x69, 0x73, 0x74,
+	0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x73, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x03, 0x90, 0x02, 0x01, 0x12, 0x9d, 0x01, 0x0a, 0x19, 0x50,
+	0x75, 0x74, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x53, 0x69,
+	0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x73, 0x12, 0x3c, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x70, 0x6b, 0x67, 0x2e, 0x72, 0x65, 0x67, 0x69, 0x73, 0x74, 0x72, 0x79, 0x2e, 0x76, 0x31, 0x61,
+	0x6c, 0x70, 0x68, 0x61, 0x31, 0x2e, 0x50, 0x75, 0x74, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50, 0x61,
+	0x63, 0x6b, 0x61, 0x67, 0x65, 0x53, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x73, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x3d, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x70, 0x6b,
+	0x67, 0x2e, 0x72, 0x65, 0x67, 0x69, 0x73, 0x74, 0x72, 0x79, 0x2e, 0x76, 0x31, 0x61, 0x6c, 0x70,
+	0x68, 0x61, 0x31, 0x2e, 0x50, 0x75, 0x74, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50, 0x61, 0x63, 0x6b,
+	0x61, 0x67, 0x65, 0x53, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x73, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x03, 0x90, 0x02, 0x02, 0x42, 0x35, 0x5a, 0x33, 0x67, 0x69,
+	0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x70, 0x6b,
+	0x67, 0x2f, 0x61, 0x70, 0x69, 0x73, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x70, 0x6b, 0x67, 0x2f,
+	0x72, 0x65, 0x67, 0x69, 0x73, 0x74, 0x72, 0x79, 0x2f, 0x76, 0x31, 0x61, 0x6c, 0x70, 0x68, 0x61,
+	0x31, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_protopkg_registry_v1alpha1_registry_proto_rawDescOnce sync.Once
+	file_protopkg_registry_v1alpha1_registry_proto_rawDescData = file_protopkg_registry_v1alpha1_registry_proto_rawDesc
+)
+
+func file_protopkg_registry_v1alpha1_registry_proto_rawDescGZIP() []byte {
+	file_protopkg_registry_v1alpha1_registry_proto_rawDescOnce.Do(func() {
+		file_protopkg_registry_v1alpha1_registry_proto_rawDescData = protoimpl.X.CompressGZIP(file_protopkg_registry_v1alpha1_registry_proto_rawDescData)
+	})
+	return file_protopkg_registry_v1alpha1_registry_proto_rawDescData
+}
+
+var file_protopkg_registry_v1alpha1_registry_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_protopkg_registry_v1alpha1_registry_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
+var file_protopkg_registry_v1alpha1_registry_proto_goTypes = []interface{}{
+	(ProtoPackageStatus_State)(0),             // 0: protopkg.registry.v1alpha1.ProtoPackageStatus.State
+	(*ProtoPackageRef)(nil),                   // 1: protopkg.registry.v1alpha1.ProtoPackageRef
+	(*ProtoPackageStatus)(nil),                // 2: protopkg.registry.v1alpha1.ProtoPackageStatus
+	(*CheckProtoPackagesRequest)(nil),         // 3: protopkg.registry.v1alpha1.CheckProtoPackagesRequest
+	(*CheckProtoPackagesResponse)(nil),        // 4: protopkg.registry.v1alpha1.CheckProtoPackagesResponse
+	(*CreateProtoPackageResponse)(nil),        // 5: protopkg.registry.v1alpha1.CreateProtoPackageResponse
+	(*Signature)(nil),                         // 6: protopkg.registry.v1alpha1.Signature
+	(*SignatureEnvelope)(nil),                 // 7: protopkg.registry.v1alpha1.SignatureEnvelope
+	(*SignatureEnvelopeList)(nil),             // 8: protopkg.registry.v1alpha1.SignatureEnvelopeList
+	(*PutProtoPackageSignaturesRequest)(nil),  // 9: protopkg.registry.v1alpha1.PutProtoPackageSignaturesRequest
+	(*PutProtoPackageSignaturesResponse)(nil), // 10: protopkg.registry.v1alpha1.PutProtoPackageSignaturesResponse
+	(*LookupProtoPackageRequest)(nil),         // 11: protopkg.registry.v1alpha1.LookupProtoPackageRequest
+	(*ListProtoPackagesRequest)(nil),          // 12: protopkg.registry.v1alpha1.ListProtoPackagesRequest
+	(*ListProtoPackagesResponse)(nil),         // 13: protopkg.registry.v1alpha1.ListProtoPackagesResponse
+	(*FindProtoPackagesByFileRequest)(nil),    // 14: protopkg.registry.v1alpha1.FindProtoPackagesByFileRequest
+	(*FindProtoPackagesBySymbolRequest)(nil),  // 15: protopkg.registry.v1alpha1.FindProtoPackagesBySymbolRequest
+	(*fieldmaskpb.FieldMask)(nil),             // 16: google.protobuf.FieldMask
+	(*v1alpha2.ProtoPackage)(nil),             // 17: build.stack.protobuf.package.v1alpha2.ProtoPackage
+}
+var file_protopkg_registry_v1alpha1_registry_proto_depIdxs = []int32{
+	1,  // 0: protopkg.registry.v1alpha1.ProtoPackageStatus.pkg:type_name -> protopkg.registry.v1alpha1.ProtoPackageRef
+	0,  // 1: protopkg.registry.v1alpha1.ProtoPackageStatus.state:type_name -> protopkg.registry.v1alpha1.ProtoPackageStatus.State
+	1,  // 2: protopkg.registry.v1alpha1.CheckProtoPackagesRequest.packages:type_name -> protopkg.registry.v1alpha1.ProtoPackageRef
+	2,  // 3: protopkg.registry.v1alpha1.CheckProtoPackagesResponse.packages:type_name -> protopkg.registry.v1alpha1.ProtoPackageStatus
+	2,  // 4: protopkg.registry.v1alpha1.CreateProtoPackageResponse.packages:type_name -> protopkg.registry.v1alpha1.ProtoPackageStatus
+	6,  // 5: protopkg.registry.v1alpha1.SignatureEnvelope.signatures:type_name -> protopkg.registry.v1alpha1.Signature
+	7,  // 6: protopkg.registry.v1alpha1.SignatureEnvelopeList.envelopes:type_name -> protopkg.registry.v1alpha1.SignatureEnvelope
+	7,  // 7: protopkg.registry.v1alpha1.PutProtoPackageSignaturesRequest.envelopes:type_name -> protopkg.registry.v1alpha1.SignatureEnvelope
+	16, // 8: protopkg.registry.v1alpha1.LookupProtoPackageRequest.read_mask:type_name -> google.protobuf.FieldMask
+	16, // 9: protopkg.registry.v1alpha1.ListProtoPackagesRequest.read_mask:type_name -> google.protobuf.FieldMask
+	17, // 10: protopkg.registry.v1alpha1.ListProtoPackagesResponse.packages:type_name -> build.stack.protobuf.package.v1alpha2.ProtoPackage
+	16, // 11: protopkg.registry.v1alpha1.FindProtoPackagesByFileRequest.read_mask:type_name -> google.protobuf.FieldMask
+	16, // 12: protopkg.registry.v1alpha1.FindProtoPackagesBySymbolRequest.read_mask:type_name -> google.protobuf.FieldMask
+	3,  // 13: protopkg.registry.v1alpha1.Registry.CheckProtoPackages:input_type -> protopkg.registry.v1alpha1.CheckProtoPackagesRequest
+	11, // 14: protopkg.registry.v1alpha1.Registry.LookupProtoPackage:input_type -> protopkg.registry.v1alpha1.LookupProtoPackageRequest
+	12, // 15: protopkg.registry.v1alpha1.Registry.ListProtoPackages:input_type -> protopkg.registry.v1alpha1.ListProtoPackagesRequest
+	14, // 16: protopkg.registry.v1alpha1.Registry.FindProtoPackagesByFile:input_type -> protopkg.registry.v1alpha1.FindProtoPackagesByFileRequest
+	15, // 17: protopkg.registry.v1alpha1.Registry.FindProtoPackagesBySymbol:input_type -> protopkg.registry.v1alpha1.FindProtoPackagesBySymbolRequest
+	9,  // 18: protopkg.registry.v1alpha1.Registry.PutProtoPackageSignatures:input_type -> protopkg.registry.v1alpha1.PutProtoPackageSignaturesRequest
+	4,  // 19: protopkg.registry.v1alpha1.Registry.CheckProtoPackages:output_type -> protopkg.registry.v1alpha1.CheckProtoPackagesResponse
+	17, // 20: protopkg.registry.v1alpha1.Registry.LookupProtoPackage:output_type -> build.stack.protobuf.package.v1alpha2.ProtoPackage
+	13, // 21: protopkg.registry.v1alpha1.Registry.ListProtoPackages:output_type -> protopkg.registry.v1alpha1.ListProtoPackagesResponse
+	13, // 22: protopkg.registry.v1alpha1.Registry.FindProtoPackagesByFile:output_type -> protopkg.registry.v1alpha1.ListProtoPackagesResponse
+	13, // 23: protopkg.registry.v1alpha1.Registry.FindProtoPackagesBySymbol:output_type -> protopkg.registry.v1alpha1.ListProtoPackagesResponse
+	10, // 24: protopkg.registry.v1alpha1.Registry.PutProtoPackageSignatures:output_type -> protopkg.registry.v1alpha1.PutProtoPackageSignaturesResponse
+	19, // [19:25] is the sub-list for method output_type
+	13, // [13:19] is the sub-list for method input_type
+	13, // [13:13] is the sub-list for extension type_name
+	13, // [13:13] is the sub-list for extension extendee
+	0,  // [0:13] is the sub-list for field type_name
+}
+
+func init() { file_protopkg_registry_v1alpha1_registry_proto_init() }
+func file_protopkg_registry_v1alpha1_registry_proto_init() {
+	if File_protopkg_registry_v1alpha1_registry_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_protopkg_registry_v1alpha1_registry_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ProtoPackageRef); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_protopkg_registry_v1alpha1_registry_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ProtoPackageStatus); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_protopkg_registry_v1alpha1_registry_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CheckProtoPackagesRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_protopkg_registry_v1alpha1_registry_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CheckProtoPackagesResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_protopkg_registry_v1alpha1_registry_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CreateProtoPackageResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_protopkg_registry_v1alpha1_registry_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Signature); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_protopkg_registry_v1alpha1_registry_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SignatureEnvelope); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_protopkg_registry_v1alpha1_registry_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SignatureEnvelopeList); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_protopkg_registry_v1alpha1_registry_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PutProtoPackageSignaturesRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_protopkg_registry_v1alpha1_registry_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PutProtoPackageSignaturesResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_protopkg_registry_v1alpha1_registry_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*LookupProtoPackageRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_protopkg_registry_v1alpha1_registry_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListProtoPackagesRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_protopkg_registry_v1alpha1_registry_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListProtoPackagesResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_protopkg_registry_v1alpha1_registry_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FindProtoPackagesByFileRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_protopkg_registry_v1alpha1_registry_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FindProtoPackagesBySymbolRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_protopkg_registry_v1alpha1_registry_proto_rawDesc,
+			NumEnums:      1,
+			NumMessages:   15,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_protopkg_registry_v1alpha1_registry_proto_goTypes,
+		DependencyIndexes: file_protopkg_registry_v1alpha1_registry_proto_depIdxs,
+		EnumInfos:         file_protopkg_registry_v1alpha1_registry_proto_enumTypes,
+		MessageInfos:      file_protopkg_registry_v1alpha1_registry_proto_msgTypes,
+	}.Build()
+	File_protopkg_registry_v1alpha1_registry_proto = out.File
+	file_protopkg_registry_v1alpha1_registry_proto_rawDesc = nil
+	file_protopkg_registry_v1alpha1_registry_proto_goTypes = nil
+	file_protopkg_registry_v1alpha1_registry_proto_depIdxs = nil
+}
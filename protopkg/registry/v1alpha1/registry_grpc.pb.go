@@ -0,0 +1,304 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             v3.14.0
+// source: protopkg/registry/v1alpha1/registry.proto
+
+package v1alpha1
+
+import (
+	context "context"
+	v1alpha2 "github.com/stackb/apis/build/stack/protobuf/package/v1alpha2"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// RegistryClient is the client API for Registry service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type RegistryClient interface {
+	// CheckProtoPackages reports which of the given packages the server has.
+	CheckProtoPackages(ctx context.Context, in *CheckProtoPackagesRequest, opts ...grpc.CallOption) (*CheckProtoPackagesResponse, error)
+	// LookupProtoPackage returns a package by ref, id or hash.
+	LookupProtoPackage(ctx context.Context, in *LookupProtoPackageRequest, opts ...grpc.CallOption) (*v1alpha2.ProtoPackage, error)
+	// ListProtoPackages lists the packages (e.g. the versions of a package),
+	// most recently committed first.
+	ListProtoPackages(ctx context.Context, in *ListProtoPackagesRequest, opts ...grpc.CallOption) (*ListProtoPackagesResponse, error)
+	// FindProtoPackagesByFile lists the packages that provide a proto file,
+	// most recently committed first.
+	FindProtoPackagesByFile(ctx context.Context, in *FindProtoPackagesByFileRequest, opts ...grpc.CallOption) (*ListProtoPackagesResponse, error)
+	// FindProtoPackagesBySymbol lists the packages that define a symbol, most
+	// recently committed first.
+	FindProtoPackagesBySymbol(ctx context.Context, in *FindProtoPackagesBySymbolRequest, opts ...grpc.CallOption) (*ListProtoPackagesResponse, error)
+	// PutProtoPackageSignatures stores detached signatures of packages.
+	PutProtoPackageSignatures(ctx context.Context, in *PutProtoPackageSignaturesRequest, opts ...grpc.CallOption) (*PutProtoPackageSignaturesResponse, error)
+}
+
+type registryClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRegistryClient(cc grpc.ClientConnInterface) RegistryClient {
+	return &registryClient{cc}
+}
+
+func (c *registryClient) CheckProtoPackages(ctx context.Context, in *CheckProtoPackagesRequest, opts ...grpc.CallOption) (*CheckProtoPackagesResponse, error) {
+	out := new(CheckProtoPackagesResponse)
+	err := c.cc.Invoke(ctx, "/protopkg.registry.v1alpha1.Registry/CheckProtoPackages", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *registryClient) LookupProtoPackage(ctx context.Context, in *LookupProtoPackageRequest, opts ...grpc.CallOption) (*v1alpha2.ProtoPackage, error) {
+	out := new(v1alpha2.ProtoPackage)
+	err := c.cc.Invoke(ctx, "/protopkg.registry.v1alpha1.Registry/LookupProtoPackage", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *registryClient) ListProtoPackages(ctx context.Context, in *ListProtoPackagesRequest, opts ...grpc.CallOption) (*ListProtoPackagesResponse, error) {
+	out := new(ListProtoPackagesResponse)
+	err := c.cc.Invoke(ctx, "/protopkg.registry.v1alpha1.Registry/ListProtoPackages", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *registryClient) FindProtoPackagesByFile(ctx context.Context, in *FindProtoPackagesByFileRequest, opts ...grpc.CallOption) (*ListProtoPackagesResponse, error) {
+	out := new(ListProtoPackagesResponse)
+	err := c.cc.Invoke(ctx, "/protopkg.registry.v1alpha1.Registry/FindProtoPackagesByFile", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *registryClient) FindProtoPackagesBySymbol(ctx context.Context, in *FindProtoPackagesBySymbolRequest, opts ...grpc.CallOption) (*ListProtoPackagesResponse, error) {
+	out := new(ListProtoPackagesResponse)
+	err := c.cc.Invoke(ctx, "/protopkg.registry.v1alpha1.Registry/FindProtoPackagesBySymbol", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *registryClient) PutProtoPackageSignatures(ctx context.Context, in *PutProtoPackageSignaturesRequest, opts ...grpc.CallOption) (*PutProtoPackageSignaturesResponse, error) {
+	out := new(PutProtoPackageSignaturesResponse)
+	err := c.cc.Invoke(ctx, "/protopkg.registry.v1alpha1.Registry/PutProtoPackageSignatures", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RegistryServer is the server API for Registry service.
+// All implementations must embed UnimplementedRegistryServer
+// for forward compatibility
+type RegistryServer interface {
+	// CheckProtoPackages reports which of the given packages the server has.
+	CheckProtoPackages(context.Context, *CheckProtoPackagesRequest) (*CheckProtoPackagesResponse, error)
+	// LookupProtoPackage returns a package by ref, id or hash.
+	LookupProtoPackage(context.Context, *LookupProtoPackageRequest) (*v1alpha2.ProtoPackage, error)
+	// ListProtoPackages lists the packages (e.g. the versions of a package),
+	// most recently committed first.
+	ListProtoPackages(context.Context, *ListProtoPackagesRequest) (*ListProtoPackagesResponse, error)
+	// FindProtoPackagesByFile lists the packages that provide a proto file,
+	// most recently committed first.
+	FindProtoPackagesByFile(context.Context, *FindProtoPackagesByFileRequest) (*ListProtoPackagesResponse, error)
+	// FindProtoPackagesBySymbol lists the packages that define a symbol, most
+	// recently committed first.
+	FindProtoPackagesBySymbol(context.Context, *FindProtoPackagesBySymbolRequest) (*ListProtoPackagesResponse, error)
+	// PutProtoPackageSignatures stores detached signatures of packages.
+	PutProtoPackageSignatures(context.Context, *PutProtoPackageSignaturesRequest) (*PutProtoPackageSignaturesResponse, error)
+	mustEmbedUnimplementedRegistryServer()
+}
+
+// UnimplementedRegistryServer must be embedded to have forward compatible implementations.
+type UnimplementedRegistryServer struct {
+}
+
+func (UnimplementedRegistryServer) CheckProtoPackages(context.Context, *CheckProtoPackagesRequest) (*CheckProtoPackagesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CheckProtoPackages not implemented")
+}
+func (UnimplementedRegistryServer) LookupProtoPackage(context.Context, *LookupProtoPackageRequest) (*v1alpha2.ProtoPackage, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LookupProtoPackage not implemented")
+}
+func (UnimplementedRegistryServer) ListProtoPackages(context.Context, *ListProtoPackagesRequest) (*ListProtoPackagesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListProtoPackages not implemented")
+}
+func (UnimplementedRegistryServer) FindProtoPackagesByFile(context.Context, *FindProtoPackagesByFileRequest) (*ListProtoPackagesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FindProtoPackagesByFile not implemented")
+}
+func (UnimplementedRegistryServer) FindProtoPackagesBySymbol(context.Context, *FindProtoPackagesBySymbolRequest) (*ListProtoPackagesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FindProtoPackagesBySymbol not implemented")
+}
+func (UnimplementedRegistryServer) PutProtoPackageSignatures(context.Context, *PutProtoPackageSignaturesRequest) (*PutProtoPackageSignaturesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PutProtoPackageSignatures not implemented")
+}
+func (UnimplementedRegistryServer) mustEmbedUnimplementedRegistryServer() {}
+
+// UnsafeRegistryServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RegistryServer will
+// result in compilation errors.
+type UnsafeRegistryServer interface {
+	mustEmbedUnimplementedRegistryServer()
+}
+
+func RegisterRegistryServer(s grpc.ServiceRegistrar, srv RegistryServer) {
+	s.RegisterService(&Registry_ServiceDesc, srv)
+}
+
+func _Registry_CheckProtoPackages_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckProtoPackagesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistryServer).CheckProtoPackages(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/protopkg.registry.v1alpha1.Registry/CheckProtoPackages",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistryServer).CheckProtoPackages(ctx, req.(*CheckProtoPackagesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Registry_LookupProtoPackage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LookupProtoPackageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistryServer).LookupProtoPackage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/protopkg.registry.v1alpha1.Registry/LookupProtoPackage",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistryServer).LookupProtoPackage(ctx, req.(*LookupProtoPackageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Registry_ListProtoPackages_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListProtoPackagesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistryServer).ListProtoPackages(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/protopkg.registry.v1alpha1.Registry/ListProtoPackages",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistryServer).ListProtoPackages(ctx, req.(*ListProtoPackagesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Registry_FindProtoPackagesByFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FindProtoPackagesByFileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistryServer).FindProtoPackagesByFile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/protopkg.registry.v1alpha1.Registry/FindProtoPackagesByFile",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistryServer).FindProtoPackagesByFile(ctx, req.(*FindProtoPackagesByFileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Registry_FindProtoPackagesBySymbol_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FindProtoPackagesBySymbolRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistryServer).FindProtoPackagesBySymbol(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/protopkg.registry.v1alpha1.Registry/FindProtoPackagesBySymbol",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistryServer).FindProtoPackagesBySymbol(ctx, req.(*FindProtoPackagesBySymbolRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Registry_PutProtoPackageSignatures_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PutProtoPackageSignaturesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistryServer).PutProtoPackageSignatures(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/protopkg.registry.v1alpha1.Registry/PutProtoPackageSignatures",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistryServer).PutProtoPackageSignatures(ctx, req.(*PutProtoPackageSignaturesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Registry_ServiceDesc is the grpc.ServiceDesc for Registry service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Registry_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "protopkg.registry.v1alpha1.Registry",
+	HandlerType: (*RegistryServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CheckProtoPackages",
+			Handler:    _Registry_CheckProtoPackages_Handler,
+		},
+		{
+			MethodName: "LookupProtoPackage",
+			Handler:    _Registry_LookupProtoPackage_Handler,
+		},
+		{
+			MethodName: "ListProtoPackages",
+			Handler:    _Registry_ListProtoPackages_Handler,
+		},
+		{
+			MethodName: "FindProtoPackagesByFile",
+			Handler:    _Registry_FindProtoPackagesByFile_Handler,
+		},
+		{
+			MethodName: "FindProtoPackagesBySymbol",
+			Handler:    _Registry_FindProtoPackagesBySymbol_Handler,
+		},
+		{
+			MethodName: "PutProtoPackageSignatures",
+			Handler:    _Registry_PutProtoPackageSignatures_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "protopkg/registry/v1alpha1/registry.proto",
+}
@@ -14,6 +14,7 @@ def _protopkg_file_impl(ctx):
     transitive_deps = [dep[ProtoFileInfo].proto_file_transitive_depset for dep in ctx.attr.deps]
 
     args = ctx.actions.args()
+    args.add("file")
     args.add("-proto_descriptor_set_file", proto_descriptor_set_file.path)
     args.add("-proto_repository_host", proto_repository_info.source_host)
     args.add("-proto_repository_owner", proto_repository_info.source_owner)
@@ -22,6 +23,7 @@ def _protopkg_file_impl(ctx):
     args.add("-proto_repository_root", proto_repository_info.source_prefix)
     args.add("-proto_compiler_name", proto_compiler_info.name)
     args.add("-proto_compiler_version_file", proto_compiler_version_file.path)
+    args.add("-hash_mode", ctx.attr.hash_mode)
     args.add_joined(
         "-proto_file_direct_dependency_files",
         [f.path for f in direct_deps_files.to_list()],
@@ -90,8 +92,13 @@ _protopkg_file = rule(
             mandatory = True,
             providers = [ProtoCompilerInfo],
         ),
+        "hash_mode": attr.string(
+            doc = "how proto file hashes are calculated: 'object' hashes the canonical descriptor, 'wire' hashes its wire-compatible projection (equivalent proto2/proto3 and editions files, and packages made of them, hash the same)",
+            default = "object",
+            values = ["object", "wire"],
+        ),
         "_tool": attr.label(
-            default = str(Label("//cmd/protopkg_file")),
+            default = str(Label("//cmd/protopkg")),
             executable = True,
             cfg = "exec",
         ),
@@ -0,0 +1,47 @@
+load("//rules:providers.bzl", "ProtoPackageInfo")
+
+def _protopkg_oci_layout_impl(ctx):
+    pkg = ctx.attr.pkg[ProtoPackageInfo]
+    layout = ctx.actions.declare_directory(ctx.label.name + ".oci")
+
+    args = ctx.actions.args()
+    args.add("oci")
+    args.add("-pkgset_file", pkg.output_file.path)
+    args.add("-oci_layout", layout.path)
+    args.add("-repository_prefix", ctx.attr.repository_prefix)
+    if ctx.attr.tag:
+        args.add("-tag", ctx.attr.tag)
+
+    ctx.actions.run(
+        executable = ctx.executable._tool,
+        arguments = [args],
+        inputs = [pkg.output_file],
+        outputs = [layout],
+        mnemonic = "ProtoPkgOciLayout",
+    )
+
+    return [DefaultInfo(files = depset([layout]))]
+
+protopkg_oci_layout = rule(
+    implementation = _protopkg_oci_layout_impl,
+    doc = "writes the packages of a protopkg_package as OCI artifacts to an OCI image layout directory",
+    attrs = {
+        "pkg": attr.label(
+            doc = "protopkg_package dependency",
+            mandatory = True,
+            providers = [ProtoPackageInfo],
+        ),
+        "repository_prefix": attr.string(
+            doc = "repository prefix; each package is named '<prefix>/<package name>'",
+            default = "protopkg",
+        ),
+        "tag": attr.string(
+            doc = "tag of the artifacts (default is the short commit of the package archive)",
+        ),
+        "_tool": attr.label(
+            default = str(Label("//cmd/protopkg")),
+            executable = True,
+            cfg = "exec",
+        ),
+    },
+)
@@ -10,27 +10,55 @@ def _protopkg_package_impl(ctx):
     config = struct(
         direct_deps = [f.path for f in direct_deps_files],
         transitive_deps = [f.path for f in transitive_deps_files],
+        include_packages = ctx.attr.include_packages,
+        exclude_packages = ctx.attr.exclude_packages,
+        include_files = ctx.attr.include_files,
+        exclude_files = ctx.attr.exclude_files,
+        include_repositories = ctx.attr.include_repositories,
+        exclude_repositories = ctx.attr.exclude_repositories,
     )
 
     ctx.actions.write(config_json_file, config.to_json())
 
     args = ctx.actions.args()
+    args.add("package")
     args.add("-config_json_file", config_json_file.path)
 
     inputs = [config_json_file] + direct_deps_files + transitive_deps_files
 
+    # the lockfile is only checked by the action that generates the proto
+    # output, the json output is not gated by it.
+    lockfile_args = []
+    lockfile_inputs = []
+    if ctx.file.lockfile:
+        lockfile_args = [
+            "-lockfile",
+            ctx.file.lockfile.path,
+            "-lockfile_mode",
+            "check",
+            # the '.lock' target of the protopkg_package macro
+            "-lockfile_target",
+            "//%s:%s.lock" % (ctx.label.package, ctx.label.name),
+        ]
+        lockfile_inputs = [ctx.file.lockfile]
+
     ctx.actions.run(
         executable = ctx.executable._tool,
-        arguments = [args] + ["-proto_out", ctx.outputs.proto.path],
-        inputs = inputs,
+        arguments = [args] + lockfile_args + ["-proto_out", ctx.outputs.proto.path],
+        inputs = inputs + lockfile_inputs,
         outputs = [ctx.outputs.proto],
     )
 
     ctx.actions.run(
         executable = ctx.executable._tool,
-        arguments = [args] + ["-json_out", ctx.outputs.json.path],
+        arguments = [args] + [
+            "-json_out",
+            ctx.outputs.json.path,
+            "-report_out",
+            ctx.outputs.report.path,
+        ],
         inputs = inputs,
-        outputs = [ctx.outputs.json],
+        outputs = [ctx.outputs.json, ctx.outputs.report],
     )
 
     return [
@@ -39,6 +67,7 @@ def _protopkg_package_impl(ctx):
         ),
         OutputGroupInfo(
             json = depset([ctx.outputs.json]),
+            report = depset([ctx.outputs.report]),
         ),
         ProtoPackageInfo(
             label = ctx.label,
@@ -55,8 +84,30 @@ _protopkg_package = rule(
             doc = "protopkg_file dependencies",
             providers = [ProtoFileInfo],
         ),
+        "include_packages": attr.string_list(
+            doc = "proto package name patterns to retain (e.g. 'google.api'); all packages are retained if empty",
+        ),
+        "exclude_packages": attr.string_list(
+            doc = "proto package name patterns to remove (e.g. 'google.rpc'); a retained package still depends on a removed package by ref, which must be published separately",
+        ),
+        "include_files": attr.string_list(
+            doc = "proto file path patterns to retain; all files are retained if empty",
+        ),
+        "exclude_files": attr.string_list(
+            doc = "proto file path patterns to remove (e.g. '*_test.proto')",
+        ),
+        "include_repositories": attr.string_list(
+            doc = "repository full name patterns to retain (e.g. 'github.com/googleapis/*'); all repositories are retained if empty",
+        ),
+        "exclude_repositories": attr.string_list(
+            doc = "repository full name patterns to remove",
+        ),
+        "lockfile": attr.label(
+            doc = "json lockfile that pins the hashes of the dependency packages (the packages that other packages of the set depend on; the packages nothing depends on are the ones being built, and are not locked); the build fails if the dependencies have drifted from it (run the '.lock' target to update)",
+            allow_single_file = True,
+        ),
         "_tool": attr.label(
-            default = str(Label("//cmd/protopkg_package")),
+            default = str(Label("//cmd/protopkg")),
             executable = True,
             cfg = "exec",
         ),
@@ -64,24 +115,52 @@ _protopkg_package = rule(
     outputs = {
         "proto": "%{name}.pkg.pb",
         "json": "%{name}.pkg.json",
+        "report": "%{name}.pkg.report.json",
     },
 )
 
 def _protopkg_create_impl(ctx):
     pkg = ctx.attr.pkg[ProtoPackageInfo]
 
-    script = """
-#/bin/bash
+    flags = []
+    files = []
+    if ctx.attr.tls:
+        flags.append("-tls")
+    if ctx.file.tls_ca_cert:
+        flags.append("-tls_ca_cert_file=" + ctx.file.tls_ca_cert.short_path)
+        files.append(ctx.file.tls_ca_cert)
+    if ctx.file.tls_client_cert:
+        flags.append("-tls_client_cert_file=" + ctx.file.tls_client_cert.short_path)
+        files.append(ctx.file.tls_client_cert)
+    if ctx.file.tls_client_key:
+        flags.append("-tls_client_key_file=" + ctx.file.tls_client_key.short_path)
+        files.append(ctx.file.tls_client_key)
+    if ctx.attr.tls_server_name:
+        flags.append("-tls_server_name=" + ctx.attr.tls_server_name)
+    if ctx.attr.auth_token_env:
+        flags.append("-auth_token_env=" + ctx.attr.auth_token_env)
+    if ctx.attr.auth_credential_helper:
+        flags.append("-auth_credential_helper=" + ctx.attr.auth_credential_helper)
+    if ctx.attr.wait:
+        flags.append("-wait")
+    if ctx.file.signatures:
+        flags.append("-signatures_file=" + ctx.file.signatures.short_path)
+        files.append(ctx.file.signatures)
+
+    script = """#!/bin/bash
 set -euo pipefail
 
-{executable} \
+{executable} create \
     -output_file={file} \
-    -packages_server_address={address}
+    -packages_server_address={address} \
+    {flags} \
+    "$@"
 
     """.format(
-        executable = ctx.executable._protopkg_create.short_path,
+        executable = ctx.executable._protopkg.short_path,
         file = pkg.output_file.short_path,
         address = ctx.attr.address,
+        flags = " ".join(flags),
     )
 
     ctx.actions.write(
@@ -92,9 +171,9 @@ set -euo pipefail
 
     runfiles = ctx.runfiles(
         files = [
-            ctx.executable._protopkg_create,
+            ctx.executable._protopkg,
             pkg.output_file,
-        ],
+        ] + files,
         collect_data = True,
         collect_default = True,
     )
@@ -116,8 +195,39 @@ _protopkg_create = rule(
         "address": attr.string(
             default = "localhost:1080",
         ),
-        "_protopkg_create": attr.label(
-            default = str(Label("//cmd/protopkg_create")),
+        "tls": attr.bool(
+            doc = "connect to the server with TLS (implied by the tls_* attributes; default is plaintext)",
+        ),
+        "tls_ca_cert": attr.label(
+            doc = "PEM bundle of CA certificates that verify the server (default is the system cert pool)",
+            allow_single_file = True,
+        ),
+        "tls_client_cert": attr.label(
+            doc = "PEM client certificate for mutual TLS",
+            allow_single_file = True,
+        ),
+        "tls_client_key": attr.label(
+            doc = "PEM private key of the client certificate",
+            allow_single_file = True,
+        ),
+        "tls_server_name": attr.string(
+            doc = "override the server name used to verify the server certificate",
+        ),
+        "auth_token_env": attr.string(
+            doc = "name of the environment variable that holds the bearer token for uploads",
+        ),
+        "auth_credential_helper": attr.string(
+            doc = "executable that prints a bearer token for the server address",
+        ),
+        "wait": attr.bool(
+            doc = "wait for the server operation to complete, and fail if it fails",
+        ),
+        "signatures": attr.label(
+            doc = "signatures file written by protopkg_sign, uploaded along with the packages",
+            allow_single_file = True,
+        ),
+        "_protopkg": attr.label(
+            default = str(Label("//cmd/protopkg")),
             executable = True,
             cfg = "exec",
         ),
@@ -125,14 +235,101 @@ _protopkg_create = rule(
     executable = True,
 )
 
+def _protopkg_lockfile_impl(ctx):
+    pkg = ctx.attr.pkg[ProtoPackageInfo]
+
+    script = """#!/bin/bash
+set -euo pipefail
+
+{executable} package \
+    -pkgset_file={file} \
+    -lockfile="${{BUILD_WORKSPACE_DIRECTORY}}/{lockfile}" \
+    -lockfile_mode=update
+
+    """.format(
+        executable = ctx.executable._protopkg.short_path,
+        file = pkg.output_file.short_path,
+        lockfile = ctx.file.lockfile.short_path,
+    )
+
+    ctx.actions.write(
+        ctx.outputs.executable,
+        script,
+        is_executable = True,
+    )
+
+    runfiles = ctx.runfiles(
+        files = [
+            ctx.executable._protopkg,
+            pkg.output_file,
+        ],
+    )
+
+    return [DefaultInfo(
+        files = depset([ctx.outputs.executable]),
+        runfiles = runfiles,
+        executable = ctx.outputs.executable,
+    )]
+
+_protopkg_lockfile = rule(
+    implementation = _protopkg_lockfile_impl,
+    attrs = {
+        "pkg": attr.label(
+            doc = "protopkg_package dependency",
+            mandatory = True,
+            providers = [ProtoPackageInfo],
+        ),
+        "lockfile": attr.label(
+            doc = "the lockfile to rewrite",
+            mandatory = True,
+            allow_single_file = True,
+        ),
+        "_protopkg": attr.label(
+            default = str(Label("//cmd/protopkg")),
+            executable = True,
+            cfg = "exec",
+        ),
+    },
+    executable = True,
+)
+
+# attributes of the protopkg_package macro that are forwarded to the '.create'
+# target.
+_CREATE_ATTRS = [
+    "address",
+    "tls",
+    "tls_ca_cert",
+    "tls_client_cert",
+    "tls_client_key",
+    "tls_server_name",
+    "auth_token_env",
+    "auth_credential_helper",
+    "wait",
+    "signatures",
+]
+
 def protopkg_package(**kwargs):
     name = kwargs.pop("name")
-    address = kwargs.pop("address", None)
+    create_kwargs = {k: kwargs.pop(k) for k in _CREATE_ATTRS if k in kwargs}
+    lockfile = kwargs.get("lockfile")
 
     _protopkg_package(name = name, **kwargs)
 
+    if lockfile:
+        # the lockfile update target builds the package set without checking
+        # the (stale) lockfile, via the '.unlocked' variant.
+        _protopkg_package(
+            name = name + ".unlocked",
+            **{k: v for k, v in kwargs.items() if k != "lockfile"}
+        )
+        _protopkg_lockfile(
+            name = name + ".lock",
+            pkg = name + ".unlocked",
+            lockfile = lockfile,
+        )
+
     _protopkg_create(
         name = name + ".create",
         pkg = name,
-        address = address,
+        **create_kwargs
     )